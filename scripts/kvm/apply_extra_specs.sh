@@ -0,0 +1,155 @@
+#!/bin/bash
+
+cd $(dirname $0)
+source ../cloudrc
+
+[ $# -lt 3 ] && die "$0 <vm_ID> <cpu> <memory_KiB>"
+
+ID=$1
+vm_ID=inst-$ID
+vm_cpu=$2
+vm_mem=$3
+vm_xml=$xml_dir/$vm_ID/${vm_ID}.xml
+specs=$(cat)
+[ -z "$specs" ] && specs='{}'
+cpu_policy=$(jq -r '.cpu_policy // empty' <<<$specs)
+numa_nodes=$(jq -r '.numa_nodes // 0' <<<$specs)
+hugepage_size=$(jq -r '.hugepage_size // empty' <<<$specs)
+cpu_model=$(jq -r '.cpu_model // empty' <<<$specs)
+cpu_features=$(jq -r '.cpu_features // empty' <<<$specs)
+watchdog=$(jq -r '.watchdog // empty' <<<$specs)
+virtio_queues=$(jq -r '.virtio_queues // 0' <<<$specs)
+max_cpu=$(jq -r '.max_cpu // 0' <<<$specs)
+max_memory=$(jq -r '.max_memory // 0' <<<$specs)
+
+# drop what a previous run added, resize applies the specs again on the dumped xml
+sed -i "/<cputune>/,/<\/cputune>/d; /<numatune>/,/<\/numatune>/d; /<numa>/,/<\/numa>/d; /<watchdog /d; /<model fallback=/d" $vm_xml
+sed -i "/<feature policy=/{/name='\(vmx\|svm\)'/!d}" $vm_xml
+sed -i "/<maxMemory /d; /<memory model='dimm'/,/<\/memory>/d; s#<vcpu [^>]*>#<vcpu placement='static'>#" $vm_xml
+sed -i "s#<cpu mode='[^']*'[^>]*>#<cpu mode='host-model' check='none'>#" $vm_xml
+shared_memory=false
+if grep -q "<access mode='shared'/>" $vm_xml; then
+    # vhost-user disks need the shared 2M hugepages of the wds templates
+    shared_memory=true
+    sed -i "s#<page size='[0-9]*' unit='[A-Za-z]*'/>#<page size='2048' unit='KiB'/>#" $vm_xml
+else
+    sed -i "/<memoryBacking>/,/<\/memoryBacking>/d" $vm_xml
+fi
+
+page=""
+case "$hugepage_size" in
+    2M) page="<page size='2048' unit='KiB'/>" ;;
+    1G) page="<page size='1048576' unit='KiB'/>" ;;
+esac
+if [ -n "$page" ]; then
+    if [ "$shared_memory" = "true" ]; then
+        sed -i "s#<page size='[0-9]*' unit='[A-Za-z]*'/>#$page#" $vm_xml
+    else
+        sed -i "s#</currentMemory>#</currentMemory>\n  <memoryBacking>\n    <hugepages>\n      $page\n    </hugepages>\n  </memoryBacking>#" $vm_xml
+    fi
+fi
+
+# the guest boots with vm_cpu and vm_mem and can hot-add up to the max of the flavor,
+# pinned vcpus are not hot-added
+[ "$cpu_policy" = "dedicated" -o "$max_cpu" -lt "$vm_cpu" ] && max_cpu=$vm_cpu
+if [ "$max_cpu" -gt "$vm_cpu" ]; then
+    sed -i "s#<vcpu placement='static'>[0-9]*</vcpu>#<vcpu placement='static' current='$vm_cpu'>$max_cpu</vcpu>#" $vm_xml
+fi
+let max_mem=$max_memory*1024
+hotplug_memory=false
+if [ "$max_mem" -gt "$vm_mem" ]; then
+    hotplug_memory=true
+    sed -i "s#^\( *\)<memory unit=#\1<maxMemory slots='16' unit='KiB'>$max_mem</maxMemory>\n\1<memory unit=#" $vm_xml
+fi
+
+cells=1
+[ "$numa_nodes" -gt 1 ] && cells=$numa_nodes
+let cell_cpu=$max_cpu/$cells
+let cell_mem=$vm_mem/$cells
+sed -i "s#<topology [^>]*/>#<topology sockets='$cells' cores='$cell_cpu' threads='1'/>#" $vm_xml
+
+if [ "$cpu_policy" = "dedicated" ]; then
+    # hold the lock until the xml is written so concurrent launches do not pick the same cpus
+    exec {lock_fd}>$run_dir/pinned_cpus.lock
+    flock $lock_fd
+    free_cpus=$(free_dedicated_cpus)
+    host_nodes=($(ls -d /sys/devices/system/node/node[0-9]* 2>/dev/null | sed 's#.*/node##' | sort -n))
+    [ ${#host_nodes[@]} -eq 0 ] && host_nodes=(0)
+    pcpus=()
+    cell_nodes=()
+    for (( cell=0; cell < cells; cell++ )); do
+        picked=""
+        # one host node per guest cell, spread the cells over the host nodes
+        for (( j=0; j < ${#host_nodes[@]}; j++ )); do
+            node=${host_nodes[$(( (cell + j) % ${#host_nodes[@]} ))]}
+            node_cpulist=/sys/devices/system/node/node$node/cpulist
+            if [ -f $node_cpulist ]; then
+                candidates=($(comm -12 <(expand_cpuset $(cat $node_cpulist) | sort) <(echo "$free_cpus" | sort) | sort -n))
+            else
+                candidates=($free_cpus)
+            fi
+            if [ ${#candidates[@]} -ge $cell_cpu ]; then
+                picked="${candidates[@]:0:$cell_cpu}"
+                cell_nodes[$cell]=$node
+                break
+            fi
+        done
+        [ -z "$picked" ] && die "Not enough dedicated cpus in a numa node for $vm_ID"
+        pcpus+=($picked)
+        free_cpus=$(comm -23 <(echo "$free_cpus" | sort) <(tr ' ' '\n' <<<"$picked" | sort) | sort -n)
+    done
+    cputune="  <cputune>\n"
+    for (( i=0; i < vm_cpu; i++ )); do
+        cputune="$cputune    <vcpupin vcpu='$i' cpuset='${pcpus[$i]}'/>\n"
+    done
+    cputune="$cputune    <emulatorpin cpuset='$(echo ${pcpus[@]} | tr ' ' ',')'/>\n  </cputune>"
+    numatune="  <numatune>\n    <memory mode='strict' nodeset='$(tr ' ' '\n' <<<"${cell_nodes[@]}" | sort -un | xargs | tr ' ' ',')'/>\n"
+    if [ $cells -gt 1 ]; then
+        for (( cell=0; cell < cells; cell++ )); do
+            numatune="$numatune    <memnode cellid='$cell' mode='strict' nodeset='${cell_nodes[$cell]}'/>\n"
+        done
+    fi
+    numatune="$numatune  </numatune>"
+    sed -i "s#</vcpu>#</vcpu>\n$cputune\n$numatune#" $vm_xml
+fi
+
+case "$cpu_model" in
+    ""|host-model)
+        ;;
+    host-passthrough)
+        sed -i "s#<cpu mode='host-model' check='none'>#<cpu mode='host-passthrough' check='none'>#" $vm_xml
+        ;;
+    *)
+        sed -i "s#<cpu mode='host-model' check='none'>#<cpu mode='custom' match='exact' check='partial'>\n    <model fallback='forbid'>$cpu_model</model>#" $vm_xml
+        ;;
+esac
+cpu_extra=""
+for feature in ${cpu_features//,/ }; do
+    if [ "${feature:0:1}" = "-" ]; then
+        cpu_extra="$cpu_extra    <feature policy='disable' name='${feature:1}'/>\n"
+    else
+        cpu_extra="$cpu_extra    <feature policy='require' name='$feature'/>\n"
+    fi
+done
+# memory hot-add plugs dimms into a guest numa cell, so it needs one even with a single node
+if [ $cells -gt 1 -o "$hotplug_memory" = "true" ]; then
+    mem_access=""
+    [ "$shared_memory" = "true" ] && mem_access=" memAccess='shared'"
+    cpu_extra="$cpu_extra    <numa>\n"
+    for (( cell=0; cell < cells; cell++ )); do
+        first=$(( cell * cell_cpu ))
+        last=$(( first + cell_cpu - 1 ))
+        cell_cpus=$first
+        [ $last -gt $first ] && cell_cpus=$first-$last
+        cpu_extra="$cpu_extra      <cell id='$cell' cpus='$cell_cpus' memory='$cell_mem' unit='KiB'$mem_access/>\n"
+    done
+    cpu_extra="$cpu_extra    </numa>\n"
+fi
+[ -n "$cpu_extra" ] && sed -i "s#^ *</cpu>#${cpu_extra}  </cpu>#" $vm_xml
+
+[ -n "$watchdog" ] && sed -i "s#^ *</devices>#    <watchdog model='i6300esb' action='$watchdog'/>\n  </devices>#" $vm_xml
+if [ "$virtio_queues" -gt 0 ]; then
+    sed -i "s#<driver name='vhost' queues='[0-9]*'/>#<driver name='vhost' queues='$virtio_queues'/>#" $vm_xml
+fi
+# attach_vm_nic.sh picks the queue number of new nics from here
+echo "$specs" >$xml_dir/$vm_ID/extra_specs.json
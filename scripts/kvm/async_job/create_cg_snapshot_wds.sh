@@ -3,13 +3,14 @@
 cd $(dirname $0)
 source ../../cloudrc
 
-# Parameters: cg_ID, snapshot_ID, snapshot_name, wds_cg_id
-[ $# -lt 4 ] && echo "$0 <cg_ID> <snapshot_ID> <snapshot_name> <wds_cg_id>" && exit -1
+# Parameters: cg_ID, snapshot_ID, snapshot_name, wds_cg_id, quiesce_guests
+[ $# -lt 4 ] && echo "$0 <cg_ID> <snapshot_ID> <snapshot_name> <wds_cg_id> [quiesce_guests]" && exit -1
 
 cg_ID=$1
 snapshot_ID=$2
 snapshot_name=$3
 wds_cg_id=$4
+quiesce=$5
 
 state='error'
 wds_snap_id=''
@@ -35,7 +36,16 @@ get_wds_token
 # 创建一致性组快照
 wds_snapshot_name="cg_snap_${snapshot_ID}"
 log_debug $cg_ID "create_cg_snapshot_wds: Creating CG snapshot, wds_snapshot_name=$wds_snapshot_name"
+# freeze the filesystems of all guests of the group so the snapshot is consistent across them
+if [ -n "$quiesce" ]; then
+    trap "quiesce_guests '$quiesce' thaw" EXIT
+    quiesce_guests "$quiesce" freeze
+fi
 result=$(wds_curl "POST" "api/v2/sync/block/cg_snaps/" "{\"description\": \"$snapshot_name\", \"name\": \"$wds_snapshot_name\", \"cg_id\": \"$wds_cg_id\"}")
+if [ -n "$quiesce" ]; then
+    quiesce_guests "$quiesce" thaw
+    trap - EXIT
+fi
 log_debug $cg_ID "create_cg_snapshot_wds: WDS API response: $result"
 ret_code=$(echo $result | jq -r '.ret_code // empty')
 message=$(echo $result | jq -r '.message // empty')
@@ -4,7 +4,7 @@ cd $(dirname $0)
 source ../../cloudrc
 
 # backup.ID, backup.UUID, volume_ID, wdsUUID, wdsOriginPoolID
-[ $# -lt 6 ] && echo "$0 <task_ID> <backup_ID> <backup_UUID> <backup_Name> <volume_ID> <wdsUUID> <wdsOriginPoolID> <wdsPoolID>" && exit -1
+[ $# -lt 6 ] && echo "$0 <task_ID> <backup_ID> <backup_UUID> <backup_Name> <volume_ID> <wdsUUID> <wdsOriginPoolID> <wdsPoolID> <quiesce_guests>" && exit -1
 
 task_ID=$1
 backup_ID=$2
@@ -14,11 +14,8 @@ volume_ID=$5
 wdsUUID=$6  
 wdsOriginPoolID=$7
 
-if [ $# -lt 8 ]; then
-    wdsPoolID=$wdsOriginPoolID
-else
-    wdsPoolID=$8
-fi
+wdsPoolID=${8:-$wdsOriginPoolID}
+quiesce=$9
 
 state='error'
 snapshot_id=''
@@ -28,8 +25,16 @@ middle_snapshot_id=""
 
 get_wds_token
 
-# 1. take the snapshot of the volume
+# 1. take the snapshot of the volume, with the filesystems of the guest frozen if it has an agent
+if [ -n "$quiesce" ]; then
+    trap "quiesce_guests '$quiesce' thaw" EXIT
+    quiesce_guests "$quiesce" freeze
+fi
 snapshot_ret=$(wds_curl POST "api/v2/sync/block/snaps/" "{\"description\": \"$backup_ID\", \"name\": \"$wds_backup_name\",  \"volume_id\": \"$wdsUUID\"}")
+if [ -n "$quiesce" ]; then
+    quiesce_guests "$quiesce" thaw
+    trap - EXIT
+fi
 
 read -d'\n' -r snapshot_id ret_code message < <(jq -r ".id, .ret_code, .message" <<<$snapshot_ret)
 if [ "$ret_code" != "0" ]; then 
@@ -0,0 +1,37 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../../cloudrc
+[ $# -lt 3 ] && echo "$0 <vm_ID> <cmd_ID> <timeout>" && exit 1
+
+ID=$1
+vm_ID=inst-$ID
+cmd_ID=$2
+timeout=$3
+# path and arg of the command as json, base64 encoded on stdin
+exec_args=$(cat | base64 -d)
+max_output=65536
+
+request=$(jq -c '{"execute": "guest-exec", "arguments": {"path": .path, "arg": (.arg // []), "capture-output": true}}' <<< "$exec_args")
+ret=$(virsh qemu-agent-command $vm_ID "$request" 2>&1)
+pid=$(jq -r '.return.pid // empty' <<< "$ret" 2>/dev/null)
+if [ -z "$pid" ]; then
+    log_debug $vm_ID "guest exec of command $cmd_ID failed: $ret"
+    echo "|:-COMMAND-:| $(basename $0) '$cmd_ID' 'error' '-1' '$(echo -n "$ret" | head -c $max_output | base64 -w 0)'"
+    exit 1
+fi
+
+state='timeout'
+exitcode=-1
+output=''
+for i in $(seq $timeout); do
+    sleep 1
+    ret=$(virsh qemu-agent-command $vm_ID "{\"execute\": \"guest-exec-status\", \"arguments\": {\"pid\": $pid}}")
+    [ "$(jq -r '.return.exited' <<< "$ret")" = "true" ] || continue
+    state='done'
+    exitcode=$(jq -r '.return.exitcode // -1' <<< "$ret")
+    output=$({ jq -r '.return."out-data" // empty' <<< "$ret" | base64 -d; jq -r '.return."err-data" // empty' <<< "$ret" | base64 -d; } | head -c $max_output | base64 -w 0)
+    break
+done
+log_debug $vm_ID "guest exec of command $cmd_ID finished as $state with exit code $exitcode"
+echo "|:-COMMAND-:| $(basename $0) '$cmd_ID' '$state' '$exitcode' '$output'"
@@ -22,6 +22,8 @@ if [ $? -ne 0 ]; then
     template=$template_dir/interface.xml
     interface_xml=$xml_dir/$vm_ID/$nic_name.xml
     let queue_num=($(virsh dominfo $vm_ID | grep 'CPU(s)' | awk '{print $2}')+1)/2
+    virtio_queues=$(jq -r '.virtio_queues // 0' $xml_dir/$vm_ID/extra_specs.json 2>/dev/null)
+    [ -n "$virtio_queues" ] && [ "$virtio_queues" -gt 0 ] && queue_num=$virtio_queues
     cp $template $interface_xml
     sed -i "s/VM_MAC/$mac/g; s/VM_BRIDGE/$vm_br/g; s/VM_VTEP/$nic_name/g; s/QUEUE_NUM/$queue_num/g" $interface_xml
     virsh attach-device $vm_ID $interface_xml --live --persistent
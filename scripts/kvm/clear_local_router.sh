@@ -28,6 +28,8 @@ for link in $ns_links; do
     [ -n "$dnsmasq_pid" ] && kill -9 $dnsmasq_pid
 done
 
+meta_pid=$(cat $router_dir/$router/metadata/haproxy.pid 2>/dev/null)
+[ -n "$meta_pid" ] && kill $meta_pid
 ip netns exec $router ip link set lo down
 suffix=$ID
 ip netns exec router-0 ip link del int-$suffix
@@ -0,0 +1,46 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 4 ] && die "$0 <router> <nat_id> <ext_ip> <mark_id>"
+
+ID=$1
+router=router-$1
+nat_id=$2
+ext_addr=$3
+ext_ip=${3%/*}
+mark_id=$(($4 % 2147483647))
+nat_data=$(cat)
+
+[ -z "$ID" -o "$router" = "router-0" ] && exit 1
+[ ! -f "/var/run/netns/$router" ] && exit 0
+len=$(jq length <<< $nat_data)
+i=0
+while [ $i -lt $len ]; do
+    read -d'\n' -r vni network nat_ip < <(jq -r ".[$i].vni, .[$i].network, .[$i].nat_ip" <<<$nat_data)
+    let i=$i+1
+    nat_ip=${nat_ip%/*}
+    ip netns exec $router ip rule del from $network pref 30001
+    ip netns exec $router ip route flush table $(( 100000 + $vni ))
+    ip netns exec $router ip neigh del $nat_ip dev ns-$vni
+    ip netns exec $router ip addr del $nat_ip/32 dev ns-$vni
+    ip netns exec $router iptables -t nat -D POSTROUTING -s $network -m set ! --match-set nonat dst -j SNAT --to-source $ext_ip
+    ip netns exec $router tc filter del dev ns-$vni protocol ip parent 1:0 prio $mark_id handle $mark_id fw flowid 1:$mark_id
+    ip netns exec $router tc class del dev ns-$vni parent 1: classid 1:$mark_id
+done
+ip netns exec $router ip rule show | grep -q "pref 30001\|^30001:"
+[ $? -ne 0 ] && ip netns exec $router ip rule del from all lookup main suppress_prefixlength 0 pref 30000
+
+ext_dev=$(ip netns exec $router ip -o addr | grep "$ext_addr" | awk '{print $2}')
+[ -z "$ext_dev" ] && exit 0
+ip netns exec $router ip addr del $ext_addr dev $ext_dev
+ip netns exec $router iptables -t mangle -D PREROUTING -i $ext_dev -d $ext_ip -j MARK --set-mark $mark_id
+let mark_id=$mark_id+2147483647
+ip netns exec $router tc filter del dev $ext_dev protocol ip parent 1:0 prio $mark_id u32 match ip src $ext_ip/32 flowid 1:$mark_id
+ip netns exec $router tc class del dev $ext_dev parent 1: classid 1:$mark_id
+ip netns exec $router ip addr show $ext_dev | grep 'inet '
+if [ $? -ne 0 ]; then
+    ip netns exec $router ip link del $ext_dev
+fi
+exit 0
@@ -0,0 +1,26 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 1 ] && die "$0 <peering_id>"
+
+peering_id=$1
+peering_data=$(cat)
+table=$(( 2000 + $peering_id ))
+
+for n in 0 1; do
+    p=$(( 1 - $n ))
+    ID=$(jq -r ".routers[$n].id" <<<$peering_data)
+    router=router-$ID
+    [ ! -f "/var/run/netns/$router" ] && continue
+    for network in $(jq -r ".routers[$p].subnets[].network" <<<$peering_data); do
+        ip netns exec $router ip route del $network
+        ip netns exec $router ipset del nonat $network
+        ip netns exec router-0 ip rule del iif int-$ID to $network lookup $table
+    done
+    ip netns exec $router iptables -S FORWARD | grep "peering-$peering_id\"" | sed 's/^-A/-D/' | while read rule; do
+        eval ip netns exec $router iptables $rule
+    done
+done
+ip netns exec router-0 ip route flush table $table
@@ -0,0 +1,15 @@
+#!/bin/bash
+
+cd $(dirname $0)
+source ../cloudrc
+
+[ $# -lt 2 ] && die "$0 <vm_ID> <lines>"
+
+ID=$1
+vm_ID=inst-$ID
+lines=$2
+# the serial console of the templates logs here, guests launched before have no log
+log_file=/var/log/libvirt/qemu/$vm_ID-serial.log
+output=""
+[ -f "$log_file" ] && output=$(tail -n $lines $log_file | tail -c 65536 | base64 -w 0)
+echo "|:-COMMAND-:| $(basename $0) '$ID' '$lines' '$output'"
@@ -9,7 +9,10 @@ router=$1
 
 [ "${router/router-/}" = "$router" ] && router=router-$1
 [ -z "$router" -o "$router" = "router-0" ] && exit 1
-[ -f "/var/run/netns/$router" ] && exit 0
+if [ -f "/var/run/netns/$router" ]; then
+    ./create_metadata_proxy.sh $router
+    exit 0
+fi
 
 ip netns add $router
 #ip netns exec $router iptables -A INPUT -m mark --mark 0x1/0xffff -j ACCEPT
@@ -58,3 +61,4 @@ ip netns exec $router iptables -t nat -C POSTROUTING -m set --match-set nonat sr
 [ $? -ne 0 ] && ip netns exec $router iptables -t nat -A POSTROUTING -m set --match-set nonat src -m set ! --match-set nonat dst -j SNAT --to-source $local_ip
 
 ip netns exec $router bash -c "echo 1 >/proc/sys/net/ipv4/ip_forward"
+./create_metadata_proxy.sh $router
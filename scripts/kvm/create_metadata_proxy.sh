@@ -0,0 +1,57 @@
+#!/bin/bash
+
+cd $(dirname $0)
+source ../cloudrc
+
+[ $# -lt 1 ] && die "$0 <router>"
+
+router=$1
+[ "${router/router-/}" = "$router" ] && router=router-$1
+[ -z "$router" -o "$router" = "router-0" ] && exit 1
+# the live metadata service is optional, guests still get the config drive without it
+[ -z "$metadata_server" -o -z "$metadata_secret" ] && exit 0
+
+suffix=${router/router-/}
+meta_ip=169.254.169.254
+meta_port=8775
+meta_dir=$router_dir/$router/metadata
+mkdir -p $meta_dir
+token=$(echo -n "$suffix" | openssl dgst -sha256 -hmac "$metadata_secret" | awk '{print $NF}')
+
+cat >$meta_dir/haproxy.conf <<EOT
+global
+    log /dev/log local0 notice
+    maxconn 256
+    pidfile $meta_dir/haproxy.pid
+    user haproxy
+    group haproxy
+
+defaults
+    log global
+    mode http
+    option httplog
+    timeout connect 5s
+    timeout client 30s
+    timeout server 30s
+
+frontend metadata
+    bind $meta_ip:$meta_port
+    http-request set-header X-Forwarded-For %[src]
+    http-request set-header X-Router-ID $suffix
+    http-request set-header X-Router-Token $token
+    default_backend metadata_server
+
+backend metadata_server
+    server metadata $metadata_server
+EOT
+
+ip netns exec $router ip addr show dev lo | grep -q "\<$meta_ip/32\>"
+[ $? -ne 0 ] && ip netns exec $router ip addr add $meta_ip/32 dev lo
+ip netns exec $router iptables -t nat -C PREROUTING -d $meta_ip/32 -p tcp --dport 80 -j DNAT --to-destination $meta_ip:$meta_port
+[ $? -ne 0 ] && ip netns exec $router iptables -t nat -A PREROUTING -d $meta_ip/32 -p tcp --dport 80 -j DNAT --to-destination $meta_ip:$meta_port
+ip netns exec $router iptables -C INPUT -d $meta_ip/32 -p tcp --dport $meta_port -j ACCEPT
+[ $? -ne 0 ] && ip netns exec $router iptables -I INPUT -d $meta_ip/32 -p tcp --dport $meta_port -j ACCEPT
+
+haproxy_pid=$(cat $meta_dir/haproxy.pid 2>/dev/null)
+[ -n "$haproxy_pid" ] && ip netns exec $router haproxy -D -f $meta_dir/haproxy.conf -sf $haproxy_pid -p $meta_dir/haproxy.pid
+[ -z "$haproxy_pid" -o $? -ne 0 ] && ip netns exec $router haproxy -D -p $meta_dir/haproxy.pid -f $meta_dir/haproxy.conf
@@ -0,0 +1,91 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 7 ] && die "$0 <router> <nat_id> <ext_ip> <ext_gw> <ext_vlan> <mark_id> <inbound> <outbound>"
+
+ID=$1
+router=router-$1
+nat_id=$2
+ext_cidr=$3
+ext_ip=${3%/*}
+ext_gw=${4%/*}
+ext_vlan=$5
+mark_id=$(($6 % 2147483647))
+inbound=$7
+outbound=$8
+nat_data=$(cat)
+
+[ -z "$ID" -o "$router" = "router-0" -o -z "$ext_ip" ] && exit 1
+len=$(jq length <<< $nat_data)
+i=0
+while [ $i -lt $len ]; do
+    read -d'\n' -r vni gateway < <(jq -r ".[$i].vni, .[$i].gateway" <<<$nat_data)
+    let i=$i+1
+    cat /proc/net/dev | grep -q "\<br$vni\>:"
+    if [ $? -ne 0 ]; then
+        ./create_link.sh $vni
+        ./set_subnet_gw.sh $ID $vni $gateway
+    fi
+done
+
+rtID=$(( $ext_vlan % 250 + 2 ))
+table=fip-$ext_vlan
+rt_file=/etc/iproute2/rt_tables
+grep "^$rtID $table" $rt_file
+if [ $? -ne 0 ]; then
+    for i in {1..250}; do
+        grep "^$rtID\s" $rt_file
+	[ $? -ne 0 ] && break
+        rtID=$(( ($rtID + 17) % 250 + 2 ))
+    done
+    echo "$rtID $table" >>$rt_file
+fi
+suffix=${ID}-${ext_vlan}
+ext_dev=te-$suffix
+./create_veth.sh $router ext-$suffix te-$suffix
+
+ip netns exec $router ip addr add $ext_cidr dev $ext_dev
+ip netns exec $router ip route replace default via $ext_gw table $table
+ip netns exec $router ip -o addr | grep "ns-.* inet " | awk '{print $2, $4}' | while read ns_link ns_gw; do
+    ip_net=$(ipcalc -b $ns_gw | grep Network | awk '{print $2}')
+    ip netns exec $router ip route add $ip_net dev $ns_link table $table
+done
+ip netns exec $router ip rule show | grep -q "lookup main suppress_prefixlength 0"
+[ $? -ne 0 ] && ip netns exec $router ip rule add from all lookup main suppress_prefixlength 0 pref 30000
+i=0
+while [ $i -lt $len ]; do
+    read -d'\n' -r vni network nat_ip < <(jq -r ".[$i].vni, .[$i].network, .[$i].nat_ip" <<<$nat_data)
+    let i=$i+1
+    ip netns exec $router ip addr add ${nat_ip%/*}/32 dev ns-$vni
+    ip netns exec $router ip rule del from $network pref 30001
+    ip netns exec $router ip rule add from $network lookup $table pref 30001
+    ip netns exec $router iptables -t nat -C POSTROUTING -s $network -m set ! --match-set nonat dst -j SNAT --to-source $ext_ip
+    [ $? -ne 0 ] && ip netns exec $router iptables -t nat -A POSTROUTING -s $network -m set ! --match-set nonat dst -j SNAT --to-source $ext_ip
+    if [ "$inbound" -gt 0 ]; then
+        ip netns exec $router tc qdisc add dev ns-$vni root handle 1: htb default 10
+        ip netns exec $router tc class add dev ns-$vni parent 1: classid 1:$mark_id htb rate ${inbound}mbit burst ${inbound}kbit
+        ip netns exec $router tc filter add dev ns-$vni protocol ip parent 1:0 prio $mark_id handle $mark_id fw flowid 1:$mark_id
+    else
+        ip netns exec $router tc filter del dev ns-$vni protocol ip parent 1:0 prio $mark_id handle $mark_id fw flowid 1:$mark_id
+        ip netns exec $router tc class del dev ns-$vni parent 1: classid 1:$mark_id
+    fi
+done
+async_exec ip netns exec $router arping -c 1 -A -U -I $ext_dev $ext_ip
+
+if [ "$inbound" -gt 0 ]; then
+    ip netns exec $router iptables -t mangle -C PREROUTING -i $ext_dev -d $ext_ip -j MARK --set-mark $mark_id
+    [ $? -ne 0 ] && ip netns exec $router iptables -t mangle -I PREROUTING -i $ext_dev -d $ext_ip -j MARK --set-mark $mark_id
+else
+    ip netns exec $router iptables -t mangle -D PREROUTING -i $ext_dev -d $ext_ip -j MARK --set-mark $mark_id
+fi
+let mark_id=$mark_id+2147483647
+if [ "$outbound" -gt 0 ]; then
+    ip netns exec $router tc qdisc add dev $ext_dev root handle 1: htb default 10
+    ip netns exec $router tc class add dev $ext_dev parent 1: classid 1:$mark_id htb rate ${outbound}mbit burst ${outbound}kbit
+    ip netns exec $router tc filter add dev $ext_dev protocol ip parent 1:0 prio $mark_id u32 match ip src $ext_ip/32 flowid 1:$mark_id
+else
+    ip netns exec $router tc filter del dev $ext_dev protocol ip parent 1:0 prio $mark_id u32 match ip src $ext_ip/32 flowid 1:$mark_id
+    ip netns exec $router tc class del dev $ext_dev parent 1: classid 1:$mark_id
+fi
@@ -16,4 +16,4 @@ ssh_cmd="ip netns exec $router ssh -i $cland_private_key -f -n -N -T -o StrictHo
 sed -i "\#$ssh_cmd#d" $notify_sh
 sed -i "/\"MASTER\")/a $ssh_cmd" $notify_sh
 eval $ssh_cmd &>/dev/null
-[ $? -eq 0 ] && echo "|:-COMMAND-:| `basename $0` '$portmap_remote_ip' '$rport'"
+[ $? -eq 0 ] && echo "|:-COMMAND-:| `basename $0` '$portmap_remote_ip' '$rport' '$1'"
@@ -0,0 +1,51 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 1 ] && die "$0 <peering_id>"
+
+peering_id=$1
+peering_data=$(cat)
+table=$(( 2000 + $peering_id ))
+router1=$(jq -r '.routers[0].id' <<<$peering_data)
+router2=$(jq -r '.routers[1].id' <<<$peering_data)
+[ ! -f "/var/run/netns/router-$router1" -a ! -f "/var/run/netns/router-$router2" ] && exit 0
+
+for n in 0 1; do
+    ID=$(jq -r ".routers[$n].id" <<<$peering_data)
+    nsubnets=$(jq -r ".routers[$n].subnets | length" <<<$peering_data)
+    i=0
+    while [ $i -lt $nsubnets ]; do
+        read -d'\n' -r vni gateway < <(jq -r ".routers[$n].subnets[$i].vni, .routers[$n].subnets[$i].gateway" <<<$peering_data)
+        let i=$i+1
+        cat /proc/net/dev | grep -q "\<br$vni\>:"
+        if [ $? -ne 0 ]; then
+            ./create_link.sh $vni
+            ./set_subnet_gw.sh $ID $vni $gateway
+        fi
+    done
+done
+
+for n in 0 1; do
+    p=$(( 1 - $n ))
+    ID=$(jq -r ".routers[$n].id" <<<$peering_data)
+    peer_ID=$(jq -r ".routers[$p].id" <<<$peering_data)
+    router=router-$ID
+    [ ! -f "/var/run/netns/$router" -o ! -f "/var/run/netns/router-$peer_ID" ] && continue
+    via_ip=$(ip netns exec $router ip route show default | awk '/via/ {print $3}' | head -1)
+    peer_local=$(ip netns exec router-$peer_ID ip -o -4 addr show ti-$peer_ID | awk '{print $4}' | head -1)
+    peer_local=${peer_local%/*}
+    [ -z "$via_ip" -o -z "$peer_local" ] && continue
+    for network in $(jq -r ".routers[$p].subnets[].network" <<<$peering_data); do
+        ip netns exec $router ip route replace $network via $via_ip
+        ip netns exec $router ipset add nonat $network -exist
+        ip netns exec $router iptables -C FORWARD -o ti-$ID -d $network -m comment --comment "peering-$peering_id" -j ACCEPT
+        [ $? -ne 0 ] && ip netns exec $router iptables -I FORWARD -o ti-$ID -d $network -m comment --comment "peering-$peering_id" -j ACCEPT
+        ip netns exec $router iptables -C FORWARD -i ti-$ID -s $network -m comment --comment "peering-$peering_id" -j ACCEPT
+        [ $? -ne 0 ] && ip netns exec $router iptables -I FORWARD -i ti-$ID -s $network -m comment --comment "peering-$peering_id" -j ACCEPT
+        ip netns exec router-0 ip rule show | grep -q "iif int-$ID to $network lookup $table\>"
+        [ $? -ne 0 ] && ip netns exec router-0 ip rule add iif int-$ID to $network lookup $table
+        ip netns exec router-0 ip route replace $network via $peer_local table $table
+    done
+done
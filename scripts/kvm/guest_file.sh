@@ -0,0 +1,21 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 3 ] && die "$0 <vm_ID> <cmd_ID> <base64_path>"
+
+vm_ID=inst-$1
+cmd_ID=$2
+path=$(echo $3 | base64 -d)
+# the file content, base64 encoded on stdin, is what guest-file-write takes
+content=$(cat | tr -d '\n')
+
+state='error'
+handle=$(virsh qemu-agent-command $vm_ID "$(jq -nc --arg path "$path" '{"execute": "guest-file-open", "arguments": {"path": $path, "mode": "w"}}')" | jq -r '.return // empty')
+if [ -n "$handle" ]; then
+    virsh qemu-agent-command $vm_ID "{\"execute\": \"guest-file-write\", \"arguments\": {\"handle\": $handle, \"buf-b64\": \"$content\"}}" >/dev/null && state='done'
+    virsh qemu-agent-command $vm_ID "{\"execute\": \"guest-file-close\", \"arguments\": {\"handle\": $handle}}" >/dev/null
+fi
+log_debug $vm_ID "write of file $path by command $cmd_ID is $state"
+echo "|:-COMMAND-:| $(basename $0) '$cmd_ID' '$state' '0' ''"
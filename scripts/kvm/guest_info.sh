@@ -0,0 +1,14 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 1 ] && die "$0 <vm_ID>"
+
+ID=$1
+vm_ID=inst-$ID
+osinfo=$(virsh qemu-agent-command $vm_ID '{"execute": "guest-get-osinfo"}')
+[ $? -ne 0 ] && die "Failed to query guest agent of $vm_ID"
+os=$(jq -r '.return."pretty-name" // .return.name // empty' <<< "$osinfo")
+ips=$(virsh qemu-agent-command $vm_ID '{"execute": "guest-network-get-interfaces"}' | jq -r '[.return[] | select(.name != "lo") | ."ip-addresses"[]? | ."ip-address" | select(startswith("127.") or startswith("fe80") or . == "::1" | not)] | join(",")')
+echo "|:-COMMAND-:| $(basename $0) '$ID' '$(echo -n "$os" | base64 -w 0)' '$ips'"
@@ -0,0 +1,45 @@
+#!/bin/bash
+
+cd $(dirname $0)
+source ../cloudrc
+
+[ $# -lt 3 ] && die "$0 <vm_ID> <cpu> <memory>"
+
+ID=$1
+vm_ID=inst-$1
+vm_cpu=$2
+vm_mem=${3%[m|M]}
+state=error
+
+# hot-add vcpus and a memory dimm for the difference, the guest must stay within the max of its flavor
+if virsh domstate $vm_ID | grep -q running; then
+    state=running
+    cur_cpu=$(virsh vcpucount $vm_ID --live --active)
+    if [ "$vm_cpu" -gt "$cur_cpu" ]; then
+        virsh setvcpus $vm_ID $vm_cpu --live --config
+        [ $? -ne 0 ] && echo "Failed to hot-add vcpus to $vm_ID"
+    fi
+    cur_mem=$(virsh dumpxml $vm_ID | sed -n "s#^ *<memory unit='KiB'>\([0-9]*\)</memory>#\1#p")
+    let add_mem=$vm_mem*1024-$cur_mem
+    if [ "$add_mem" -gt 0 ]; then
+        dimm_xml=$xml_dir/$vm_ID/dimm-$(date +'%s').xml
+        cat >$dimm_xml <<EOT
+<memory model='dimm'>
+  <target>
+    <size unit='KiB'>$add_mem</size>
+    <node>0</node>
+  </target>
+</memory>
+EOT
+        virsh attach-device $vm_ID $dimm_xml --live --config
+        [ $? -ne 0 ] && echo "Failed to hot-add memory to $vm_ID"
+        rm -f $dimm_xml
+    fi
+    vm_xml=$xml_dir/$vm_ID/$vm_ID.xml
+    virsh dumpxml --security-info $vm_ID 2>/dev/null | sed "s/autoport='yes'/autoport='no'/g" >$vm_xml.dump && mv -f $vm_xml.dump $vm_xml
+fi
+# report what the guest really has, a failed hot-add keeps the old size
+vm_cpu=$(virsh vcpucount $vm_ID --live --active 2>/dev/null || echo 0)
+vm_mem=$(virsh dumpxml $vm_ID | sed -n "s#^ *<memory unit='KiB'>\([0-9]*\)</memory>#\1#p")
+let vm_mem=${vm_mem:-0}/1024
+echo "|:-COMMAND-:| $(basename $0) '$ID' '$vm_cpu' '$vm_mem' '$state'"
@@ -185,6 +185,12 @@ else
     -e "s/INSTANCE_UUID/$instance_uuid/g" \
     $vm_xml
 fi
+extra_specs=$(jq -c '.extra_specs // {}' <<<$metadata)
+./apply_extra_specs.sh "$ID" "$vm_cpu" "$vm_mem" <<<$extra_specs
+if [ $? -ne 0 ]; then
+    echo "|:-COMMAND-:| $(basename $0) '$ID' '$state' '$SCI_CLIENT_ID' 'init' '$snapshot'"
+    exit -1
+fi
 
 virsh define $vm_xml
 ./generate_vm_instance_map.sh add $vm_ID
@@ -281,11 +281,17 @@ function calc_resource()
     let disk=$disk/1000*1000
     let total_disk=$total_disk/1000*1000
     old_resource_list=$(cat old_resource_list 2>/dev/null)
-    resource_list="'$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state'"
-    echo "'$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state'" >/opt/cloudland/run/old_resource_list
+    # capabilities for flavor extra specs
+    numa_nodes=$(ls -d /sys/devices/system/node/node[0-9]* 2>/dev/null | wc -l)
+    dedicated_cpus=$(free_dedicated_cpus | wc -l)
+    hp_1g_free=$(cat /sys/kernel/mm/hugepages/hugepages-1048576kB/free_hugepages 2>/dev/null || echo 0)
+    resource_list="'$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state' '$dedicated_cpus' '$hp_2m_free' '$hp_1g_free'"
+    echo "$resource_list" >/opt/cloudland/run/old_resource_list
     [ "$resource_list" = "$old_resource_list" ] && return
     cpu_model=$(lscpu | grep 'Model name:' | cut -d: -f2 | xargs)
-    echo "|:-COMMAND-:| hyper_status.sh '$SCI_CLIENT_ID' '$HOSTNAME' '$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state' '$vtep_ip' '$ZONE_NAME' '$cpu_over_ratio' '$mem_over_ratio' '$disk_over_ratio' '$cpu_model'"
+    cpu_models=$(sudo virsh domcapabilities 2>/dev/null | grep "usable='yes'" | sed 's#.*>\(.*\)</model>#\1#' | xargs | tr ' ' ',')
+    cpu_flags=$(grep -m1 '^flags' /proc/cpuinfo | cut -d: -f2 | xargs | tr ' ' ',')
+    echo "|:-COMMAND-:| hyper_status.sh '$SCI_CLIENT_ID' '$HOSTNAME' '$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state' '$vtep_ip' '$ZONE_NAME' '$cpu_over_ratio' '$mem_over_ratio' '$disk_over_ratio' '$cpu_model' '$numa_nodes' '$dedicated_cpus' '$hp_2m_free' '$hp_1g_free' '$cpu_models' '$cpu_flags'"
 }
 
 calc_resource
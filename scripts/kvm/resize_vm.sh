@@ -10,6 +10,8 @@ vm_ID=inst-$1
 vm_cpu=$2
 vm_mem=$3
 state=error
+md=$(cat)
+extra_specs=$(echo $md | base64 -d)
 
 # Graceful shutdown first, fall back to hard stop after 30s timeout
 ./action_vm.sh $ID stop
@@ -20,8 +22,10 @@ let vm_mem=${vm_mem%[m|M]}*1024
 
 # backup vm xml
 vm_xml=$xml_dir/$vm_ID/${vm_ID}.xml
-mv $vm_xml $vm_xml-$(date +'%s.%N')
+vm_xml_backup=$vm_xml-$(date +'%s.%N')
+mv $vm_xml $vm_xml_backup
 virsh dumpxml $vm_ID >$vm_xml
+[ -z "$extra_specs" ] && extra_specs=$(cat $xml_dir/$vm_ID/extra_specs.json 2>/dev/null)
 
 virsh undefine --nvram $vm_ID
 if [ $? -ne 0 ]; then
@@ -31,6 +35,11 @@ fi
 # edit vm xml
 sed_cmd="s#>.*</memory>#>$vm_mem</memory>#g; s#>.*</currentMemory>#>$vm_mem</currentMemory>#g; s#>.*</vcpu>#>$vm_cpu</vcpu>#g; s#\(<topology[^>]*\)cores='[0-9]*'#\1cores='$vm_cpu'#g"
 sed -i "$sed_cmd" $vm_xml
+./apply_extra_specs.sh "$ID" "$vm_cpu" "$vm_mem" <<<$extra_specs
+if [ $? -ne 0 ]; then
+    # keep the old size if the specs can not be applied on this host
+    cp $vm_xml_backup $vm_xml
+fi
 virsh define $vm_xml
 virsh autostart $vm_ID
 virsh start $vm_ID
@@ -3,13 +3,14 @@
 cd $(dirname $0)
 source ../cloudrc
 
-[ $# -lt 5 ] && echo "$0 <volume_ID> <volume_UUID> <size> <booting> <vm_ID>" && exit -1
+[ $# -lt 5 ] && echo "$0 <volume_ID> <volume_UUID> <size> <booting> <vm_ID> [online]" && exit -1
 
 vol_ID=$1
 vol_UUID=$2
 vol_size=$3
 booting=$4
 vm_ID=$5
+online=${6:-false}
 if [ "$booting" = "false" ]; then
     vol_path="$volume_dir/volume-${vol_ID}.disk"
 else
@@ -20,10 +21,6 @@ if [ ! -f "$vol_path" ]; then
     exit -1
 fi
 
-# if volume attached to a VM, stop the VM first
-if [ "$vm_ID" != "0" ]; then
-    ./action_vm.sh $vm_ID hard_stop
-fi
 old_size=$(qemu-img info -U $vol_path | grep 'virtual size:' | cut -d' ' -f5 | tr -d '(')
 let new_size=$vol_size*1024*1024*1024
 
@@ -33,6 +30,21 @@ if [ "$old_size" -ge "$new_size" ]; then
     exit -1
 fi
 
+# grow the disk under the running guest if asked to, otherwise stop the VM first
+if [ "$vm_ID" != "0" -a "$online" = "true" ] && virsh domstate inst-$vm_ID | grep -q running; then
+    virsh blockresize inst-$vm_ID $vol_path "${vol_size}G"
+    if [ $? -eq 0 ]; then
+        echo "|:-COMMAND-:| resize_volume '$vol_ID' 'success'"
+    else
+        echo "|:-COMMAND-:| resize_volume '$vol_ID' 'error'"
+        exit -1
+    fi
+    exit 0
+fi
+if [ "$vm_ID" != "0" ]; then
+    ./action_vm.sh $vm_ID hard_stop
+fi
+
 # resize the volume
 qemu-img resize -q $vol_path "${vol_size}G"
 if [ $? -eq 0 ]; then
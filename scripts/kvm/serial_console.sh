@@ -0,0 +1,41 @@
+#!/bin/bash
+
+cd $(dirname $0)
+source ../cloudrc
+
+[ $# -lt 3 ] && die "$0 <vm_ID> <session_ID> <timeout>"
+
+ID=${1##inst-}
+vm_ID=inst-$ID
+session_ID=$2
+timeout=$3
+read -r secret
+port_min=16000
+port_max=16999
+current_vm=$vm_ID
+vm_rescue=$(virsh list --all | grep "\<$vm_ID-" | awk '{print $2}')
+[ -n "$vm_rescue" ] && current_vm=$vm_rescue
+pty=$(virsh ttyconsole $current_vm 2>/dev/null)
+local_ip=$(ifconfig $vnc_interface | grep 'inet ' | awk '{print $2}')
+port=0
+if [ -n "$pty" ]; then
+    for (( p=port_min; p <= port_max; p++ )); do
+        ss -ltn | grep -q ":$p " && continue
+        port=$p
+        break
+    done
+fi
+if [ "$port" -gt 0 -a -n "$secret" ]; then
+    # one connection to the serial pty, gone when the session times out or idles for 10 minutes,
+    # the port is reachable by others on the network so the connection has to pass the session secret
+    secret_file=$run_dir/serial_console_$session_ID
+    (umask 077; echo "$secret" > $secret_file)
+    (
+        timeout $timeout socat -T 600 TCP-LISTEN:$port,bind=$local_ip,reuseaddr EXEC:"$PWD/serial_console_attach.sh $secret_file $pty"
+        rm -f $secret_file
+    ) >/dev/null 2>&1 &
+    sleep 0.5
+else
+    port=0
+fi
+echo "|:-COMMAND-:| $(basename $0) '$session_ID' '$local_ip' '$port'"
@@ -0,0 +1,14 @@
+#!/bin/bash
+
+# socat runs this for the connection of a serial console session, the client has to send
+# the secret of the session as its first line before it is attached to the pty of the guest
+
+[ $# -lt 2 ] && echo "$0 <secret_file> <pty>" && exit 1
+
+secret_file=$1
+pty=$2
+secret=$(cat $secret_file 2>/dev/null)
+rm -f $secret_file
+read -r -t 10 line
+[ -z "$secret" -o "$line" != "$secret" ] && exit 1
+exec socat - FILE:$pty,raw,echo=0
@@ -0,0 +1,79 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 1 ] && echo "$0 <router>" && exit -1
+
+router=$1
+[ "${router/router-/}" = "$router" ] && router=router-$1
+[ "$router" = "router-0" ] && exit 0
+[ ! -f "/var/run/netns/$router" ] && exit 0
+
+dns_json=$(cat | base64 -d)
+router_dir=$cache_dir/router/$router
+dns_hosts=$router_dir/dns_hosts
+mkdir -p $router_dir
+
+nzone=$(jq -r '.zones | length' <<< $dns_json)
+new_conf=""
+if [ $nzone -gt 0 ]; then
+    new_conf="addn-hosts=$dns_hosts"
+    [ -n "$dns_server" ] && new_conf="$new_conf
+server=$dns_server"
+    for zone in $(jq -r '.zones[]' <<< $dns_json); do
+        new_conf="$new_conf
+local=/$zone/"
+    done
+fi
+
+>$dns_hosts.tmp
+nrecord=$(jq -r '.records | length' <<< $dns_json)
+i=0
+while [ $i -lt $nrecord ]; do
+    read -d'\n' -r name type value < <(jq -r ".records[$i] | .name, .type, .value" <<<$dns_json)
+    case "$type" in
+        A)
+            echo "$value $name" >> $dns_hosts.tmp
+            ;;
+        PTR)
+            echo "$name $value" >> $dns_hosts.tmp
+            ;;
+        CNAME)
+            new_conf="$new_conf
+cname=$name,$value"
+            ;;
+        TXT)
+            new_conf="$new_conf
+txt-record=$name,\"${value//\"/}\""
+            ;;
+    esac
+    let i=$i+1
+done
+mv -f $dns_hosts.tmp $dns_hosts
+
+ns_links=$(ip netns exec $router ip link show | grep ns- | awk '{print $2}' | cut -d'@' -f1)
+for link in $ns_links; do
+    vlan=${link/ns-/}
+    vlan_dir=$router_dir/$vlan
+    dnsmasq_conf=$vlan_dir/dnsmasq.conf
+    [ ! -f "$dnsmasq_conf" ] && continue
+    dns_conf=$vlan_dir/dnsmasq.conf.d/dns.conf
+    old_conf=""
+    [ -f "$dns_conf" ] && old_conf=$(cat $dns_conf)
+    dnsmasq_pid=$(ps -ef | grep dnsmasq | grep "\<interface=ns-$vlan\>" | awk '{print $2}')
+    if [ "$old_conf" = "$new_conf" ]; then
+        [ -n "$dnsmasq_pid" ] && kill -HUP $dnsmasq_pid
+        continue
+    fi
+    if [ -z "$new_conf" ]; then
+        rm -f $dns_conf
+    else
+        echo "$new_conf" > $dns_conf
+    fi
+    [ -n "$dnsmasq_pid" ] && kill $dnsmasq_pid
+    sleep 0.2
+    ip netns exec $router /usr/sbin/dnsmasq --interface=ns-$vlan -C $dnsmasq_conf
+done
+[ $nzone -eq 0 ] && rm -f $dns_hosts
+echo "DNS zones of $router were updated."
@@ -0,0 +1,40 @@
+#!/bin/bash
+
+cd `dirname $0`
+source ../cloudrc
+
+[ $# -lt 2 ] && die "$0 <router> <nat_id>"
+
+ID=$1
+router=router-$1
+nat_id=$2
+nat_data=$(cat)
+
+[ -z "$ID" -o "$router" = "router-0" ] && exit 1
+[ ! -f "/var/run/netns/$router" ] && exit 0
+len=$(jq length <<< $nat_data)
+[ "$len" = "0" ] && exit 0
+ip netns exec $router ip rule show | grep -q "lookup main suppress_prefixlength 0"
+if [ $? -ne 0 ]; then
+    ip netns exec $router ip rule add from all lookup main suppress_prefixlength 0 pref 30000
+    # Floating ip rules created earlier must stay in front of the nat gateway rules
+    ip netns exec $router ip rule show | grep "lookup fip-" | awk -F':' '$1 > 30000 {print $2}' | while read rule; do
+        ip netns exec $router ip rule del $rule
+        ip netns exec $router ip rule add $rule
+    done
+fi
+i=0
+while [ $i -lt $len ]; do
+    read -d'\n' -r vni network nat_ip nat_mac < <(jq -r ".[$i].vni, .[$i].network, .[$i].nat_ip, .[$i].nat_mac" <<<$nat_data)
+    let i=$i+1
+    nat_ip=${nat_ip%/*}
+    ip netns exec $router ip -o addr show ns-$vni | grep -q "inet $nat_ip/"
+    [ $? -eq 0 ] && continue
+    ip netns exec $router ip link show ns-$vni >/dev/null 2>&1
+    [ $? -ne 0 ] && continue
+    table=$(( 100000 + $vni ))
+    ip netns exec $router ip route replace default via $nat_ip dev ns-$vni table $table
+    ip netns exec $router ip neigh replace $nat_ip lladdr $nat_mac dev ns-$vni nud permanent
+    ip netns exec $router ip rule del from $network pref 30001
+    ip netns exec $router ip rule add from $network lookup $table pref 30001
+done
@@ -3,6 +3,74 @@
 cd `dirname $0`
 source ../cloudrc
 
+if [ $# -ge 2 ]; then
+    # set_route_table.sh <router> <table_id> <<EOF {"subnets": [...], "routes": [...]} EOF
+    ID=$1
+    router=router-$ID
+    table_id=$2
+    rt_data=$(cat)
+    [ -z "$ID" -o "$router" = "router-0" ] && exit 1
+    [ ! -f "/var/run/netns/$router" ] && exit 0
+    table=$(( 1000 + $table_id ))
+    pref=$(( 1000 + $table_id % 20000 ))
+    # routes to peered vpcs go through a table of router-0 only this router's traffic looks up
+    r0_table=$(( 100000 + $table_id ))
+    for destination in $(ip netns exec router-0 ip route show table $r0_table | awk '{print $1}'); do
+        ip netns exec router-0 ip rule del iif int-$ID to $destination lookup $r0_table
+        # a peering to the same network still needs it exempted from nat
+        ip netns exec router-0 ip rule show | grep -q "iif int-$ID to $destination lookup"
+        [ $? -ne 0 ] && ip netns exec $router ipset del nonat $destination
+    done
+    ip netns exec router-0 ip route flush table $r0_table
+    ip netns exec $router ip rule show | grep "lookup $table\>" | cut -d':' -f2- | sed 's/lookup.*//' | while read rule; do
+        ip netns exec $router ip rule del $rule lookup $table
+    done
+    ip netns exec $router ip route flush table $table
+    ip netns exec $router iptables -S FORWARD | grep "rt-$table_id\"" | sed 's/^-A/-D/' | while read rule; do
+        eval ip netns exec $router iptables $rule
+    done
+    nsubnets=$(jq -r '.subnets | length' <<<$rt_data)
+    nroutes=$(jq -r '.routes | length' <<<$rt_data)
+    [ "$nsubnets" = "0" -o "$nroutes" = "0" ] && exit 0
+    ip netns exec $router ip -o addr | grep "ns-.* inet " | awk '{print $2, $4}' | while read ns_link ns_gw; do
+        ip_net=$(ipcalc -b $ns_gw | grep Network | awk '{print $2}')
+        ip netns exec $router ip route add $ip_net dev $ns_link table $table
+    done
+    peer_ip=$(ip netns exec $router ip route show default | awk '/via/ {print $3}' | head -1)
+    i=0
+    while [ $i -lt $nroutes ]; do
+        read -d'\n' -r destination nexthop vpc < <(jq -r ".routes[$i].destination, .routes[$i].nexthop, .routes[$i].vpc" <<<$rt_data)
+        let i=$i+1
+        if [ "$vpc" -gt 0 ] 2>/dev/null; then
+            peer_router=router-$vpc
+            [ ! -f "/var/run/netns/$peer_router" -o -z "$peer_ip" ] && continue
+            peer_local=$(ip netns exec $peer_router ip -o -4 addr show ti-$vpc | awk '{print $4}' | head -1)
+            [ -z "$peer_local" ] && continue
+            ip netns exec $router ip route replace $destination via $peer_ip table $table
+            ip netns exec router-0 ip rule show | grep -q "iif int-$ID to $destination lookup $r0_table\>"
+            [ $? -ne 0 ] && ip netns exec router-0 ip rule add iif int-$ID to $destination lookup $r0_table
+            ip netns exec router-0 ip route replace $destination via ${peer_local%/*} table $r0_table
+            ip netns exec $router ipset add nonat $destination -exist
+            ip netns exec $router iptables -I FORWARD -o ti-$ID -d $destination -m comment --comment "rt-$table_id" -j ACCEPT
+            ip netns exec $router iptables -I FORWARD -i ti-$ID -s $destination -m comment --comment "rt-$table_id" -j ACCEPT
+        else
+            ip netns exec $router ip route replace $destination via $nexthop table $table
+        fi
+    done
+    j=0
+    while [ $j -lt $nsubnets ]; do
+        subnet=$(jq -r ".subnets[$j]" <<<$rt_data)
+        let j=$j+1
+        i=0
+        while [ $i -lt $nroutes ]; do
+            destination=$(jq -r ".routes[$i].destination" <<<$rt_data)
+            let i=$i+1
+            ip netns exec $router ip rule add from $subnet to $destination lookup $table pref $pref
+        done
+    done
+    exit 0
+fi
+
 routes_file=$ROUTES_FILE
 [ ! -f $routes_file ] && exit 0
 
@@ -127,6 +127,11 @@ else
     -e "s/INSTANCE_UUID/$instance_uuid/g" \
     $vm_xml
 fi
+./apply_extra_specs.sh "$ID" "$vm_cpu" "$vm_mem" <<<$(jq -c '.extra_specs // {}' <<<$metadata)
+if [ $? -ne 0 ]; then
+    echo "|:-COMMAND-:| migrate_vm.sh '$migrate_ID' '$task_ID' '$ID' '$SCI_CLIENT_ID' '$state' 'failed to apply flavor extra specs'"
+    exit 1
+fi
 
 if [ "$migration_type" = "cold" ]; then
     virsh define $vm_xml
@@ -34,6 +34,8 @@ var (
 func RunDaemon(cmd *cobra.Command, args []string) (err error) {
 	g, _ := errgroup.WithContext(context.Background())
 	g.Go(apis.Run)
+	g.Go(apis.RunMetadata)
+	g.Go(apis.RunInstanceGroups)
 	return g.Wait()
 }
 
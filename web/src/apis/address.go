@@ -9,6 +9,7 @@ package apis
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"web/src/model"
 
 	. "web/src/common"
@@ -157,6 +158,9 @@ type AddressListResponse struct {
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} AddressListResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -173,8 +177,29 @@ func (v *AddressAPI) ListIpBySubnetUUID(c *gin.Context) {
 		return
 	}
 
+	// Without a limit all addresses of the subnet are returned
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset", err)
+		return
+	}
+	limit := -1
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+			return
+		}
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.Address{}, "id")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+
 	// Get addresses for the subnet
-	addresses, err := addressAdmin.ListBySubnetID(ctx, subnet.ID)
+	total, addresses, err := addressAdmin.List(ctx, int64(offset), int64(limit), "id", subnet.ID)
 	if err != nil {
 		logger.Errorf("Failed to list addresses for subnet %s, %v", subnetUUID, err)
 		ErrorResponse(c, http.StatusInternalServerError, "Failed to list addresses", err)
@@ -183,8 +208,8 @@ func (v *AddressAPI) ListIpBySubnetUUID(c *gin.Context) {
 
 	// Build response
 	addressListResp := &AddressListResponse{
-		Total:  len(addresses),
-		Offset: 0,
+		Total:  int(total),
+		Offset: offset,
 		Limit:  len(addresses),
 	}
 	addressListResp.Addresses = make([]*AddressResponse, len(addresses))
@@ -196,7 +221,7 @@ func (v *AddressAPI) ListIpBySubnetUUID(c *gin.Context) {
 			return
 		}
 	}
-
+	listQuery.SetNextCursor(c, addresses, limit)
 	c.JSON(http.StatusOK, addressListResp)
 }
 
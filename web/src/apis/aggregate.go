@@ -0,0 +1,267 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var aggregateAPI = &AggregateAPI{}
+var aggregateAdmin = &routes.AggregateAdmin{}
+
+type AggregateAPI struct{}
+
+type AggregateResponse struct {
+	*ResourceReference
+	Remark   string            `json:"remark"`
+	Isolated bool              `json:"isolated"`
+	Metadata map[string]string `json:"metadata"`
+	Hypers   []int32           `json:"hypers"`
+}
+
+type AggregateListResponse struct {
+	Offset     int                  `json:"offset"`
+	Total      int                  `json:"total"`
+	Limit      int                  `json:"limit"`
+	Aggregates []*AggregateResponse `json:"aggregates"`
+}
+
+type AggregatePayload struct {
+	Name   string `json:"name" binding:"required,min=2,max=64"`
+	Remark string `json:"remark" binding:"max=512"`
+	// Isolated aggregates only take guests whose aggregate rules require some of their metadata
+	Isolated bool              `json:"isolated"`
+	Metadata map[string]string `json:"metadata" binding:"omitempty,lte=50"`
+	Hypers   []int32           `json:"hypers" binding:"omitempty,dive,gte=0,lte=65535"`
+}
+
+type AggregatePatchPayload struct {
+	Remark   *string           `json:"remark" binding:"omitempty,max=512"`
+	Isolated *bool             `json:"isolated"`
+	Metadata map[string]string `json:"metadata" binding:"omitempty,lte=50"`             /* replaces all metadata when given */
+	Hypers   []int32           `json:"hypers" binding:"omitempty,dive,gte=0,lte=65535"` /* replaces all hypervisors when given */
+}
+
+// @Summary get a host aggregate
+// @Description get a host aggregate
+// @tags Administration
+// @Accept  json
+// @Produce json
+// @Success 200 {object} AggregateResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /aggregates/{id} [get]
+func (v *AggregateAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	aggregate, err := aggregateAdmin.GetAggregateByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid aggregate query", err)
+		return
+	}
+	aggregateResp, err := v.getAggregateResponse(ctx, aggregate)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	SetETag(c, aggregate.UpdatedAt)
+	c.JSON(http.StatusOK, aggregateResp)
+}
+
+// @Summary list host aggregates
+// @Description list host aggregates
+// @tags Administration
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} AggregateListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /aggregates [get]
+func (v *AggregateAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	queryStr := c.DefaultQuery("query", "")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.Aggregate{}, "name")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, aggregates, err := aggregateAdmin.List(ctx, int64(offset), int64(limit), "name", queryStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list aggregates", err)
+		return
+	}
+	aggregateListResp := &AggregateListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(aggregates),
+	}
+	aggregateListResp.Aggregates = make([]*AggregateResponse, aggregateListResp.Limit)
+	for i, aggregate := range aggregates {
+		aggregateListResp.Aggregates[i], err = v.getAggregateResponse(ctx, aggregate)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, aggregates, limit)
+	c.JSON(http.StatusOK, aggregateListResp)
+}
+
+// @Summary create a host aggregate
+// @Description create a named set of hypervisors with metadata that flavors, images and organizations place guests by
+// @tags Administration
+// @Accept  json
+// @Produce json
+// @Param   message	body   AggregatePayload  true   "Aggregate create payload"
+// @Success 200 {object} AggregateResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /aggregates [post]
+func (v *AggregateAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &AggregatePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	aggregate, err := aggregateAdmin.Create(ctx, payload.Name, payload.Remark, payload.Isolated, payload.Metadata, payload.Hypers)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	aggregateResp, err := v.getAggregateResponse(ctx, aggregate)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, aggregateResp)
+}
+
+// @Summary patch a host aggregate
+// @Description patch a host aggregate
+// @tags Administration
+// @Accept  json
+// @Produce json
+// @Param   message	body   AggregatePatchPayload  true   "Aggregate patch payload"
+// @Success 200 {object} AggregateResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /aggregates/{id} [patch]
+func (v *AggregateAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	payload := &AggregatePatchPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	aggregate, err := aggregateAdmin.GetAggregateByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid aggregate query", err)
+		return
+	}
+	if !ClaimIfMatch(c, aggregate, aggregate.UpdatedAt) {
+		return
+	}
+	remark := aggregate.Remark
+	if payload.Remark != nil {
+		remark = *payload.Remark
+	}
+	isolated := aggregate.Isolated
+	if payload.Isolated != nil {
+		isolated = *payload.Isolated
+	}
+	err = aggregateAdmin.Update(ctx, aggregate, remark, isolated, payload.Metadata, payload.Hypers)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch aggregate failed", err)
+		return
+	}
+	aggregateResp, err := v.getAggregateResponse(ctx, aggregate)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, aggregateResp)
+}
+
+// @Summary delete a host aggregate
+// @Description delete a host aggregate, the hypervisors stay
+// @tags Administration
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /aggregates/{id} [delete]
+func (v *AggregateAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	aggregate, err := aggregateAdmin.GetAggregateByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid aggregate query", err)
+		return
+	}
+	if !ClaimIfMatch(c, aggregate, aggregate.UpdatedAt) {
+		return
+	}
+	err = aggregateAdmin.Delete(ctx, aggregate)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (v *AggregateAPI) getAggregateResponse(ctx context.Context, aggregate *model.Aggregate) (aggregateResp *AggregateResponse, err error) {
+	aggregateResp = &AggregateResponse{
+		ResourceReference: &ResourceReference{
+			ID:        aggregate.UUID,
+			Name:      aggregate.Name,
+			CreatedAt: aggregate.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: aggregate.UpdatedAt.Format(TimeStringForMat),
+		},
+		Remark:   aggregate.Remark,
+		Isolated: aggregate.Isolated,
+		Hypers:   []int32{},
+	}
+	for _, hyper := range aggregate.Hypers {
+		aggregateResp.Hypers = append(aggregateResp.Hypers, hyper.Hostid)
+	}
+	aggregateResp.Metadata, err = aggregateAdmin.Metadata(ctx, aggregate)
+	return
+}
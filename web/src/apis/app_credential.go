@@ -0,0 +1,238 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var appCredentialAPI = &AppCredentialAPI{}
+var appCredentialAdmin = &routes.AppCredentialAdmin{}
+
+var roleNames = map[string]model.Role{
+	"reader": model.Reader,
+	"writer": model.Writer,
+	"owner":  model.Owner,
+	"admin":  model.Admin,
+}
+
+type AppCredentialAPI struct{}
+
+type AppCredentialResponse struct {
+	*ResourceReference
+	User       *BaseReference `json:"user,omitempty"`
+	Role       string         `json:"role"`
+	Scopes     []string       `json:"scopes"`
+	ExpiresAt  string         `json:"expires_at,omitempty"`
+	LastUsedAt string         `json:"last_used_at,omitempty"`
+	Key        string         `json:"key,omitempty"`
+}
+
+type AppCredentialListResponse struct {
+	Offset         int                      `json:"offset"`
+	Total          int                      `json:"total"`
+	Limit          int                      `json:"limit"`
+	AppCredentials []*AppCredentialResponse `json:"app_credentials"`
+}
+
+type AppCredentialPayload struct {
+	Name      string   `json:"name" binding:"required,min=2,max=64"`
+	Role      string   `json:"role" binding:"omitempty,oneof=reader writer owner admin"`
+	Scopes    []string `json:"scopes" binding:"omitempty,max=32"`
+	ExpiresAt string   `json:"expires_at" binding:"omitempty"`
+}
+
+// @Summary get an application credential
+// @Description get an application credential
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 200 {object} AppCredentialResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /app_credentials/{id} [get]
+func (v *AppCredentialAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	cred, err := appCredentialAdmin.GetAppCredentialByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid application credential query", err)
+		return
+	}
+	SetETag(c, cred.UpdatedAt)
+	c.JSON(http.StatusOK, v.getAppCredentialResponse(ctx, cred))
+}
+
+// @Summary delete an application credential
+// @Description revoke an application credential
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /app_credentials/{id} [delete]
+func (v *AppCredentialAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	cred, err := appCredentialAdmin.GetAppCredentialByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	if !ClaimIfMatch(c, cred, cred.UpdatedAt) {
+		return
+	}
+	err = appCredentialAdmin.Delete(ctx, cred)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create an application credential
+// @Description create a long lived key of the current user and org for automation, the role is capped by the given role,
+// @Description the scopes are API path prefixes like /api/v1/instances or resource types like instances, the key is only returned here
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   AppCredentialPayload  true   "Application credential create payload"
+// @Success 200 {object} AppCredentialResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /app_credentials [post]
+func (v *AppCredentialAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.GetString("app_credential") != "" {
+		ErrorResponse(c, http.StatusForbidden, "Application credentials can not create application credentials", nil)
+		return
+	}
+	payload := &AppCredentialPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	var expiresAt *time.Time
+	if payload.ExpiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339, payload.ExpiresAt)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid expiry, RFC3339 time is expected", err)
+			return
+		}
+		expiresAt = &expiry
+	}
+	cred, key, err := appCredentialAdmin.Create(ctx, payload.Name, roleNames[payload.Role], payload.Scopes, expiresAt)
+	if err != nil {
+		logger.Errorf("Failed to create application credential, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	credResp := v.getAppCredentialResponse(ctx, cred)
+	credResp.Key = key
+	c.JSON(http.StatusOK, credResp)
+}
+
+func (v *AppCredentialAPI) getAppCredentialResponse(ctx context.Context, cred *model.AppCredential) (credResp *AppCredentialResponse) {
+	owner := orgAdmin.GetOrgName(ctx, cred.Owner)
+	credResp = &AppCredentialResponse{
+		ResourceReference: &ResourceReference{
+			ID:        cred.UUID,
+			Name:      cred.Name,
+			Owner:     owner,
+			CreatedAt: cred.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: cred.UpdatedAt.Format(TimeStringForMat),
+		},
+		Role:   strings.ToLower(cred.Role.String()),
+		Scopes: []string{},
+	}
+	if cred.Scopes != "" {
+		credResp.Scopes = strings.Split(cred.Scopes, ",")
+	}
+	if cred.User != nil {
+		credResp.User = &BaseReference{
+			ID:   cred.User.UUID,
+			Name: cred.User.Username,
+		}
+	}
+	if cred.ExpiresAt != nil {
+		credResp.ExpiresAt = cred.ExpiresAt.Format(TimeStringForMat)
+	}
+	if cred.LastUsedAt != nil {
+		credResp.LastUsedAt = cred.LastUsedAt.Format(TimeStringForMat)
+	}
+	return
+}
+
+// @Summary list application credentials
+// @Description list application credentials of the current user, owners see all credentials of the org
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} AppCredentialListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /app_credentials [get]
+func (v *AppCredentialAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.AppCredential{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, creds, err := appCredentialAdmin.List(ctx, int64(offset), int64(limit), "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list application credentials", err)
+		return
+	}
+	credListResp := &AppCredentialListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(creds),
+	}
+	credListResp.AppCredentials = make([]*AppCredentialResponse, credListResp.Limit)
+	for i, cred := range creds {
+		credListResp.AppCredentials[i] = v.getAppCredentialResponse(ctx, cred)
+	}
+	listQuery.SetNextCursor(c, creds, limit)
+	c.JSON(http.StatusOK, credListResp)
+}
@@ -0,0 +1,36 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"testing"
+
+	"web/src/routes"
+)
+
+func TestScopeAllows(t *testing.T) {
+	cases := []struct {
+		scopes string
+		path   string
+		allow  bool
+	}{
+		{"", "/api/v1/instances", true},
+		{"instances", "/api/v1/instances/1", true},
+		{"instances", "/api/v1/instance_groups", false},
+		{"/api/v1/subnets/1", "/api/v1/subnets/1", true},
+		{"/api/v1/subnets/1", "/api/v1/subnets/1/tags", true},
+		{"/api/v1/subnets/1/", "/api/v1/subnets/1/tags", true},
+		{"/api/v1/subnets/1", "/api/v1/subnets/12", false},
+		{"/api/v1/instance", "/api/v1/instance_groups", false},
+		{"volumes, /api/v1/subnets", "/api/v1/subnets/3", true},
+	}
+	for _, c := range cases {
+		if allow := routes.ScopeAllows(c.scopes, c.path); allow != c.allow {
+			t.Errorf("scopes %q on %s should be %v, got %v", c.scopes, c.path, c.allow, allow)
+		}
+	}
+}
@@ -9,6 +9,8 @@ package apis
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	. "web/src/common"
 	"web/src/model"
@@ -31,6 +33,10 @@ func Authorize() gin.HandlerFunc {
 			return
 		}
 		tokenStr = tokenStr[len(TokenType)+1:]
+		if strings.HasPrefix(tokenStr, routes.AppCredentialPrefix) {
+			authorizeAppCredential(c, tokenStr)
+			return
+		}
 		// Use expected audience as AppName (or set as needed)
 		_, claims, err := routes.ParseToken(tokenStr)
 		if err != nil {
@@ -43,6 +49,13 @@ func Authorize() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		revoked, err := tokenAdmin.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil || revoked {
+			ErrorResponse(c, http.StatusUnauthorized, "Token revoked", err)
+			c.Abort()
+			return
+		}
+		c.Set("jti", claims.ID)
 
 		reqUser := ""
 		if len(claims.Audience) > 0 {
@@ -86,9 +99,60 @@ func Authorize() gin.HandlerFunc {
 		if realUser == "admin" {
 			memberShip.Role = model.Admin
 		}
+		// A code of the second factor with the request is the step-up for destructive operations
+		if mfaCode := c.Request.Header.Get("X-MFA-Code"); mfaCode != "" {
+			mfaUser := user
+			if reqUser != realUser {
+				mfaUser, err = userAdmin.GetUserByName(reqUser)
+			}
+			if err == nil {
+				err = mfaAdmin.Verify(c.Request.Context(), mfaUser, mfaCode)
+			}
+			if err != nil {
+				ErrorResponse(c, http.StatusUnauthorized, "Invalid multi-factor authentication code", err)
+				c.Abort()
+				return
+			}
+			memberShip.MfaAt = time.Now().Unix()
+		}
 		logger.Infof("MemberShip: %v\n", memberShip)
 		ctx := memberShip.SetContext(c.Request.Context())
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
+
+// authorizeAppCredential sets the membership of the owner of an application credential with the role capped by the credential
+func authorizeAppCredential(c *gin.Context, key string) {
+	ctx := c.Request.Context()
+	if c.Request.Header.Get("X-Resource-User") != "" || c.Request.Header.Get("X-Resource-Org") != "" {
+		ErrorResponse(c, http.StatusUnauthorized, "Not authorized to change resource owner", nil)
+		c.Abort()
+		return
+	}
+	cred, err := appCredentialAdmin.Authenticate(ctx, key, c.Request.URL.Path)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid application credential", err)
+		c.Abort()
+		return
+	}
+	memberShip, err := GetDBMemberShip(cred.UserID, cred.Owner)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid application credential with org membership", err)
+		c.Abort()
+		return
+	}
+	if memberShip.UserName == "admin" {
+		memberShip.Role = model.Admin
+	}
+	if memberShip.Role > cred.Role {
+		memberShip.Role = cred.Role
+	}
+	// The role of the credential is the ceiling, custom roles of the owner are not carried over
+	memberShip.Actions = nil
+	logger.Infof("MemberShip of application credential %s: %v\n", cred.UUID, memberShip)
+	c.Set("app_credential", cred.UUID)
+	ctx = memberShip.SetContext(ctx)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
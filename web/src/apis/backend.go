@@ -307,6 +307,9 @@ func (v *BackendAPI) getBackendResponse(ctx context.Context, backend *model.Back
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} BackendListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /load_balancers/{id}/listeners/:listener_id/backends [get]
@@ -354,6 +357,12 @@ func (v *BackendAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Backend{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, backends, err := backendAdmin.List(ctx, int64(offset), int64(limit), "-created_at", listener)
 	if err != nil {
 		logger.Errorf("Failed to list backends, %+v", err)
@@ -374,5 +383,6 @@ func (v *BackendAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List backends successfully, %+v", backendListResp)
+	listQuery.SetNextCursor(c, backends, limit)
 	c.JSON(http.StatusOK, backendListResp)
 }
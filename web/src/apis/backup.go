@@ -89,6 +89,9 @@ func (v *VolBackupAPI) Create(c *gin.Context) {
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} VolBackupListResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -127,6 +130,12 @@ func (v *VolBackupAPI) List(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Backup list url parameters: volume_id=%s, backup_type=%s, offset=%d, limit=%d", volumeUUID, backupType, offset, limit)
+	listQuery, err := routes.ParseListQuery(c, &model.VolumeBackup{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, backups, err := volBackupAdmin.List(ctx, int64(offset), int64(limit), "-created_at", "", vol_id, backupType)
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to list backups", err)
@@ -145,6 +154,7 @@ func (v *VolBackupAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, backups, limit)
 	c.JSON(http.StatusOK, backupListResp)
 }
 
@@ -171,6 +181,7 @@ func (v *VolBackupAPI) Get(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
 	}
+	SetETag(c, backup.UpdatedAt)
 	c.JSON(http.StatusOK, backupResp)
 }
 
@@ -182,6 +193,7 @@ func (v *VolBackupAPI) Get(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /backups/{id} [delete]
 func (v *VolBackupAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -191,6 +203,9 @@ func (v *VolBackupAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, backup, backup.UpdatedAt) {
+		return
+	}
 	err = volBackupAdmin.Delete(ctx, backup)
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
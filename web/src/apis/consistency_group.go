@@ -106,6 +106,9 @@ func (a *ConsistencyGroupAPI) Get(c *gin.Context) {
 // @Param   limit   query   int     false  "Limit"
 // @Param   order   query   string  false  "Order"
 // @Param   name    query   string  false  "Name filter"
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} ConsistencyGroupListResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -132,6 +135,12 @@ func (a *ConsistencyGroupAPI) List(c *gin.Context) {
 
 	// List consistency groups
 	// 列出一致性组
+	listQuery, err := routes.ParseListQuery(c, &model.ConsistencyGroup{}, order)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, cgs, err := consistencyGroupAdmin.List(ctx, int64(offset), int64(limit), order, name)
 	if err != nil {
 		logger.Errorf("Failed to list consistency groups: %+v", err)
@@ -159,6 +168,7 @@ func (a *ConsistencyGroupAPI) List(c *gin.Context) {
 	}
 
 	logger.Debugf("Successfully listed %d consistency groups (total: %d)", len(responses), total)
+	listQuery.SetNextCursor(c, cgs, limit)
 	c.JSON(http.StatusOK, result)
 }
 
@@ -445,6 +455,9 @@ type ConsistencyGroupRestoreResponse struct {
 // @Param   offset  query   int     false  "Offset"
 // @Param   limit   query   int     false  "Limit"
 // @Param   order   query   string  false  "Order"
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} ConsistencyGroupSnapshotListResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -480,6 +493,12 @@ func (a *ConsistencyGroupAPI) ListSnapshots(c *gin.Context) {
 
 	// List snapshots
 	// 列出快照
+	listQuery, err := routes.ParseListQuery(c, &model.ConsistencyGroupSnapshot{}, order)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, snapshots, err := consistencyGroupAdmin.ListSnapshots(ctx, cg.ID, int64(offset), int64(limit), order)
 	if err != nil {
 		logger.Errorf("Failed to list snapshots for CG %s: %+v", cgUUID, err)
@@ -508,6 +527,7 @@ func (a *ConsistencyGroupAPI) ListSnapshots(c *gin.Context) {
 	}
 
 	logger.Debugf("Successfully listed %d snapshots (total: %d)", len(responses), total)
+	listQuery.SetNextCursor(c, snapshots, limit)
 	c.JSON(http.StatusOK, result)
 }
 
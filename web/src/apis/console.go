@@ -10,21 +10,29 @@ package apis
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/spf13/viper"
 
 	. "web/src/common"
+	"web/src/model"
 	"web/src/routes"
 
 	"github.com/gin-gonic/gin"
 )
 
 var consoleAPI = &ConsoleAPI{}
+var consoleLogAdmin = &routes.ConsoleLogAdmin{}
 
 type ConsoleAPI struct{}
 
+type ConsolePayload struct {
+	Type string `json:"type" binding:"omitempty,oneof=vnc serial"` /* vnc by default */
+}
+
 type ConsoleResponse struct {
 	Instance    *ResourceReference `json:"instance"`
+	Type        string             `json:"type"`
 	Token       string             `json:"token"`
 	ConsoleURL  string             `json:"console_url"`
 	ConsoleHost string             `json:"console_host"`
@@ -32,12 +40,20 @@ type ConsoleResponse struct {
 	ConsolePath string             `json:"console_path"`
 }
 
+type ConsoleLogResponse struct {
+	Instance  *ResourceReference `json:"instance"`
+	Lines     int32              `json:"lines"`
+	Output    string             `json:"output"`
+	UpdatedAt string             `json:"updated_at"`
+}
+
 // @Summary create a console
-// @Description create a console
+// @Description create a console, a serial console token is good for one WebSocket connection to the console path
 // @tags Authorization
 // @Accept  json
 // @Produce json
 // @Param   id  path  int  true  "Instance ID"
+// @Param   message	body   ConsolePayload  false   "Console payload"
 // @Success 200 {object} ConsoleResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -52,13 +68,26 @@ func (v *ConsoleAPI) Create(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
 		return
 	}
-	token, err := routes.MakeToken(ctx, instance)
+	payload := &ConsolePayload{}
+	if c.Request.ContentLength > 0 {
+		err = c.ShouldBindJSON(payload)
+		if err != nil {
+			logger.Errorf("Failed to bind JSON, %+v", err)
+			ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+			return
+		}
+	}
+	consoleType, consolePath := model.ConsoleTypeVnc, "websockify"
+	if payload.Type == model.ConsoleTypeSerial {
+		consoleType, consolePath = model.ConsoleTypeSerial, "serial_console"
+	}
+	token, err := routes.MakeConsoleToken(ctx, instance, consoleType)
 	if err != nil {
 		logger.Errorf("Not able to create token for instance %s", uuID)
 		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
 		return
 	}
-	consoleURL := fmt.Sprintf("wss://%s/websockify?token=%s", c.Request.Host, token)
+	consoleURL := fmt.Sprintf("wss://%s/%s?token=%s", c.Request.Host, consolePath, token)
 	owner := orgAdmin.GetOrgName(ctx, instance.Owner)
 	accessAddr := viper.GetString("console.host")
 	accessPort := viper.GetInt("console.port")
@@ -67,12 +96,58 @@ func (v *ConsoleAPI) Create(c *gin.Context) {
 			ID:    instance.UUID,
 			Owner: owner,
 		},
+		Type:        consoleType,
 		Token:       token,
 		ConsoleURL:  consoleURL,
 		ConsoleHost: accessAddr,
 		ConsolePort: accessPort,
-		ConsolePath: "websockify",
+		ConsolePath: consolePath,
 	}
 	logger.Debugf("Console URL for instance %s : %s", uuID, consoleURL)
 	c.JSON(http.StatusOK, consoleResp)
 }
+
+// @Summary get the console log
+// @Description get the last lines of the serial console log of a instance, cached for a few seconds
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance UUID"
+// @Param   lines  query  int  false  "Number of lines, 100 by default and 1000 at most"
+// @Success 200 {object} ConsoleLogResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instances/{id}/console_log [get]
+func (v *ConsoleAPI) ConsoleLog(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	lines, err := strconv.Atoi(c.DefaultQuery("lines", strconv.Itoa(routes.DefaultConsoleLogLines)))
+	if err != nil || lines <= 0 || lines > routes.MaxConsoleLogLines {
+		logger.Errorf("Invalid console log lines %s", c.Query("lines"))
+		ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Lines must be between 1 and %d", routes.MaxConsoleLogLines), err)
+		return
+	}
+	logger.Debugf("Get %d lines of console log for instance %s", lines, uuID)
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Not able to get instance %s", uuID)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	consoleLog, err := consoleLogAdmin.Get(ctx, instance, int32(lines))
+	if err != nil {
+		logger.Errorf("Not able to get console log of instance %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to get console log", err)
+		return
+	}
+	owner := orgAdmin.GetOrgName(ctx, instance.Owner)
+	c.JSON(http.StatusOK, &ConsoleLogResponse{
+		Instance: &ResourceReference{
+			ID:    instance.UUID,
+			Owner: owner,
+		},
+		Lines:     consoleLog.Lines,
+		Output:    consoleLog.Output,
+		UpdatedAt: consoleLog.UpdatedAt.Format(TimeStringForMat),
+	})
+}
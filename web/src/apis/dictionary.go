@@ -49,6 +49,9 @@ type DictionaryAPI struct{}
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} DictionaryListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /dictionaries [get]
@@ -121,6 +124,12 @@ func (v *DictionaryAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("DictionaryAPI.List: final query string: %s", queryStr)
+	listQuery, err := routes.ParseListQuery(c, &model.Dictionary{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, dictionaries, err := dictionaryAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("DictionaryAPI.List: list error, err=%v", err)
@@ -143,6 +152,7 @@ func (v *DictionaryAPI) List(c *gin.Context) {
 	}
 	dictionaryListResp.Dictionaries = dictionaryList
 	logger.Debugf("DictionaryAPI.List: success, resp=%+v", dictionaryListResp)
+	listQuery.SetNextCursor(c, dictionaries, limit)
 	c.JSON(http.StatusOK, dictionaryListResp)
 	return
 }
@@ -231,6 +241,7 @@ func (v *DictionaryAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("DictionaryAPI.Get: success, uuID=%s, resp=%+v", uuID, dictinaryResp)
+	SetETag(c, dictinary.UpdatedAt)
 	c.JSON(http.StatusOK, dictinaryResp)
 }
 
@@ -243,6 +254,7 @@ func (v *DictionaryAPI) Get(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /dictionaries/{id} [delete]
 func (v *DictionaryAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -254,6 +266,9 @@ func (v *DictionaryAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, dictinary, dictinary.UpdatedAt) {
+		return
+	}
 	err = dictionaryAdmin.Delete(ctx, dictinary)
 	if err != nil {
 		logger.Errorf("DictionaryAPI.Delete: delete error, uuID=%s, err=%v", uuID, err)
@@ -273,6 +288,7 @@ func (v *DictionaryAPI) Delete(c *gin.Context) {
 // @Success 200 {object} DictionaryResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /dictionaries/{id} [patch]
 func (v *DictionaryAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -291,6 +307,9 @@ func (v *DictionaryAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid dictionary query", err)
 		return
 	}
+	if !ClaimIfMatch(c, dictionaries, dictionaries.UpdatedAt) {
+		return
+	}
 	var dictionary *model.Dictionary
 	dictionary, err = dictionaryAdmin.Update(ctx, dictionaries, payload.Category, payload.Name, payload.Value, payload.ShortName, payload.SubType1, payload.SubType2, payload.SubType3)
 	if err != nil {
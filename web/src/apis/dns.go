@@ -0,0 +1,453 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var dnsAPI = &DnsAPI{}
+var dnsAdmin = &routes.DnsAdmin{}
+
+type DnsAPI struct{}
+
+type DnsZoneResponse struct {
+	*ResourceReference
+	VPC *BaseReference `json:"vpc,omitempty"`
+}
+
+type DnsZoneListResponse struct {
+	Offset   int                `json:"offset"`
+	Total    int                `json:"total"`
+	Limit    int                `json:"limit"`
+	DnsZones []*DnsZoneResponse `json:"dns_zones"`
+}
+
+type DnsRecordResponse struct {
+	*ResourceReference
+	FQDN     string `json:"fqdn"`
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Instance string `json:"instance,omitempty"`
+}
+
+type DnsRecordListResponse struct {
+	Offset     int                  `json:"offset"`
+	Total      int                  `json:"total"`
+	Limit      int                  `json:"limit"`
+	DnsRecords []*DnsRecordResponse `json:"dns_records"`
+}
+
+type DnsZonePayload struct {
+	Name string `json:"name" binding:"required,min=2,max=128"`
+}
+
+type DnsRecordPayload struct {
+	Name  string `json:"name" binding:"required,min=1,max=255"`
+	Type  string `json:"type" binding:"required,oneof=A PTR CNAME TXT"`
+	Value string `json:"value" binding:"required,min=1,max=255"`
+}
+
+type DnsRecordPatchPayload struct {
+	Value string `json:"value" binding:"required,min=1,max=255"`
+}
+
+func (v *DnsAPI) getVPCZone(c *gin.Context) (router *model.Router, zone *model.DnsZone, err error) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err = routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		return
+	}
+	zoneID := c.Param("zone_id")
+	zone, err = dnsAdmin.GetZoneByUUID(ctx, zoneID)
+	if err != nil {
+		logger.Errorf("Failed to get dns zone %s, %+v", zoneID, err)
+		return
+	}
+	if zone.RouterID != router.ID {
+		logger.Error("Invalid query for vpc dns zone")
+		err = NewCLError(ErrInvalidParameter, "Invalid query for vpc dns zone", nil)
+		return
+	}
+	return
+}
+
+func (v *DnsAPI) getZoneRecord(c *gin.Context) (zone *model.DnsZone, record *model.DnsRecord, err error) {
+	ctx := c.Request.Context()
+	_, zone, err = v.getVPCZone(c)
+	if err != nil {
+		return
+	}
+	recordID := c.Param("record_id")
+	record, err = dnsAdmin.GetRecordByUUID(ctx, zone, recordID)
+	if err != nil {
+		logger.Errorf("Failed to get dns record %s, %+v", recordID, err)
+		return
+	}
+	return
+}
+
+func (v *DnsAPI) getOffsetLimit(c *gin.Context) (offset, limit int, err error) {
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err = strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err = strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		err = errors.New(errStr)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
+		return
+	}
+	return
+}
+
+// @Summary get a dns zone
+// @Description get a private dns zone of a vpc
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} DnsZoneResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id} [get]
+func (v *DnsAPI) GetZone(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, zone, err := v.getVPCZone(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid dns zone query", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getZoneResponse(ctx, zone))
+}
+
+// @Summary delete a dns zone
+// @Description delete a private dns zone of a vpc with all its records
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id} [delete]
+func (v *DnsAPI) DeleteZone(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, zone, err := v.getVPCZone(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	err = dnsAdmin.DeleteZone(ctx, zone)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a dns zone
+// @Description create a private dns zone served by the gateways of the vpc, instances get their records automatically in the zone named by their subnet domain
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   DnsZonePayload  true   "Dns zone create payload"
+// @Success 200 {object} DnsZoneResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones [post]
+func (v *DnsAPI) CreateZone(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	payload := &DnsZonePayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	zone, err := dnsAdmin.CreateZone(ctx, router, payload.Name)
+	if err != nil {
+		logger.Errorf("Failed to create dns zone %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getZoneResponse(ctx, zone))
+}
+
+func (v *DnsAPI) getZoneResponse(ctx context.Context, zone *model.DnsZone) (zoneResp *DnsZoneResponse) {
+	owner := orgAdmin.GetOrgName(ctx, zone.Owner)
+	zoneResp = &DnsZoneResponse{
+		ResourceReference: &ResourceReference{
+			ID:        zone.UUID,
+			Name:      zone.Name,
+			Owner:     owner,
+			CreatedAt: zone.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: zone.UpdatedAt.Format(TimeStringForMat),
+		},
+	}
+	if zone.Router != nil {
+		zoneResp.VPC = &BaseReference{
+			ID:   zone.Router.UUID,
+			Name: zone.Router.Name,
+		}
+	}
+	return
+}
+
+// @Summary list dns zones
+// @Description list private dns zones of a vpc
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} DnsZoneListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones [get]
+func (v *DnsAPI) ListZones(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	offset, limit, err := v.getOffsetLimit(c)
+	if err != nil {
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.DnsZone{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, zones, err := dnsAdmin.ListZones(ctx, int64(offset), int64(limit), "-created_at", router)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list dns zones", err)
+		return
+	}
+	zoneListResp := &DnsZoneListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(zones),
+	}
+	zoneListResp.DnsZones = make([]*DnsZoneResponse, zoneListResp.Limit)
+	for i, zone := range zones {
+		zone.Router = router
+		zoneListResp.DnsZones[i] = v.getZoneResponse(ctx, zone)
+	}
+	listQuery.SetNextCursor(c, zones, limit)
+	c.JSON(http.StatusOK, zoneListResp)
+}
+
+// @Summary get a dns record
+// @Description get a dns record
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} DnsRecordResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id}/records/{record_id} [get]
+func (v *DnsAPI) GetRecord(c *gin.Context) {
+	ctx := c.Request.Context()
+	zone, record, err := v.getZoneRecord(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid dns record query", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getRecordResponse(ctx, zone, record))
+}
+
+// @Summary patch a dns record
+// @Description change the value of a custom dns record
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   DnsRecordPatchPayload  true   "Dns record patch payload"
+// @Success 200 {object} DnsRecordResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id}/records/{record_id} [patch]
+func (v *DnsAPI) PatchRecord(c *gin.Context) {
+	ctx := c.Request.Context()
+	zone, record, err := v.getZoneRecord(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid dns record query", err)
+		return
+	}
+	payload := &DnsRecordPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = dnsAdmin.UpdateRecord(ctx, zone, record, payload.Value)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch dns record failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getRecordResponse(ctx, zone, record))
+}
+
+// @Summary delete a dns record
+// @Description delete a custom dns record
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id}/records/{record_id} [delete]
+func (v *DnsAPI) DeleteRecord(c *gin.Context) {
+	ctx := c.Request.Context()
+	zone, record, err := v.getZoneRecord(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	err = dnsAdmin.DeleteRecord(ctx, zone, record)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a dns record
+// @Description create a custom dns record, the name is relative to the zone with "@" for the zone itself, the name of a PTR record is an ipv4 address
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   DnsRecordPayload  true   "Dns record create payload"
+// @Success 200 {object} DnsRecordResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id}/records [post]
+func (v *DnsAPI) CreateRecord(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, zone, err := v.getVPCZone(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid dns zone query", err)
+		return
+	}
+	payload := &DnsRecordPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	record, err := dnsAdmin.CreateRecord(ctx, zone, payload.Name, payload.Type, payload.Value)
+	if err != nil {
+		logger.Errorf("Failed to create dns record %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getRecordResponse(ctx, zone, record))
+}
+
+func (v *DnsAPI) getRecordResponse(ctx context.Context, zone *model.DnsZone, record *model.DnsRecord) (recordResp *DnsRecordResponse) {
+	owner := orgAdmin.GetOrgName(ctx, record.Owner)
+	recordResp = &DnsRecordResponse{
+		ResourceReference: &ResourceReference{
+			ID:        record.UUID,
+			Name:      record.Name,
+			Owner:     owner,
+			CreatedAt: record.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: record.UpdatedAt.Format(TimeStringForMat),
+		},
+		FQDN:  routes.RecordFQDN(zone, record),
+		Type:  record.Type,
+		Value: record.Value,
+	}
+	if record.Type == model.DnsRecordPTR {
+		recordResp.FQDN = record.Name
+	}
+	if record.InstanceID > 0 {
+		instance, err := instanceAdmin.Get(ctx, record.InstanceID)
+		if err == nil {
+			recordResp.Instance = instance.UUID
+		}
+	}
+	return
+}
+
+// @Summary list dns records
+// @Description list dns records of a zone
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} DnsRecordListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/dns_zones/{zone_id}/records [get]
+func (v *DnsAPI) ListRecords(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, zone, err := v.getVPCZone(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid dns zone query", err)
+		return
+	}
+	offset, limit, err := v.getOffsetLimit(c)
+	if err != nil {
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.DnsRecord{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, records, err := dnsAdmin.ListRecords(ctx, int64(offset), int64(limit), "-created_at", zone)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list dns records", err)
+		return
+	}
+	recordListResp := &DnsRecordListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(records),
+	}
+	recordListResp.DnsRecords = make([]*DnsRecordResponse, recordListResp.Limit)
+	for i, record := range records {
+		recordListResp.DnsRecords[i] = v.getRecordResponse(ctx, zone, record)
+	}
+	listQuery.SetNextCursor(c, records, limit)
+	c.JSON(http.StatusOK, recordListResp)
+}
@@ -11,6 +11,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	. "web/src/common"
 	"web/src/model"
@@ -25,10 +26,25 @@ var flavorAdmin = &routes.FlavorAdmin{}
 type FlavorAPI struct{}
 
 type FlavorResponse struct {
-	Name   string `json:"name"`
-	Cpu    int32  `json:"cpu"`
-	Memory int32  `json:"memory"`
-	Disk   int32  `json:"disk"`
+	Name       string             `json:"name"`
+	Cpu        int32              `json:"cpu"`
+	Memory     int32              `json:"memory"`
+	Disk       int32              `json:"disk"`
+	ExtraSpecs *ExtraSpecsPayload `json:"extra_specs,omitempty"`
+}
+
+type ExtraSpecsPayload struct {
+	CpuPolicy    string   `json:"cpu_policy,omitempty" binding:"omitempty,oneof=shared dedicated"`
+	NumaNodes    int32    `json:"numa_nodes,omitempty" binding:"omitempty,gte=1,lte=8"`
+	HugepageSize string   `json:"hugepage_size,omitempty" binding:"omitempty,oneof=2M 1G"`
+	CpuModel     string   `json:"cpu_model,omitempty" binding:"omitempty,min=1,max=64"`
+	CpuFeatures  []string `json:"cpu_features,omitempty" binding:"omitempty,lte=32,dive,min=1,max=32"`
+	Watchdog     string   `json:"watchdog,omitempty" binding:"omitempty,oneof=reset shutdown poweroff pause none inject-nmi"`
+	VirtioQueues int32    `json:"virtio_queues,omitempty" binding:"omitempty,gte=1,lte=64"`
+	MaxCpu       int32    `json:"max_cpu,omitempty" binding:"omitempty,gte=1,lte=256"`
+	MaxMemory    int32    `json:"max_memory,omitempty" binding:"omitempty,gte=16"`
+	// AggregateRules require key=value or forbid !key=value metadata of the host aggregates
+	AggregateRules []string `json:"aggregate_rules,omitempty" binding:"omitempty,lte=16"`
 }
 
 type FlavorListResponse struct {
@@ -43,6 +59,8 @@ type FlavorPayload struct {
 	CPU    int32  `json:"cpu" binding:"required,gte=1"`
 	Memory int32  `json:"memory" binding:"required,gte=16"`
 	Disk   int32  `json:"disk" binding:"required,gte=1"`
+	// ExtraSpecs tune the guest, e.g. {"cpu_policy": "dedicated", "numa_nodes": 2, "hugepage_size": "1G"}
+	ExtraSpecs *ExtraSpecsPayload `json:"extra_specs" binding:"omitempty"`
 }
 
 // @Summary get a flavor
@@ -67,6 +85,7 @@ func (v *FlavorAPI) Get(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
 	}
+	SetETag(c, flavor.UpdatedAt)
 	c.JSON(http.StatusOK, flavorResp)
 }
 
@@ -78,6 +97,7 @@ func (v *FlavorAPI) Get(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /flavors/{name} [delete]
 func (v *FlavorAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -87,6 +107,9 @@ func (v *FlavorAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, flavor, flavor.UpdatedAt) {
+		return
+	}
 	err = flavorAdmin.Delete(ctx, flavor)
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
@@ -113,7 +136,7 @@ func (v *FlavorAPI) Create(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
 		return
 	}
-	flavor, err := flavorAdmin.Create(ctx, payload.Name, payload.CPU, payload.Memory, payload.Disk)
+	flavor, err := flavorAdmin.Create(ctx, payload.Name, payload.CPU, payload.Memory, payload.Disk, payload.ExtraSpecs.toModel())
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
 		return
@@ -133,6 +156,46 @@ func (v *FlavorAPI) getFlavorResponse(ctx context.Context, flavor *model.Flavor)
 		Memory: flavor.Memory,
 		Disk:   flavor.Disk,
 	}
+	flavorResp.ExtraSpecs = getExtraSpecsPayload(&flavor.ExtraSpecs)
+	return
+}
+
+func (p *ExtraSpecsPayload) toModel() (specs *model.ExtraSpecs) {
+	if p == nil {
+		return
+	}
+	return &model.ExtraSpecs{
+		CpuPolicy:      p.CpuPolicy,
+		NumaNodes:      p.NumaNodes,
+		HugepageSize:   p.HugepageSize,
+		CpuModel:       p.CpuModel,
+		CpuFeatures:    strings.Join(p.CpuFeatures, ","),
+		Watchdog:       p.Watchdog,
+		VirtioQueues:   p.VirtioQueues,
+		MaxCpu:         p.MaxCpu,
+		MaxMemory:      p.MaxMemory,
+		AggregateRules: strings.Join(p.AggregateRules, ","),
+	}
+}
+
+func getExtraSpecsPayload(specs *model.ExtraSpecs) (payload *ExtraSpecsPayload) {
+	if specs.IsEmpty() {
+		return
+	}
+	payload = &ExtraSpecsPayload{
+		CpuPolicy:    specs.CpuPolicy,
+		NumaNodes:    specs.NumaNodes,
+		HugepageSize: specs.HugepageSize,
+		CpuModel:     specs.CpuModel,
+		Watchdog:     specs.Watchdog,
+		VirtioQueues: specs.VirtioQueues,
+		MaxCpu:       specs.MaxCpu,
+		MaxMemory:    specs.MaxMemory,
+	}
+	if specs.CpuFeatures != "" {
+		payload.CpuFeatures = strings.Split(specs.CpuFeatures, ",")
+	}
+	payload.AggregateRules = AggregateRules(specs.AggregateRules)
 	return
 }
 
@@ -141,6 +204,9 @@ func (v *FlavorAPI) getFlavorResponse(ctx context.Context, flavor *model.Flavor)
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} FlavorListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /flavors [get]
@@ -162,6 +228,12 @@ func (v *FlavorAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Flavor{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, flavors, err := flavorAdmin.List(ctx, int64(offset), int64(limit), "-created_at", "")
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to list flavors", err)
@@ -180,5 +252,6 @@ func (v *FlavorAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, flavors, limit)
 	c.JSON(http.StatusOK, flavorListResp)
 }
@@ -123,6 +123,7 @@ func (v *FloatingIpAPI) Get(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
 	}
+	SetETag(c, floatingIp.UpdatedAt)
 	c.JSON(http.StatusOK, floatingIpResp)
 }
 
@@ -135,6 +136,7 @@ func (v *FloatingIpAPI) Get(c *gin.Context) {
 // @Success 200 {object} FloatingIpResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /floating_ips/{id} [patch]
 func (v *FloatingIpAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -146,6 +148,9 @@ func (v *FloatingIpAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid floating ip query", err)
 		return
 	}
+	if !ClaimIfMatch(c, floatingIp, floatingIp.UpdatedAt) {
+		return
+	}
 	if ElasticType(floatingIp.Type) != PublicFloating {
 		logger.Errorf("Wrong public ip type %+v", floatingIp.Type)
 		ErrorResponse(c, http.StatusBadRequest, "Invalid public ip type", err)
@@ -218,6 +223,7 @@ func (v *FloatingIpAPI) Patch(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /floating_ips/{id} [delete]
 func (v *FloatingIpAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -229,6 +235,9 @@ func (v *FloatingIpAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, floatingIp, floatingIp.UpdatedAt) {
+		return
+	}
 	err = floatingIpAdmin.Delete(ctx, floatingIp)
 	if err != nil {
 		logger.Errorf("Failed to delete floating ip %+v", err)
@@ -421,6 +430,9 @@ func (v *FloatingIpAPI) getFloatingIpResponse(ctx context.Context, floatingIp *m
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} FloatingIpListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /floating_ips [get]
@@ -447,6 +459,12 @@ func (v *FloatingIpAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.FloatingIp{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, floatingIps, err := floatingIpAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr, "")
 	if err != nil {
 		logger.Errorf("Failed to list floatingIps %+v", err)
@@ -466,6 +484,7 @@ func (v *FloatingIpAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, floatingIps, limit)
 	c.JSON(http.StatusOK, floatingIpListResp)
 }
 
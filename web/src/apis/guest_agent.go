@@ -0,0 +1,210 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var guestAgentAPI = &GuestAgentAPI{}
+var guestAgentAdmin = &routes.GuestAgentAdmin{}
+
+type GuestAgentAPI struct{}
+
+type GuestExecPayload struct {
+	Path    string   `json:"path" binding:"required,min=1,max=512"`
+	Args    []string `json:"args" binding:"omitempty,lte=64"`
+	Timeout int32    `json:"timeout" binding:"omitempty,gte=1,lte=300"` /* seconds, 30 by default */
+}
+
+type GuestFilePayload struct {
+	Path    string `json:"path" binding:"required,min=1,max=512"`
+	Content string `json:"content" binding:"required,base64"` /* base64 encoded, 64KB at most once decoded */
+}
+
+type GuestCommandResponse struct {
+	*ResourceReference
+	Instance *ResourceReference `json:"instance"`
+	Type     string             `json:"type"`
+	Command  string             `json:"command"`
+	Status   string             `json:"status"`
+	ExitCode int32              `json:"exit_code"`
+	Output   string             `json:"output"`
+}
+
+// @Summary execute a command in a instance
+// @Description run a command inside a instance through its guest agent, admin only. The result is returned if the command finishes within 30 seconds, otherwise 202 is returned and the command can be looked up later
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance UUID"
+// @Param   message	body   GuestExecPayload  true   "Guest exec payload"
+// @Success 200 {object} GuestCommandResponse
+// @Success 202 {object} GuestCommandResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instances/{id}/guest_exec [post]
+func (v *GuestAgentAPI) Exec(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Failed to get instance %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	payload := &GuestExecPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind JSON, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	logger.Debugf("Execute %s in instance %s", payload.Path, uuID)
+	guestCmd, err := guestAgentAdmin.Exec(ctx, instance, payload.Path, payload.Args, payload.Timeout)
+	if err != nil {
+		logger.Errorf("Guest exec failed, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Guest exec failed", err)
+		return
+	}
+	status := http.StatusOK
+	if guestCmd.Status == model.GuestCommandStatusRunning {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, v.getGuestCommandResponse(c, instance, guestCmd))
+}
+
+// @Summary write a file in a instance
+// @Description write a file inside a instance through its guest agent, the file is created or truncated
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance UUID"
+// @Param   message	body   GuestFilePayload  true   "Guest file payload"
+// @Success 202 {object} GuestCommandResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instances/{id}/guest_file [post]
+func (v *GuestAgentAPI) InjectFile(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Failed to get instance %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	payload := &GuestFilePayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind JSON, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(payload.Content)
+	if err != nil || len(content) > routes.MaxGuestFileSize {
+		logger.Errorf("Invalid file content for instance %s", uuID)
+		ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Content must be base64 of at most %d bytes", routes.MaxGuestFileSize), err)
+		return
+	}
+	logger.Debugf("Write file %s in instance %s", payload.Path, uuID)
+	guestCmd, err := guestAgentAdmin.InjectFile(ctx, instance, payload.Path, content)
+	if err != nil {
+		logger.Errorf("Guest file write failed, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Guest file write failed", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, v.getGuestCommandResponse(c, instance, guestCmd))
+}
+
+// @Summary refresh the guest info of a instance
+// @Description ask the guest agent of a instance for its OS and addresses, they show up on the instance when the agent answers
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance UUID"
+// @Success 202
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instances/{id}/guest_info [post]
+func (v *GuestAgentAPI) RefreshInfo(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Failed to get instance %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	err = guestAgentAdmin.RefreshInfo(ctx, instance)
+	if err != nil {
+		logger.Errorf("Guest info refresh failed, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Guest info refresh failed", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, nil)
+}
+
+// @Summary get a guest command
+// @Description get the status and output of a command run or a file written in a instance
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance UUID"
+// @Param   command_id  path  string  true  "Guest command UUID"
+// @Success 200 {object} GuestCommandResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instances/{id}/guest_commands/{command_id} [get]
+func (v *GuestAgentAPI) GetCommand(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	cmdID := c.Param("command_id")
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Failed to get instance %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	guestCmd, err := guestAgentAdmin.GetCommand(ctx, instance, cmdID)
+	if err != nil {
+		logger.Errorf("Failed to get guest command %s, %+v", cmdID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid guest command query", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getGuestCommandResponse(c, instance, guestCmd))
+}
+
+func (v *GuestAgentAPI) getGuestCommandResponse(c *gin.Context, instance *model.Instance, guestCmd *model.GuestCommand) *GuestCommandResponse {
+	owner := orgAdmin.GetOrgName(c.Request.Context(), guestCmd.Owner)
+	return &GuestCommandResponse{
+		ResourceReference: &ResourceReference{
+			ID:        guestCmd.UUID,
+			Owner:     owner,
+			CreatedAt: guestCmd.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: guestCmd.UpdatedAt.Format(TimeStringForMat),
+		},
+		Instance: &ResourceReference{
+			ID:    instance.UUID,
+			Owner: owner,
+		},
+		Type:     guestCmd.Type,
+		Command:  guestCmd.Command,
+		Status:   guestCmd.Status,
+		ExitCode: guestCmd.ExitCode,
+		Output:   guestCmd.Output,
+	}
+}
@@ -24,27 +24,28 @@ var hyperAdmin = &routes.HyperAdmin{}
 type HyperAPI struct{}
 
 type HyperResponse struct {
-	Hostid       int32   `json:"hostid"`
-	Hostname     string  `json:"hostname"`
-	Status       int32   `json:"status"`
-	StatusName   string  `json:"status_name"`
-	Parentid     int32   `json:"parentid"`
-	Children     int32   `json:"children"`
-	HostIP       string  `json:"host_ip"`
-	RouteIP      string  `json:"route_ip"`
-	VirtType     string  `json:"virt_type"`
-	CpuOverRate  float32 `json:"cpu_over_rate"`
-	MemOverRate  float32 `json:"mem_over_rate"`
-	DiskOverRate float32 `json:"disk_over_rate"`
-	ZoneID       int64   `json:"zone_id"`
-	ZoneName     string  `json:"zone_name"`
-	Remark       string  `json:"remark"`
-	Cpu          int64   `json:"cpu"`
-	CpuTotal     int64   `json:"cpu_total"`
-	Memory       int64   `json:"memory"`
-	MemoryTotal  int64   `json:"memory_total"`
-	Disk         int64   `json:"disk"`
-	DiskTotal    int64   `json:"disk_total"`
+	Hostid       int32             `json:"hostid"`
+	Hostname     string            `json:"hostname"`
+	Status       int32             `json:"status"`
+	StatusName   string            `json:"status_name"`
+	Parentid     int32             `json:"parentid"`
+	Children     int32             `json:"children"`
+	HostIP       string            `json:"host_ip"`
+	RouteIP      string            `json:"route_ip"`
+	VirtType     string            `json:"virt_type"`
+	CpuOverRate  float32           `json:"cpu_over_rate"`
+	MemOverRate  float32           `json:"mem_over_rate"`
+	DiskOverRate float32           `json:"disk_over_rate"`
+	ZoneID       int64             `json:"zone_id"`
+	ZoneName     string            `json:"zone_name"`
+	Remark       string            `json:"remark"`
+	Cpu          int64             `json:"cpu"`
+	CpuTotal     int64             `json:"cpu_total"`
+	Memory       int64             `json:"memory"`
+	MemoryTotal  int64             `json:"memory_total"`
+	Disk         int64             `json:"disk"`
+	DiskTotal    int64             `json:"disk_total"`
+	Tags         map[string]string `json:"tags,omitempty"`
 }
 
 type HyperListResponse struct {
@@ -58,14 +59,15 @@ type HyperPayload struct {
 }
 
 type HyperPatchPayload struct {
-	Status       *int32         `json:"status" binding:"omitempty,min=0,max=1"`
-	ZoneID       *int64         `json:"zone_id" binding:"omitempty,min=1"`
-	RouteIP      *string        `json:"route_ip"`
-	Subnet       *BaseReference `json:"subnet" binding:"omitempty"`
-	CpuOverRate  *float32       `json:"cpu_over_rate" binding:"omitempty,min=1"`
-	MemOverRate  *float32       `json:"mem_over_rate" binding:"omitempty,min=1"`
-	DiskOverRate *float32       `json:"disk_over_rate" binding:"omitempty,min=1"`
-	Remark       *string        `json:"remark"`
+	Status       *int32            `json:"status" binding:"omitempty,min=0,max=1"`
+	ZoneID       *int64            `json:"zone_id" binding:"omitempty,min=1"`
+	RouteIP      *string           `json:"route_ip"`
+	Subnet       *BaseReference    `json:"subnet" binding:"omitempty"`
+	CpuOverRate  *float32          `json:"cpu_over_rate" binding:"omitempty,min=1"`
+	MemOverRate  *float32          `json:"mem_over_rate" binding:"omitempty,min=1"`
+	DiskOverRate *float32          `json:"disk_over_rate" binding:"omitempty,min=1"`
+	Remark       *string           `json:"remark"`
+	Tags         map[string]string `json:"tags"` /* replaces all tags of the hypervisor when given */
 }
 
 // @Summary get a hypervisor
@@ -94,6 +96,11 @@ func (v *HyperAPI) Get(c *gin.Context) {
 	}
 
 	hyperResp := convertHyperToResponse(hyper)
+	hyperResp.Tags, err = hyperAdmin.Tags(c.Request.Context(), hyper)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to query hypervisor tags", err)
+		return
+	}
 	c.JSON(http.StatusOK, hyperResp)
 }
 
@@ -106,6 +113,9 @@ func (v *HyperAPI) Get(c *gin.Context) {
 // @Param limit query int false "Limit for pagination"
 // @Param order query string false "Order by field"
 // @Param q query string false "Search query"
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} HyperListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /hypers [get]
@@ -134,7 +144,13 @@ func (v *HyperAPI) List(c *gin.Context) {
 		limitInt = 16
 	}
 
-	total, hypers, err := hyperAdmin.List(c.Request.Context(), offsetInt, limitInt, order, query)
+	listQuery, err := routes.ParseListQuery(c, &model.Hyper{}, order)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx := WithListQuery(c.Request.Context(), listQuery)
+	total, hypers, err := hyperAdmin.List(ctx, offsetInt, limitInt, order, query)
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
@@ -151,11 +167,12 @@ func (v *HyperAPI) List(c *gin.Context) {
 		Limit:  int(limitInt),
 		Hypers: hyperResponses,
 	}
+	listQuery.SetNextCursor(c, hypers, int(limitInt))
 	c.JSON(http.StatusOK, hyperListResp)
 }
 
 // @Summary update a hypervisor
-// @Description update hypervisor status, zone, over-commit rates, and remark
+// @Description update hypervisor status, zone, over-commit rates, remark, and tags
 // @tags Administration
 // @Accept  json
 // @Produce json
@@ -216,6 +233,13 @@ func (v *HyperAPI) Patch(c *gin.Context) {
 		return
 	}
 
+	if payload.Tags != nil {
+		if err := hyperAdmin.SetTags(c.Request.Context(), hyper, payload.Tags); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid tags", err)
+			return
+		}
+	}
+
 	// Handle RouteIP change: nil = don't touch, "" = auto-assign from subnet, value = use specified IP
 	if payload.RouteIP != nil {
 		routeIP := *payload.RouteIP
@@ -244,6 +268,11 @@ func (v *HyperAPI) Patch(c *gin.Context) {
 	}
 
 	hyperResp := convertHyperToResponse(updatedHyper)
+	hyperResp.Tags, err = hyperAdmin.Tags(c.Request.Context(), updatedHyper)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to query hypervisor tags", err)
+		return
+	}
 	c.JSON(http.StatusOK, hyperResp)
 }
 
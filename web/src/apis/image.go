@@ -28,15 +28,16 @@ type ImageAPI struct{}
 
 type ImageResponse struct {
 	*ResourceReference
-	OSCode       string `json:"os_code"`
-	OSVersion    string `json:"os_version"`
-	Size         int64  `json:"size"`
-	Format       string `json:"format"`
-	Architecture string `json:"architecture"`
-	User         string `json:"user"`
-	Status       string `json:"status"`
-	BootLoader   string `json:"boot_loader"`
-	OsFamily     string `json:"os_family"`
+	OSCode         string   `json:"os_code"`
+	OSVersion      string   `json:"os_version"`
+	Size           int64    `json:"size"`
+	Format         string   `json:"format"`
+	Architecture   string   `json:"architecture"`
+	User           string   `json:"user"`
+	Status         string   `json:"status"`
+	BootLoader     string   `json:"boot_loader"`
+	OsFamily       string   `json:"os_family"`
+	AggregateRules []string `json:"aggregate_rules,omitempty"`
 	// QAEnabled    bool   `json:"qa_enabled"`
 }
 
@@ -69,6 +70,8 @@ type ImagePatchPayload struct {
 	Pools     []string `json:"pools" binding:"omitempty"`
 	OsFamily  string   `json:"os_family" binding:"required"`
 	UUID      string   `json:"uuid,omitempty" binding:"omitempty"`
+	// AggregateRules keep the guests of the image on or off hypervisors by aggregate metadata, e.g. ["license=windows"], admin only
+	AggregateRules *[]string `json:"aggregate_rules,omitempty" binding:"omitempty"`
 }
 
 type ImageStorageResponse struct {
@@ -111,6 +114,7 @@ func (v *ImageAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get image %s success, response: %+v", uuID, imageResp)
+	SetETag(c, image.UpdatedAt)
 	c.JSON(http.StatusOK, imageResp)
 }
 
@@ -123,6 +127,7 @@ func (v *ImageAPI) Get(c *gin.Context) {
 // @Success 200 {object} ImageResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /images/{id} [patch]
 func (v *ImageAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -134,6 +139,9 @@ func (v *ImageAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid image query", err)
 		return
 	}
+	if !ClaimIfMatch(c, image, image.UpdatedAt) {
+		return
+	}
 	payload := &ImagePatchPayload{}
 	err = c.ShouldBindJSON(payload)
 	if err != nil {
@@ -147,6 +155,13 @@ func (v *ImageAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Patch image failed", err)
 		return
 	}
+	if payload.AggregateRules != nil {
+		image.AggregateRules, err = aggregateAdmin.SetRules(ctx, image, *payload.AggregateRules)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Patch image failed", err)
+			return
+		}
+	}
 	imageResp, err := v.getImageResponse(ctx, image)
 	if err != nil {
 		logger.Errorf("Failed to create image response, %+v", err)
@@ -165,6 +180,7 @@ func (v *ImageAPI) Patch(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /images/{id} [delete]
 func (v *ImageAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -176,6 +192,9 @@ func (v *ImageAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, image, image.UpdatedAt) {
+		return
+	}
 	err = imageAdmin.Delete(ctx, image)
 	if err != nil {
 		logger.Errorf("Failed to delete image %s, %+v", uuID, err)
@@ -266,6 +285,7 @@ func (v *ImageAPI) getImageResponse(ctx context.Context, image *model.Image) (im
 		OsFamily:     image.OsFamily,
 		// QAEnabled:    image.QAEnabled,
 	}
+	imageResp.AggregateRules = AggregateRules(image.AggregateRules)
 	return
 }
 
@@ -274,6 +294,9 @@ func (v *ImageAPI) getImageResponse(ctx context.Context, image *model.Image) (im
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} ImageListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /images [get]
@@ -300,6 +323,12 @@ func (v *ImageAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Image{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, images, err := imageAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list images %+v", err)
@@ -321,6 +350,7 @@ func (v *ImageAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List images success, response: %+v", imageListResp)
+	listQuery.SetNextCursor(c, images, limit)
 	c.JSON(http.StatusOK, imageListResp)
 }
 
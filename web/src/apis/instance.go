@@ -9,6 +9,7 @@ package apis
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -18,6 +19,7 @@ import (
 	"web/src/routes"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 var instanceAPI = &InstanceAPI{}
@@ -44,8 +46,14 @@ type InstanceReinstallPayload struct {
 }
 
 type InstanceResizePayload struct {
-	Cpu    int32 `json:"cpu" binding:"omitempty,gte=1"`
-	Memory int32 `json:"memory" binding:"omitempty,gte=1"`
+	Flavor string `json:"flavor" binding:"omitempty,min=1,max=32"`
+	Cpu    int32  `json:"cpu" binding:"omitempty,gte=1"`
+	Memory int32  `json:"memory" binding:"omitempty,gte=1"`
+	Disk   int32  `json:"disk" binding:"omitempty,gte=1"` /* boot disk in GB, grown online only */
+	// Mode online hot-adds vcpus and memory to the running guest, offline restarts it with the new size
+	Mode string `json:"mode" binding:"omitempty,oneof=online offline"`
+	// Migrate moves the guest to another hypervisor if its own lacks the capacity, offline only
+	Migrate bool `json:"migrate"`
 }
 
 type InstanceRescuePayload struct {
@@ -54,29 +62,40 @@ type InstanceRescuePayload struct {
 }
 
 type InstancePayload struct {
-	Count               int                 `json:"count" binding:"omitempty,gte=1,lte=16"`
-	Hypervisor          *int                `json:"hypervisor" binding:"omitempty,gte=0,lte=65535"`
-	Hostname            string              `json:"hostname" binding:"required,hostname|fqdn"`
-	Keys                []*BaseReference    `json:"keys" binding:"omitempty,gte=0,lte=16"`
-	RootPasswd          string              `json:"root_passwd" binding:"omitempty,min=8,max=32"`
-	LoginPort           int                 `json:"login_port" binding:"omitempty,min=0,max=65535"`
-	Cpu                 int32               `json:"cpu" binding:"omitempty,gte=1"`
-	Memory              int32               `json:"memory" binding:"omitempty,gte=1"`
-	Disk                int32               `json:"disk" binding:"omitempty,gte=1"`
-	DiskIopsLimit       int32               `json:"disk_iops_limit" binding:"omitempty,gte=0,lte=10000000"`
-	DiskBpsLimit        int32               `json:"disk_bps_limit" binding:"omitempty,gte=0,lte=102400"` // in MB/s
-	Flavor              string              `json:"flavor" binding:"omitempty,min=1,max=32"`
-	Image               *BaseReference      `json:"image" binding:"required"`
-	PrimaryInterface    *InterfacePayload   `json:"primary_interface" binding:"required"`
-	SecondaryInterfaces []*InterfacePayload `json:"secondary_interfaces" binding:"omitempty,gte=0,lte=7"`
-	Zone                string              `json:"zone" binding:"required,min=1,max=32"`
-	VPC                 *BaseReference      `json:"vpc" binding:"omitempty"`
-	Userdata            string              `json:"userdata,omitempty"`
-	UserdataType        string              `json:"userdata_type,omitempty"`
-	NestedEnable        bool                `json:"nested_enable,omitempty"`
-	PoolID              string              `json:"pool_id" binding:"omitempty"`
-	Vendordata          string              `json:"vendordata,omitempty"`
-	Vendordatatype      string              `json:"vendordatatype,omitempty"`
+	Count               int                      `json:"count" binding:"omitempty,gte=1,lte=16"`
+	Hypervisor          *int                     `json:"hypervisor" binding:"omitempty,gte=0,lte=65535"`
+	Hostname            string                   `json:"hostname" binding:"required,hostname|fqdn"`
+	Keys                []*BaseReference         `json:"keys" binding:"omitempty,gte=0,lte=16"`
+	RootPasswd          string                   `json:"root_passwd" binding:"omitempty,min=8,max=32"`
+	LoginPort           int                      `json:"login_port" binding:"omitempty,min=0,max=65535"`
+	Cpu                 int32                    `json:"cpu" binding:"omitempty,gte=1"`
+	Memory              int32                    `json:"memory" binding:"omitempty,gte=1"`
+	Disk                int32                    `json:"disk" binding:"omitempty,gte=1"`
+	DiskIopsLimit       int32                    `json:"disk_iops_limit" binding:"omitempty,gte=0,lte=10000000"`
+	DiskBpsLimit        int32                    `json:"disk_bps_limit" binding:"omitempty,gte=0,lte=102400"` // in MB/s
+	Flavor              string                   `json:"flavor" binding:"omitempty,min=1,max=32"`
+	Image               *BaseReference           `json:"image" binding:"required"`
+	PrimaryInterface    *InterfacePayload        `json:"primary_interface" binding:"required"`
+	SecondaryInterfaces []*InterfacePayload      `json:"secondary_interfaces" binding:"omitempty,gte=0,lte=7"`
+	Zone                string                   `json:"zone" binding:"required,min=1,max=32"`
+	VPC                 *BaseReference           `json:"vpc" binding:"omitempty"`
+	Userdata            string                   `json:"userdata,omitempty"`
+	UserdataType        string                   `json:"userdata_type,omitempty"`
+	NestedEnable        bool                     `json:"nested_enable,omitempty"`
+	PoolID              string                   `json:"pool_id" binding:"omitempty"`
+	Vendordata          string                   `json:"vendordata,omitempty"`
+	Vendordatatype      string                   `json:"vendordatatype,omitempty"`
+	Tags                map[string]string        `json:"tags,omitempty"`
+	LaunchTemplate      *LaunchTemplateReference `json:"launch_template,omitempty"`
+	SchedulerHints      *SchedulerHintsPayload   `json:"scheduler_hints,omitempty"`
+}
+
+// SchedulerHintsPayload places the instances next to or away from existing instances
+// and on hypervisors with the given tags
+type SchedulerHintsPayload struct {
+	SameHost      []*BaseReference  `json:"same_host" binding:"omitempty,lte=16"`
+	DifferentHost []*BaseReference  `json:"different_host" binding:"omitempty,lte=16"`
+	HyperTags     map[string]string `json:"hyper_tags,omitempty"`
 }
 
 type InstanceResponse struct {
@@ -90,6 +109,7 @@ type InstanceResponse struct {
 	Memory      int32                 `json:"memory"`
 	Disk        int32                 `json:"disk"`
 	Flavor      string                `json:"flavor"`
+	ExtraSpecs  *ExtraSpecsPayload    `json:"extra_specs,omitempty"`
 	Image       *ResourceReference    `json:"image"`
 	Keys        []*ResourceReference  `json:"keys"`
 	PasswdLogin bool                  `json:"passwd_login"`
@@ -97,6 +117,9 @@ type InstanceResponse struct {
 	VPC         *ResourceReference    `json:"vpc,omitempty"`
 	Hypervisor  string                `json:"hypervisor,omitempty"`
 	Reason      string                `json:"reason"`
+	GuestOS     string                `json:"guest_os,omitempty"`
+	GuestIps    []string              `json:"guest_ips,omitempty"`
+	GuestInfoAt string                `json:"guest_info_at,omitempty"`
 }
 
 type InstanceListResponse struct {
@@ -131,6 +154,7 @@ func (v *InstanceAPI) Get(c *gin.Context) {
 		return
 	}
 
+	SetETag(c, instance.UpdatedAt)
 	c.JSON(http.StatusOK, instanceResp)
 }
 
@@ -143,6 +167,7 @@ func (v *InstanceAPI) Get(c *gin.Context) {
 // @Success 200 {object} InstanceResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /instances/{id} [patch]
 func (v *InstanceAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -154,6 +179,9 @@ func (v *InstanceAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
 		return
 	}
+	if !ClaimIfMatch(c, instance, instance.UpdatedAt) {
+		return
+	}
 	payload := &InstancePatchPayload{}
 	err = c.ShouldBindJSON(payload)
 	if err != nil {
@@ -422,8 +450,12 @@ func (v *InstanceAPI) Resize(c *gin.Context) {
 	}
 	logger.Debugf("Resize instance %s with payload %+v", uuID, payload)
 
+	if payload.Flavor != "" && (payload.Cpu > 0 || payload.Memory > 0) {
+		logger.Errorf("Resize instance %s with both flavor and size", uuID)
+		ErrorResponse(c, http.StatusBadRequest, "Resize with either a flavor or cpu and memory", nil)
+		return
+	}
 	// old data compatibility
-	cpu, memory := instance.Cpu, instance.Memory
 	if instance.Cpu == 0 {
 		var flavor *model.Flavor
 		flavor, err = flavorAdmin.Get(ctx, instance.FlavorID)
@@ -432,8 +464,20 @@ func (v *InstanceAPI) Resize(c *gin.Context) {
 			ErrorResponse(c, http.StatusBadRequest, "Invalid flavor", err)
 			return
 		}
-		cpu, memory = flavor.Cpu, flavor.Memory
+		instance.Cpu, instance.Memory = flavor.Cpu, flavor.Memory
+	}
+	// a new flavor becomes the flavor of the instance and brings its extra specs along,
+	// otherwise the instance keeps its own
+	var flavor *model.Flavor
+	if payload.Flavor != "" {
+		flavor, err = flavorAdmin.GetFlavorByName(ctx, payload.Flavor)
+		if err != nil {
+			logger.Errorf("Failed to get flavor %+v, %+v", payload.Flavor, err)
+			ErrorResponse(c, http.StatusBadRequest, "Invalid flavor", err)
+			return
+		}
 	}
+	cpu, memory := instance.Cpu, instance.Memory
 	if payload.Cpu > 0 {
 		cpu = payload.Cpu
 	}
@@ -441,8 +485,8 @@ func (v *InstanceAPI) Resize(c *gin.Context) {
 		memory = payload.Memory
 	}
 
-	logger.Debugf("Resize instance %s to cpu %d, memory %d", uuID, cpu, memory)
-	err = instanceAdmin.Resize(ctx, instance, cpu, memory)
+	logger.Debugf("Resize instance %s to cpu %d, memory %d, disk %d", uuID, cpu, memory, payload.Disk)
+	err = instanceAdmin.Resize(ctx, instance, flavor, cpu, memory, payload.Disk, payload.Mode, payload.Migrate)
 	if err != nil {
 		logger.Errorf("Failed to resize instance %s, %+v", uuID, err)
 		ErrorResponse(c, http.StatusBadRequest, "Failed to resize instance", err)
@@ -461,6 +505,7 @@ func (v *InstanceAPI) Resize(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /instances/{id} [delete]
 func (v *InstanceAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -472,6 +517,9 @@ func (v *InstanceAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, instance, instance.UpdatedAt) {
+		return
+	}
 	err = instanceAdmin.Delete(ctx, instance)
 	if err != nil {
 		logger.Errorf("Failed to delete instance %s, %+v", uuID, err)
@@ -486,7 +534,7 @@ func (v *InstanceAPI) Delete(c *gin.Context) {
 // @tags Compute
 // @Accept  json
 // @Produce json
-// @Param   message	body   InstancePayload  true   "Instance create payload"
+// @Param   message	body   InstancePayload  true   "Instance create payload, with launch_template its fields override the template version"
 // @Success 200 {array} InstanceResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -495,12 +543,37 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 	logger.Debug("Create instance")
 	ctx := c.Request.Context()
 	payload := &InstancePayload{}
-	err := c.ShouldBindJSON(payload)
-	if err != nil {
+	err := v.bindPayload(c, payload)
+	if _, ok := err.(*CLError); ok {
+		logger.Errorf("Failed to apply launch template, %+v", err)
+		ErrorResponse(c, launchTemplateAPI.errorStatus(err), "Invalid launch template", err)
+		return
+	} else if err != nil {
 		logger.Errorf("Failed to bind instance payload JSON, %+v", err)
 		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
 		return
 	}
+	instances, err := v.create(ctx, payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to create instances", err)
+		return
+	}
+	logger.Debugf("Created %d instances, %+v", len(instances), instances)
+	instancesResp := make([]*InstanceResponse, len(instances))
+	for i, instance := range instances {
+		instancesResp[i], err = v.getInstanceResponse(ctx, instance)
+		if err != nil {
+			logger.Errorf("Failed to create instance response, %+v", err)
+			ErrorResponse(c, http.StatusInternalServerError, "Failed to create instances", err)
+			return
+		}
+	}
+	logger.Debugf("Create instance success, %+v", instancesResp)
+	c.JSON(http.StatusOK, instancesResp)
+}
+
+// create resolves the references of the payload and creates the instances, also used by instance groups
+func (v *InstanceAPI) create(ctx context.Context, payload *InstancePayload) ([]*model.Instance, error) {
 	logger.Debugf("Creating instance with payload: %+v", payload)
 	hostname := payload.Hostname
 	rootPasswd := payload.RootPasswd
@@ -508,8 +581,7 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 	image, err := imageAdmin.GetImage(ctx, payload.Image)
 	if err != nil {
 		logger.Errorf("Failed to get image %+v, %+v", payload.Image, err)
-		ErrorResponse(c, http.StatusBadRequest, "Invalid image", err)
-		return
+		return nil, fmt.Errorf("Invalid image, %w", err)
 	}
 
 	var flavor *model.Flavor
@@ -517,30 +589,26 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 		flavor, err = flavorAdmin.GetFlavorByName(ctx, payload.Flavor)
 		if err != nil {
 			logger.Errorf("Failed to get flavor %+v, %+v", payload.Flavor, err)
-			ErrorResponse(c, http.StatusBadRequest, "Invalid flavor", err)
-			return
+			return nil, fmt.Errorf("Invalid flavor, %w", err)
 		}
 	}
 	zone, err := zoneAdmin.GetZoneByName(ctx, payload.Zone)
 	if err != nil {
 		logger.Errorf("Failed to get zone %+v, %+v", payload.Zone, err)
-		ErrorResponse(c, http.StatusBadRequest, "Invalid zone", err)
-		return
+		return nil, fmt.Errorf("Invalid zone, %w", err)
 	}
 	var router *model.Router
 	if payload.VPC != nil {
 		router, err = routerAdmin.GetRouter(ctx, payload.VPC)
 		if err != nil {
 			logger.Errorf("Failed to get VPC %+v, %+v", payload.VPC, err)
-			ErrorResponse(c, http.StatusBadRequest, "Invalid VPC", nil)
-			return
+			return nil, fmt.Errorf("Invalid VPC")
 		}
 	}
 	router, primaryIface, err := interfaceAPI.getInterfaceInfo(ctx, router, payload.PrimaryInterface)
 	if err != nil {
 		logger.Errorf("Failed to get primary interface %+v, %+v", payload.PrimaryInterface, err)
-		ErrorResponse(c, http.StatusBadRequest, "Invalid primary interface", err)
-		return
+		return nil, fmt.Errorf("Invalid primary interface, %w", err)
 	}
 	var secondaryIfaces []*routes.InterfaceInfo
 	for _, ifacePayload := range payload.SecondaryInterfaces {
@@ -548,8 +616,7 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 		router, ifaceInfo, err = interfaceAPI.getInterfaceInfo(ctx, router, ifacePayload)
 		if err != nil {
 			logger.Errorf("Failed to get secondary interface %+v, %+v", ifacePayload, err)
-			ErrorResponse(c, http.StatusBadRequest, "Invalid secondary interfaces", err)
-			return
+			return nil, fmt.Errorf("Invalid secondary interfaces, %w", err)
 		}
 		secondaryIfaces = append(secondaryIfaces, ifaceInfo)
 	}
@@ -573,8 +640,7 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 	}
 	if flavor == nil && (payload.Cpu <= 0 || payload.Memory <= 0 || payload.Disk <= 0) {
 		logger.Errorf("no valid configuration")
-		ErrorResponse(c, http.StatusBadRequest, "no valid configuration", nil)
-		return
+		return nil, fmt.Errorf("no valid configuration")
 	}
 	if payload.Cpu <= 0 {
 		payload.Cpu = flavor.Cpu
@@ -585,13 +651,20 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 	if payload.Disk <= 0 {
 		payload.Disk = flavor.Disk
 	}
+	var specs *model.ExtraSpecs
+	if flavor != nil {
+		specs = &flavor.ExtraSpecs
+	}
+	hints, err := v.getSchedulerHints(ctx, payload.SchedulerHints)
+	if err != nil {
+		return nil, err
+	}
 	userdataType := payload.UserdataType
 	if userdataType == "" {
 		userdataType = model.UserDataTypePlain
 	} else if !model.IsValidUserDataType(userdataType) {
 		logger.Errorf("Invalid userdata_type: %s", userdataType)
-		ErrorResponse(c, http.StatusBadRequest, "Invalid userdata_type", nil)
-		return
+		return nil, fmt.Errorf("Invalid userdata_type")
 	}
 	vendorData := payload.Vendordata
 	vendorDataType := payload.Vendordatatype
@@ -599,30 +672,80 @@ func (v *InstanceAPI) Create(c *gin.Context) {
 		vendorDataType = model.UserDataTypePlain
 	} else if !model.IsValidUserDataType(vendorDataType) {
 		logger.Errorf("Invalid vendor_data_type: %s", vendorDataType)
-		ErrorResponse(c, http.StatusBadRequest, "Invalid vendor_data_type", nil)
-		return
+		return nil, fmt.Errorf("Invalid vendor_data_type")
 	}
 
 	logger.Debugf("Creating %d instances with hostname %s, userdata %s, userdata_type %s, vendordata %s, vendordatatype %s, image %s, zone %s, router %d, primaryIface %v, secondaryIfaces %v, keys %v, login_port %d, hypervisor %d, cpu %d, memory %d, disk %d, disk_iops_limit %d, disk_bps_limit %d, nestedEnable %v, poolID: %s",
 		count, hostname, userdata, userdataType, vendorData, vendorDataType, image.Name, zone.Name, routerID, primaryIface, secondaryIfaces, keys, payload.LoginPort, hypervisor, payload.Cpu, payload.Memory, payload.Disk, payload.DiskIopsLimit, payload.DiskBpsLimit, payload.NestedEnable, payload.PoolID)
-	instances, err := instanceAdmin.Create(ctx, count, hostname, userdata, userdataType, vendorData, vendorDataType, image, zone, routerID, primaryIface, secondaryIfaces, keys, rootPasswd, payload.LoginPort, hypervisor, payload.Cpu, payload.Memory, payload.Disk, payload.DiskIopsLimit, payload.DiskBpsLimit, payload.NestedEnable, payload.PoolID)
+	instances, err := instanceAdmin.Create(ctx, count, hostname, userdata, userdataType, vendorData, vendorDataType, image, zone, routerID, primaryIface, secondaryIfaces, keys, rootPasswd, payload.LoginPort, hypervisor, payload.Cpu, payload.Memory, payload.Disk, specs, hints, payload.DiskIopsLimit, payload.DiskBpsLimit, payload.NestedEnable, payload.PoolID, payload.Tags)
 	if err != nil {
 		logger.Errorf("Failed to create instances, %+v", err)
-		ErrorResponse(c, http.StatusBadRequest, "Failed to create instances", err)
+		return nil, err
+	}
+	return instances, nil
+}
+
+// getSchedulerHints resolves the instances of the hints to the hypervisors they run on
+func (v *InstanceAPI) getSchedulerHints(ctx context.Context, payload *SchedulerHintsPayload) (hints *SchedulerHints, err error) {
+	if payload == nil {
+		return
+	}
+	hints = &SchedulerHints{HyperTags: payload.HyperTags}
+	hypers := func(references []*BaseReference) (hostids []int32, err error) {
+		for _, reference := range references {
+			if reference == nil {
+				continue
+			}
+			var instance *model.Instance
+			instance, err = instanceAdmin.GetInstanceByUUID(ctx, reference.ID)
+			if err != nil {
+				logger.Errorf("Failed to get instance %+v of scheduler hints, %+v", reference, err)
+				return nil, fmt.Errorf("Invalid instance in scheduler hints, %w", err)
+			}
+			if instance.Hyper < 0 {
+				return nil, fmt.Errorf("Instance %s in scheduler hints is not placed yet", reference.ID)
+			}
+			hostids = append(hostids, instance.Hyper)
+		}
 		return
 	}
-	logger.Debugf("Created %d instances, %+v", len(instances), instances)
-	instancesResp := make([]*InstanceResponse, len(instances))
-	for i, instance := range instances {
-		instancesResp[i], err = v.getInstanceResponse(ctx, instance)
+	if hints.SameHost, err = hypers(payload.SameHost); err != nil {
+		return nil, err
+	}
+	if hints.DifferentHost, err = hypers(payload.DifferentHost); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// bindPayload binds the instance payload, with launch_template the fields of the request are laid over
+// the payload of the template version before the payload is validated
+func (v *InstanceAPI) bindPayload(c *gin.Context, payload *InstancePayload) (err error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return
+	}
+	if raw, ok := fields["launch_template"]; ok && string(raw) != "null" {
+		reference := &LaunchTemplateReference{}
+		if err = json.Unmarshal(raw, reference); err != nil {
+			return
+		}
+		fields, err = launchTemplateAPI.instanceFields(c.Request.Context(), reference, fields)
 		if err != nil {
-			logger.Errorf("Failed to create instance response, %+v", err)
-			ErrorResponse(c, http.StatusInternalServerError, "Failed to create instances", err)
+			return
+		}
+		if body, err = json.Marshal(fields); err != nil {
 			return
 		}
 	}
-	logger.Debugf("Create instance success, %+v", instancesResp)
-	c.JSON(http.StatusOK, instancesResp)
+	if err = json.Unmarshal(body, payload); err != nil {
+		return
+	}
+	return binding.Validator.ValidateStruct(payload)
 }
 
 func (v *InstanceAPI) getInstanceResponse(ctx context.Context, instance *model.Instance) (instanceResp *InstanceResponse, err error) {
@@ -642,6 +765,13 @@ func (v *InstanceAPI) getInstanceResponse(ctx context.Context, instance *model.I
 		Cpu:       instance.Cpu,
 		Memory:    instance.Memory,
 		Disk:      instance.Disk,
+		GuestOS:   instance.GuestOS,
+	}
+	if instance.GuestIps != "" {
+		instanceResp.GuestIps = strings.Split(instance.GuestIps, ",")
+	}
+	if instance.GuestInfoAt != nil {
+		instanceResp.GuestInfoAt = instance.GuestInfoAt.Format(TimeStringForMat)
 	}
 	if instance.Image != nil {
 		instanceResp.Image = &ResourceReference{
@@ -652,6 +782,7 @@ func (v *InstanceAPI) getInstanceResponse(ctx context.Context, instance *model.I
 	if instance.Flavor != nil {
 		instanceResp.Flavor = instance.Flavor.Name
 	}
+	instanceResp.ExtraSpecs = getExtraSpecsPayload(&instance.ExtraSpecs)
 	if instance.Zone != nil {
 		instanceResp.Zone = instance.Zone.Name
 	}
@@ -704,6 +835,9 @@ func (v *InstanceAPI) getInstanceResponse(ctx context.Context, instance *model.I
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} InstanceListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /instances [get]
@@ -746,6 +880,12 @@ func (v *InstanceAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Instance{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, instances, err := instanceAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list instances, %+v", err)
@@ -768,6 +908,7 @@ func (v *InstanceAPI) List(c *gin.Context) {
 	}
 	instanceListResp.Instances = instanceList
 	logger.Debugf("List instances success, %+v", instanceListResp)
+	listQuery.SetNextCursor(c, instances, limit)
 	c.JSON(http.StatusOK, instanceListResp)
 }
 
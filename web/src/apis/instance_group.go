@@ -0,0 +1,697 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var instanceGroupAPI = &InstanceGroupAPI{}
+var instanceGroupAdmin = &routes.InstanceGroupAdmin{}
+
+// InstanceGroupAPI serves the instance groups and reconciles their members with the desired count,
+// on changes of the group, on scaling alerts and every instance_group.reconcile_interval seconds
+type InstanceGroupAPI struct{}
+
+type InstanceGroupPayload struct {
+	Name           string                   `json:"name" binding:"required,hostname,max=48"`
+	LaunchTemplate *LaunchTemplateReference `json:"launch_template" binding:"required"`
+	MinCount       int32                    `json:"min_count" binding:"omitempty,gte=0,lte=256"`
+	MaxCount       int32                    `json:"max_count" binding:"required,gte=1,lte=256"`
+	DesiredCount   *int32                   `json:"desired_count" binding:"omitempty,gte=0,lte=256"`
+	Listener       *BaseReference           `json:"listener" binding:"omitempty"`
+	BackendPort    int32                    `json:"backend_port" binding:"omitempty,min=1,max=65535"`
+}
+
+type InstanceGroupPatchPayload struct {
+	MinCount        *int32 `json:"min_count" binding:"omitempty,gte=0,lte=256"`
+	MaxCount        *int32 `json:"max_count" binding:"omitempty,gte=1,lte=256"`
+	DesiredCount    *int32 `json:"desired_count" binding:"omitempty,gte=0,lte=256"`
+	TemplateVersion *int32 `json:"template_version" binding:"omitempty,gte=0"`
+}
+
+type ScalingPolicyPayload struct {
+	Name       string `json:"name" binding:"required,min=2,max=64"`
+	Action     string `json:"action" binding:"required,oneof=scale_out scale_in"`
+	Adjustment int32  `json:"adjustment" binding:"required,gte=1,lte=256"`
+	Cooldown   *int32 `json:"cooldown" binding:"omitempty,gte=0,lte=86400"`
+}
+
+type InstanceGroupResponse struct {
+	*ResourceReference
+	LaunchTemplate  *ResourceReference   `json:"launch_template"`
+	TemplateVersion int32                `json:"template_version"`
+	MinCount        int32                `json:"min_count"`
+	MaxCount        int32                `json:"max_count"`
+	DesiredCount    int32                `json:"desired_count"`
+	Listener        *ResourceReference   `json:"listener,omitempty"`
+	BackendPort     int32                `json:"backend_port,omitempty"`
+	Status          string               `json:"status"`
+	Reason          string               `json:"reason"`
+	Members         []*ResourceReference `json:"members,omitempty"`
+}
+
+type InstanceGroupListResponse struct {
+	Offset         int                      `json:"offset"`
+	Total          int                      `json:"total"`
+	Limit          int                      `json:"limit"`
+	InstanceGroups []*InstanceGroupResponse `json:"instance_groups"`
+}
+
+type ScalingPolicyResponse struct {
+	*ResourceReference
+	Action        string `json:"action"`
+	Adjustment    int32  `json:"adjustment"`
+	Cooldown      int32  `json:"cooldown"`
+	LastTriggered string `json:"last_triggered,omitempty"`
+	// AlertLabels are returned once on creation, the alert rule of the policy must carry them
+	AlertLabels map[string]string `json:"alert_labels,omitempty"`
+}
+
+type ScalingPolicyListResponse struct {
+	Policies []*ScalingPolicyResponse `json:"policies"`
+}
+
+// RunInstanceGroups reconciles all instance groups every instance_group.reconcile_interval seconds,
+// 60 by default, a negative interval disables the background reconciliation
+func RunInstanceGroups() (err error) {
+	interval := viper.GetInt("instance_group.reconcile_interval")
+	if interval < 0 {
+		logger.Info("Instance group reconciliation is disabled")
+		return
+	}
+	if interval == 0 {
+		interval = 60
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx := context.Background()
+		groups, err := instanceGroupAdmin.ListAll(ctx)
+		if err != nil {
+			continue
+		}
+		for _, group := range groups {
+			instanceGroupAPI.reconcile(ctx, group.Owner, group.ID)
+		}
+	}
+	return
+}
+
+// reconcile creates or deletes members of the group until it has the desired count, members whose
+// instance was deleted are dropped and replaced, the newest members are deleted first on scale-in
+func (v *InstanceGroupAPI) reconcile(ctx context.Context, owner, groupID int64) (err error) {
+	// a group being reconciled here or on another API node is left to the next round
+	release, claimed, err := instanceGroupAdmin.Claim(ctx, groupID)
+	if err != nil || !claimed {
+		return
+	}
+	defer release()
+	ctx = instanceGroupAdmin.GroupContext(ctx, &model.InstanceGroup{Owner: owner})
+	group, err := instanceGroupAdmin.Get(ctx, groupID)
+	if err != nil {
+		return
+	}
+	ctx = instanceGroupAdmin.GroupContext(ctx, group)
+	defer func() {
+		if err != nil {
+			logger.Errorf("Failed to reconcile instance group %s, %v", group.Name, err)
+			instanceGroupAdmin.SetStatus(ctx, group, "error", err.Error())
+		} else if group.Status != "active" || group.Reason != "" {
+			instanceGroupAdmin.SetStatus(ctx, group, "active", "")
+		}
+	}()
+	members, gone, err := instanceGroupAdmin.Members(ctx, group)
+	if err != nil {
+		return
+	}
+	for _, member := range gone {
+		logger.Infof("Instance %d of group %s is gone", member.InstanceID, group.Name)
+		if err = v.removeMember(ctx, group, member); err != nil {
+			return
+		}
+	}
+	for _, member := range members {
+		if group.ListenerID > 0 && member.BackendID == 0 {
+			if err = v.addBackend(ctx, group, member); err != nil {
+				return
+			}
+		}
+	}
+	for len(members) < int(group.DesiredCount) {
+		var member *model.InstanceGroupMember
+		if member, err = v.addMember(ctx, group); err != nil {
+			return
+		}
+		members = append(members, member)
+	}
+	for len(members) > int(group.DesiredCount) {
+		if err = v.removeMember(ctx, group, members[len(members)-1]); err != nil {
+			return
+		}
+		members = members[:len(members)-1]
+	}
+	return
+}
+
+// addMember creates an instance from the launch template of the group and registers it in the listener
+func (v *InstanceGroupAPI) addMember(ctx context.Context, group *model.InstanceGroup) (member *model.InstanceGroupMember, err error) {
+	fields, err := launchTemplateAPI.instanceFields(ctx, &LaunchTemplateReference{ID: group.Template.UUID, Version: group.TemplateVersion}, nil)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	payload := &InstancePayload{}
+	if err = json.Unmarshal(data, payload); err != nil {
+		return
+	}
+	prefix := payload.Hostname
+	if prefix == "" {
+		prefix = group.Name
+	}
+	payload.Hostname = fmt.Sprintf("%s-%s", prefix, strings.Split(uuid.New().String(), "-")[0])
+	payload.Count = 1
+	tags := map[string]string{"instance_group": group.Name}
+	for key, value := range payload.Tags {
+		tags[key] = value
+	}
+	payload.Tags = tags
+	if err = binding.Validator.ValidateStruct(payload); err != nil {
+		err = NewCLError(ErrInvalidLaunchTemplate, "Launch template does not make a complete instance payload", err)
+		return
+	}
+	instances, err := instanceAPI.create(ctx, payload)
+	if err != nil {
+		return
+	}
+	logger.Infof("Created instance %s for group %s", instances[0].Hostname, group.Name)
+	member, err = instanceGroupAdmin.AddMember(ctx, group, instances[0], 0)
+	if err != nil {
+		return
+	}
+	if group.ListenerID > 0 {
+		err = v.addBackend(ctx, group, member)
+	}
+	return
+}
+
+// addBackend registers the primary address of the member on the backend port of the group
+func (v *InstanceGroupAPI) addBackend(ctx context.Context, group *model.InstanceGroup, member *model.InstanceGroupMember) (err error) {
+	listener, err := listenerAdmin.Get(ctx, group.ListenerID, nil)
+	if err != nil {
+		return
+	}
+	loadBalancer, err := loadBalancerAdmin.Get(ctx, listener.LoadBalancerID)
+	if err != nil {
+		return
+	}
+	instance, err := instanceAdmin.Get(ctx, member.InstanceID)
+	if err != nil {
+		return
+	}
+	address := ""
+	for _, iface := range instance.Interfaces {
+		if iface.PrimaryIf && iface.Address != nil {
+			address, _, _ = strings.Cut(iface.Address.Address, "/")
+		}
+	}
+	if address == "" {
+		return NewCLError(ErrInvalidInstanceGroup, fmt.Sprintf("Instance %s has no primary address", instance.Hostname), nil)
+	}
+	backend, err := backendAdmin.Create(ctx, instance.Hostname, fmt.Sprintf("%s:%d", address, group.BackendPort), false, listener, loadBalancer)
+	if err != nil {
+		return
+	}
+	return instanceGroupAdmin.SetMemberBackend(ctx, member, backend.ID)
+}
+
+// removeMember deregisters the member from the listener and deletes its instance
+func (v *InstanceGroupAPI) removeMember(ctx context.Context, group *model.InstanceGroup, member *model.InstanceGroupMember) (err error) {
+	if member.BackendID > 0 {
+		backend, err := backendAdmin.Get(ctx, member.BackendID, nil)
+		if err == nil {
+			listener, err := listenerAdmin.Get(ctx, backend.ListenerID, nil)
+			if err != nil {
+				return err
+			}
+			loadBalancer, err := loadBalancerAdmin.Get(ctx, listener.LoadBalancerID)
+			if err != nil {
+				return err
+			}
+			if err = backendAdmin.Delete(ctx, backend, listener, loadBalancer); err != nil {
+				return err
+			}
+		}
+	}
+	if member.Instance != nil {
+		instance, err := instanceAdmin.Get(ctx, member.InstanceID)
+		if err != nil {
+			return err
+		}
+		if err = instanceAdmin.Delete(ctx, instance); err != nil {
+			return err
+		}
+		logger.Infof("Deleted instance %s of group %s", instance.Hostname, group.Name)
+	}
+	return instanceGroupAdmin.RemoveMember(ctx, member)
+}
+
+func (v *InstanceGroupAPI) errorStatus(err error) int {
+	if clErr, ok := err.(*CLError); ok {
+		switch clErr.Code {
+		case ErrInstanceGroupNotFound, ErrScalingPolicyNotFound, ErrLaunchTemplateNotFound, ErrLaunchTemplateVersionNotFound:
+			return http.StatusNotFound
+		case ErrPermissionDenied:
+			return http.StatusForbidden
+		case ErrDatabaseError:
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusBadRequest
+}
+
+func (v *InstanceGroupAPI) getInstanceGroupResponse(ctx context.Context, group *model.InstanceGroup, withMembers bool) (groupResp *InstanceGroupResponse, err error) {
+	owner := orgAdmin.GetOrgName(ctx, group.Owner)
+	groupResp = &InstanceGroupResponse{
+		ResourceReference: &ResourceReference{
+			ID:        group.UUID,
+			Name:      group.Name,
+			Owner:     owner,
+			CreatedAt: group.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: group.UpdatedAt.Format(TimeStringForMat),
+		},
+		TemplateVersion: group.TemplateVersion,
+		MinCount:        group.MinCount,
+		MaxCount:        group.MaxCount,
+		DesiredCount:    group.DesiredCount,
+		BackendPort:     group.BackendPort,
+		Status:          group.Status,
+		Reason:          group.Reason,
+	}
+	if group.Template != nil {
+		groupResp.LaunchTemplate = &ResourceReference{ID: group.Template.UUID, Name: group.Template.Name}
+	}
+	if group.Listener != nil {
+		groupResp.Listener = &ResourceReference{ID: group.Listener.UUID, Name: group.Listener.Name}
+	}
+	if withMembers {
+		var members []*model.InstanceGroupMember
+		members, _, err = instanceGroupAdmin.Members(ctx, group)
+		if err != nil {
+			return
+		}
+		groupResp.Members = make([]*ResourceReference, len(members))
+		for i, member := range members {
+			groupResp.Members[i] = &ResourceReference{ID: member.Instance.UUID, Name: member.Instance.Hostname}
+		}
+	}
+	return
+}
+
+func (v *InstanceGroupAPI) getScalingPolicyResponse(policy *model.ScalingPolicy) *ScalingPolicyResponse {
+	policyResp := &ScalingPolicyResponse{
+		ResourceReference: &ResourceReference{
+			ID:        policy.UUID,
+			Name:      policy.Name,
+			CreatedAt: policy.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: policy.UpdatedAt.Format(TimeStringForMat),
+		},
+		Action:     policy.Action,
+		Adjustment: policy.Adjustment,
+		Cooldown:   policy.Cooldown,
+	}
+	if policy.LastTriggered != nil {
+		policyResp.LastTriggered = policy.LastTriggered.Format(TimeStringForMat)
+	}
+	return policyResp
+}
+
+// @Summary get an instance group
+// @Description get an instance group with its members
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Success 200 {object} InstanceGroupResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /instance_groups/{id} [get]
+func (v *InstanceGroupAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	groupResp, err := v.getInstanceGroupResponse(ctx, group, true)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	SetETag(c, group.UpdatedAt)
+	c.JSON(http.StatusOK, groupResp)
+}
+
+// @Summary create an instance group
+// @Description create an instance group keeping the desired count of instances of a launch template,
+// @Description the members are registered as backends of the listener on the backend port
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   message	body   InstanceGroupPayload  true   "Instance group create payload"
+// @Success 200 {object} InstanceGroupResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instance_groups [post]
+func (v *InstanceGroupAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &InstanceGroupPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	template, err := launchTemplateAdmin.GetLaunchTemplate(ctx, &BaseReference{ID: payload.LaunchTemplate.ID, Name: payload.LaunchTemplate.Name})
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template", err)
+		return
+	}
+	var listener *model.Listener
+	if payload.Listener != nil {
+		listener, err = listenerAdmin.GetListener(ctx, payload.Listener)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid listener", err)
+			return
+		}
+	}
+	desiredCount := payload.MinCount
+	if payload.DesiredCount != nil {
+		desiredCount = *payload.DesiredCount
+	}
+	group, err := instanceGroupAdmin.Create(ctx, payload.Name, template, payload.LaunchTemplate.Version, payload.MinCount, payload.MaxCount, desiredCount, listener, payload.BackendPort)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to create", err)
+		return
+	}
+	go v.reconcile(context.Background(), group.Owner, group.ID)
+	groupResp, err := v.getInstanceGroupResponse(ctx, group, false)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, groupResp)
+}
+
+// @Summary patch an instance group
+// @Description change the counts or the launch template version of an instance group, new members use the new version
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Param   message	body   InstanceGroupPatchPayload  true   "Instance group patch payload"
+// @Success 200 {object} InstanceGroupResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /instance_groups/{id} [patch]
+func (v *InstanceGroupAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	if !ClaimIfMatch(c, group, group.UpdatedAt) {
+		return
+	}
+	payload := &InstanceGroupPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = instanceGroupAdmin.Update(ctx, group, payload.MinCount, payload.MaxCount, payload.DesiredCount, payload.TemplateVersion)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to update", err)
+		return
+	}
+	go v.reconcile(context.Background(), group.Owner, group.ID)
+	groupResp, err := v.getInstanceGroupResponse(ctx, group, false)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, groupResp)
+}
+
+// @Summary delete an instance group
+// @Description delete an instance group with all its members and scaling policies
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /instance_groups/{id} [delete]
+func (v *InstanceGroupAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	if !ClaimIfMatch(c, group, group.UpdatedAt) {
+		return
+	}
+	zero := int32(0)
+	err = instanceGroupAdmin.Update(ctx, group, &zero, nil, &zero, nil)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to delete", err)
+		return
+	}
+	err = v.reconcile(context.Background(), group.Owner, group.ID)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to delete members", err)
+		return
+	}
+	err = instanceGroupAdmin.Delete(ctx, group)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary list instance groups
+// @Description list instance groups
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} InstanceGroupListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instance_groups [get]
+func (v *InstanceGroupAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	queryStr := c.DefaultQuery("query", "")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.InstanceGroup{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, groups, err := instanceGroupAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list instance groups", err)
+		return
+	}
+	groupListResp := &InstanceGroupListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(groups),
+	}
+	groupListResp.InstanceGroups = make([]*InstanceGroupResponse, groupListResp.Limit)
+	for i, group := range groups {
+		groupListResp.InstanceGroups[i], err = v.getInstanceGroupResponse(ctx, group, false)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, groups, limit)
+	c.JSON(http.StatusOK, groupListResp)
+}
+
+// @Summary create a scaling policy
+// @Description create a scaling policy of an instance group, an N9E alert rule posting to /alerts/instance-group-scaling
+// @Description triggers it with the returned alert_labels, which are only shown once
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Param   message	body   ScalingPolicyPayload  true   "Scaling policy payload"
+// @Success 200 {object} ScalingPolicyResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /instance_groups/{id}/policies [post]
+func (v *InstanceGroupAPI) CreatePolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	payload := &ScalingPolicyPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	cooldown := int32(300)
+	if payload.Cooldown != nil {
+		cooldown = *payload.Cooldown
+	}
+	policy, err := instanceGroupAdmin.CreatePolicy(ctx, group, payload.Name, payload.Action, payload.Adjustment, cooldown)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to create", err)
+		return
+	}
+	policyResp := v.getScalingPolicyResponse(policy)
+	policyResp.AlertLabels = map[string]string{"scaling_policy": policy.UUID, "scaling_token": policy.Token}
+	c.JSON(http.StatusOK, policyResp)
+}
+
+// @Summary list scaling policies
+// @Description list the scaling policies of an instance group
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Success 200 {object} ScalingPolicyListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /instance_groups/{id}/policies [get]
+func (v *InstanceGroupAPI) ListPolicies(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	policies, err := instanceGroupAdmin.ListPolicies(ctx, group)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to list scaling policies", err)
+		return
+	}
+	policyListResp := &ScalingPolicyListResponse{Policies: make([]*ScalingPolicyResponse, len(policies))}
+	for i, policy := range policies {
+		policyListResp.Policies[i] = v.getScalingPolicyResponse(policy)
+	}
+	c.JSON(http.StatusOK, policyListResp)
+}
+
+// @Summary delete a scaling policy
+// @Description delete a scaling policy of an instance group
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Instance group UUID"
+// @Param   policy_id  path  string  true  "Scaling policy UUID"
+// @Success 204
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /instance_groups/{id}/policies/{policy_id} [delete]
+func (v *InstanceGroupAPI) DeletePolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, err := instanceGroupAdmin.GetInstanceGroupByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid instance group query", err)
+		return
+	}
+	err = instanceGroupAdmin.DeletePolicy(ctx, group, c.Param("policy_id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ProcessScalingWebhook processes N9E alerts of scaling policies, a firing alert carrying the
+// scaling_policy and scaling_token labels of a policy changes the desired count of its group
+func (v *InstanceGroupAPI) ProcessScalingWebhook(c *gin.Context) {
+	var req routes.AlertWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf("[SCALING-ERROR] Failed to parse request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert format", "code": "INVALID_FORMAT"})
+		return
+	}
+	logger.Infof("[SCALING-INFO] Processing %d scaling alert(s) with status: %s", len(req.Alerts), req.Status)
+	triggeredCount := 0
+	for _, alert := range req.Alerts {
+		status := alert.Status
+		if status == "" {
+			status = req.Status
+		}
+		if status != "firing" {
+			continue
+		}
+		policyID := alert.Labels["scaling_policy"]
+		group, triggered, err := instanceGroupAdmin.TriggerPolicy(c.Request.Context(), policyID, alert.Labels["scaling_token"])
+		if err != nil {
+			logger.Errorf("[SCALING-ERROR] Failed to trigger scaling policy %s: %v", policyID, err)
+			continue
+		}
+		if triggered {
+			triggeredCount++
+			go v.reconcile(context.Background(), group.Owner, group.ID)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"total_alerts":    len(req.Alerts),
+		"triggered_count": triggeredCount,
+		"processed_at":    time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,38 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"context"
+	"testing"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func TestInstanceGroupClaim(t *testing.T) {
+	group := &model.InstanceGroup{Name: "test-group-claim"}
+	if err := DB().Create(group).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer DB().Unscoped().Delete(group)
+
+	ctx := context.Background()
+	release, claimed, err := instanceGroupAdmin.Claim(ctx, group.ID)
+	if err != nil || !claimed {
+		t.Fatal("group is not claimed", err)
+	}
+	if _, claimed, err = instanceGroupAdmin.Claim(ctx, group.ID); err != nil || claimed {
+		t.Fatal("group is claimed twice", err)
+	}
+	release()
+	release, claimed, err = instanceGroupAdmin.Claim(ctx, group.ID)
+	if err != nil || !claimed {
+		t.Fatal("released group is not claimed", err)
+	}
+	release()
+}
@@ -111,6 +111,7 @@ func (v *InterfaceAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get interface successfully, %s, %+v", ifaceID, interfaceResp)
+	SetETag(c, iface.UpdatedAt)
 	c.JSON(http.StatusOK, interfaceResp)
 }
 
@@ -218,6 +219,7 @@ func (v *InterfaceAPI) getInterfaceResponse(ctx context.Context, instance *model
 // @Success 200 {object} InterfaceResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /instances/{id}/interfaces/{interface_id} [patch]
 func (v *InterfaceAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -236,6 +238,9 @@ func (v *InterfaceAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid interface query", err)
 		return
 	}
+	if !ClaimIfMatch(c, iface, iface.UpdatedAt) {
+		return
+	}
 	payload := &InterfacePatchPayload{}
 	err = c.ShouldBindJSON(payload)
 	if err != nil {
@@ -414,6 +419,7 @@ func (v *InterfaceAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /instance/{id}/interfaces/{interface_id} [delete]
 func (v *InterfaceAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -431,6 +437,9 @@ func (v *InterfaceAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid interface query", err)
 		return
 	}
+	if !ClaimIfMatch(c, iface, iface.UpdatedAt) {
+		return
+	}
 	err = interfaceAdmin.Delete(ctx, instance, iface)
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
@@ -637,6 +646,9 @@ func (v *InterfaceAPI) Create(c *gin.Context) {
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {array} InterfaceResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /instance/{id}/interfaces [get]
@@ -670,6 +682,12 @@ func (v *InterfaceAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to get instance", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Interface{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, interfaces, err := interfaceAdmin.List(ctx, int64(offset), int64(limit), "-created_at", instance)
 	if err != nil {
 		logger.Errorf("Failed to list interfaces, %+v", err)
@@ -690,5 +708,6 @@ func (v *InterfaceAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List secrules successfully for SG %s, %+v", uuID, interfaceListResp)
+	listQuery.SetNextCursor(c, interfaces, limit)
 	c.JSON(http.StatusOK, interfaceListResp)
 }
@@ -109,6 +109,7 @@ func (v *IpGroupAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("IpGroupAPI.Get: success, uuID=%s, resp=%+v", uuID, ipGroupResp)
+	SetETag(c, ipGroup.UpdatedAt)
 	c.JSON(http.StatusOK, ipGroupResp)
 }
 
@@ -121,6 +122,7 @@ func (v *IpGroupAPI) Get(c *gin.Context) {
 // @Success 200 {object} IpGroupResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /ip_groups/{id} [patch]
 func (v *IpGroupAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -138,6 +140,9 @@ func (v *IpGroupAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid ipGroup query", err)
 		return
 	}
+	if !ClaimIfMatch(c, ipGroup, ipGroup.UpdatedAt) {
+		return
+	}
 	if payload.IpGroupType == nil && payload.Name == "" {
 		logger.Errorf("IpGroupAPI.Patch: missing name and dictionaries id")
 		ErrorResponse(c, http.StatusBadRequest, "Name or Dictionaries ID is required", err)
@@ -192,6 +197,7 @@ func (v *IpGroupAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /ip_groups/{id} [delete]
 func (v *IpGroupAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -203,6 +209,9 @@ func (v *IpGroupAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, ipGroup, ipGroup.UpdatedAt) {
+		return
+	}
 	err = ipGroupAdmin.Delete(ctx, ipGroup)
 	if err != nil {
 		logger.Errorf("IpGroupAPI.Delete: delete error, uuID=%s, err=%v", uuID, err)
@@ -392,6 +401,9 @@ func (v *IpGroupAPI) getIpGroupResponse(ctx context.Context, ipGroup *model.IpGr
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} IpGroupListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /ip_groups [get]
@@ -452,6 +464,12 @@ func (v *IpGroupAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.IpGroup{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, ipGroups, err := ipGroupAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("IpGroupAPI.List: list error, err=%v", err)
@@ -473,5 +491,6 @@ func (v *IpGroupAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("IpGroupAPI.List: success, resp=%+v", ipGroupListResp)
+	listQuery.SetNextCursor(c, ipGroups, limit)
 	c.JSON(http.StatusOK, ipGroupListResp)
 }
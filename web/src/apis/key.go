@@ -70,6 +70,7 @@ func (v *KeyAPI) Get(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
 	}
+	SetETag(c, key.UpdatedAt)
 	c.JSON(http.StatusOK, keyResp)
 }
 
@@ -96,6 +97,7 @@ func (v *KeyAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /keys/{id} [delete]
 func (v *KeyAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -105,6 +107,9 @@ func (v *KeyAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, key, key.UpdatedAt) {
+		return
+	}
 	err = keyAdmin.Delete(ctx, key)
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
@@ -170,6 +175,9 @@ func (v *KeyAPI) getKeyResponse(ctx context.Context, key *model.Key) (keyResp *K
 // @tags Authorization
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} KeyListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /keys [get]
@@ -191,6 +199,12 @@ func (v *KeyAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Key{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, keys, err := keyAdmin.List(ctx, int64(offset), int64(limit), "-created_at", "")
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to list vpcs", err)
@@ -209,5 +223,6 @@ func (v *KeyAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, keys, limit)
 	c.JSON(http.StatusOK, keyListResp)
 }
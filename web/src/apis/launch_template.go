@@ -0,0 +1,538 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var launchTemplateAPI = &LaunchTemplateAPI{}
+var launchTemplateAdmin = &routes.LaunchTemplateAdmin{}
+
+// LaunchTemplateAPI keeps instance payloads under a name, POST /instances with launch_template
+// lays the fields of the request over the payload of a template version
+type LaunchTemplateAPI struct{}
+
+// LaunchTemplateReference selects a launch template by id or name, version 0 is the default version
+type LaunchTemplateReference struct {
+	ID      string `json:"id" binding:"omitempty,uuid"`
+	Name    string `json:"name" binding:"omitempty,min=2,max=64"`
+	Version int32  `json:"version" binding:"omitempty,gte=1"`
+}
+
+type LaunchTemplatePayload struct {
+	Name               string          `json:"name" binding:"required,min=2,max=64"`
+	Description        string          `json:"description" binding:"omitempty,max=256"`
+	VersionDescription string          `json:"version_description" binding:"omitempty,max=256"`
+	Instance           json.RawMessage `json:"instance" binding:"required" swaggertype:"object"`
+}
+
+type LaunchTemplatePatchPayload struct {
+	Name           string `json:"name" binding:"omitempty,min=2,max=64"`
+	Description    string `json:"description" binding:"omitempty,max=256"`
+	DefaultVersion int32  `json:"default_version" binding:"omitempty,gte=1"`
+}
+
+type LaunchTemplateVersionPayload struct {
+	Description string          `json:"description" binding:"omitempty,max=256"`
+	Instance    json.RawMessage `json:"instance" binding:"required" swaggertype:"object"`
+	SetDefault  bool            `json:"set_default"`
+}
+
+type LaunchTemplateResponse struct {
+	*ResourceReference
+	Description    string `json:"description"`
+	DefaultVersion int32  `json:"default_version"`
+	LatestVersion  int32  `json:"latest_version"`
+}
+
+type LaunchTemplateListResponse struct {
+	Offset          int                       `json:"offset"`
+	Total           int                       `json:"total"`
+	Limit           int                       `json:"limit"`
+	LaunchTemplates []*LaunchTemplateResponse `json:"launch_templates"`
+}
+
+type LaunchTemplateVersionResponse struct {
+	Version     int32           `json:"version"`
+	Description string          `json:"description"`
+	Default     bool            `json:"default"`
+	CreatedAt   string          `json:"created_at"`
+	Instance    json.RawMessage `json:"instance" swaggertype:"object"`
+}
+
+type LaunchTemplateVersionListResponse struct {
+	Offset   int                              `json:"offset"`
+	Total    int                              `json:"total"`
+	Limit    int                              `json:"limit"`
+	Versions []*LaunchTemplateVersionResponse `json:"versions"`
+}
+
+// checkInstance validates a partial instance payload of a template and resolves the resources it refers to,
+// required fields may be left to the request creating the instances
+func (v *LaunchTemplateAPI) checkInstance(ctx context.Context, instance json.RawMessage) (data string, err error) {
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(instance, &fields); err != nil {
+		return "", NewCLError(ErrInvalidLaunchTemplate, "Instance of launch template must be a JSON object", err)
+	}
+	if _, ok := fields["root_passwd"]; ok {
+		return "", NewCLError(ErrInvalidLaunchTemplate, "Root password can not be kept in launch templates", nil)
+	}
+	if _, ok := fields["launch_template"]; ok {
+		return "", NewCLError(ErrInvalidLaunchTemplate, "Launch templates can not refer to launch templates", nil)
+	}
+	payload := &InstancePayload{}
+	decoder := json.NewDecoder(bytes.NewReader(instance))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(payload); err != nil {
+		return "", NewCLError(ErrInvalidLaunchTemplate, "Invalid instance of launch template", err)
+	}
+	if err = binding.Validator.ValidateStruct(payload); err != nil {
+		if fieldErrs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range fieldErrs {
+				// top level fields are required only once the request is laid over the template
+				if fieldErr.Tag() == "required" && strings.Count(fieldErr.Namespace(), ".") == 1 {
+					continue
+				}
+				return "", NewCLError(ErrInvalidLaunchTemplate, "Invalid instance of launch template", fieldErr)
+			}
+			err = nil
+		} else {
+			return "", NewCLError(ErrInvalidLaunchTemplate, "Invalid instance of launch template", err)
+		}
+	}
+	if err = v.checkReferences(ctx, payload); err != nil {
+		return "", NewCLError(ErrInvalidLaunchTemplate, err.Error(), err)
+	}
+	compact := &bytes.Buffer{}
+	if err = json.Compact(compact, instance); err != nil {
+		return "", NewCLError(ErrInvalidLaunchTemplate, "Invalid instance of launch template", err)
+	}
+	return compact.String(), nil
+}
+
+// checkReferences resolves the image, flavor, zone, VPC, subnets, security groups and keys of the payload
+func (v *LaunchTemplateAPI) checkReferences(ctx context.Context, payload *InstancePayload) (err error) {
+	if payload.Image != nil {
+		if _, err = imageAdmin.GetImage(ctx, payload.Image); err != nil {
+			return fmt.Errorf("Invalid image %+v", *payload.Image)
+		}
+	}
+	if payload.Flavor != "" {
+		if _, err = flavorAdmin.GetFlavorByName(ctx, payload.Flavor); err != nil {
+			return fmt.Errorf("Invalid flavor %s", payload.Flavor)
+		}
+	}
+	if payload.Zone != "" {
+		if _, err = zoneAdmin.GetZoneByName(ctx, payload.Zone); err != nil {
+			return fmt.Errorf("Invalid zone %s", payload.Zone)
+		}
+	}
+	var router *model.Router
+	if payload.VPC != nil {
+		if router, err = routerAdmin.GetRouter(ctx, payload.VPC); err != nil {
+			return fmt.Errorf("Invalid VPC %+v", *payload.VPC)
+		}
+	}
+	if payload.PrimaryInterface != nil {
+		if router, _, err = interfaceAPI.getInterfaceInfo(ctx, router, payload.PrimaryInterface); err != nil {
+			return fmt.Errorf("Invalid primary interface, %v", err)
+		}
+	}
+	for _, ifacePayload := range payload.SecondaryInterfaces {
+		if router, _, err = interfaceAPI.getInterfaceInfo(ctx, router, ifacePayload); err != nil {
+			return fmt.Errorf("Invalid secondary interface, %v", err)
+		}
+	}
+	for _, key := range payload.Keys {
+		if _, err = keyAdmin.GetKey(ctx, key); err != nil {
+			return fmt.Errorf("Invalid key %+v", *key)
+		}
+	}
+	return
+}
+
+// instanceFields lays the fields of an instance request over the payload of the referenced template version
+func (v *LaunchTemplateAPI) instanceFields(ctx context.Context, reference *LaunchTemplateReference, request map[string]json.RawMessage) (fields map[string]json.RawMessage, err error) {
+	if err = binding.Validator.ValidateStruct(reference); err != nil {
+		return
+	}
+	template, err := launchTemplateAdmin.GetLaunchTemplate(ctx, &BaseReference{ID: reference.ID, Name: reference.Name})
+	if err != nil {
+		return
+	}
+	version, err := launchTemplateAdmin.GetVersion(ctx, template, reference.Version)
+	if err != nil {
+		return
+	}
+	fields = map[string]json.RawMessage{}
+	if err = json.Unmarshal([]byte(version.Payload), &fields); err != nil {
+		logger.Errorf("Invalid payload of launch template %s version %d, %v", template.Name, version.Version, err)
+		err = NewCLError(ErrInvalidLaunchTemplate, "Invalid payload of launch template", err)
+		return
+	}
+	for key, value := range request {
+		if key == "launch_template" {
+			continue
+		}
+		fields[key] = value
+	}
+	logger.Debugf("Instance payload from launch template %s version %d", template.Name, version.Version)
+	return
+}
+
+func (v *LaunchTemplateAPI) errorStatus(err error) int {
+	if clErr, ok := err.(*CLError); ok {
+		switch clErr.Code {
+		case ErrLaunchTemplateNotFound, ErrLaunchTemplateVersionNotFound:
+			return http.StatusNotFound
+		case ErrPermissionDenied:
+			return http.StatusForbidden
+		case ErrDatabaseError:
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusBadRequest
+}
+
+func (v *LaunchTemplateAPI) getLaunchTemplateResponse(ctx context.Context, template *model.LaunchTemplate) *LaunchTemplateResponse {
+	owner := orgAdmin.GetOrgName(ctx, template.Owner)
+	return &LaunchTemplateResponse{
+		ResourceReference: &ResourceReference{
+			ID:        template.UUID,
+			Name:      template.Name,
+			Owner:     owner,
+			CreatedAt: template.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: template.UpdatedAt.Format(TimeStringForMat),
+		},
+		Description:    template.Description,
+		DefaultVersion: template.DefaultVersion,
+		LatestVersion:  template.LatestVersion,
+	}
+}
+
+func (v *LaunchTemplateAPI) getVersionResponse(template *model.LaunchTemplate, version *model.LaunchTemplateVersion) *LaunchTemplateVersionResponse {
+	return &LaunchTemplateVersionResponse{
+		Version:     version.Version,
+		Description: version.Description,
+		Default:     version.Version == template.DefaultVersion,
+		CreatedAt:   version.CreatedAt.Format(TimeStringForMat),
+		Instance:    json.RawMessage(version.Payload),
+	}
+}
+
+// @Summary get a launch template
+// @Description get a launch template
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Success 200 {object} LaunchTemplateResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /launch_templates/{id} [get]
+func (v *LaunchTemplateAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	SetETag(c, template.UpdatedAt)
+	c.JSON(http.StatusOK, v.getLaunchTemplateResponse(ctx, template))
+}
+
+// @Summary create a launch template
+// @Description create a launch template with the instance payload as version 1, root_passwd is not accepted
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   message	body   LaunchTemplatePayload  true   "Launch template create payload"
+// @Success 200 {object} LaunchTemplateResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /launch_templates [post]
+func (v *LaunchTemplateAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &LaunchTemplatePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	data, err := v.checkInstance(ctx, payload.Instance)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid launch template", err)
+		return
+	}
+	template, _, err := launchTemplateAdmin.Create(ctx, payload.Name, payload.Description, payload.VersionDescription, data)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to create", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getLaunchTemplateResponse(ctx, template))
+}
+
+// @Summary patch a launch template
+// @Description change the name, description or default version of a launch template
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Param   message	body   LaunchTemplatePatchPayload  true   "Launch template patch payload"
+// @Success 200 {object} LaunchTemplateResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /launch_templates/{id} [patch]
+func (v *LaunchTemplateAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	if !ClaimIfMatch(c, template, template.UpdatedAt) {
+		return
+	}
+	payload := &LaunchTemplatePatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = launchTemplateAdmin.Update(ctx, template, payload.Name, payload.Description, payload.DefaultVersion)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to update", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getLaunchTemplateResponse(ctx, template))
+}
+
+// @Summary delete a launch template
+// @Description delete a launch template with all its versions
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /launch_templates/{id} [delete]
+func (v *LaunchTemplateAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	if !ClaimIfMatch(c, template, template.UpdatedAt) {
+		return
+	}
+	err = launchTemplateAdmin.Delete(ctx, template)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary list launch templates
+// @Description list launch templates
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[name]=web or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} LaunchTemplateListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /launch_templates [get]
+func (v *LaunchTemplateAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	queryStr := c.DefaultQuery("query", "")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.LaunchTemplate{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, templates, err := launchTemplateAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list launch templates", err)
+		return
+	}
+	templateListResp := &LaunchTemplateListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(templates),
+	}
+	templateListResp.LaunchTemplates = make([]*LaunchTemplateResponse, templateListResp.Limit)
+	for i, template := range templates {
+		templateListResp.LaunchTemplates[i] = v.getLaunchTemplateResponse(ctx, template)
+	}
+	listQuery.SetNextCursor(c, templates, limit)
+	c.JSON(http.StatusOK, templateListResp)
+}
+
+// @Summary create a launch template version
+// @Description add the instance payload as the latest version of a launch template
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Param   message	body   LaunchTemplateVersionPayload  true   "Launch template version payload"
+// @Success 200 {object} LaunchTemplateVersionResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /launch_templates/{id}/versions [post]
+func (v *LaunchTemplateAPI) CreateVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	payload := &LaunchTemplateVersionPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	data, err := v.checkInstance(ctx, payload.Instance)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid launch template", err)
+		return
+	}
+	version, err := launchTemplateAdmin.CreateVersion(ctx, template, payload.Description, data, payload.SetDefault)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Not able to create version", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getVersionResponse(template, version))
+}
+
+// @Summary list launch template versions
+// @Description list the versions of a launch template, latest first
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Success 200 {object} LaunchTemplateVersionListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /launch_templates/{id}/versions [get]
+func (v *LaunchTemplateAPI) ListVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
+		return
+	}
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	total, versions, err := launchTemplateAdmin.ListVersions(ctx, template, int64(offset), int64(limit))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to list launch template versions", err)
+		return
+	}
+	versionListResp := &LaunchTemplateVersionListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(versions),
+	}
+	versionListResp.Versions = make([]*LaunchTemplateVersionResponse, versionListResp.Limit)
+	for i, version := range versions {
+		versionListResp.Versions[i] = v.getVersionResponse(template, version)
+	}
+	c.JSON(http.StatusOK, versionListResp)
+}
+
+// @Summary get a launch template version
+// @Description get a version of a launch template, the version is a number, default or latest
+// @tags Compute
+// @Accept  json
+// @Produce json
+// @Param   id  path  string  true  "Launch template UUID"
+// @Param   version  path  string  true  "Version number, default or latest"
+// @Success 200 {object} LaunchTemplateVersionResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /launch_templates/{id}/versions/{version} [get]
+func (v *LaunchTemplateAPI) GetVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	template, err := launchTemplateAdmin.GetLaunchTemplateByUUID(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template query", err)
+		return
+	}
+	var number int32
+	switch versionStr := c.Param("version"); versionStr {
+	case "default":
+		number = template.DefaultVersion
+	case "latest":
+		number = template.LatestVersion
+	default:
+		parsed, err := strconv.ParseInt(versionStr, 10, 32)
+		if err != nil || parsed <= 0 {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid version: "+versionStr, err)
+			return
+		}
+		number = int32(parsed)
+	}
+	version, err := launchTemplateAdmin.GetVersion(ctx, template, number)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Invalid launch template version", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getVersionResponse(template, version))
+}
@@ -14,6 +14,7 @@ import (
 
 	. "web/src/common"
 	"web/src/model"
+	"web/src/routes"
 
 	"github.com/gin-gonic/gin"
 )
@@ -193,6 +194,9 @@ func (v *LBFloatingIpAPI) Create(c *gin.Context) {
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} FloatingIpListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /load_balancers/{id}/floating_ips [get]
@@ -227,6 +231,12 @@ func (v *LBFloatingIpAPI) List(c *gin.Context) {
 		return
 	}
 	intQuery := fmt.Sprintf("load_balancer_id = %d", loadBalancer.ID)
+	listQuery, err := routes.ParseListQuery(c, &model.FloatingIp{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, floatingIps, err := floatingIpAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr, intQuery)
 	if err != nil {
 		logger.Errorf("Failed to list floatingIps %+v", err)
@@ -246,5 +256,6 @@ func (v *LBFloatingIpAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, floatingIps, limit)
 	c.JSON(http.StatusOK, floatingIpListResp)
 }
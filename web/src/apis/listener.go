@@ -269,6 +269,9 @@ func (v *ListenerAPI) getListenerResponse(ctx context.Context, listener *model.L
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} ListenerListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /load_balancers/{id}/listeners [get]
@@ -306,6 +309,12 @@ func (v *ListenerAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Listener{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, listeners, err := listenerAdmin.List(ctx, int64(offset), int64(limit), "-created_at", loadBalancer)
 	if err != nil {
 		logger.Errorf("Failed to list listeners, %+v", err)
@@ -326,5 +335,6 @@ func (v *ListenerAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List listeners successfully, %+v", listenerListResp)
+	listQuery.SetNextCursor(c, listeners, limit)
 	c.JSON(http.StatusOK, listenerListResp)
 }
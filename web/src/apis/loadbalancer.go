@@ -78,6 +78,7 @@ func (v *LoadBalancerAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get loadBalancer successfully, %s, %+v", uuID, loadBalancerResp)
+	SetETag(c, loadBalancer.UpdatedAt)
 	c.JSON(http.StatusOK, loadBalancerResp)
 }
 
@@ -90,6 +91,7 @@ func (v *LoadBalancerAPI) Get(c *gin.Context) {
 // @Success 200 {object} LoadBalancerResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /load_balancers/{id} [patch]
 func (v *LoadBalancerAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -101,6 +103,9 @@ func (v *LoadBalancerAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid load balancer query", err)
 		return
 	}
+	if !ClaimIfMatch(c, loadBalancer, loadBalancer.UpdatedAt) {
+		return
+	}
 	payload := &LoadBalancerPatchPayload{}
 	err = c.ShouldBindJSON(payload)
 	if err != nil {
@@ -134,6 +139,7 @@ func (v *LoadBalancerAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /load_balancers/{id} [delete]
 func (v *LoadBalancerAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -145,6 +151,9 @@ func (v *LoadBalancerAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, loadBalancer, loadBalancer.UpdatedAt) {
+		return
+	}
 	err = loadBalancerAdmin.Delete(ctx, loadBalancer)
 	if err != nil {
 		logger.Errorf("Failed to delete loadBalancer %s, %+v", uuID, err)
@@ -254,6 +263,9 @@ func (v *LoadBalancerAPI) getLoadBalancerResponse(ctx context.Context, loadBalan
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} LoadBalancerListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /load_balancers [get]
@@ -298,6 +310,12 @@ func (v *LoadBalancerAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.LoadBalancer{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, loadBalancers, err := loadBalancerAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list loadBalancers, %+v", err)
@@ -318,5 +336,6 @@ func (v *LoadBalancerAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List loadBalancers successfully, %+v", loadBalancerListResp)
+	listQuery.SetNextCursor(c, loadBalancers, limit)
 	c.JSON(http.StatusOK, loadBalancerListResp)
 }
@@ -0,0 +1,292 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+var metadataAPI = &MetadataAPI{}
+var metadataAdmin = &routes.MetadataAdmin{}
+
+// MetadataAPI serves the live metadata of instances in the OpenStack and EC2 layouts,
+// guests reach it at 169.254.169.254 through the metadata proxy in the namespace of their router,
+// the proxy passes the guest address in X-Forwarded-For with X-Router-ID and X-Router-Token
+type MetadataAPI struct{}
+
+type metadataKey struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type openstackMetadata struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	Hostname         string            `json:"hostname"`
+	AvailabilityZone string            `json:"availability_zone"`
+	LaunchIndex      int               `json:"launch_index"`
+	PublicKeys       map[string]string `json:"public_keys,omitempty"`
+	Keys             []*metadataKey    `json:"keys,omitempty"`
+	Meta             map[string]string `json:"meta,omitempty"`
+}
+
+type openstackNetworkData struct {
+	Links    []*routes.NetworkLink `json:"links"`
+	Networks []*InstanceNetwork    `json:"networks"`
+	Services []map[string]string   `json:"services"`
+}
+
+// RunMetadata runs the metadata service on metadata.listen, it is disabled without the listen address or metadata.secret
+func RunMetadata() (err error) {
+	listen := viper.GetString("metadata.listen")
+	if listen == "" || viper.GetString("metadata.secret") == "" {
+		logger.Info("Metadata service is disabled")
+		return
+	}
+	r := RegisterMetadata()
+	logger.Infof("Running metadata service on %s\n", listen)
+	return r.Run(listen)
+}
+
+func RegisterMetadata() (r *gin.Engine) {
+	r = gin.Default()
+	r.GET("/openstack", metadataAPI.OpenstackVersions)
+	r.GET("/openstack/:version", metadataAPI.OpenstackFiles)
+	r.GET("/openstack/:version/meta_data.json", metadataAPI.OpenstackMetadata)
+	r.GET("/openstack/:version/network_data.json", metadataAPI.OpenstackNetworkData)
+	r.GET("/openstack/:version/vendor_data.json", metadataAPI.OpenstackVendorData)
+	r.GET("/openstack/:version/user_data", metadataAPI.UserData)
+	r.GET("/cloudland/latest/instance_data.json", metadataAPI.InstanceData)
+	r.GET("/:version/user-data", metadataAPI.UserData)
+	r.GET("/:version/meta-data", metadataAPI.EC2Metadata)
+	r.GET("/:version/meta-data/*path", metadataAPI.EC2Metadata)
+	return
+}
+
+// caller finds the instance of the request and its live metadata, it responds on failure
+func (v *MetadataAPI) caller(c *gin.Context) (instance *model.Instance, instData *routes.InstanceData, ok bool) {
+	routerID, _ := strconv.ParseInt(c.GetHeader("X-Router-ID"), 10, 64)
+	if !metadataAdmin.VerifyRouter(routerID, c.GetHeader("X-Router-Token")) {
+		logger.Errorf("Metadata request from %s with invalid router token", c.Request.RemoteAddr)
+		c.String(http.StatusForbidden, "Forbidden")
+		return
+	}
+	ip := guestAddress(c.GetHeader("X-Forwarded-For"))
+	if ip == nil {
+		c.String(http.StatusBadRequest, "Bad Request")
+		return
+	}
+	instance, instData, err := metadataAdmin.GetInstanceData(c.Request.Context(), routerID, ip.String())
+	if err != nil {
+		c.String(http.StatusNotFound, "Not Found")
+		return
+	}
+	return instance, instData, true
+}
+
+// guestAddress is the last entry of X-Forwarded-For, the one the proxy added, anything before it comes from the guest
+func guestAddress(forwardedFor string) net.IP {
+	forwarded := strings.Split(forwardedFor, ",")
+	return net.ParseIP(strings.TrimSpace(forwarded[len(forwarded)-1]))
+}
+
+func (v *MetadataAPI) userData(instData *routes.InstanceData) (data []byte, err error) {
+	data = []byte(instData.Userdata)
+	if instData.UserdataType == "base64" {
+		data, err = base64.StdEncoding.DecodeString(instData.Userdata)
+	}
+	return
+}
+
+func (v *MetadataAPI) OpenstackVersions(c *gin.Context) {
+	c.String(http.StatusOK, "latest\n")
+}
+
+func (v *MetadataAPI) OpenstackFiles(c *gin.Context) {
+	if c.Param("version") != "latest" {
+		c.String(http.StatusNotFound, "Not Found")
+		return
+	}
+	c.String(http.StatusOK, "meta_data.json\nnetwork_data.json\nuser_data\nvendor_data.json\n")
+}
+
+func (v *MetadataAPI) OpenstackMetadata(c *gin.Context) {
+	instance, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	metadata := &openstackMetadata{
+		UUID:     instance.UUID,
+		Name:     instance.Hostname,
+		Hostname: instance.Hostname,
+		Meta:     instData.Tags,
+	}
+	if instance.Zone != nil {
+		metadata.AvailabilityZone = instance.Zone.Name
+	}
+	if len(instance.Keys) > 0 {
+		metadata.PublicKeys = make(map[string]string)
+		for _, key := range instance.Keys {
+			metadata.PublicKeys[key.Name] = key.PublicKey
+			metadata.Keys = append(metadata.Keys, &metadataKey{Name: key.Name, Type: "ssh", Data: key.PublicKey})
+		}
+	}
+	c.JSON(http.StatusOK, metadata)
+}
+
+func (v *MetadataAPI) OpenstackNetworkData(c *gin.Context) {
+	_, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	networkData := &openstackNetworkData{
+		Links:    instData.Links,
+		Networks: instData.Networks,
+		Services: []map[string]string{},
+	}
+	if instData.DNS != "" {
+		networkData.Services = append(networkData.Services, map[string]string{"type": "dns", "address": instData.DNS})
+	}
+	c.JSON(http.StatusOK, networkData)
+}
+
+func (v *MetadataAPI) OpenstackVendorData(c *gin.Context) {
+	_, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	vendorData := map[string]string{}
+	if instData.Vendordata != "" {
+		data := []byte(instData.Vendordata)
+		if instData.VendordataType == "base64" {
+			var err error
+			if data, err = base64.StdEncoding.DecodeString(instData.Vendordata); err != nil {
+				c.String(http.StatusInternalServerError, "Invalid vendor data")
+				return
+			}
+		}
+		vendorData["cloud-init"] = string(data)
+	}
+	c.JSON(http.StatusOK, vendorData)
+}
+
+func (v *MetadataAPI) UserData(c *gin.Context) {
+	_, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	if instData.Userdata == "" {
+		c.String(http.StatusNotFound, "Not Found")
+		return
+	}
+	data, err := v.userData(instData)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Invalid user data")
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// InstanceData serves the metadata of the instance as launch_vm.sh receives it, without the root password
+func (v *MetadataAPI) InstanceData(c *gin.Context) {
+	_, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, instData)
+}
+
+// ec2Entries flattens the EC2 meta-data tree of the instance to paths and values
+func (v *MetadataAPI) ec2Entries(instance *model.Instance, instData *routes.InstanceData) (entries map[string]string) {
+	entries = map[string]string{
+		"instance-id":    instance.UUID,
+		"hostname":       instance.Hostname,
+		"local-hostname": instance.Hostname,
+	}
+	if instance.Zone != nil {
+		entries["placement/availability-zone"] = instance.Zone.Name
+	}
+	for _, network := range instData.Networks {
+		if network.Link == "eth0" {
+			entries["local-ipv4"] = network.Address
+			break
+		}
+	}
+	for _, fip := range instance.FloatingIps {
+		address, _, _ := strings.Cut(fip.FipAddress, "/")
+		if address != "" {
+			entries["public-ipv4"] = address
+			break
+		}
+	}
+	for i, key := range instance.Keys {
+		entries[fmt.Sprintf("public-keys/%d/openssh-key", i)] = key.PublicKey
+	}
+	for key, value := range instData.Tags {
+		entries["tags/instance/"+key] = value
+	}
+	return
+}
+
+// EC2Metadata serves a value of the meta-data tree or lists the entries of a directory
+func (v *MetadataAPI) EC2Metadata(c *gin.Context) {
+	instance, instData, ok := v.caller(c)
+	if !ok {
+		return
+	}
+	entries := v.ec2Entries(instance, instData)
+	path := strings.Trim(c.Param("path"), "/")
+	if value, found := entries[path]; found {
+		c.String(http.StatusOK, value)
+		return
+	}
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+	children := map[string]bool{}
+	for entry := range entries {
+		if !strings.HasPrefix(entry, prefix) {
+			continue
+		}
+		child := strings.TrimPrefix(entry, prefix)
+		if name, _, isDir := strings.Cut(child, "/"); isDir {
+			children[name+"/"] = true
+		} else {
+			children[child] = true
+		}
+	}
+	if len(children) == 0 {
+		c.String(http.StatusNotFound, "Not Found")
+		return
+	}
+	listing := []string{}
+	for child := range children {
+		// EC2 lists the keys as index=name
+		if path == "public-keys" {
+			index, _ := strconv.Atoi(strings.TrimSuffix(child, "/"))
+			child = fmt.Sprintf("%d=%s", index, instance.Keys[index].Name)
+		}
+		listing = append(listing, child)
+	}
+	sort.Strings(listing)
+	c.String(http.StatusOK, strings.Join(listing, "\n"))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMetadataRouterToken(t *testing.T) {
+	secret := viper.GetString("metadata.secret")
+	defer viper.Set("metadata.secret", secret)
+
+	// nothing is trusted without a secret, not even the token of an empty key
+	viper.Set("metadata.secret", "")
+	if metadataAdmin.VerifyRouter(1, metadataAdmin.RouterToken(1)) {
+		t.Error("router token is accepted without metadata.secret")
+	}
+
+	viper.Set("metadata.secret", "test-metadata-secret")
+	token := metadataAdmin.RouterToken(1)
+	if !metadataAdmin.VerifyRouter(1, token) {
+		t.Error("router token is rejected")
+	}
+	if metadataAdmin.VerifyRouter(1, "") || metadataAdmin.VerifyRouter(1, token[1:]) || metadataAdmin.VerifyRouter(2, token) {
+		t.Error("wrong router token is accepted")
+	}
+	if metadataAdmin.VerifyRouter(0, metadataAdmin.RouterToken(0)) || metadataAdmin.VerifyRouter(-1, metadataAdmin.RouterToken(-1)) {
+		t.Error("token of router 0 is accepted")
+	}
+	viper.Set("metadata.secret", "another-metadata-secret")
+	if metadataAdmin.VerifyRouter(1, token) {
+		t.Error("router token of another secret is accepted")
+	}
+}
+
+func TestGuestAddress(t *testing.T) {
+	cases := map[string]string{
+		"192.168.1.10":                 "192.168.1.10",
+		" 192.168.1.10 ":               "192.168.1.10",
+		"10.0.0.1, 192.168.1.10":       "192.168.1.10",
+		"192.168.1.99,192.168.1.10":    "192.168.1.10",
+		"fd00::10":                     "fd00::10",
+		"":                             "",
+		"192.168.1.10, not-an-address": "",
+		"192.168.1.10,":                "",
+	}
+	for header, expected := range cases {
+		ip := guestAddress(header)
+		if expected == "" {
+			if ip != nil {
+				t.Errorf("X-Forwarded-For %q should be rejected, got %s", header, ip)
+			}
+			continue
+		}
+		if ip == nil || ip.String() != expected {
+			t.Errorf("X-Forwarded-For %q should be %s, got %s", header, expected, ip)
+		}
+	}
+}
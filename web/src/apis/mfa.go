@@ -0,0 +1,197 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"net/http"
+
+	. "web/src/common"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var mfaAPI = &MfaAPI{}
+var mfaAdmin = &routes.MfaAdmin{}
+
+type MfaAPI struct{}
+
+type MfaStatusResponse struct {
+	Enabled       bool `json:"enabled"`
+	Pending       bool `json:"pending"`
+	RecoveryCodes int  `json:"recovery_codes"`
+}
+
+type MfaEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OtpAuthURL string `json:"otpauth_url"`
+}
+
+type MfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MfaPasscodePayload struct {
+	Passcode string `json:"passcode" binding:"required,min=6,max=32"`
+}
+
+// @Summary get multi-factor authentication status
+// @Description get multi-factor authentication status of the current user
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 200 {object} MfaStatusResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /mfa [get]
+func (v *MfaAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	enabled, pending, recoveryCodes, err := mfaAdmin.Status(ctx)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to get multi-factor authentication status", err)
+		return
+	}
+	c.JSON(http.StatusOK, &MfaStatusResponse{Enabled: enabled, Pending: pending, RecoveryCodes: recoveryCodes})
+}
+
+// @Summary enroll multi-factor authentication
+// @Description generate a TOTP secret for the current user, it is enabled after activation with a code
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 200 {object} MfaEnrollResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /mfa/enroll [post]
+func (v *MfaAPI) Enroll(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.GetString("app_credential") != "" {
+		ErrorResponse(c, http.StatusForbidden, "Application credentials can not manage multi-factor authentication", nil)
+		return
+	}
+	secret, otpURL, err := mfaAdmin.Enroll(ctx)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to enroll multi-factor authentication", err)
+		return
+	}
+	c.JSON(http.StatusOK, &MfaEnrollResponse{Secret: secret, OtpAuthURL: otpURL})
+}
+
+// @Summary activate multi-factor authentication
+// @Description activate the enrolled TOTP secret with a code, the recovery codes are only returned here
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   MfaPasscodePayload  true   "TOTP code"
+// @Success 200 {object} MfaRecoveryCodesResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /mfa/activate [post]
+func (v *MfaAPI) Activate(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.GetString("app_credential") != "" {
+		ErrorResponse(c, http.StatusForbidden, "Application credentials can not manage multi-factor authentication", nil)
+		return
+	}
+	payload := &MfaPasscodePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	recoveryCodes, err := mfaAdmin.Activate(ctx, payload.Passcode)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to activate multi-factor authentication", err)
+		return
+	}
+	c.JSON(http.StatusOK, &MfaRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @Summary disable multi-factor authentication
+// @Description disable multi-factor authentication of the current user with a TOTP or recovery code
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   MfaPasscodePayload  true   "TOTP or recovery code"
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /mfa/disable [post]
+func (v *MfaAPI) Disable(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.GetString("app_credential") != "" {
+		ErrorResponse(c, http.StatusForbidden, "Application credentials can not manage multi-factor authentication", nil)
+		return
+	}
+	payload := &MfaPasscodePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = mfaAdmin.Disable(ctx, payload.Passcode)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to disable multi-factor authentication", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary regenerate recovery codes
+// @Description replace the recovery codes of the current user, a TOTP or recovery code is required
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   MfaPasscodePayload  true   "TOTP or recovery code"
+// @Success 200 {object} MfaRecoveryCodesResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /mfa/recovery_codes [post]
+func (v *MfaAPI) RecoveryCodes(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.GetString("app_credential") != "" {
+		ErrorResponse(c, http.StatusForbidden, "Application credentials can not manage multi-factor authentication", nil)
+		return
+	}
+	payload := &MfaPasscodePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	recoveryCodes, err := mfaAdmin.RegenerateRecoveryCodes(ctx, payload.Passcode)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to regenerate recovery codes", err)
+		return
+	}
+	c.JSON(http.StatusOK, &MfaRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @Summary reset multi-factor authentication of a user
+// @Description remove the second factor of a user who lost the authenticator and the recovery codes, admin only
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /users/{id}/mfa/reset [post]
+func (v *MfaAPI) Reset(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	user, err := userAdmin.GetUserByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid user query", err)
+		return
+	}
+	err = mfaAdmin.Reset(ctx, user)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to reset multi-factor authentication", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -82,6 +82,7 @@ func (v *MigrationAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get migration %s success, response: %+v", uuID, migrationResp)
+	SetETag(c, migration.UpdatedAt)
 	c.JSON(http.StatusOK, migrationResp)
 }
 
@@ -179,6 +180,9 @@ func (v *MigrationAPI) getMigrationResponse(ctx context.Context, migration *mode
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} MigrationListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /migrations [get]
@@ -205,6 +209,12 @@ func (v *MigrationAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Migration{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, migrations, err := migrationAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list migrations %+v", err)
@@ -226,5 +236,6 @@ func (v *MigrationAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List migrations success, response: %+v", migrationListResp)
+	listQuery.SetNextCursor(c, migrations, limit)
 	c.JSON(http.StatusOK, migrationListResp)
 }
@@ -0,0 +1,356 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var natGatewayAPI = &NatGatewayAPI{}
+var natGatewayAdmin = &routes.NatGatewayAdmin{}
+
+type NatGatewayAPI struct{}
+
+type NatGatewayResponse struct {
+	*ResourceReference
+	PublicIp   string           `json:"public_ip"`
+	Subnets    []*BaseReference `json:"subnets"`
+	AllSubnets bool             `json:"all_subnets"`
+	Inbound    int32            `json:"inbound"`
+	Outbound   int32            `json:"outbound"`
+	VPC        *BaseReference   `json:"vpc,omitempty"`
+	Status     string           `json:"status"`
+}
+
+type NatGatewayListResponse struct {
+	Offset      int                   `json:"offset"`
+	Total       int                   `json:"total"`
+	Limit       int                   `json:"limit"`
+	NatGateways []*NatGatewayResponse `json:"nat_gateways"`
+}
+
+type NatGatewayPayload struct {
+	Name         string           `json:"name" binding:"omitempty,min=2,max=32"`
+	PublicSubnet *BaseReference   `json:"public_subnet" binding:"omitempty"`
+	PublicIp     string           `json:"public_ip" binding:"omitempty,ipv4"`
+	Inbound      int32            `json:"inbound" binding:"omitempty,min=1,max=20000"`
+	Outbound     int32            `json:"outbound" binding:"omitempty,min=1,max=20000"`
+	Subnets      []*BaseReference `json:"subnets" binding:"omitempty"`
+}
+
+type NatGatewayPatchPayload struct {
+	Name     string           `json:"name" binding:"omitempty,min=2,max=32"`
+	Inbound  *int32           `json:"inbound" binding:"omitempty,min=0,max=20000"`
+	Outbound *int32           `json:"outbound" binding:"omitempty,min=0,max=20000"`
+	Subnets  []*BaseReference `json:"subnets" binding:"omitempty"`
+}
+
+func (v *NatGatewayAPI) getVPCNatGateway(c *gin.Context) (router *model.Router, natGateway *model.NatGateway, err error) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err = routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		return
+	}
+	natGatewayID := c.Param("nat_gateway_id")
+	natGateway, err = natGatewayAdmin.GetNatGatewayByUUID(ctx, natGatewayID)
+	if err != nil {
+		logger.Errorf("Failed to get nat gateway %s, %+v", natGatewayID, err)
+		return
+	}
+	if natGateway.RouterID != router.ID {
+		logger.Error("Invalid query for vpc nat gateway")
+		err = NewCLError(ErrInvalidParameter, "Invalid query for vpc nat gateway", nil)
+		return
+	}
+	return
+}
+
+func (v *NatGatewayAPI) getSubnets(ctx context.Context, subnetRefs []*BaseReference) (subnets []*model.Subnet, err error) {
+	subnets = []*model.Subnet{}
+	for _, subnetRef := range subnetRefs {
+		var subnet *model.Subnet
+		subnet, err = subnetAdmin.GetSubnet(ctx, subnetRef)
+		if err != nil {
+			logger.Errorf("Failed to get subnet %+v", err)
+			return
+		}
+		subnets = append(subnets, subnet)
+	}
+	return
+}
+
+// @Summary get a nat gateway
+// @Description get a nat gateway
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} NatGatewayResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/nat_gateways/{nat_gateway_id} [get]
+func (v *NatGatewayAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, natGateway, err := v.getVPCNatGateway(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid nat gateway query", err)
+		return
+	}
+	natGatewayResp, err := v.getNatGatewayResponse(ctx, natGateway)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, natGatewayResp)
+}
+
+// @Summary patch a nat gateway
+// @Description patch a nat gateway, an empty subnet list translates all subnets of the vpc
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   NatGatewayPatchPayload  true   "Nat gateway patch payload"
+// @Success 200 {object} NatGatewayResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/nat_gateways/{nat_gateway_id} [patch]
+func (v *NatGatewayAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, natGateway, err := v.getVPCNatGateway(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid nat gateway query", err)
+		return
+	}
+	payload := &NatGatewayPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	inbound := natGateway.Inbound
+	if payload.Inbound != nil {
+		inbound = *payload.Inbound
+	}
+	outbound := natGateway.Outbound
+	if payload.Outbound != nil {
+		outbound = *payload.Outbound
+	}
+	var subnets []*model.Subnet
+	if payload.Subnets != nil {
+		subnets, err = v.getSubnets(ctx, payload.Subnets)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Invalid subnet query", err)
+			return
+		}
+	}
+	err = natGatewayAdmin.Update(ctx, natGateway, payload.Name, inbound, outbound, subnets)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch nat gateway failed", err)
+		return
+	}
+	natGatewayResp, err := v.getNatGatewayResponse(ctx, natGateway)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, natGatewayResp)
+}
+
+// @Summary delete a nat gateway
+// @Description delete a nat gateway
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/nat_gateways/{nat_gateway_id} [delete]
+func (v *NatGatewayAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, natGateway, err := v.getVPCNatGateway(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	err = natGatewayAdmin.Delete(ctx, natGateway)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a nat gateway
+// @Description allocate a public address for the vpc and translate the given subnets or all subnets of the vpc
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   NatGatewayPayload  true   "Nat gateway create payload"
+// @Success 200 {object} NatGatewayResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/nat_gateways [post]
+func (v *NatGatewayAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	payload := &NatGatewayPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	logger.Debugf("Creating nat gateway with %+v", payload)
+	var publicSubnets []*model.Subnet
+	if payload.PublicSubnet != nil {
+		subnet, err := subnetAdmin.GetSubnet(ctx, payload.PublicSubnet)
+		if err != nil {
+			logger.Errorf("Failed to get public subnet %+v", err)
+			ErrorResponse(c, http.StatusBadRequest, "Failed to get public subnet", err)
+			return
+		}
+		publicSubnets = append(publicSubnets, subnet)
+	}
+	subnets, err := v.getSubnets(ctx, payload.Subnets)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid subnet query", err)
+		return
+	}
+	natGateway, err := natGatewayAdmin.Create(ctx, payload.Name, router, publicSubnets, payload.PublicIp, payload.Inbound, payload.Outbound, subnets)
+	if err != nil {
+		logger.Errorf("Failed to create nat gateway %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	natGatewayResp, err := v.getNatGatewayResponse(ctx, natGateway)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, natGatewayResp)
+}
+
+func (v *NatGatewayAPI) getNatGatewayResponse(ctx context.Context, natGateway *model.NatGateway) (natGatewayResp *NatGatewayResponse, err error) {
+	owner := orgAdmin.GetOrgName(ctx, natGateway.Owner)
+	natGatewayResp = &NatGatewayResponse{
+		ResourceReference: &ResourceReference{
+			ID:        natGateway.UUID,
+			Name:      natGateway.Name,
+			Owner:     owner,
+			CreatedAt: natGateway.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: natGateway.UpdatedAt.Format(TimeStringForMat),
+		},
+		AllSubnets: natGateway.AllSubnets,
+		Inbound:    natGateway.Inbound,
+		Outbound:   natGateway.Outbound,
+		Status:     natGateway.Status,
+	}
+	if natGateway.FloatingIp != nil {
+		natGatewayResp.PublicIp = natGateway.FloatingIp.FipAddress
+	}
+	natGatewayResp.Subnets = []*BaseReference{}
+	for _, iface := range natGateway.Interfaces {
+		if iface.Address == nil || iface.Address.Subnet == nil {
+			continue
+		}
+		natGatewayResp.Subnets = append(natGatewayResp.Subnets, &BaseReference{
+			ID:   iface.Address.Subnet.UUID,
+			Name: iface.Address.Subnet.Name,
+		})
+	}
+	if natGateway.Router != nil {
+		natGatewayResp.VPC = &BaseReference{
+			ID:   natGateway.Router.UUID,
+			Name: natGateway.Router.Name,
+		}
+	}
+	return
+}
+
+// @Summary list nat gateways
+// @Description list nat gateways
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} NatGatewayListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/nat_gateways [get]
+func (v *NatGatewayAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.NatGateway{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, natGateways, err := natGatewayAdmin.List(ctx, int64(offset), int64(limit), "-created_at", router)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list nat gateways", err)
+		return
+	}
+	natGatewayListResp := &NatGatewayListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(natGateways),
+	}
+	natGatewayListResp.NatGateways = make([]*NatGatewayResponse, natGatewayListResp.Limit)
+	for i, natGateway := range natGateways {
+		natGateway.Router = router
+		natGatewayListResp.NatGateways[i], err = v.getNatGatewayResponse(ctx, natGateway)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, natGateways, limit)
+	c.JSON(http.StatusOK, natGatewayListResp)
+}
@@ -32,7 +32,9 @@ type MemberInfo struct {
 
 type OrgResponse struct {
 	*ResourceReference
-	Members []*MemberInfo `json:"members"`
+	Members        []*MemberInfo `json:"members"`
+	RequireMfa     bool          `json:"require_mfa"`
+	AggregateRules []string      `json:"aggregate_rules,omitempty"`
 }
 
 type OrgListResponse struct {
@@ -46,6 +48,10 @@ type OrgPayload struct {
 }
 
 type OrgPatchPayload struct {
+	RequireMfa *bool `json:"require_mfa,omitempty" binding:"omitempty"`
+	// AggregateRules keep the guests of the organization on or off hypervisors by aggregate metadata,
+	// e.g. ["tenant=finance"] with an isolated aggregate gives the organization dedicated hosts, admin only
+	AggregateRules *[]string `json:"aggregate_rules,omitempty" binding:"omitempty"`
 }
 
 // @Summary get a org
@@ -74,6 +80,7 @@ func (v *OrgAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Got org : %+v", orgResp)
+	SetETag(c, org.UpdatedAt)
 	c.JSON(http.StatusOK, orgResp)
 }
 
@@ -83,7 +90,9 @@ func (v *OrgAPI) getOrgResponse(ctx context.Context, org *model.Organization) (o
 			ID:   org.UUID,
 			Name: org.Name,
 		},
+		RequireMfa: org.RequireMfa,
 	}
+	orgResp.AggregateRules = AggregateRules(org.AggregateRules)
 	for _, member := range org.Members {
 		orgResp.Members = append(orgResp.Members, &MemberInfo{
 			ResourceReference: &ResourceReference{
@@ -105,9 +114,46 @@ func (v *OrgAPI) getOrgResponse(ctx context.Context, org *model.Organization) (o
 // @Success 200 {object} OrgResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /orgs/{id} [patch]
 func (v *OrgAPI) Patch(c *gin.Context) {
-	orgResp := &OrgResponse{}
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	org, err := orgAdmin.GetOrgByUUID(ctx, uuID)
+	if err != nil {
+		logger.Errorf("Failed to get org by uuid: %s, %+v", uuID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	if !ClaimIfMatch(c, org, org.UpdatedAt) {
+		return
+	}
+	payload := &OrgPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	if payload.RequireMfa != nil && *payload.RequireMfa != org.RequireMfa {
+		err = mfaAdmin.SetOrgRequirement(ctx, org, *payload.RequireMfa)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Patch org failed", err)
+			return
+		}
+	}
+	if payload.AggregateRules != nil {
+		org.AggregateRules, err = aggregateAdmin.SetRules(ctx, org, *payload.AggregateRules)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Patch org failed", err)
+			return
+		}
+	}
+	orgResp, err := v.getOrgResponse(ctx, org)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
 	c.JSON(http.StatusOK, orgResp)
 }
 
@@ -119,6 +165,7 @@ func (v *OrgAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /orgs/{id} [delete]
 func (v *OrgAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -130,6 +177,9 @@ func (v *OrgAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, org, org.UpdatedAt) {
+		return
+	}
 	err = orgAdmin.Delete(ctx, org)
 	if err != nil {
 		logger.Errorf("Failed to delete org %s, %+v", uuID, err)
@@ -159,6 +209,9 @@ func (v *OrgAPI) Create(c *gin.Context) {
 // @tags Authorization
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} OrgListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /orgs [get]
@@ -186,6 +239,12 @@ func (v *OrgAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Organization{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, orgs, err := orgAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list orgs, %+v", err)
@@ -206,5 +265,6 @@ func (v *OrgAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List orgs successfully, %+v", orgListResp)
+	listQuery.SetNextCursor(c, orgs, limit)
 	c.JSON(http.StatusOK, orgListResp)
 }
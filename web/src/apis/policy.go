@@ -0,0 +1,377 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var customRoleAPI = &CustomRoleAPI{}
+var policyAdmin = &routes.PolicyAdmin{}
+
+type CustomRoleAPI struct{}
+
+type CustomRoleResponse struct {
+	*ResourceReference
+	Description string   `json:"description,omitempty"`
+	Actions     []string `json:"actions"`
+}
+
+type CustomRoleListResponse struct {
+	Offset      int                   `json:"offset"`
+	Total       int                   `json:"total"`
+	Limit       int                   `json:"limit"`
+	CustomRoles []*CustomRoleResponse `json:"roles"`
+}
+
+type RoleBindingResponse struct {
+	*ResourceReference
+	Role *BaseReference `json:"role"`
+	User *BaseReference `json:"user"`
+}
+
+type RoleBindingListResponse struct {
+	RoleBindings []*RoleBindingResponse `json:"bindings"`
+}
+
+type CustomRolePayload struct {
+	Name        string   `json:"name" binding:"required,min=2,max=64"`
+	Description string   `json:"description" binding:"omitempty,max=255"`
+	Actions     []string `json:"actions" binding:"required,min=1,max=256"`
+}
+
+type CustomRolePatchPayload struct {
+	Name        string   `json:"name" binding:"omitempty,min=2,max=64"`
+	Description string   `json:"description" binding:"omitempty,max=255"`
+	Actions     []string `json:"actions" binding:"omitempty,max=256"`
+}
+
+type RoleBindingPayload struct {
+	Username string `json:"username" binding:"required,min=2"`
+}
+
+// @Summary get a custom role
+// @Description get a custom role
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 200 {object} CustomRoleResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles/{id} [get]
+func (v *CustomRoleAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid custom role query", err)
+		return
+	}
+	SetETag(c, role.UpdatedAt)
+	c.JSON(http.StatusOK, v.getCustomRoleResponse(ctx, role))
+}
+
+// @Summary patch a custom role
+// @Description patch a custom role, the actions replace the actions of the role
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   CustomRolePatchPayload  true   "Custom role patch payload"
+// @Success 200 {object} CustomRoleResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /roles/{id} [patch]
+func (v *CustomRoleAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid custom role query", err)
+		return
+	}
+	if !ClaimIfMatch(c, role, role.UpdatedAt) {
+		return
+	}
+	payload := &CustomRolePatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = policyAdmin.UpdateRole(ctx, role, payload.Name, payload.Description, payload.Actions)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch custom role failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getCustomRoleResponse(ctx, role))
+}
+
+// @Summary delete a custom role
+// @Description delete a custom role together with its bindings
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /roles/{id} [delete]
+func (v *CustomRoleAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	if !ClaimIfMatch(c, role, role.UpdatedAt) {
+		return
+	}
+	err = policyAdmin.DeleteRole(ctx, role)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a custom role
+// @Description create a custom role of the current org, an action is resource:verb like instance:reboot or floating_ip:create,
+// @Description either part can be * like instance:* or *:get, custom roles never grant admin only operations
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   CustomRolePayload  true   "Custom role create payload"
+// @Success 200 {object} CustomRoleResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles [post]
+func (v *CustomRoleAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &CustomRolePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	role, err := policyAdmin.CreateRole(ctx, payload.Name, payload.Description, payload.Actions)
+	if err != nil {
+		logger.Errorf("Failed to create custom role, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getCustomRoleResponse(ctx, role))
+}
+
+func (v *CustomRoleAPI) getCustomRoleResponse(ctx context.Context, role *model.CustomRole) (roleResp *CustomRoleResponse) {
+	owner := orgAdmin.GetOrgName(ctx, role.Owner)
+	roleResp = &CustomRoleResponse{
+		ResourceReference: &ResourceReference{
+			ID:        role.UUID,
+			Name:      role.Name,
+			Owner:     owner,
+			CreatedAt: role.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: role.UpdatedAt.Format(TimeStringForMat),
+		},
+		Description: role.Description,
+		Actions:     []string{},
+	}
+	if role.Actions != "" {
+		roleResp.Actions = strings.Split(role.Actions, ",")
+	}
+	return
+}
+
+// @Summary list custom roles
+// @Description list custom roles
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} CustomRoleListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles [get]
+func (v *CustomRoleAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.CustomRole{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, roles, err := policyAdmin.ListRoles(ctx, int64(offset), int64(limit), "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list custom roles", err)
+		return
+	}
+	roleListResp := &CustomRoleListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(roles),
+	}
+	roleListResp.CustomRoles = make([]*CustomRoleResponse, roleListResp.Limit)
+	for i, role := range roles {
+		roleListResp.CustomRoles[i] = v.getCustomRoleResponse(ctx, role)
+	}
+	listQuery.SetNextCursor(c, roles, limit)
+	c.JSON(http.StatusOK, roleListResp)
+}
+
+func (v *CustomRoleAPI) getRoleBindingResponse(ctx context.Context, binding *model.RoleBinding) (bindingResp *RoleBindingResponse) {
+	owner := orgAdmin.GetOrgName(ctx, binding.Owner)
+	bindingResp = &RoleBindingResponse{
+		ResourceReference: &ResourceReference{
+			ID:        binding.UUID,
+			Owner:     owner,
+			CreatedAt: binding.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: binding.UpdatedAt.Format(TimeStringForMat),
+		},
+	}
+	if binding.Role != nil {
+		bindingResp.Role = &BaseReference{
+			ID:   binding.Role.UUID,
+			Name: binding.Role.Name,
+		}
+	}
+	if binding.User != nil {
+		bindingResp.Name = binding.User.Username
+		bindingResp.User = &BaseReference{
+			ID:   binding.User.UUID,
+			Name: binding.User.Username,
+		}
+	}
+	return
+}
+
+// @Summary bind a custom role
+// @Description bind a custom role to a member of the org of the role
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   RoleBindingPayload  true   "Role binding create payload"
+// @Success 200 {object} RoleBindingResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles/{id}/bindings [post]
+func (v *CustomRoleAPI) CreateBinding(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid custom role query", err)
+		return
+	}
+	payload := &RoleBindingPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	binding, err := policyAdmin.CreateBinding(ctx, role, payload.Username)
+	if err != nil {
+		logger.Errorf("Failed to create role binding, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	c.JSON(http.StatusOK, v.getRoleBindingResponse(ctx, binding))
+}
+
+// @Summary delete a role binding
+// @Description unbind a custom role from a user
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles/{id}/bindings/{binding_id} [delete]
+func (v *CustomRoleAPI) DeleteBinding(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid custom role query", err)
+		return
+	}
+	bindingID := c.Param("binding_id")
+	binding, err := policyAdmin.GetBindingByUUID(ctx, role, bindingID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid role binding query", err)
+		return
+	}
+	err = policyAdmin.DeleteBinding(ctx, binding)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary list role bindings
+// @Description list the users a custom role is bound to
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 200 {object} RoleBindingListResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /roles/{id}/bindings [get]
+func (v *CustomRoleAPI) ListBindings(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	role, err := policyAdmin.GetRoleByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid custom role query", err)
+		return
+	}
+	bindings, err := policyAdmin.ListBindings(ctx, role)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list role bindings", err)
+		return
+	}
+	bindingListResp := &RoleBindingListResponse{
+		RoleBindings: make([]*RoleBindingResponse, len(bindings)),
+	}
+	for i, binding := range bindings {
+		bindingListResp.RoleBindings[i] = v.getRoleBindingResponse(ctx, binding)
+	}
+	c.JSON(http.StatusOK, bindingListResp)
+}
@@ -0,0 +1,308 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var portForwardingAPI = &PortForwardingAPI{}
+var portmapAdmin = &routes.PortmapAdmin{}
+
+type PortForwardingAPI struct{}
+
+type PortForwardingResponse struct {
+	*ResourceReference
+	Instance     *InstanceInfo  `json:"instance,omitempty"`
+	InternalIp   string         `json:"internal_ip"`
+	InternalPort int32          `json:"internal_port"`
+	ExternalIp   string         `json:"external_ip,omitempty"`
+	ExternalPort int32          `json:"external_port"`
+	VPC          *BaseReference `json:"vpc,omitempty"`
+	Status       string         `json:"status"`
+}
+
+type PortForwardingListResponse struct {
+	Offset          int                       `json:"offset"`
+	Total           int                       `json:"total"`
+	Limit           int                       `json:"limit"`
+	PortForwardings []*PortForwardingResponse `json:"port_forwardings"`
+}
+
+type PortForwardingPayload struct {
+	Name         string  `json:"name" binding:"omitempty,min=2,max=32"`
+	Instance     *BaseID `json:"instance" binding:"required"`
+	InternalPort int32   `json:"internal_port" binding:"required,min=1,max=65535"`
+	ExternalPort int32   `json:"external_port" binding:"omitempty,min=1,max=65535"`
+}
+
+type PortForwardingPatchPayload struct {
+	Name string `json:"name" binding:"required,min=2,max=32"`
+}
+
+func (v *PortForwardingAPI) getVPCPortmap(c *gin.Context) (router *model.Router, portmap *model.Portmap, err error) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err = routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		return
+	}
+	portmapID := c.Param("port_forwarding_id")
+	portmap, err = portmapAdmin.GetPortmapByUUID(ctx, portmapID)
+	if err != nil {
+		logger.Errorf("Failed to get port forwarding %s, %+v", portmapID, err)
+		return
+	}
+	if portmap.RouterID != router.ID {
+		logger.Error("Invalid query for vpc port forwarding")
+		err = NewCLError(ErrInvalidParameter, "Invalid query for vpc port forwarding", nil)
+		return
+	}
+	return
+}
+
+// @Summary get a port forwarding
+// @Description get a port forwarding
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} PortForwardingResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/port_forwardings/{port_forwarding_id} [get]
+func (v *PortForwardingAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, portmap, err := v.getVPCPortmap(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid port forwarding query", err)
+		return
+	}
+	portmapResp, err := v.getPortForwardingResponse(ctx, portmap)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, portmapResp)
+}
+
+// @Summary patch a port forwarding
+// @Description patch a port forwarding
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   PortForwardingPatchPayload  true   "Port forwarding patch payload"
+// @Success 200 {object} PortForwardingResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/port_forwardings/{port_forwarding_id} [patch]
+func (v *PortForwardingAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, portmap, err := v.getVPCPortmap(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid port forwarding query", err)
+		return
+	}
+	payload := &PortForwardingPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = portmapAdmin.Update(ctx, portmap, payload.Name)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch port forwarding failed", err)
+		return
+	}
+	portmapResp, err := v.getPortForwardingResponse(ctx, portmap)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, portmapResp)
+}
+
+// @Summary delete a port forwarding
+// @Description delete a port forwarding
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/port_forwardings/{port_forwarding_id} [delete]
+func (v *PortForwardingAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, portmap, err := v.getVPCPortmap(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	err = portmapAdmin.Delete(ctx, portmap)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a port forwarding
+// @Description map a port of the vpc gateway to a port of an instance in the vpc
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   PortForwardingPayload  true   "Port forwarding create payload"
+// @Success 200 {object} PortForwardingResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/port_forwardings [post]
+func (v *PortForwardingAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	payload := &PortForwardingPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	logger.Debugf("Creating port forwarding with %+v", payload)
+	instance, err := instanceAdmin.GetInstanceByUUID(ctx, payload.Instance.ID)
+	if err != nil {
+		logger.Errorf("Failed to get instance %s, %+v", payload.Instance.ID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid instance query", err)
+		return
+	}
+	portmap, err := portmapAdmin.Create(ctx, payload.Name, router, instance, payload.InternalPort, payload.ExternalPort)
+	if err != nil {
+		logger.Errorf("Failed to create port forwarding %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	portmapResp, err := v.getPortForwardingResponse(ctx, portmap)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, portmapResp)
+}
+
+func (v *PortForwardingAPI) getPortForwardingResponse(ctx context.Context, portmap *model.Portmap) (portmapResp *PortForwardingResponse, err error) {
+	owner := orgAdmin.GetOrgName(ctx, portmap.Owner)
+	portmapResp = &PortForwardingResponse{
+		ResourceReference: &ResourceReference{
+			ID:        portmap.UUID,
+			Name:      portmap.Name,
+			Owner:     owner,
+			CreatedAt: portmap.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: portmap.UpdatedAt.Format(TimeStringForMat),
+		},
+		InternalIp:   portmap.LocalAddress,
+		InternalPort: portmap.LocalPort,
+		ExternalIp:   portmap.RemoteAddress,
+		ExternalPort: portmap.RemotePort,
+		Status:       portmap.Status,
+	}
+	if portmap.Instance != nil {
+		portmapResp.Instance = &InstanceInfo{
+			ResourceReference: &ResourceReference{
+				ID: portmap.Instance.UUID,
+			},
+			Hostname: portmap.Instance.Hostname,
+		}
+	}
+	if portmap.Router != nil {
+		portmapResp.VPC = &BaseReference{
+			ID:   portmap.Router.UUID,
+			Name: portmap.Router.Name,
+		}
+	}
+	return
+}
+
+// @Summary list port forwardings
+// @Description list port forwardings
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} PortForwardingListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/port_forwardings [get]
+func (v *PortForwardingAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.Portmap{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, portmaps, err := portmapAdmin.List(ctx, int64(offset), int64(limit), "-created_at", router)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list port forwardings", err)
+		return
+	}
+	portmapListResp := &PortForwardingListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(portmaps),
+	}
+	portmapListResp.PortForwardings = make([]*PortForwardingResponse, portmapListResp.Limit)
+	for i, portmap := range portmaps {
+		portmap.Router = router
+		portmapListResp.PortForwardings[i], err = v.getPortForwardingResponse(ctx, portmap)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, portmaps, limit)
+	c.JSON(http.StatusOK, portmapListResp)
+}
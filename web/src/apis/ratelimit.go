@@ -0,0 +1,108 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const defaultRateGroup = "default"
+
+// rateLimit is a [ratelimit.<group>] section of the config, rates are requests per second and 0 is unlimited
+type rateLimit struct {
+	OrgRate         float64
+	OrgBurst        int
+	CredentialRate  float64
+	CredentialBurst int
+}
+
+func rateBurst(rate float64, burst int) int {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return burst
+}
+
+// getRateLimit returns the group with the longest prefix matching the path, default if none matches
+func getRateLimit(path string) (group string, limit *rateLimit) {
+	group = defaultRateGroup
+	longest := 0
+	for name := range viper.GetStringMap("ratelimit") {
+		prefix := viper.GetString("ratelimit." + name + ".prefix")
+		if prefix != "" && strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			group = name
+			longest = len(prefix)
+		}
+	}
+	section := "ratelimit." + group + "."
+	limit = &rateLimit{
+		OrgRate:         viper.GetFloat64(section + "org_rate"),
+		CredentialRate:  viper.GetFloat64(section + "credential_rate"),
+		OrgBurst:        viper.GetInt(section + "org_burst"),
+		CredentialBurst: viper.GetInt(section + "credential_burst"),
+	}
+	limit.OrgBurst = rateBurst(limit.OrgRate, limit.OrgBurst)
+	limit.CredentialBurst = rateBurst(limit.CredentialRate, limit.CredentialBurst)
+	return
+}
+
+// RateLimit throttles authorized requests with token buckets per organization and per credential,
+// it must run after Authorize, admin requests are not limited
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		memberShip := GetMemberShip(c.Request.Context())
+		if memberShip.Role == model.Admin {
+			c.Next()
+			return
+		}
+		group, limit := getRateLimit(c.Request.URL.Path)
+		// Tokens of a user are keyed by the user instead of the jti, otherwise refreshing would reset the bucket
+		credential := c.GetString("app_credential")
+		if credential == "" {
+			credential = fmt.Sprintf("user-%d", memberShip.UserID)
+		}
+		buckets := []struct {
+			key   string
+			rate  float64
+			burst int
+		}{
+			{fmt.Sprintf("%s:cred:%s:%d", group, credential, memberShip.OrgID), limit.CredentialRate, limit.CredentialBurst},
+			{fmt.Sprintf("%s:org:%d", group, memberShip.OrgID), limit.OrgRate, limit.OrgBurst},
+		}
+		store := GetRateStore(viper.GetBool("ratelimit.shared"))
+		now := time.Now()
+		for _, bucket := range buckets {
+			if bucket.rate <= 0 {
+				continue
+			}
+			retryAfter, ok := store.Take(bucket.key, bucket.rate, bucket.burst, now)
+			if !ok {
+				logger.Errorf("Rate limit %s exceeded by %s", bucket.key, memberShip.UserName)
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded", NewCLError(ErrRateLimited, fmt.Sprintf("Rate limit of %s exceeded, retry later", group), nil))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
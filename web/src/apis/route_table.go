@@ -0,0 +1,421 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var routeTableAPI = &RouteTableAPI{}
+var routeTableAdmin = &routes.RouteTableAdmin{}
+
+type RouteTableAPI struct{}
+
+type RouteResponse struct {
+	Destination string         `json:"destination"`
+	NexthopType string         `json:"nexthop_type"`
+	Nexthop     *BaseReference `json:"nexthop,omitempty"`
+	NexthopIp   string         `json:"nexthop_ip,omitempty"`
+}
+
+type RouteTableResponse struct {
+	*ResourceReference
+	VPC     *BaseReference   `json:"vpc,omitempty"`
+	Routes  []*RouteResponse `json:"routes"`
+	Subnets []*BaseReference `json:"subnets"`
+}
+
+type RouteTableListResponse struct {
+	Offset      int                   `json:"offset"`
+	Total       int                   `json:"total"`
+	Limit       int                   `json:"limit"`
+	RouteTables []*RouteTableResponse `json:"route_tables"`
+}
+
+type RoutePayload struct {
+	Destination string         `json:"destination" binding:"required,cidrv4"`
+	NexthopType string         `json:"nexthop_type" binding:"required,oneof=instance interface ip vpc"`
+	Nexthop     *BaseReference `json:"nexthop" binding:"omitempty"`
+	NexthopIp   string         `json:"nexthop_ip" binding:"omitempty,ipv4"`
+}
+
+type RouteTablePayload struct {
+	Name    string           `json:"name" binding:"required,min=2,max=32"`
+	Routes  []*RoutePayload  `json:"routes" binding:"omitempty,dive"`
+	Subnets []*BaseReference `json:"subnets" binding:"omitempty"`
+}
+
+type RouteTablePatchPayload struct {
+	Name    string           `json:"name" binding:"omitempty,min=2,max=32"`
+	Routes  []*RoutePayload  `json:"routes" binding:"omitempty,dive"`
+	Subnets []*BaseReference `json:"subnets" binding:"omitempty"`
+}
+
+func (v *RouteTableAPI) getVPCRouteTable(c *gin.Context) (router *model.Router, routeTable *model.RouteTable, err error) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err = routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		return
+	}
+	routeTableID := c.Param("route_table_id")
+	routeTable, err = routeTableAdmin.GetRouteTableByUUID(ctx, routeTableID)
+	if err != nil {
+		logger.Errorf("Failed to get route table %s, %+v", routeTableID, err)
+		return
+	}
+	if routeTable.RouterID != router.ID {
+		logger.Error("Invalid query for vpc route table")
+		err = NewCLError(ErrInvalidParameter, "Invalid query for vpc route table", nil)
+		return
+	}
+	return
+}
+
+func (v *RouteTableAPI) getRouteSpecs(ctx context.Context, routePayloads []*RoutePayload) (specs []*routes.RouteSpec, err error) {
+	if routePayloads == nil {
+		return
+	}
+	specs = []*routes.RouteSpec{}
+	for _, rp := range routePayloads {
+		spec := &routes.RouteSpec{Destination: rp.Destination, NexthopType: rp.NexthopType}
+		if rp.NexthopType == model.RouteNexthopIP {
+			if rp.NexthopIp == "" {
+				err = NewCLError(ErrInvalidRouteNexthop, "Next hop ip is required for ip type route", nil)
+				return
+			}
+			spec.Nexthop = rp.NexthopIp
+			specs = append(specs, spec)
+			continue
+		}
+		if rp.Nexthop == nil || rp.Nexthop.ID == "" {
+			err = NewCLError(ErrInvalidRouteNexthop, "Next hop reference is required for "+rp.NexthopType+" type route", nil)
+			return
+		}
+		switch rp.NexthopType {
+		case model.RouteNexthopInstance:
+			var instance *model.Instance
+			instance, err = instanceAdmin.GetInstanceByUUID(ctx, rp.Nexthop.ID)
+			if err != nil {
+				logger.Errorf("Failed to get instance %s, %+v", rp.Nexthop.ID, err)
+				return
+			}
+			spec.NexthopID = instance.ID
+		case model.RouteNexthopInterface:
+			var iface *model.Interface
+			iface, err = interfaceAdmin.GetInterfaceByUUID(ctx, rp.Nexthop.ID)
+			if err != nil {
+				logger.Errorf("Failed to get interface %s, %+v", rp.Nexthop.ID, err)
+				return
+			}
+			spec.NexthopID = iface.ID
+		case model.RouteNexthopVPC:
+			var peer *model.Router
+			peer, err = routerAdmin.GetRouterByUUID(ctx, rp.Nexthop.ID)
+			if err != nil {
+				logger.Errorf("Failed to get vpc %s, %+v", rp.Nexthop.ID, err)
+				return
+			}
+			spec.NexthopID = peer.ID
+		}
+		specs = append(specs, spec)
+	}
+	return
+}
+
+func (v *RouteTableAPI) getSubnets(ctx context.Context, subnetRefs []*BaseReference) (subnets []*model.Subnet, err error) {
+	if subnetRefs == nil {
+		return
+	}
+	subnets = []*model.Subnet{}
+	for _, ref := range subnetRefs {
+		var subnet *model.Subnet
+		subnet, err = subnetAdmin.GetSubnet(ctx, ref)
+		if err != nil {
+			logger.Errorf("Failed to get subnet %+v, %+v", ref, err)
+			return
+		}
+		subnets = append(subnets, subnet)
+	}
+	return
+}
+
+// @Summary get a route table
+// @Description get a route table
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} RouteTableResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/route_tables/{route_table_id} [get]
+func (v *RouteTableAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, routeTable, err := v.getVPCRouteTable(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid route table query", err)
+		return
+	}
+	routeTableResp, err := v.getRouteTableResponse(ctx, routeTable)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, routeTableResp)
+}
+
+// @Summary patch a route table
+// @Description patch a route table, routes and subnets are replaced as a whole if present
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   RouteTablePatchPayload  true   "Route table patch payload"
+// @Success 200 {object} RouteTableResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/route_tables/{route_table_id} [patch]
+func (v *RouteTableAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, routeTable, err := v.getVPCRouteTable(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid route table query", err)
+		return
+	}
+	payload := &RouteTablePatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	specs, err := v.getRouteSpecs(ctx, payload.Routes)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid routes", err)
+		return
+	}
+	subnets, err := v.getSubnets(ctx, payload.Subnets)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid subnets", err)
+		return
+	}
+	err = routeTableAdmin.Update(ctx, routeTable, payload.Name, specs, subnets)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch route table failed", err)
+		return
+	}
+	routeTableResp, err := v.getRouteTableResponse(ctx, routeTable)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, routeTableResp)
+}
+
+// @Summary delete a route table
+// @Description delete a route table
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/route_tables/{route_table_id} [delete]
+func (v *RouteTableAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, routeTable, err := v.getVPCRouteTable(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	err = routeTableAdmin.Delete(ctx, routeTable)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a route table
+// @Description create a route table for a vpc and associate it with subnets
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   RouteTablePayload  true   "Route table create payload"
+// @Success 200 {object} RouteTableResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/route_tables [post]
+func (v *RouteTableAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	payload := &RouteTablePayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	logger.Debugf("Creating route table with %+v", payload)
+	specs, err := v.getRouteSpecs(ctx, payload.Routes)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid routes", err)
+		return
+	}
+	subnets, err := v.getSubnets(ctx, payload.Subnets)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid subnets", err)
+		return
+	}
+	routeTable, err := routeTableAdmin.Create(ctx, payload.Name, router, specs, subnets)
+	if err != nil {
+		logger.Errorf("Failed to create route table %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	routeTableResp, err := v.getRouteTableResponse(ctx, routeTable)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, routeTableResp)
+}
+
+func (v *RouteTableAPI) getRouteTableResponse(ctx context.Context, routeTable *model.RouteTable) (routeTableResp *RouteTableResponse, err error) {
+	owner := orgAdmin.GetOrgName(ctx, routeTable.Owner)
+	routeTableResp = &RouteTableResponse{
+		ResourceReference: &ResourceReference{
+			ID:        routeTable.UUID,
+			Name:      routeTable.Name,
+			Owner:     owner,
+			CreatedAt: routeTable.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: routeTable.UpdatedAt.Format(TimeStringForMat),
+		},
+		Routes:  []*RouteResponse{},
+		Subnets: []*BaseReference{},
+	}
+	if routeTable.Router != nil {
+		routeTableResp.VPC = &BaseReference{
+			ID:   routeTable.Router.UUID,
+			Name: routeTable.Router.Name,
+		}
+	}
+	for _, entry := range routeTable.Entries {
+		routeResp := &RouteResponse{
+			Destination: entry.Destination,
+			NexthopType: entry.NexthopType,
+			NexthopIp:   entry.Nexthop,
+		}
+		switch entry.NexthopType {
+		case model.RouteNexthopInstance:
+			instance, getErr := instanceAdmin.Get(ctx, entry.NexthopID)
+			if getErr == nil {
+				routeResp.Nexthop = &BaseReference{ID: instance.UUID, Name: instance.Hostname}
+			}
+		case model.RouteNexthopInterface:
+			iface, getErr := interfaceAdmin.Get(ctx, entry.NexthopID)
+			if getErr == nil {
+				routeResp.Nexthop = &BaseReference{ID: iface.UUID, Name: iface.Name}
+			}
+		case model.RouteNexthopVPC:
+			peer, getErr := routerAdmin.Get(ctx, entry.NexthopID)
+			if getErr == nil {
+				routeResp.Nexthop = &BaseReference{ID: peer.UUID, Name: peer.Name}
+			}
+		}
+		routeTableResp.Routes = append(routeTableResp.Routes, routeResp)
+	}
+	for _, subnet := range routeTable.Subnets {
+		routeTableResp.Subnets = append(routeTableResp.Subnets, &BaseReference{
+			ID:   subnet.UUID,
+			Name: subnet.Name,
+		})
+	}
+	return
+}
+
+// @Summary list route tables
+// @Description list route tables
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} RouteTableListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpcs/{id}/route_tables [get]
+func (v *RouteTableAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	vpcID := c.Param("id")
+	router, err := routerAdmin.GetRouterByUUID(ctx, vpcID)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.RouteTable{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, routeTables, err := routeTableAdmin.List(ctx, int64(offset), int64(limit), "-created_at", router)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list route tables", err)
+		return
+	}
+	routeTableListResp := &RouteTableListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(routeTables),
+	}
+	routeTableListResp.RouteTables = make([]*RouteTableResponse, routeTableListResp.Limit)
+	for i, routeTable := range routeTables {
+		routeTable.Router = router
+		routeTableListResp.RouteTables[i], err = v.getRouteTableResponse(ctx, routeTable)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, routeTables, limit)
+	c.JSON(http.StatusOK, routeTableListResp)
+}
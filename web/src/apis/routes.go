@@ -44,14 +44,44 @@ func Run() (err error) {
 // @BasePath /api/v1
 func Register() (r *gin.Engine) {
 	r = gin.Default()
+	// client addresses key the login throttle, only the proxy in front may tell them in X-Forwarded-For
+	trustedProxies := viper.GetStringSlice("rest.trusted_proxies")
+	if len(trustedProxies) == 0 {
+		trustedProxies = []string{"127.0.0.1", "::1"}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		logger.Errorf("Invalid rest.trusted_proxies %v, %v", trustedProxies, err)
+		r.SetTrustedProxies(nil)
+	}
 
 	r.POST("/api/v1/login", userAPI.LoginPost)
+	r.POST("/api/v1/token/refresh", userAPI.RefreshPost)
+	r.POST("/api/v1/token/exchange", userAPI.ExchangePost)
 	r.GET("/api/v1/version", versionAPI.Get)
 	r.POST("/api/v1/alerts/process", alarmAPI.ProcessAlertWebhook)
 	r.POST("/api/v1/alerts/resource-adjustment", adjustAPI.ProcessResourceAdjustmentWebhook)
-	authGroup := r.Group("").Use(Authorize())
+	r.POST("/api/v1/alerts/instance-group-scaling", instanceGroupAPI.ProcessScalingWebhook)
+	authGroup := r.Group("").Use(Authorize(), RateLimit())
 	{
 		//authGroup.GET("/api/v1/version", versionAPI.Get)
+		authGroup.POST("/api/v1/logout", userAPI.LogoutPost)
+		authGroup.GET("/api/v1/mfa", mfaAPI.Get)
+		authGroup.POST("/api/v1/mfa/enroll", mfaAPI.Enroll)
+		authGroup.POST("/api/v1/mfa/activate", mfaAPI.Activate)
+		authGroup.POST("/api/v1/mfa/disable", mfaAPI.Disable)
+		authGroup.POST("/api/v1/mfa/recovery_codes", mfaAPI.RecoveryCodes)
+		authGroup.GET("/api/v1/app_credentials", appCredentialAPI.List)
+		authGroup.POST("/api/v1/app_credentials", appCredentialAPI.Create)
+		authGroup.GET("/api/v1/app_credentials/:id", appCredentialAPI.Get)
+		authGroup.DELETE("/api/v1/app_credentials/:id", appCredentialAPI.Delete)
+		authGroup.GET("/api/v1/roles", customRoleAPI.List)
+		authGroup.POST("/api/v1/roles", customRoleAPI.Create)
+		authGroup.GET("/api/v1/roles/:id", customRoleAPI.Get)
+		authGroup.PATCH("/api/v1/roles/:id", customRoleAPI.Patch)
+		authGroup.DELETE("/api/v1/roles/:id", customRoleAPI.Delete)
+		authGroup.GET("/api/v1/roles/:id/bindings", customRoleAPI.ListBindings)
+		authGroup.POST("/api/v1/roles/:id/bindings", customRoleAPI.CreateBinding)
+		authGroup.DELETE("/api/v1/roles/:id/bindings/:binding_id", customRoleAPI.DeleteBinding)
 		authGroup.GET("/api/v1/zones", zoneAPI.List)
 		authGroup.POST("/api/v1/zones", zoneAPI.Create)
 		authGroup.GET("/api/v1/zones/:name", zoneAPI.Get)
@@ -61,6 +91,11 @@ func Register() (r *gin.Engine) {
 		authGroup.GET("/api/v1/hypers", hyperAPI.List)
 		authGroup.GET("/api/v1/hypers/:hostid", hyperAPI.Get)
 		authGroup.PATCH("/api/v1/hypers/:hostid", hyperAPI.Patch)
+		authGroup.GET("/api/v1/aggregates", aggregateAPI.List)
+		authGroup.POST("/api/v1/aggregates", aggregateAPI.Create)
+		authGroup.GET("/api/v1/aggregates/:id", aggregateAPI.Get)
+		authGroup.PATCH("/api/v1/aggregates/:id", aggregateAPI.Patch)
+		authGroup.DELETE("/api/v1/aggregates/:id", aggregateAPI.Delete)
 
 		authGroup.GET("/api/v1/migrations", migrationAPI.List)
 		authGroup.POST("/api/v1/migrations", migrationAPI.Create)
@@ -71,6 +106,8 @@ func Register() (r *gin.Engine) {
 		authGroup.GET("/api/v1/users/:id", userAPI.Get)
 		authGroup.DELETE("/api/v1/users/:id", userAPI.Delete)
 		authGroup.PATCH("/api/v1/users/:id", userAPI.Patch)
+		authGroup.POST("/api/v1/users/:id/mfa/reset", mfaAPI.Reset)
+		authGroup.POST("/api/v1/users/:id/unlock", userAPI.Unlock)
 
 		authGroup.GET("/api/v1/orgs", orgAPI.List)
 		authGroup.POST("/api/v1/orgs", orgAPI.Create)
@@ -84,6 +121,42 @@ func Register() (r *gin.Engine) {
 		authGroup.DELETE("/api/v1/vpcs/:id", vpcAPI.Delete)
 		authGroup.PATCH("/api/v1/vpcs/:id", vpcAPI.Patch)
 
+		authGroup.GET("/api/v1/vpcs/:id/port_forwardings", portForwardingAPI.List)
+		authGroup.POST("/api/v1/vpcs/:id/port_forwardings", portForwardingAPI.Create)
+		authGroup.GET("/api/v1/vpcs/:id/port_forwardings/:port_forwarding_id", portForwardingAPI.Get)
+		authGroup.DELETE("/api/v1/vpcs/:id/port_forwardings/:port_forwarding_id", portForwardingAPI.Delete)
+		authGroup.PATCH("/api/v1/vpcs/:id/port_forwardings/:port_forwarding_id", portForwardingAPI.Patch)
+
+		authGroup.GET("/api/v1/vpcs/:id/route_tables", routeTableAPI.List)
+		authGroup.POST("/api/v1/vpcs/:id/route_tables", routeTableAPI.Create)
+		authGroup.GET("/api/v1/vpcs/:id/route_tables/:route_table_id", routeTableAPI.Get)
+		authGroup.DELETE("/api/v1/vpcs/:id/route_tables/:route_table_id", routeTableAPI.Delete)
+		authGroup.PATCH("/api/v1/vpcs/:id/route_tables/:route_table_id", routeTableAPI.Patch)
+
+		authGroup.GET("/api/v1/vpc_peerings", vpcPeeringAPI.List)
+		authGroup.POST("/api/v1/vpc_peerings", vpcPeeringAPI.Create)
+		authGroup.GET("/api/v1/vpc_peerings/:id", vpcPeeringAPI.Get)
+		authGroup.DELETE("/api/v1/vpc_peerings/:id", vpcPeeringAPI.Delete)
+		authGroup.PATCH("/api/v1/vpc_peerings/:id", vpcPeeringAPI.Patch)
+		authGroup.POST("/api/v1/vpc_peerings/:id/accept", vpcPeeringAPI.Accept)
+		authGroup.POST("/api/v1/vpc_peerings/:id/reject", vpcPeeringAPI.Reject)
+
+		authGroup.GET("/api/v1/vpcs/:id/nat_gateways", natGatewayAPI.List)
+		authGroup.POST("/api/v1/vpcs/:id/nat_gateways", natGatewayAPI.Create)
+		authGroup.GET("/api/v1/vpcs/:id/nat_gateways/:nat_gateway_id", natGatewayAPI.Get)
+		authGroup.DELETE("/api/v1/vpcs/:id/nat_gateways/:nat_gateway_id", natGatewayAPI.Delete)
+		authGroup.PATCH("/api/v1/vpcs/:id/nat_gateways/:nat_gateway_id", natGatewayAPI.Patch)
+
+		authGroup.GET("/api/v1/vpcs/:id/dns_zones", dnsAPI.ListZones)
+		authGroup.POST("/api/v1/vpcs/:id/dns_zones", dnsAPI.CreateZone)
+		authGroup.GET("/api/v1/vpcs/:id/dns_zones/:zone_id", dnsAPI.GetZone)
+		authGroup.DELETE("/api/v1/vpcs/:id/dns_zones/:zone_id", dnsAPI.DeleteZone)
+		authGroup.GET("/api/v1/vpcs/:id/dns_zones/:zone_id/records", dnsAPI.ListRecords)
+		authGroup.POST("/api/v1/vpcs/:id/dns_zones/:zone_id/records", dnsAPI.CreateRecord)
+		authGroup.GET("/api/v1/vpcs/:id/dns_zones/:zone_id/records/:record_id", dnsAPI.GetRecord)
+		authGroup.DELETE("/api/v1/vpcs/:id/dns_zones/:zone_id/records/:record_id", dnsAPI.DeleteRecord)
+		authGroup.PATCH("/api/v1/vpcs/:id/dns_zones/:zone_id/records/:record_id", dnsAPI.PatchRecord)
+
 		authGroup.GET("/api/v1/dictionaries", dictionaryAPI.List)
 		authGroup.POST("/api/v1/dictionaries", dictionaryAPI.Create)
 		authGroup.GET("/api/v1/dictionaries/:id", dictionaryAPI.Get)
@@ -203,11 +276,34 @@ func Register() (r *gin.Engine) {
 
 		authGroup.POST("/api/v1/instances/:id/set_user_password", instanceAPI.SetUserPassword)
 		authGroup.POST("/api/v1/instances/:id/console", consoleAPI.Create)
+		authGroup.GET("/api/v1/instances/:id/console_log", consoleAPI.ConsoleLog)
+		authGroup.POST("/api/v1/instances/:id/guest_exec", guestAgentAPI.Exec)
+		authGroup.POST("/api/v1/instances/:id/guest_file", guestAgentAPI.InjectFile)
+		authGroup.POST("/api/v1/instances/:id/guest_info", guestAgentAPI.RefreshInfo)
+		authGroup.GET("/api/v1/instances/:id/guest_commands/:command_id", guestAgentAPI.GetCommand)
 		authGroup.POST("/api/v1/instances/:id/reinstall", instanceAPI.Reinstall)
 		authGroup.POST("/api/v1/instances/:id/resize", instanceAPI.Resize)
 		authGroup.POST("/api/v1/instances/:id/rescue", instanceAPI.Rescue)
 		authGroup.POST("/api/v1/instances/:id/end_rescue", instanceAPI.EndRescue)
 
+		authGroup.GET("/api/v1/instance_groups", instanceGroupAPI.List)
+		authGroup.POST("/api/v1/instance_groups", instanceGroupAPI.Create)
+		authGroup.GET("/api/v1/instance_groups/:id", instanceGroupAPI.Get)
+		authGroup.DELETE("/api/v1/instance_groups/:id", instanceGroupAPI.Delete)
+		authGroup.PATCH("/api/v1/instance_groups/:id", instanceGroupAPI.Patch)
+		authGroup.GET("/api/v1/instance_groups/:id/policies", instanceGroupAPI.ListPolicies)
+		authGroup.POST("/api/v1/instance_groups/:id/policies", instanceGroupAPI.CreatePolicy)
+		authGroup.DELETE("/api/v1/instance_groups/:id/policies/:policy_id", instanceGroupAPI.DeletePolicy)
+
+		authGroup.GET("/api/v1/launch_templates", launchTemplateAPI.List)
+		authGroup.POST("/api/v1/launch_templates", launchTemplateAPI.Create)
+		authGroup.GET("/api/v1/launch_templates/:id", launchTemplateAPI.Get)
+		authGroup.DELETE("/api/v1/launch_templates/:id", launchTemplateAPI.Delete)
+		authGroup.PATCH("/api/v1/launch_templates/:id", launchTemplateAPI.Patch)
+		authGroup.GET("/api/v1/launch_templates/:id/versions", launchTemplateAPI.ListVersions)
+		authGroup.POST("/api/v1/launch_templates/:id/versions", launchTemplateAPI.CreateVersion)
+		authGroup.GET("/api/v1/launch_templates/:id/versions/:version", launchTemplateAPI.GetVersion)
+
 		authGroup.GET("/api/v1/instances/:id/interfaces", interfaceAPI.List)
 		authGroup.POST("/api/v1/instances/:id/interfaces", interfaceAPI.Create)
 		authGroup.GET("/api/v1/instances/:id/interfaces/:interface_id", interfaceAPI.Get)
@@ -217,6 +313,13 @@ func Register() (r *gin.Engine) {
 		authGroup.GET("/api/v1/tasks", taskAPI.List)
 		authGroup.GET("/api/v1/tasks/:id", taskAPI.Get)
 
+		for _, resource := range []string{"instances", "volumes", "floating_ips", "subnets", "security_groups", "load_balancers", "images"} {
+			authGroup.GET("/api/v1/"+resource+"/:id/tags", tagAPI.List)
+			authGroup.PUT("/api/v1/"+resource+"/:id/tags", tagAPI.Replace)
+			authGroup.PUT("/api/v1/"+resource+"/:id/tags/:key", tagAPI.Set)
+			authGroup.DELETE("/api/v1/"+resource+"/:id/tags/:key", tagAPI.Delete)
+		}
+
 		metricsGroup := authGroup.(*gin.RouterGroup).Group("/api/v1/metrics")
 		{
 			metricsGroup.POST("/instances/cpu/his_data", monitorAPI.GetCPU)
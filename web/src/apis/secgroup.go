@@ -77,6 +77,7 @@ func (v *SecgroupAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get secgroup successfully, %s, %+v", uuID, secgroupResp)
+	SetETag(c, secgroup.UpdatedAt)
 	c.JSON(http.StatusOK, secgroupResp)
 }
 
@@ -89,6 +90,7 @@ func (v *SecgroupAPI) Get(c *gin.Context) {
 // @Success 200 {object} SecurityGroupResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /security_groups/{id} [patch]
 func (v *SecgroupAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -100,6 +102,9 @@ func (v *SecgroupAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid security group query", err)
 		return
 	}
+	if !ClaimIfMatch(c, secgroup, secgroup.UpdatedAt) {
+		return
+	}
 	payload := &SecurityGroupPatchPayload{}
 	err = c.ShouldBindJSON(payload)
 	if err != nil {
@@ -136,6 +141,7 @@ func (v *SecgroupAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /security_groups/{id} [delete]
 func (v *SecgroupAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -147,6 +153,9 @@ func (v *SecgroupAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, secgroup, secgroup.UpdatedAt) {
+		return
+	}
 	err = secgroupAdmin.Delete(ctx, secgroup)
 	if err != nil {
 		logger.Errorf("Failed to delete secgroup %s, %+v", uuID, err)
@@ -258,6 +267,9 @@ func (v *SecgroupAPI) getSecgroupResponse(ctx context.Context, secgroup *model.S
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} SecurityGroupListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /security_groups [get]
@@ -302,6 +314,12 @@ func (v *SecgroupAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.SecurityGroup{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, secgroups, err := secgroupAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list secgroups, %+v", err)
@@ -322,5 +340,6 @@ func (v *SecgroupAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List secgroups successfully, %+v", secgroupListResp)
+	listQuery.SetNextCursor(c, secgroups, limit)
 	c.JSON(http.StatusOK, secgroupListResp)
 }
@@ -198,6 +198,9 @@ func (v *SecruleAPI) getSecruleResponse(ctx context.Context, secrule *model.Secu
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} SecruleListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /security_groups/{id}/rules [get]
@@ -231,6 +234,12 @@ func (v *SecruleAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to get security group", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.SecurityRule{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, secrules, err := secruleAdmin.List(ctx, int64(offset), int64(limit), "-created_at", secgroup)
 	if err != nil {
 		logger.Errorf("Failed to list secrules, %+v", err)
@@ -251,5 +260,6 @@ func (v *SecruleAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List secrules successfully for SG %s, %+v", uuID, secruleListResp)
+	listQuery.SetNextCursor(c, secrules, limit)
 	c.JSON(http.StatusOK, secruleListResp)
 }
@@ -109,6 +109,7 @@ func (v *SubnetAPI) Get(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
 		return
 	}
+	SetETag(c, subnet.UpdatedAt)
 	c.JSON(http.StatusOK, subnetResp)
 }
 
@@ -121,6 +122,7 @@ func (v *SubnetAPI) Get(c *gin.Context) {
 // @Success 200 {object} SubnetResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /subnets/{id} [patch]
 func (v *SubnetAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -140,6 +142,9 @@ func (v *SubnetAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid subnet query", err)
 		return
 	}
+	if !ClaimIfMatch(c, subnet, subnet.UpdatedAt) {
+		return
+	}
 	if payload.Name != "" {
 		subnet.Name = payload.Name
 	}
@@ -182,6 +187,7 @@ func (v *SubnetAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /subnets/{id} [delete]
 func (v *SubnetAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -191,6 +197,9 @@ func (v *SubnetAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, subnet, subnet.UpdatedAt) {
+		return
+	}
 	err = subnetAdmin.Delete(ctx, subnet)
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
@@ -330,6 +339,9 @@ func (v *SubnetAPI) getSubnetResponse(ctx context.Context, subnet *model.Subnet)
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} SubnetListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /subnets [get]
@@ -387,6 +399,12 @@ func (v *SubnetAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Subnet{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, subnets, err := subnetAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr, "")
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to list subnets", err)
@@ -405,5 +423,6 @@ func (v *SubnetAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, subnets, limit)
 	c.JSON(http.StatusOK, subnetListResp)
 }
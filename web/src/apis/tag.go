@@ -0,0 +1,152 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"net/http"
+	"strings"
+
+	. "web/src/common"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var tagAPI = &TagAPI{}
+var tagAdmin = &routes.TagAdmin{}
+
+// TagAPI serves /api/v1/{resource}/:id/tags for all taggable resources
+type TagAPI struct{}
+
+type TagsPayload struct {
+	Tags map[string]string `json:"tags" binding:"required"`
+}
+
+type TagPayload struct {
+	Value string `json:"value" binding:"max=256"`
+}
+
+type TagsResponse struct {
+	Tags map[string]string `json:"tags"`
+}
+
+// resourceType is the collection of the route, /api/v1/instances/:id/tags is tagging instances
+func (v *TagAPI) resourceType(c *gin.Context) string {
+	path := strings.TrimPrefix(c.FullPath(), "/api/v1/")
+	resourceType, _, _ := strings.Cut(path, "/")
+	return resourceType
+}
+
+func (v *TagAPI) errorStatus(err error) int {
+	if clErr, ok := err.(*CLError); ok {
+		switch clErr.Code {
+		case ErrResourceNotFound:
+			return http.StatusNotFound
+		case ErrPermissionDenied:
+			return http.StatusForbidden
+		case ErrTagNotFound:
+			return http.StatusNotFound
+		case ErrDatabaseError:
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusBadRequest
+}
+
+// @Summary list the tags of a resource
+// @Description list the tags of an instance, volume, floating ip, subnet, security group, load balancer or image
+// @tags Tags
+// @Accept  json
+// @Produce json
+// @Param resource path string true "instances, volumes, floating_ips, subnets, security_groups, load_balancers or images"
+// @Success 200 {object} TagsResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /{resource}/{id}/tags [get]
+func (v *TagAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	tags, err := tagAdmin.List(ctx, v.resourceType(c), c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to list tags", err)
+		return
+	}
+	c.JSON(http.StatusOK, &TagsResponse{Tags: tags})
+}
+
+// @Summary replace the tags of a resource
+// @Description replace all tags of an instance, volume, floating ip, subnet, security group, load balancer or image
+// @tags Tags
+// @Accept  json
+// @Produce json
+// @Param resource path string true "instances, volumes, floating_ips, subnets, security_groups, load_balancers or images"
+// @Param   message	body   TagsPayload  true   "Tags"
+// @Success 200 {object} TagsResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /{resource}/{id}/tags [put]
+func (v *TagAPI) Replace(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &TagsPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = tagAdmin.Replace(ctx, v.resourceType(c), c.Param("id"), payload.Tags)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to replace tags", err)
+		return
+	}
+	c.JSON(http.StatusOK, &TagsResponse{Tags: payload.Tags})
+}
+
+// @Summary set a tag of a resource
+// @Description add or update one tag of an instance, volume, floating ip, subnet, security group, load balancer or image
+// @tags Tags
+// @Accept  json
+// @Produce json
+// @Param resource path string true "instances, volumes, floating_ips, subnets, security_groups, load_balancers or images"
+// @Param   message	body   TagPayload  true   "Tag value"
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /{resource}/{id}/tags/{key} [put]
+func (v *TagAPI) Set(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &TagPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = tagAdmin.Set(ctx, v.resourceType(c), c.Param("id"), c.Param("key"), payload.Value)
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to set tag", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary delete a tag of a resource
+// @Description delete one tag of an instance, volume, floating ip, subnet, security group, load balancer or image
+// @tags Tags
+// @Accept  json
+// @Produce json
+// @Param resource path string true "instances, volumes, floating_ips, subnets, security_groups, load_balancers or images"
+// @Success 204
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 404 {object} common.APIError "Not found"
+// @Router /{resource}/{id}/tags/{key} [delete]
+func (v *TagAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	err := tagAdmin.Delete(ctx, v.resourceType(c), c.Param("id"), c.Param("key"))
+	if err != nil {
+		ErrorResponse(c, v.errorStatus(err), "Failed to delete tag", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
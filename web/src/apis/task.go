@@ -77,6 +77,9 @@ func (a *TaskAPI) Get(c *gin.Context) {
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} CLTaskListResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
@@ -106,6 +109,12 @@ func (a *TaskAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Task{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, tasks, err := taskAdmin.List(ctx, int64(offset), int64(limit), "-created_at", c.DefaultQuery("query", ""), c.DefaultQuery("source", ""))
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, "Failed to list tasks", err)
@@ -124,6 +133,7 @@ func (a *TaskAPI) List(c *gin.Context) {
 			return
 		}
 	}
+	listQuery.SetNextCursor(c, tasks, limit)
 	c.JSON(http.StatusOK, taskListResp)
 }
 
@@ -22,14 +22,18 @@ import (
 
 var userAPI = &UserAPI{}
 var userAdmin = &routes.UserAdmin{}
+var tokenAdmin = &routes.TokenAdmin{}
+var identityAdmin = &routes.IdentityAdmin{}
 
 type UserAPI struct{}
 
 type UserPayload struct {
-	Username string         `json:"username" binding:"required,min=2"`
-	Password string         `json:"password" binding:"required,min=8,max=32"`
-	ID       string         `json:"id,omitempty" binding:"omitempty"`
-	Org      *BaseReference `json:"org,omitempty" binding:"omitempty"`
+	Username    string         `json:"username" binding:"required,min=2"`
+	Password    string         `json:"password" binding:"required,min=8,max=32"`
+	ID          string         `json:"id,omitempty" binding:"omitempty"`
+	Org         *BaseReference `json:"org,omitempty" binding:"omitempty"`
+	Passcode    string         `json:"passcode,omitempty" binding:"omitempty"`                  /* TOTP or recovery code when multi-factor authentication is enabled */
+	NewPassword string         `json:"new_password,omitempty" binding:"omitempty,min=8,max=32"` /* Replaces an expired password at login */
 }
 
 type UserPatchPayload struct {
@@ -37,10 +41,23 @@ type UserPatchPayload struct {
 }
 
 type UserResponse struct {
-	UserInfo    *ResourceReference `json:"user"`
-	OrgInfo     *ResourceReference `json:"org,omitempty"`
-	AccessToken string             `json:"token,omitempty"`
-	Role        string             `json:"role,omitempty"`
+	UserInfo     *ResourceReference `json:"user"`
+	OrgInfo      *ResourceReference `json:"org,omitempty"`
+	AccessToken  string             `json:"token,omitempty"`
+	RefreshToken string             `json:"refresh_token,omitempty"`
+	ExpiresAt    int64              `json:"expires_at,omitempty"`
+	Role         string             `json:"role,omitempty"`
+	Locked       bool               `json:"locked,omitempty"`
+}
+
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenExchangePayload struct {
+	SubjectToken string         `json:"subject_token" binding:"required"`
+	Org          *BaseReference `json:"org,omitempty" binding:"omitempty"`
+	Passcode     string         `json:"passcode,omitempty" binding:"omitempty"` /* TOTP or recovery code when multi-factor authentication is enabled */
 }
 
 type UserListResponse struct {
@@ -74,8 +91,10 @@ func (v *UserAPI) Get(c *gin.Context) {
 			ID:   user.UUID,
 			Name: user.Username,
 		},
+		Locked: userAdmin.Locked(user),
 	}
 	logger.Debugf("Got user : %+v", userResp)
+	SetETag(c, user.UpdatedAt)
 	c.JSON(http.StatusOK, userResp)
 }
 
@@ -88,6 +107,7 @@ func (v *UserAPI) Get(c *gin.Context) {
 // @Success 200 {object} UserResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /users/{id} [patch]
 func (v *UserAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -105,6 +125,9 @@ func (v *UserAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, user, user.UpdatedAt) {
+		return
+	}
 	logger.Debugf("Patch user %s with %+v", uuID, payload)
 	user, err = userAdmin.Update(ctx, user.ID, payload.Password, nil)
 	if err != nil {
@@ -131,6 +154,7 @@ func (v *UserAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /users/{id} [delete]
 func (v *UserAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -142,6 +166,9 @@ func (v *UserAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, user, user.UpdatedAt) {
+		return
+	}
 	err = userAdmin.Delete(ctx, user)
 	if err != nil {
 		logger.Errorf("Failed to delete user %s, %+v", uuID, err)
@@ -151,6 +178,31 @@ func (v *UserAPI) Delete(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// @Summary unlock a user
+// @Description unlock a user locked after too many failed logins, admin only
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /users/{id}/unlock [post]
+func (v *UserAPI) Unlock(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	user, err := userAdmin.GetUserByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid user query", err)
+		return
+	}
+	err = userAdmin.Unlock(ctx, user)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to unlock user", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
 // @Summary create a user
 // @Description create a user
 // @tags Authorization
@@ -180,6 +232,11 @@ func (v *UserAPI) Create(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid user uuid", nil)
 		return
 	}
+	err = userAdmin.CheckPassword(username, password)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Password does not meet the policy", err)
+		return
+	}
 
 	user, err := userAdmin.Create(ctx, username, password, userUUID)
 	if err != nil {
@@ -226,6 +283,9 @@ func (v *UserAPI) Create(c *gin.Context) {
 // @tags Authorization
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} UserListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /users [get]
@@ -253,6 +313,12 @@ func (v *UserAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.User{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, users, err := userAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list users, %+v", err)
@@ -276,6 +342,7 @@ func (v *UserAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List users successfully, %+v", userListResp)
+	listQuery.SetNextCursor(c, users, limit)
 	c.JSON(http.StatusOK, userListResp)
 }
 
@@ -287,6 +354,8 @@ func (v *UserAPI) List(c *gin.Context) {
 // @Param   message	body   UserPayload  true   "User Credential"
 // @Success 200 {object} UserResponse
 // @Failure 401 {object} common.APIError "Invalied user name or password"
+// @Failure 403 {object} common.APIError "Password expired, retry with new_password"
+// @Failure 429 {object} common.APIError "Too many failed logins"
 // @Router /login [post]
 func (v *UserAPI) LoginPost(c *gin.Context) {
 	payload := &UserPayload{}
@@ -295,12 +364,21 @@ func (v *UserAPI) LoginPost(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Input JSON format error", err)
 		return
 	}
+	clientIP := c.ClientIP()
+	retryAfter, ok := LoginThrottle.Allow(clientIP)
+	if !ok {
+		logger.Errorf("Login from %s is throttled", clientIP)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		ErrorResponse(c, http.StatusTooManyRequests, "Too many failed logins", NewCLError(ErrLoginThrottled, "Too many failed logins, please try again later", nil))
+		return
+	}
 	username := payload.Username
 	password := payload.Password
 	logger.Debugf("Login with username: %s", username)
 	user, err := userAdmin.Validate(c.Request.Context(), username, password)
 	if err != nil {
-		logger.Errorf("Failed to validate user: %+v", err)
+		LoginThrottle.Fail(clientIP)
+		logger.Errorf("Failed to validate user %s from %s: %+v", username, clientIP, err)
 		ErrorResponse(c, http.StatusBadRequest, "Invalid username or password", err)
 		return
 	}
@@ -315,7 +393,28 @@ func (v *UserAPI) LoginPost(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid organization", err)
 		return
 	}
-	_, role, token, _, _, err := userAdmin.AccessToken(ctx, user.ID, username, orgName)
+	err = mfaAdmin.Check(ctx, user, org, payload.Passcode)
+	if err != nil {
+		if payload.Passcode != "" {
+			LoginThrottle.Fail(clientIP)
+		}
+		logger.Errorf("Failed multi-factor authentication: %+v", err)
+		ErrorResponse(c, http.StatusUnauthorized, "Multi-factor authentication failed", err)
+		return
+	}
+	if userAdmin.PasswordExpired(user) {
+		if payload.NewPassword == "" {
+			ErrorResponse(c, http.StatusForbidden, "Password expired", NewCLError(ErrPasswordExpired, "Password expired, login again with new_password", nil))
+			return
+		}
+		err = userAdmin.ChangePassword(ctx, user, payload.NewPassword)
+		if err != nil {
+			logger.Errorf("Failed to change expired password: %+v", err)
+			ErrorResponse(c, http.StatusBadRequest, "Failed to change expired password", err)
+			return
+		}
+	}
+	role, token, refreshToken, expiresAt, err := tokenAdmin.Issue(ctx, user.ID, username, orgName)
 	if err != nil {
 		logger.Errorf("Failed to get access token: %+v", err)
 		ErrorResponse(c, http.StatusBadRequest, "Invalid organization with username", err)
@@ -330,10 +429,145 @@ func (v *UserAPI) LoginPost(c *gin.Context) {
 			Name: orgName,
 			ID:   org.UUID,
 		},
-		AccessToken: token,
-		Role:        role.String(),
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		Role:         role.String(),
 	}
 	logger.Debugf("Login successfully, %+v", userResp)
 	c.JSON(http.StatusOK, userResp)
 	return
 }
+
+// @Summary refresh the access token
+// @Description exchange a refresh token for a new access token and a new refresh token, the used refresh token is revoked
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   RefreshPayload  true   "Refresh token"
+// @Success 200 {object} UserResponse
+// @Failure 401 {object} common.APIError "Invalid or revoked refresh token"
+// @Router /token/refresh [post]
+func (v *UserAPI) RefreshPost(c *gin.Context) {
+	payload := &RefreshPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Input JSON format error", err)
+		return
+	}
+	ctx := c.Request.Context()
+	user, org, role, token, refreshToken, expiresAt, err := tokenAdmin.Refresh(ctx, payload.RefreshToken)
+	if err != nil {
+		logger.Errorf("Failed to refresh token: %+v", err)
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token", err)
+		return
+	}
+	userResp := &UserResponse{
+		UserInfo: &ResourceReference{
+			Name: user.Username,
+			ID:   user.UUID,
+		},
+		OrgInfo: &ResourceReference{
+			Name: org.Name,
+			ID:   org.UUID,
+		},
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		Role:         role.String(),
+	}
+	c.JSON(http.StatusOK, userResp)
+}
+
+// @Summary exchange an OIDC ID token for an access token
+// @Description verify an ID token of the configured issuer, provision the user on the first use and issue tokens like login
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Param   message	body   TokenExchangePayload  true   "Token exchange payload"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Invalid subject token"
+// @Failure 429 {object} common.APIError "Too many failed logins"
+// @Router /token/exchange [post]
+func (v *UserAPI) ExchangePost(c *gin.Context) {
+	payload := &TokenExchangePayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Input JSON format error", err)
+		return
+	}
+	clientIP := c.ClientIP()
+	retryAfter, ok := LoginThrottle.Allow(clientIP)
+	if !ok {
+		logger.Errorf("Token exchange from %s is throttled", clientIP)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		ErrorResponse(c, http.StatusTooManyRequests, "Too many failed logins", NewCLError(ErrLoginThrottled, "Too many failed logins, please try again later", nil))
+		return
+	}
+	ctx := c.Request.Context()
+	user, err := identityAdmin.Exchange(ctx, payload.SubjectToken)
+	if err != nil {
+		logger.Errorf("Failed to exchange token: %+v", err)
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid subject token", err)
+		return
+	}
+	orgName := user.Username
+	if payload.Org != nil {
+		orgName = payload.Org.Name
+	}
+	org, err := orgAdmin.GetOrgByName(ctx, orgName)
+	if err != nil {
+		logger.Errorf("Failed to get org: %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid organization", err)
+		return
+	}
+	err = mfaAdmin.Check(ctx, user, org, payload.Passcode)
+	if err != nil {
+		if payload.Passcode != "" {
+			LoginThrottle.Fail(clientIP)
+		}
+		logger.Errorf("Failed multi-factor authentication: %+v", err)
+		ErrorResponse(c, http.StatusUnauthorized, "Multi-factor authentication failed", err)
+		return
+	}
+	role, token, refreshToken, expiresAt, err := tokenAdmin.Issue(ctx, user.ID, user.Username, orgName)
+	if err != nil {
+		logger.Errorf("Failed to get access token: %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid organization with username", err)
+		return
+	}
+	userResp := &UserResponse{
+		UserInfo: &ResourceReference{
+			Name: user.Username,
+			ID:   user.UUID,
+		},
+		OrgInfo: &ResourceReference{
+			Name: orgName,
+			ID:   org.UUID,
+		},
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		Role:         role.String(),
+	}
+	c.JSON(http.StatusOK, userResp)
+}
+
+// @Summary logout
+// @Description revoke the access token and all the tokens refreshed from the same login
+// @tags Authorization
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /logout [post]
+func (v *UserAPI) LogoutPost(c *gin.Context) {
+	ctx := c.Request.Context()
+	err := tokenAdmin.Logout(ctx, c.GetString("jti"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to logout", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	. "web/src/common"
+	"web/src/model"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/spf13/viper"
+)
+
+func TestRefreshReplayRevokesFamily(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := viper.GetString("key.private")
+	defer viper.Set("key.private", privateKey)
+	viper.Set("key.private", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})))
+
+	user := &model.User{Username: "test-refresh-replay"}
+	org := &model.Organization{Name: "test-refresh-replay"}
+	if err = DB().Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = DB().Create(org).Error; err != nil {
+		t.Fatal(err)
+	}
+	member := &model.Member{UserID: user.ID, UserName: user.Username, OrgID: org.ID, OrgName: org.Name, Role: model.Writer}
+	if err = DB().Create(member).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		DB().Unscoped().Where("user_id = ?", user.ID).Delete(&model.Token{})
+		DB().Unscoped().Delete(member)
+		DB().Unscoped().Delete(org)
+		DB().Unscoped().Delete(user)
+	}()
+
+	ctx := context.Background()
+	_, _, first, _, err := tokenAdmin.Issue(ctx, user.ID, user.Username, org.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, _, second, _, err := tokenAdmin.Refresh(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replaying the first token rolls back the refresh but still revokes the family
+	_, _, _, _, _, _, err = tokenAdmin.Refresh(ctx, first)
+	if clErr, ok := err.(*CLError); !ok || clErr.Code != ErrTokenRevoked {
+		t.Fatal("replayed refresh token is accepted", err)
+	}
+	count := 0
+	if err = DB().Model(&model.Token{}).Where("user_id = ? and revoked = ?", user.ID, false).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("%d tokens of the family are still valid", count)
+	}
+	if _, _, _, _, _, _, err = tokenAdmin.Refresh(ctx, second); err == nil {
+		t.Fatal("refresh token of a revoked family is accepted")
+	}
+}
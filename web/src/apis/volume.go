@@ -106,6 +106,7 @@ func (v *VolumeAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Got volume : %+v", volumeResp)
+	SetETag(c, volume.UpdatedAt)
 	c.JSON(http.StatusOK, volumeResp)
 }
 
@@ -154,6 +155,7 @@ func (v *VolumeAPI) UpdateQos(c *gin.Context) {
 // @Success 200 {object} VolumeResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /volumes/{id} [patch]
 func (v *VolumeAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -166,6 +168,14 @@ func (v *VolumeAPI) Patch(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Patching volume %s with %+v", uuID, payload)
+	current, err := volumeAdmin.GetVolumeByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid volume query", err)
+		return
+	}
+	if !ClaimIfMatch(c, current, current.UpdatedAt) {
+		return
+	}
 	instanceID := int64(0)
 	if payload.Instance != nil {
 		var instance *model.Instance
@@ -201,12 +211,21 @@ func (v *VolumeAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /volumes/{id} [delete]
 func (v *VolumeAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
 	uuID := c.Param("id")
 	logger.Debugf("Deleting volume %s", uuID)
-	err := volumeAdmin.DeleteVolumeByUUID(ctx, uuID)
+	volume, err := volumeAdmin.GetVolumeByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid volume query", err)
+		return
+	}
+	if !ClaimIfMatch(c, volume, volume.UpdatedAt) {
+		return
+	}
+	err = volumeAdmin.DeleteVolumeByUUID(ctx, uuID)
 	if err != nil {
 		logger.Errorf("Failed to delete volume %s, %+v", uuID, err)
 		ErrorResponse(c, http.StatusBadRequest, "Failed to delete volume", err)
@@ -257,6 +276,9 @@ func (v *VolumeAPI) Create(c *gin.Context) {
 // @tags Compute
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} VolumeListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /volumes [get]
@@ -288,6 +310,12 @@ func (v *VolumeAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Volume{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, volumes, err := volumeAdmin.ListVolume(ctx, int64(offset), int64(limit), "-created_at", nameStr, typeStr)
 	if err != nil {
 		logger.Errorf("Failed to list volumes, %+v", err)
@@ -311,6 +339,7 @@ func (v *VolumeAPI) List(c *gin.Context) {
 
 	volumeListResp.Volumes = volumeList
 	logger.Debugf("List volumes successfully, %+v", volumeListResp)
+	listQuery.SetNextCursor(c, volumes, limit)
 	c.JSON(http.StatusOK, volumeListResp)
 }
 
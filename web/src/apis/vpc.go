@@ -70,6 +70,7 @@ func (v *VPCAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get vpc by uuid: %s, %+v", uuID, vpcResp)
+	SetETag(c, router.UpdatedAt)
 	c.JSON(http.StatusOK, vpcResp)
 }
 
@@ -82,6 +83,7 @@ func (v *VPCAPI) Get(c *gin.Context) {
 // @Success 200 {object} VPCResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /vpcs/{id} [patch]
 func (v *VPCAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -99,6 +101,9 @@ func (v *VPCAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
 		return
 	}
+	if !ClaimIfMatch(c, router, router.UpdatedAt) {
+		return
+	}
 	logger.Debugf("Patching vpc %s with %+v", uuID, payload)
 	router, err = routerAdmin.Update(ctx, router.ID, payload.Name, 0)
 	if err != nil {
@@ -123,6 +128,7 @@ func (v *VPCAPI) Patch(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /vpcs/{id} [delete]
 func (v *VPCAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -134,6 +140,9 @@ func (v *VPCAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, router, router.UpdatedAt) {
+		return
+	}
 	err = routerAdmin.Delete(ctx, router)
 	if err != nil {
 		logger.Errorf("Failed to delete vpc by uuid: %s, %+v", uuID, err)
@@ -205,6 +214,9 @@ func (v *VPCAPI) getVPCResponse(ctx context.Context, router *model.Router) (vpcR
 // @tags Network
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} VPCListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /vpcs [get]
@@ -232,6 +244,12 @@ func (v *VPCAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Router{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, routers, err := routerAdmin.List(ctx, int64(offset), int64(limit), "-created_at", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list vpcs, %+v", err)
@@ -252,5 +270,6 @@ func (v *VPCAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List vpcs successfully, %+v", vpcListResp)
+	listQuery.SetNextCursor(c, routers, limit)
 	c.JSON(http.StatusOK, vpcListResp)
 }
@@ -0,0 +1,350 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+	"web/src/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var vpcPeeringAPI = &VpcPeeringAPI{}
+var vpcPeeringAdmin = &routes.VpcPeeringAdmin{}
+
+type VpcPeeringAPI struct{}
+
+type VpcPeeringResponse struct {
+	*ResourceReference
+	VPC       *BaseReference `json:"vpc,omitempty"`
+	PeerVPC   *BaseReference `json:"peer_vpc,omitempty"`
+	PeerOwner string         `json:"peer_owner,omitempty"`
+	Status    string         `json:"status"`
+}
+
+type VpcPeeringListResponse struct {
+	Offset      int                   `json:"offset"`
+	Total       int                   `json:"total"`
+	Limit       int                   `json:"limit"`
+	VpcPeerings []*VpcPeeringResponse `json:"vpc_peerings"`
+}
+
+type VpcPeeringPayload struct {
+	Name    string         `json:"name" binding:"omitempty,min=2,max=32"`
+	VPC     *BaseReference `json:"vpc" binding:"required"`
+	PeerVPC *BaseID        `json:"peer_vpc" binding:"required"`
+}
+
+type VpcPeeringPatchPayload struct {
+	Name string `json:"name" binding:"required,min=2,max=32"`
+}
+
+// @Summary get a vpc peering
+// @Description get a vpc peering
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} VpcPeeringResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpc_peerings/{id} [get]
+func (v *VpcPeeringAPI) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	peering, err := vpcPeeringAdmin.GetVpcPeeringByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc peering query", err)
+		return
+	}
+	peeringResp, err := v.getVpcPeeringResponse(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	SetETag(c, peering.UpdatedAt)
+	c.JSON(http.StatusOK, peeringResp)
+}
+
+// @Summary patch a vpc peering
+// @Description patch a vpc peering
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   VpcPeeringPatchPayload  true   "Vpc peering patch payload"
+// @Success 200 {object} VpcPeeringResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /vpc_peerings/{id} [patch]
+func (v *VpcPeeringAPI) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	peering, err := vpcPeeringAdmin.GetVpcPeeringByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc peering query", err)
+		return
+	}
+	if !ClaimIfMatch(c, peering, peering.UpdatedAt) {
+		return
+	}
+	payload := &VpcPeeringPatchPayload{}
+	err = c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	err = vpcPeeringAdmin.Update(ctx, peering, payload.Name)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Patch vpc peering failed", err)
+		return
+	}
+	peeringResp, err := v.getVpcPeeringResponse(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, peeringResp)
+}
+
+// @Summary accept a vpc peering
+// @Description accept a pending vpc peering requested to a vpc of the current organization
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} VpcPeeringResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpc_peerings/{id}/accept [post]
+func (v *VpcPeeringAPI) Accept(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	peering, err := vpcPeeringAdmin.GetVpcPeeringByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc peering query", err)
+		return
+	}
+	err = vpcPeeringAdmin.Accept(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Accept vpc peering failed", err)
+		return
+	}
+	peeringResp, err := v.getVpcPeeringResponse(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, peeringResp)
+}
+
+// @Summary reject a vpc peering
+// @Description reject a pending vpc peering requested to a vpc of the current organization
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 200 {object} VpcPeeringResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpc_peerings/{id}/reject [post]
+func (v *VpcPeeringAPI) Reject(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	peering, err := vpcPeeringAdmin.GetVpcPeeringByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc peering query", err)
+		return
+	}
+	err = vpcPeeringAdmin.Reject(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Reject vpc peering failed", err)
+		return
+	}
+	peeringResp, err := v.getVpcPeeringResponse(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, peeringResp)
+}
+
+// @Summary delete a vpc peering
+// @Description delete a vpc peering
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Success 204
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
+// @Router /vpc_peerings/{id} [delete]
+func (v *VpcPeeringAPI) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	uuID := c.Param("id")
+	peering, err := vpcPeeringAdmin.GetVpcPeeringByUUID(ctx, uuID)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	if !ClaimIfMatch(c, peering, peering.UpdatedAt) {
+		return
+	}
+	err = vpcPeeringAdmin.Delete(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Not able to delete", err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary create a vpc peering
+// @Description request a peering between a vpc and a peer vpc, the owner of the peer vpc needs to accept it if it belongs to another organization
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param   message	body   VpcPeeringPayload  true   "Vpc peering create payload"
+// @Success 200 {object} VpcPeeringResponse
+// @Failure 400 {object} common.APIError "Bad request"
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpc_peerings [post]
+func (v *VpcPeeringAPI) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	payload := &VpcPeeringPayload{}
+	err := c.ShouldBindJSON(payload)
+	if err != nil {
+		logger.Errorf("Failed to bind json, %+v", err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid input JSON", err)
+		return
+	}
+	logger.Debugf("Creating vpc peering with %+v", payload)
+	router, err := routerAdmin.GetRouter(ctx, payload.VPC)
+	if err != nil {
+		logger.Errorf("Failed to get vpc %+v, %+v", payload.VPC, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+		return
+	}
+	peerRouter, err := vpcPeeringAdmin.GetPeerRouterByUUID(ctx, payload.PeerVPC.ID)
+	if err != nil {
+		logger.Errorf("Failed to get peer vpc %s, %+v", payload.PeerVPC.ID, err)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid peer vpc query", err)
+		return
+	}
+	peering, err := vpcPeeringAdmin.Create(ctx, payload.Name, router, peerRouter)
+	if err != nil {
+		logger.Errorf("Failed to create vpc peering %+v, %+v", payload, err)
+		ErrorResponse(c, http.StatusBadRequest, "Not able to create", err)
+		return
+	}
+	peeringResp, err := v.getVpcPeeringResponse(ctx, peering)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+		return
+	}
+	c.JSON(http.StatusOK, peeringResp)
+}
+
+func (v *VpcPeeringAPI) getVpcPeeringResponse(ctx context.Context, peering *model.VpcPeering) (peeringResp *VpcPeeringResponse, err error) {
+	owner := orgAdmin.GetOrgName(ctx, peering.Owner)
+	peeringResp = &VpcPeeringResponse{
+		ResourceReference: &ResourceReference{
+			ID:        peering.UUID,
+			Name:      peering.Name,
+			Owner:     owner,
+			CreatedAt: peering.CreatedAt.Format(TimeStringForMat),
+			UpdatedAt: peering.UpdatedAt.Format(TimeStringForMat),
+		},
+		PeerOwner: orgAdmin.GetOrgName(ctx, peering.PeerOwner),
+		Status:    peering.Status,
+	}
+	if peering.Router != nil {
+		peeringResp.VPC = &BaseReference{
+			ID:   peering.Router.UUID,
+			Name: peering.Router.Name,
+		}
+	}
+	if peering.PeerRouter != nil {
+		peeringResp.PeerVPC = &BaseReference{
+			ID:   peering.PeerRouter.UUID,
+			Name: peering.PeerRouter.Name,
+		}
+	}
+	return
+}
+
+// @Summary list vpc peerings
+// @Description list vpc peerings requested by or to the current organization
+// @tags Network
+// @Accept  json
+// @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
+// @Success 200 {object} VpcPeeringListResponse
+// @Failure 401 {object} common.APIError "Not authorized"
+// @Router /vpc_peerings [get]
+func (v *VpcPeeringAPI) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	offsetStr := c.DefaultQuery("offset", "0")
+	limitStr := c.DefaultQuery("limit", "50")
+	vpcID := c.DefaultQuery("vpc_id", "")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset: "+offsetStr, err)
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query limit: "+limitStr, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		errStr := "Invalid query offset or limit, cannot be negative"
+		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", errors.New(errStr))
+		return
+	}
+	var router *model.Router
+	if vpcID != "" {
+		router, err = routerAdmin.GetRouterByUUID(ctx, vpcID)
+		if err != nil {
+			logger.Errorf("Failed to get vpc %s, %+v", vpcID, err)
+			ErrorResponse(c, http.StatusBadRequest, "Invalid vpc query", err)
+			return
+		}
+	}
+	listQuery, err := routes.ParseListQuery(c, &model.VpcPeering{}, "-created_at")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
+	total, peerings, err := vpcPeeringAdmin.List(ctx, int64(offset), int64(limit), "-created_at", router)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Failed to list vpc peerings", err)
+		return
+	}
+	peeringListResp := &VpcPeeringListResponse{
+		Total:  int(total),
+		Offset: offset,
+		Limit:  len(peerings),
+	}
+	peeringListResp.VpcPeerings = make([]*VpcPeeringResponse, peeringListResp.Limit)
+	for i, peering := range peerings {
+		peeringListResp.VpcPeerings[i], err = v.getVpcPeeringResponse(ctx, peering)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Internal error", err)
+			return
+		}
+	}
+	listQuery.SetNextCursor(c, peerings, limit)
+	c.JSON(http.StatusOK, peeringListResp)
+}
@@ -74,6 +74,7 @@ func (v *ZoneAPI) Get(c *gin.Context) {
 		return
 	}
 	logger.Debugf("Get zone %s success, response: %+v", name, zoneResp)
+	SetETag(c, zone.UpdatedAt)
 	c.JSON(http.StatusOK, zoneResp)
 }
 
@@ -82,6 +83,9 @@ func (v *ZoneAPI) Get(c *gin.Context) {
 // @tags Zone
 // @Accept  json
 // @Produce json
+// @Param filter query string false "Filter by field, filter[status]=active, filter[status]=in:active,error or filter[created_at]=gte:2024-01-01;lt:2024-02-01"
+// @Param sort query string false "Sort by fields, - for descending, e.g. -created_at,name"
+// @Param cursor query string false "Cursor of the next page returned in the X-Next-Cursor header"
 // @Success 200 {object} ZoneListResponse
 // @Failure 401 {object} common.APIError "Not authorized"
 // @Router /zones [get]
@@ -108,6 +112,12 @@ func (v *ZoneAPI) List(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query offset or limit", err)
 		return
 	}
+	listQuery, err := routes.ParseListQuery(c, &model.Zone{}, "name")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid list query", err)
+		return
+	}
+	ctx = WithListQuery(ctx, listQuery)
 	total, zones, err := zoneAdmin.List(ctx, int64(offset), int64(limit), "name", queryStr)
 	if err != nil {
 		logger.Errorf("Failed to list zones %+v", err)
@@ -129,6 +139,7 @@ func (v *ZoneAPI) List(c *gin.Context) {
 		}
 	}
 	logger.Debugf("List zones success, response: %+v", zoneListResp)
+	listQuery.SetNextCursor(c, zones, limit)
 	c.JSON(http.StatusOK, zoneListResp)
 }
 
@@ -177,6 +188,7 @@ func (v *ZoneAPI) Create(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /zones/{name} [delete]
 func (v *ZoneAPI) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -188,6 +200,9 @@ func (v *ZoneAPI) Delete(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, zone, zone.UpdatedAt) {
+		return
+	}
 	err = zoneAdmin.Delete(ctx, zone)
 	if err != nil {
 		logger.Errorf("Failed to delete zone %s, %+v", name, err)
@@ -206,6 +221,7 @@ func (v *ZoneAPI) Delete(c *gin.Context) {
 // @Success 200 {object} ZoneResponse
 // @Failure 400 {object} common.APIError "Bad request"
 // @Failure 401 {object} common.APIError "Not authorized"
+// @Failure 412 {object} common.APIError "Resource was modified since If-Match"
 // @Router /zones/{name} [patch]
 func (v *ZoneAPI) Patch(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -223,6 +239,9 @@ func (v *ZoneAPI) Patch(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
+	if !ClaimIfMatch(c, zone, zone.UpdatedAt) {
+		return
+	}
 	logger.Debugf("Patch zone with payload %+v", payload)
 	err = zoneAdmin.Update(ctx, zone, payload.Default, payload.Remark)
 	if err != nil {
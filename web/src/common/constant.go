@@ -31,6 +31,7 @@ const (
 	PublicFloating     ElasticType = "floating"
 	PublicSite         ElasticType = "site"
 	PublicLoadBalancer ElasticType = "loadbalancer"
+	PublicNatGateway   ElasticType = "natgateway"
 
 	ResourceIpGroupType IpGroupType = "resource"
 	SystemIpGroupType   IpGroupType = "system"
@@ -32,6 +32,7 @@ const (
 	ErrInvalidCIDR           ErrCode = 100011
 	ErrCIDRTooBig            ErrCode = 100012
 	ErrOperationNotSupported ErrCode = 100013
+	ErrQuotaExceeded         ErrCode = 100014
 
 	// database related errors (1001xx)
 	ErrDatabaseError  ErrCode = 100100
@@ -56,6 +57,86 @@ const (
 	ErrMemberUpdateFailed   ErrCode = 100302
 	ErrMemberDeleteFailed   ErrCode = 100303
 
+	// Token related errors (1004xx)
+	ErrTokenInvalid      ErrCode = 100400
+	ErrTokenExpired      ErrCode = 100401
+	ErrTokenRevoked      ErrCode = 100402
+	ErrTokenCreateFailed ErrCode = 100403
+	ErrTokenRevokeFailed ErrCode = 100404
+
+	// Application credential related errors (1005xx)
+	ErrAppCredentialNotFound     ErrCode = 100500
+	ErrAppCredentialCreateFailed ErrCode = 100501
+	ErrAppCredentialDeleteFailed ErrCode = 100502
+	ErrAppCredentialInvalid      ErrCode = 100503
+	ErrAppCredentialExpired      ErrCode = 100504
+	ErrAppCredentialScopeDenied  ErrCode = 100505
+
+	// Identity federation related errors (1006xx)
+	ErrIdentityNotConfigured   ErrCode = 100600
+	ErrIdentityProviderFailed  ErrCode = 100601
+	ErrIdentitySourceMismatch  ErrCode = 100602
+	ErrIdentityProvisionFailed ErrCode = 100603
+
+	// Custom role related errors (1007xx)
+	ErrCustomRoleNotFound      ErrCode = 100700
+	ErrCustomRoleCreateFailed  ErrCode = 100701
+	ErrCustomRoleUpdateFailed  ErrCode = 100702
+	ErrCustomRoleDeleteFailed  ErrCode = 100703
+	ErrCustomRoleExists        ErrCode = 100704
+	ErrInvalidAction           ErrCode = 100705
+	ErrRoleBindingNotFound     ErrCode = 100706
+	ErrRoleBindingCreateFailed ErrCode = 100707
+	ErrRoleBindingDeleteFailed ErrCode = 100708
+
+	// Multi-factor authentication related errors (1008xx)
+	ErrMfaRequired           ErrCode = 100800
+	ErrMfaInvalidCode        ErrCode = 100801
+	ErrMfaNotEnrolled        ErrCode = 100802
+	ErrMfaAlreadyEnabled     ErrCode = 100803
+	ErrMfaEnrollmentRequired ErrCode = 100804
+	ErrMfaStepUpRequired     ErrCode = 100805
+	ErrMfaUpdateFailed       ErrCode = 100806
+
+	// Password policy related errors (1009xx)
+	ErrPasswordTooWeak ErrCode = 100900
+	ErrPasswordReused  ErrCode = 100901
+	ErrPasswordExpired ErrCode = 100902
+	ErrUserLocked      ErrCode = 100903
+	ErrLoginThrottled  ErrCode = 100904
+
+	// Rate limit related errors (1010xx)
+	ErrRateLimited ErrCode = 101000
+
+	// Concurrency related errors (1011xx)
+	ErrPreconditionFailed ErrCode = 101100
+
+	// Tag related errors (1012xx)
+	ErrInvalidTag  ErrCode = 101200
+	ErrTooManyTags ErrCode = 101201
+	ErrTagNotFound ErrCode = 101202
+	ErrNotTaggable ErrCode = 101203
+
+	// Launch template related errors (1013xx)
+	ErrLaunchTemplateNotFound        ErrCode = 101300
+	ErrLaunchTemplateVersionNotFound ErrCode = 101301
+	ErrInvalidLaunchTemplate         ErrCode = 101302
+
+	// Instance group related errors (1014xx)
+	ErrInstanceGroupNotFound ErrCode = 101400
+	ErrInvalidInstanceGroup  ErrCode = 101401
+	ErrScalingPolicyNotFound ErrCode = 101402
+	ErrInvalidScalingToken   ErrCode = 101403
+
+	// Host aggregate related errors (1015xx)
+	ErrAggregateNotFound     ErrCode = 101500
+	ErrInvalidAggregate      ErrCode = 101501
+	ErrInvalidAggregateRules ErrCode = 101502
+
+	// Guest agent related errors (1016xx)
+	ErrGuestAgentNotEnabled ErrCode = 101600
+	ErrGuestCommandNotFound ErrCode = 101601
+
 	// Instance related errors (111xxx)
 	ErrInstanceNotFound           ErrCode = 111001
 	ErrInstanceCreationFailed     ErrCode = 111002
@@ -71,6 +152,7 @@ const (
 	ErrConsoleNotFound            ErrCode = 111013
 	ErrInvalidConsoleToken        ErrCode = 111014
 	ErrInvalidMetadata            ErrCode = 111015
+	ErrConsoleLogNotReady         ErrCode = 111016
 
 	// Flavor related errors (1119xx)
 	ErrFlavorNotFound     ErrCode = 111901
@@ -193,6 +275,7 @@ const (
 	ErrRouterHasFloatingIPs        ErrCode = 131307
 	ErrRouterHasSubnets            ErrCode = 131308
 	ErrRouterHasPortmaps           ErrCode = 131309
+	ErrRouterHasNatGateways        ErrCode = 131310
 
 	// IP Group related errors (1314xx)
 	ErrIpGroupNotFound     ErrCode = 131401
@@ -222,6 +305,49 @@ const (
 	ErrBackendUpdateFailed      = 131518
 	ErrBackendDeleteFailed      = 131519
 
+	// Port forwarding related errors (1316xx)
+	ErrPortmapNotFound     ErrCode = 131601
+	ErrPortmapCreateFailed ErrCode = 131602
+	ErrPortmapUpdateFailed ErrCode = 131603
+	ErrPortmapDeleteFailed ErrCode = 131604
+	ErrPortmapPortConflict ErrCode = 131605
+
+	// Route table related errors (1317xx)
+	ErrRouteTableNotFound     ErrCode = 131701
+	ErrRouteTableCreateFailed ErrCode = 131702
+	ErrRouteTableUpdateFailed ErrCode = 131703
+	ErrRouteTableDeleteFailed ErrCode = 131704
+	ErrRouteTableInUse        ErrCode = 131705
+	ErrRouteOverlap           ErrCode = 131706
+	ErrInvalidRouteNexthop    ErrCode = 131707
+
+	// VPC peering related errors (1318xx)
+	ErrVpcPeeringNotFound      ErrCode = 131801
+	ErrVpcPeeringCreateFailed  ErrCode = 131802
+	ErrVpcPeeringUpdateFailed  ErrCode = 131803
+	ErrVpcPeeringDeleteFailed  ErrCode = 131804
+	ErrVpcPeeringCIDROverlap   ErrCode = 131805
+	ErrVpcPeeringInvalidState  ErrCode = 131806
+	ErrVpcPeeringAlreadyExists ErrCode = 131807
+
+	// NAT gateway related errors (1319xx)
+	ErrNatGatewayNotFound     ErrCode = 131901
+	ErrNatGatewayCreateFailed ErrCode = 131902
+	ErrNatGatewayUpdateFailed ErrCode = 131903
+	ErrNatGatewayDeleteFailed ErrCode = 131904
+	ErrNatGatewayExists       ErrCode = 131905
+
+	// DNS related errors (1320xx)
+	ErrDnsZoneNotFound       ErrCode = 132001
+	ErrDnsZoneCreateFailed   ErrCode = 132002
+	ErrDnsZoneDeleteFailed   ErrCode = 132003
+	ErrDnsZoneExists         ErrCode = 132004
+	ErrDnsRecordNotFound     ErrCode = 132005
+	ErrDnsRecordCreateFailed ErrCode = 132006
+	ErrDnsRecordUpdateFailed ErrCode = 132007
+	ErrDnsRecordDeleteFailed ErrCode = 132008
+	ErrDnsRecordInvalid      ErrCode = 132009
+
 	// Security related errors (141xxx)
 	ErrSecurityGroupNotFound       ErrCode = 141001
 	ErrSecurityGroupCreateFailed   ErrCode = 141002
@@ -21,6 +21,7 @@ func _() {
 	_ = x[ErrInvalidCIDR-100011]
 	_ = x[ErrCIDRTooBig-100012]
 	_ = x[ErrOperationNotSupported-100013]
+	_ = x[ErrQuotaExceeded-100014]
 	_ = x[ErrDatabaseError-100100]
 	_ = x[ErrSQLSyntaxError-100101]
 	_ = x[ErrUserNotFound-100200]
@@ -38,6 +39,60 @@ func _() {
 	_ = x[ErrMemberCreationFailed-100301]
 	_ = x[ErrMemberUpdateFailed-100302]
 	_ = x[ErrMemberDeleteFailed-100303]
+	_ = x[ErrTokenInvalid-100400]
+	_ = x[ErrTokenExpired-100401]
+	_ = x[ErrTokenRevoked-100402]
+	_ = x[ErrTokenCreateFailed-100403]
+	_ = x[ErrTokenRevokeFailed-100404]
+	_ = x[ErrAppCredentialNotFound-100500]
+	_ = x[ErrAppCredentialCreateFailed-100501]
+	_ = x[ErrAppCredentialDeleteFailed-100502]
+	_ = x[ErrAppCredentialInvalid-100503]
+	_ = x[ErrAppCredentialExpired-100504]
+	_ = x[ErrAppCredentialScopeDenied-100505]
+	_ = x[ErrIdentityNotConfigured-100600]
+	_ = x[ErrIdentityProviderFailed-100601]
+	_ = x[ErrIdentitySourceMismatch-100602]
+	_ = x[ErrIdentityProvisionFailed-100603]
+	_ = x[ErrCustomRoleNotFound-100700]
+	_ = x[ErrCustomRoleCreateFailed-100701]
+	_ = x[ErrCustomRoleUpdateFailed-100702]
+	_ = x[ErrCustomRoleDeleteFailed-100703]
+	_ = x[ErrCustomRoleExists-100704]
+	_ = x[ErrInvalidAction-100705]
+	_ = x[ErrRoleBindingNotFound-100706]
+	_ = x[ErrRoleBindingCreateFailed-100707]
+	_ = x[ErrRoleBindingDeleteFailed-100708]
+	_ = x[ErrMfaRequired-100800]
+	_ = x[ErrMfaInvalidCode-100801]
+	_ = x[ErrMfaNotEnrolled-100802]
+	_ = x[ErrMfaAlreadyEnabled-100803]
+	_ = x[ErrMfaEnrollmentRequired-100804]
+	_ = x[ErrMfaStepUpRequired-100805]
+	_ = x[ErrMfaUpdateFailed-100806]
+	_ = x[ErrPasswordTooWeak-100900]
+	_ = x[ErrPasswordReused-100901]
+	_ = x[ErrPasswordExpired-100902]
+	_ = x[ErrUserLocked-100903]
+	_ = x[ErrLoginThrottled-100904]
+	_ = x[ErrRateLimited-101000]
+	_ = x[ErrPreconditionFailed-101100]
+	_ = x[ErrInvalidTag-101200]
+	_ = x[ErrTooManyTags-101201]
+	_ = x[ErrTagNotFound-101202]
+	_ = x[ErrNotTaggable-101203]
+	_ = x[ErrLaunchTemplateNotFound-101300]
+	_ = x[ErrLaunchTemplateVersionNotFound-101301]
+	_ = x[ErrInvalidLaunchTemplate-101302]
+	_ = x[ErrInstanceGroupNotFound-101400]
+	_ = x[ErrInvalidInstanceGroup-101401]
+	_ = x[ErrScalingPolicyNotFound-101402]
+	_ = x[ErrInvalidScalingToken-101403]
+	_ = x[ErrAggregateNotFound-101500]
+	_ = x[ErrInvalidAggregate-101501]
+	_ = x[ErrInvalidAggregateRules-101502]
+	_ = x[ErrGuestAgentNotEnabled-101600]
+	_ = x[ErrGuestCommandNotFound-101601]
 	_ = x[ErrInstanceNotFound-111001]
 	_ = x[ErrInstanceCreationFailed-111002]
 	_ = x[ErrInstanceUpdateFailed-111003]
@@ -52,6 +107,7 @@ func _() {
 	_ = x[ErrConsoleNotFound-111013]
 	_ = x[ErrInvalidConsoleToken-111014]
 	_ = x[ErrInvalidMetadata-111015]
+	_ = x[ErrConsoleLogNotReady-111016]
 	_ = x[ErrFlavorNotFound-111901]
 	_ = x[ErrFlavorCreateFailed-111902]
 	_ = x[ErrFlavorUpdateFailed-111903]
@@ -153,11 +209,45 @@ func _() {
 	_ = x[ErrRouterHasFloatingIPs-131307]
 	_ = x[ErrRouterHasSubnets-131308]
 	_ = x[ErrRouterHasPortmaps-131309]
+	_ = x[ErrRouterHasNatGateways-131310]
 	_ = x[ErrIpGroupNotFound-131401]
 	_ = x[ErrIpGroupCreateFailed-131402]
 	_ = x[ErrIpGroupUpdateFailed-131403]
 	_ = x[ErrIpGroupDeleteFailed-131404]
 	_ = x[ErrIpGroupInUse-131405]
+	_ = x[ErrPortmapNotFound-131601]
+	_ = x[ErrPortmapCreateFailed-131602]
+	_ = x[ErrPortmapUpdateFailed-131603]
+	_ = x[ErrPortmapDeleteFailed-131604]
+	_ = x[ErrPortmapPortConflict-131605]
+	_ = x[ErrRouteTableNotFound-131701]
+	_ = x[ErrRouteTableCreateFailed-131702]
+	_ = x[ErrRouteTableUpdateFailed-131703]
+	_ = x[ErrRouteTableDeleteFailed-131704]
+	_ = x[ErrRouteTableInUse-131705]
+	_ = x[ErrRouteOverlap-131706]
+	_ = x[ErrInvalidRouteNexthop-131707]
+	_ = x[ErrVpcPeeringNotFound-131801]
+	_ = x[ErrVpcPeeringCreateFailed-131802]
+	_ = x[ErrVpcPeeringUpdateFailed-131803]
+	_ = x[ErrVpcPeeringDeleteFailed-131804]
+	_ = x[ErrVpcPeeringCIDROverlap-131805]
+	_ = x[ErrVpcPeeringInvalidState-131806]
+	_ = x[ErrVpcPeeringAlreadyExists-131807]
+	_ = x[ErrNatGatewayNotFound-131901]
+	_ = x[ErrNatGatewayCreateFailed-131902]
+	_ = x[ErrNatGatewayUpdateFailed-131903]
+	_ = x[ErrNatGatewayDeleteFailed-131904]
+	_ = x[ErrNatGatewayExists-131905]
+	_ = x[ErrDnsZoneNotFound-132001]
+	_ = x[ErrDnsZoneCreateFailed-132002]
+	_ = x[ErrDnsZoneDeleteFailed-132003]
+	_ = x[ErrDnsZoneExists-132004]
+	_ = x[ErrDnsRecordNotFound-132005]
+	_ = x[ErrDnsRecordCreateFailed-132006]
+	_ = x[ErrDnsRecordUpdateFailed-132007]
+	_ = x[ErrDnsRecordDeleteFailed-132008]
+	_ = x[ErrDnsRecordInvalid-132009]
 	_ = x[ErrSecurityGroupNotFound-141001]
 	_ = x[ErrSecurityGroupCreateFailed-141002]
 	_ = x[ErrSecurityGroupUpdateFailed-141003]
@@ -207,7 +297,7 @@ func _() {
 	_ = x[ErrDictionaryDeleteFailed-199804]
 }
 
-const _ErrCode_name = "UnknownInsufficientResourceResourceNotFoundInvalidParameterPermissionDeniedExecuteOnHyperFailedOwnerNotFoundEncryptionFailedJSONMarshalFailedResourcesInOrgInvalidCIDRCIDRTooBigOperationNotSupportedDatabaseErrorSQLSyntaxErrorUserNotFoundUserCreationFailedUserUpdateFailedUserDeleteFailedOrgNotFoundOrgCreationFailedOrgUpdateFailedOrgDeleteFailedNoRoleOnUserPasswordHashFailedPasswordMismatchMemberNotFoundMemberCreationFailedMemberUpdateFailedMemberDeleteFailedInstanceNotFoundInstanceCreationFailedInstanceUpdateFailedInstanceDeleteFailedInstanceInvalidStateInstanceInvalidConfigInstancePowerActionFailInstanceNoRouterInstanceNoPrimaryInterfaceInvalidDomainFormatConsoleCreateFailedConsoleNotFoundInvalidConsoleTokenInvalidMetadataMigrationNotFoundMigrationCreateFailedMigrationUpdateFailedMigrationDeleteFailedMigrationInProgressFlavorNotFoundFlavorCreateFailedFlavorUpdateFailedFlavorDeleteFailedFlavorInUseDiskTooSmallVolumeNotFoundVolumeCreationFailedVolumeUpdateFailedVolumeDeleteFailedVolumeAttachFailedVolumeDetachFailedVolumeInvalidStateVolumeInvalidSizeBootVolumeNotFoundBootVolumeUpdateFailedBootVolumeDeleteFailedVolumeIsInUseBootVolumeCannotDetachVolumeIsBusyVolumeIsRestoringVolumeInConsistencyGroupBackupNotFoundBackupCreationFailedBackupUpdateFailedBackupDeleteFailedBackupInUseCannotRestoreWhileInstanceIsRunningCannotRestoreFromBackupBackupInvalidStateCGNotFoundCGCreationFailedCGUpdateFailedCGDeleteFailedCGInvalidStateCGIsBusyCGSnapshotExistsCGVolumeNotInSamePoolCGVolumeIsBusyCGVolumeInvalidStateCGSnapshotNotFoundCGSnapshotCreationFailedCGSnapshotDeleteFailedCGSnapshotRestoreFailedCGSnapshotIsBusyCGCannotModifyWithSnapshotsCGInstanceNotShutoffCGNoVolumesCGVolumeAttachedNoInstanceCGSnapshotCannotRestoreCGSnapshotRestoreInProgressAddressNotFoundAddressUpdateFailedAddressDeleteFailedInsufficientAddressAddressCreateFailedAddressInUseSubnetNotFoundSubnetCreateFailedSubnetUpdateFailedSubnetDeleteFailedSubnetShouldBePublicSubnetShouldBeSitePublicSubnetNotFoundSiteSubnetUpdateFailedSubnetsCrossVPCInOneInstancePublicSubnetCannotInVPCInterfaceNotFoundInterfaceCreateFailedInterfaceUpdateFailedNotAllowInterfaceInSiteSubnetInterfaceDeleteFailedCannotDeletePrimaryInterfaceTooManyInterfacesInterfaceInvalidSubnetFIPInUseDummyFIPCreateFailedUpdateGroupIDFailedFIPListFailedRouterNotFoundRouterCreateFailedRouterUpdateFailedRouterUpdateDefaultSGFailedRouterDeleteFailedRouterInUseRouterHasFloatingIPsRouterHasSubnetsRouterHasPortmapsIpGroupNotFoundIpGroupCreateFailedIpGroupUpdateFailedIpGroupDeleteFailedIpGroupInUseSecurityGroupNotFoundSecurityGroupCreateFailedSecurityGroupUpdateFailedSecurityGroupDeleteFailedAssociateSG2InterfaceFailedAtLeastOneSGRequiredCannotDeleteDefaultSGSGHasInterfacesSecurityRuleNotFoundSecurityRuleInvalidSecurityRuleDeleteFailedSecurityRuleCreateFailedSecurityRuleUpdateFailedImageNotFoundImageInUseImageNoQAImageCreateFailedImageUpdateFailedImageDeleteFailedImageNotAvailableImageStorageCreateFailedImageStorageDeleteFailedImageStorageUpdateFailedImageStorageNotFoundRescueImageNotFoundSSHKeyNotFoundSSHKeyCreateFailedSSHKeyUpdateFailedSSHKeyDeleteFailedSSHKeyGenerateFailedSSHKeyInUseNoQualifiedHypervisorHypervisorNotFoundHypervisorUpdateFailedHypervisorDeleteFailedHypervisorInvalidStateZoneNotFoundUnsetDefaultZoneFailedZoneCreationFailedZoneUpdateFailedZoneDeleteFailedHypersInZoneTaskNotFoundDictionaryRecordsNotFoundDictionaryCreateFailedDictionaryUpdateFailedDictionaryDeleteFailed"
+const _ErrCode_name = "UnknownInsufficientResourceResourceNotFoundInvalidParameterPermissionDeniedExecuteOnHyperFailedOwnerNotFoundEncryptionFailedJSONMarshalFailedResourcesInOrgInvalidCIDRCIDRTooBigOperationNotSupportedQuotaExceededDatabaseErrorSQLSyntaxErrorUserNotFoundUserCreationFailedUserUpdateFailedUserDeleteFailedOrgNotFoundOrgCreationFailedOrgUpdateFailedOrgDeleteFailedNoRoleOnUserPasswordHashFailedPasswordMismatchMemberNotFoundMemberCreationFailedMemberUpdateFailedMemberDeleteFailedTokenInvalidTokenExpiredTokenRevokedTokenCreateFailedTokenRevokeFailedAppCredentialNotFoundAppCredentialCreateFailedAppCredentialDeleteFailedAppCredentialInvalidAppCredentialExpiredAppCredentialScopeDeniedIdentityNotConfiguredIdentityProviderFailedIdentitySourceMismatchIdentityProvisionFailedCustomRoleNotFoundCustomRoleCreateFailedCustomRoleUpdateFailedCustomRoleDeleteFailedCustomRoleExistsInvalidActionRoleBindingNotFoundRoleBindingCreateFailedRoleBindingDeleteFailedMfaRequiredMfaInvalidCodeMfaNotEnrolledMfaAlreadyEnabledMfaEnrollmentRequiredMfaStepUpRequiredMfaUpdateFailedPasswordTooWeakPasswordReusedPasswordExpiredUserLockedLoginThrottledRateLimitedPreconditionFailedInvalidTagTooManyTagsTagNotFoundNotTaggableLaunchTemplateNotFoundLaunchTemplateVersionNotFoundInvalidLaunchTemplateInstanceGroupNotFoundInvalidInstanceGroupScalingPolicyNotFoundInvalidScalingTokenAggregateNotFoundInvalidAggregateInvalidAggregateRulesGuestAgentNotEnabledGuestCommandNotFoundInstanceNotFoundInstanceCreationFailedInstanceUpdateFailedInstanceDeleteFailedInstanceInvalidStateInstanceInvalidConfigInstancePowerActionFailInstanceNoRouterInstanceNoPrimaryInterfaceInvalidDomainFormatConsoleCreateFailedConsoleNotFoundInvalidConsoleTokenInvalidMetadataConsoleLogNotReadyMigrationNotFoundMigrationCreateFailedMigrationUpdateFailedMigrationDeleteFailedMigrationInProgressFlavorNotFoundFlavorCreateFailedFlavorUpdateFailedFlavorDeleteFailedFlavorInUseDiskTooSmallVolumeNotFoundVolumeCreationFailedVolumeUpdateFailedVolumeDeleteFailedVolumeAttachFailedVolumeDetachFailedVolumeInvalidStateVolumeInvalidSizeBootVolumeNotFoundBootVolumeUpdateFailedBootVolumeDeleteFailedVolumeIsInUseBootVolumeCannotDetachVolumeIsBusyVolumeIsRestoringVolumeInConsistencyGroupBackupNotFoundBackupCreationFailedBackupUpdateFailedBackupDeleteFailedBackupInUseCannotRestoreWhileInstanceIsRunningCannotRestoreFromBackupBackupInvalidStateCGNotFoundCGCreationFailedCGUpdateFailedCGDeleteFailedCGInvalidStateCGIsBusyCGSnapshotExistsCGVolumeNotInSamePoolCGVolumeIsBusyCGVolumeInvalidStateCGSnapshotNotFoundCGSnapshotCreationFailedCGSnapshotDeleteFailedCGSnapshotRestoreFailedCGSnapshotIsBusyCGCannotModifyWithSnapshotsCGInstanceNotShutoffCGNoVolumesCGVolumeAttachedNoInstanceCGSnapshotCannotRestoreCGSnapshotRestoreInProgressAddressNotFoundAddressUpdateFailedAddressDeleteFailedInsufficientAddressAddressCreateFailedAddressInUseSubnetNotFoundSubnetCreateFailedSubnetUpdateFailedSubnetDeleteFailedSubnetShouldBePublicSubnetShouldBeSitePublicSubnetNotFoundSiteSubnetUpdateFailedSubnetsCrossVPCInOneInstancePublicSubnetCannotInVPCInterfaceNotFoundInterfaceCreateFailedInterfaceUpdateFailedNotAllowInterfaceInSiteSubnetInterfaceDeleteFailedCannotDeletePrimaryInterfaceTooManyInterfacesInterfaceInvalidSubnetFIPInUseDummyFIPCreateFailedUpdateGroupIDFailedFIPListFailedRouterNotFoundRouterCreateFailedRouterUpdateFailedRouterUpdateDefaultSGFailedRouterDeleteFailedRouterInUseRouterHasFloatingIPsRouterHasSubnetsRouterHasPortmapsRouterHasNatGatewaysIpGroupNotFoundIpGroupCreateFailedIpGroupUpdateFailedIpGroupDeleteFailedIpGroupInUsePortmapNotFoundPortmapCreateFailedPortmapUpdateFailedPortmapDeleteFailedPortmapPortConflictRouteTableNotFoundRouteTableCreateFailedRouteTableUpdateFailedRouteTableDeleteFailedRouteTableInUseRouteOverlapInvalidRouteNexthopVpcPeeringNotFoundVpcPeeringCreateFailedVpcPeeringUpdateFailedVpcPeeringDeleteFailedVpcPeeringCIDROverlapVpcPeeringInvalidStateVpcPeeringAlreadyExistsNatGatewayNotFoundNatGatewayCreateFailedNatGatewayUpdateFailedNatGatewayDeleteFailedNatGatewayExistsDnsZoneNotFoundDnsZoneCreateFailedDnsZoneDeleteFailedDnsZoneExistsDnsRecordNotFoundDnsRecordCreateFailedDnsRecordUpdateFailedDnsRecordDeleteFailedDnsRecordInvalidSecurityGroupNotFoundSecurityGroupCreateFailedSecurityGroupUpdateFailedSecurityGroupDeleteFailedAssociateSG2InterfaceFailedAtLeastOneSGRequiredCannotDeleteDefaultSGSGHasInterfacesSecurityRuleNotFoundSecurityRuleInvalidSecurityRuleDeleteFailedSecurityRuleCreateFailedSecurityRuleUpdateFailedImageNotFoundImageInUseImageNoQAImageCreateFailedImageUpdateFailedImageDeleteFailedImageNotAvailableImageStorageCreateFailedImageStorageDeleteFailedImageStorageUpdateFailedImageStorageNotFoundRescueImageNotFoundSSHKeyNotFoundSSHKeyCreateFailedSSHKeyUpdateFailedSSHKeyDeleteFailedSSHKeyGenerateFailedSSHKeyInUseNoQualifiedHypervisorHypervisorNotFoundHypervisorUpdateFailedHypervisorDeleteFailedHypervisorInvalidStateZoneNotFoundUnsetDefaultZoneFailedZoneCreationFailedZoneUpdateFailedZoneDeleteFailedHypersInZoneTaskNotFoundDictionaryRecordsNotFoundDictionaryCreateFailedDictionaryUpdateFailedDictionaryDeleteFailed"
 
 var _ErrCode_map = map[ErrCode]string{
 	100000: _ErrCode_name[0:7],
@@ -223,182 +313,272 @@ var _ErrCode_map = map[ErrCode]string{
 	100011: _ErrCode_name[155:166],
 	100012: _ErrCode_name[166:176],
 	100013: _ErrCode_name[176:197],
-	100100: _ErrCode_name[197:210],
-	100101: _ErrCode_name[210:224],
-	100200: _ErrCode_name[224:236],
-	100201: _ErrCode_name[236:254],
-	100202: _ErrCode_name[254:270],
-	100203: _ErrCode_name[270:286],
-	100204: _ErrCode_name[286:297],
-	100205: _ErrCode_name[297:314],
-	100206: _ErrCode_name[314:329],
-	100207: _ErrCode_name[329:344],
-	100208: _ErrCode_name[344:356],
-	100209: _ErrCode_name[356:374],
-	100210: _ErrCode_name[374:390],
-	100300: _ErrCode_name[390:404],
-	100301: _ErrCode_name[404:424],
-	100302: _ErrCode_name[424:442],
-	100303: _ErrCode_name[442:460],
-	111001: _ErrCode_name[460:476],
-	111002: _ErrCode_name[476:498],
-	111003: _ErrCode_name[498:518],
-	111004: _ErrCode_name[518:538],
-	111005: _ErrCode_name[538:558],
-	111007: _ErrCode_name[558:579],
-	111008: _ErrCode_name[579:602],
-	111009: _ErrCode_name[602:618],
-	111010: _ErrCode_name[618:644],
-	111011: _ErrCode_name[644:663],
-	111012: _ErrCode_name[663:682],
-	111013: _ErrCode_name[682:697],
-	111014: _ErrCode_name[697:716],
-	111015: _ErrCode_name[716:731],
-	111801: _ErrCode_name[731:748],
-	111802: _ErrCode_name[748:769],
-	111803: _ErrCode_name[769:790],
-	111804: _ErrCode_name[790:811],
-	111805: _ErrCode_name[811:830],
-	111901: _ErrCode_name[830:844],
-	111902: _ErrCode_name[844:862],
-	111903: _ErrCode_name[862:880],
-	111904: _ErrCode_name[880:898],
-	111905: _ErrCode_name[898:909],
-	111906: _ErrCode_name[909:921],
-	121001: _ErrCode_name[921:935],
-	121002: _ErrCode_name[935:955],
-	121003: _ErrCode_name[955:973],
-	121004: _ErrCode_name[973:991],
-	121005: _ErrCode_name[991:1009],
-	121006: _ErrCode_name[1009:1027],
-	121007: _ErrCode_name[1027:1045],
-	121008: _ErrCode_name[1045:1062],
-	121009: _ErrCode_name[1062:1080],
-	121010: _ErrCode_name[1080:1102],
-	121011: _ErrCode_name[1102:1124],
-	121012: _ErrCode_name[1124:1137],
-	121013: _ErrCode_name[1137:1159],
-	121014: _ErrCode_name[1159:1171],
-	121015: _ErrCode_name[1171:1188],
-	121016: _ErrCode_name[1188:1212],
-	125100: _ErrCode_name[1212:1226],
-	125101: _ErrCode_name[1226:1246],
-	125102: _ErrCode_name[1246:1264],
-	125103: _ErrCode_name[1264:1282],
-	125104: _ErrCode_name[1282:1293],
-	125105: _ErrCode_name[1293:1328],
-	125106: _ErrCode_name[1328:1351],
-	125107: _ErrCode_name[1351:1369],
-	125200: _ErrCode_name[1369:1379],
-	125201: _ErrCode_name[1379:1395],
-	125202: _ErrCode_name[1395:1409],
-	125203: _ErrCode_name[1409:1423],
-	125204: _ErrCode_name[1423:1437],
-	125205: _ErrCode_name[1437:1445],
-	125206: _ErrCode_name[1445:1461],
-	125207: _ErrCode_name[1461:1482],
-	125208: _ErrCode_name[1482:1496],
-	125209: _ErrCode_name[1496:1516],
-	125210: _ErrCode_name[1516:1534],
-	125211: _ErrCode_name[1534:1558],
-	125212: _ErrCode_name[1558:1580],
-	125213: _ErrCode_name[1580:1603],
-	125214: _ErrCode_name[1603:1619],
-	125215: _ErrCode_name[1619:1646],
-	125216: _ErrCode_name[1646:1666],
-	125217: _ErrCode_name[1666:1677],
-	125218: _ErrCode_name[1677:1703],
-	125219: _ErrCode_name[1703:1726],
-	125220: _ErrCode_name[1726:1753],
-	131001: _ErrCode_name[1753:1768],
-	131002: _ErrCode_name[1768:1787],
-	131003: _ErrCode_name[1787:1806],
-	131004: _ErrCode_name[1806:1825],
-	131005: _ErrCode_name[1825:1844],
-	131006: _ErrCode_name[1844:1856],
-	131101: _ErrCode_name[1856:1870],
-	131102: _ErrCode_name[1870:1888],
-	131103: _ErrCode_name[1888:1906],
-	131104: _ErrCode_name[1906:1924],
-	131105: _ErrCode_name[1924:1944],
-	131106: _ErrCode_name[1944:1962],
-	131107: _ErrCode_name[1962:1982],
-	131108: _ErrCode_name[1982:2004],
-	131109: _ErrCode_name[2004:2032],
-	131110: _ErrCode_name[2032:2055],
-	131201: _ErrCode_name[2055:2072],
-	131202: _ErrCode_name[2072:2093],
-	131203: _ErrCode_name[2093:2114],
-	131204: _ErrCode_name[2114:2143],
-	131205: _ErrCode_name[2143:2164],
-	131206: _ErrCode_name[2164:2192],
-	131207: _ErrCode_name[2192:2209],
-	131208: _ErrCode_name[2209:2231],
-	131209: _ErrCode_name[2231:2239],
-	131210: _ErrCode_name[2239:2259],
-	131211: _ErrCode_name[2259:2278],
-	131212: _ErrCode_name[2278:2291],
-	131301: _ErrCode_name[2291:2305],
-	131302: _ErrCode_name[2305:2323],
-	131303: _ErrCode_name[2323:2341],
-	131304: _ErrCode_name[2341:2368],
-	131305: _ErrCode_name[2368:2386],
-	131306: _ErrCode_name[2386:2397],
-	131307: _ErrCode_name[2397:2417],
-	131308: _ErrCode_name[2417:2433],
-	131309: _ErrCode_name[2433:2450],
-	131401: _ErrCode_name[2450:2465],
-	131402: _ErrCode_name[2465:2484],
-	131403: _ErrCode_name[2484:2503],
-	131404: _ErrCode_name[2503:2522],
-	131405: _ErrCode_name[2522:2534],
-	141001: _ErrCode_name[2534:2555],
-	141002: _ErrCode_name[2555:2580],
-	141003: _ErrCode_name[2580:2605],
-	141004: _ErrCode_name[2605:2630],
-	141005: _ErrCode_name[2630:2657],
-	141006: _ErrCode_name[2657:2677],
-	141007: _ErrCode_name[2677:2698],
-	141008: _ErrCode_name[2698:2713],
-	141009: _ErrCode_name[2713:2733],
-	141010: _ErrCode_name[2733:2752],
-	141011: _ErrCode_name[2752:2776],
-	141012: _ErrCode_name[2776:2800],
-	141013: _ErrCode_name[2800:2824],
-	151000: _ErrCode_name[2824:2837],
-	151001: _ErrCode_name[2837:2847],
-	151002: _ErrCode_name[2847:2856],
-	151003: _ErrCode_name[2856:2873],
-	151004: _ErrCode_name[2873:2890],
-	151005: _ErrCode_name[2890:2907],
-	151006: _ErrCode_name[2907:2924],
-	151007: _ErrCode_name[2924:2948],
-	151008: _ErrCode_name[2948:2972],
-	151009: _ErrCode_name[2972:2996],
-	151010: _ErrCode_name[2996:3016],
-	151011: _ErrCode_name[3016:3035],
-	161001: _ErrCode_name[3035:3049],
-	161002: _ErrCode_name[3049:3067],
-	161003: _ErrCode_name[3067:3085],
-	161004: _ErrCode_name[3085:3103],
-	161005: _ErrCode_name[3103:3123],
-	161006: _ErrCode_name[3123:3134],
-	171001: _ErrCode_name[3134:3155],
-	171002: _ErrCode_name[3155:3173],
-	171003: _ErrCode_name[3173:3195],
-	171004: _ErrCode_name[3195:3217],
-	171005: _ErrCode_name[3217:3239],
-	171006: _ErrCode_name[3239:3251],
-	171007: _ErrCode_name[3251:3273],
-	171008: _ErrCode_name[3273:3291],
-	171009: _ErrCode_name[3291:3307],
-	171010: _ErrCode_name[3307:3323],
-	171011: _ErrCode_name[3323:3335],
-	181001: _ErrCode_name[3335:3347],
-	199801: _ErrCode_name[3347:3372],
-	199802: _ErrCode_name[3372:3394],
-	199803: _ErrCode_name[3394:3416],
-	199804: _ErrCode_name[3416:3438],
+	100014: _ErrCode_name[197:210],
+	100100: _ErrCode_name[210:223],
+	100101: _ErrCode_name[223:237],
+	100200: _ErrCode_name[237:249],
+	100201: _ErrCode_name[249:267],
+	100202: _ErrCode_name[267:283],
+	100203: _ErrCode_name[283:299],
+	100204: _ErrCode_name[299:310],
+	100205: _ErrCode_name[310:327],
+	100206: _ErrCode_name[327:342],
+	100207: _ErrCode_name[342:357],
+	100208: _ErrCode_name[357:369],
+	100209: _ErrCode_name[369:387],
+	100210: _ErrCode_name[387:403],
+	100300: _ErrCode_name[403:417],
+	100301: _ErrCode_name[417:437],
+	100302: _ErrCode_name[437:455],
+	100303: _ErrCode_name[455:473],
+	100400: _ErrCode_name[473:485],
+	100401: _ErrCode_name[485:497],
+	100402: _ErrCode_name[497:509],
+	100403: _ErrCode_name[509:526],
+	100404: _ErrCode_name[526:543],
+	100500: _ErrCode_name[543:564],
+	100501: _ErrCode_name[564:589],
+	100502: _ErrCode_name[589:614],
+	100503: _ErrCode_name[614:634],
+	100504: _ErrCode_name[634:654],
+	100505: _ErrCode_name[654:678],
+	100600: _ErrCode_name[678:699],
+	100601: _ErrCode_name[699:721],
+	100602: _ErrCode_name[721:743],
+	100603: _ErrCode_name[743:766],
+	100700: _ErrCode_name[766:784],
+	100701: _ErrCode_name[784:806],
+	100702: _ErrCode_name[806:828],
+	100703: _ErrCode_name[828:850],
+	100704: _ErrCode_name[850:866],
+	100705: _ErrCode_name[866:879],
+	100706: _ErrCode_name[879:898],
+	100707: _ErrCode_name[898:921],
+	100708: _ErrCode_name[921:944],
+	100800: _ErrCode_name[944:955],
+	100801: _ErrCode_name[955:969],
+	100802: _ErrCode_name[969:983],
+	100803: _ErrCode_name[983:1000],
+	100804: _ErrCode_name[1000:1021],
+	100805: _ErrCode_name[1021:1038],
+	100806: _ErrCode_name[1038:1053],
+	100900: _ErrCode_name[1053:1068],
+	100901: _ErrCode_name[1068:1082],
+	100902: _ErrCode_name[1082:1097],
+	100903: _ErrCode_name[1097:1107],
+	100904: _ErrCode_name[1107:1121],
+	101000: _ErrCode_name[1121:1132],
+	101100: _ErrCode_name[1132:1150],
+	101200: _ErrCode_name[1150:1160],
+	101201: _ErrCode_name[1160:1171],
+	101202: _ErrCode_name[1171:1182],
+	101203: _ErrCode_name[1182:1193],
+	101300: _ErrCode_name[1193:1215],
+	101301: _ErrCode_name[1215:1244],
+	101302: _ErrCode_name[1244:1265],
+	101400: _ErrCode_name[1265:1286],
+	101401: _ErrCode_name[1286:1306],
+	101402: _ErrCode_name[1306:1327],
+	101403: _ErrCode_name[1327:1346],
+	101500: _ErrCode_name[1346:1363],
+	101501: _ErrCode_name[1363:1379],
+	101502: _ErrCode_name[1379:1400],
+	101600: _ErrCode_name[1400:1420],
+	101601: _ErrCode_name[1420:1440],
+	111001: _ErrCode_name[1440:1456],
+	111002: _ErrCode_name[1456:1478],
+	111003: _ErrCode_name[1478:1498],
+	111004: _ErrCode_name[1498:1518],
+	111005: _ErrCode_name[1518:1538],
+	111007: _ErrCode_name[1538:1559],
+	111008: _ErrCode_name[1559:1582],
+	111009: _ErrCode_name[1582:1598],
+	111010: _ErrCode_name[1598:1624],
+	111011: _ErrCode_name[1624:1643],
+	111012: _ErrCode_name[1643:1662],
+	111013: _ErrCode_name[1662:1677],
+	111014: _ErrCode_name[1677:1696],
+	111015: _ErrCode_name[1696:1711],
+	111016: _ErrCode_name[1711:1729],
+	111801: _ErrCode_name[1729:1746],
+	111802: _ErrCode_name[1746:1767],
+	111803: _ErrCode_name[1767:1788],
+	111804: _ErrCode_name[1788:1809],
+	111805: _ErrCode_name[1809:1828],
+	111901: _ErrCode_name[1828:1842],
+	111902: _ErrCode_name[1842:1860],
+	111903: _ErrCode_name[1860:1878],
+	111904: _ErrCode_name[1878:1896],
+	111905: _ErrCode_name[1896:1907],
+	111906: _ErrCode_name[1907:1919],
+	121001: _ErrCode_name[1919:1933],
+	121002: _ErrCode_name[1933:1953],
+	121003: _ErrCode_name[1953:1971],
+	121004: _ErrCode_name[1971:1989],
+	121005: _ErrCode_name[1989:2007],
+	121006: _ErrCode_name[2007:2025],
+	121007: _ErrCode_name[2025:2043],
+	121008: _ErrCode_name[2043:2060],
+	121009: _ErrCode_name[2060:2078],
+	121010: _ErrCode_name[2078:2100],
+	121011: _ErrCode_name[2100:2122],
+	121012: _ErrCode_name[2122:2135],
+	121013: _ErrCode_name[2135:2157],
+	121014: _ErrCode_name[2157:2169],
+	121015: _ErrCode_name[2169:2186],
+	121016: _ErrCode_name[2186:2210],
+	125100: _ErrCode_name[2210:2224],
+	125101: _ErrCode_name[2224:2244],
+	125102: _ErrCode_name[2244:2262],
+	125103: _ErrCode_name[2262:2280],
+	125104: _ErrCode_name[2280:2291],
+	125105: _ErrCode_name[2291:2326],
+	125106: _ErrCode_name[2326:2349],
+	125107: _ErrCode_name[2349:2367],
+	125200: _ErrCode_name[2367:2377],
+	125201: _ErrCode_name[2377:2393],
+	125202: _ErrCode_name[2393:2407],
+	125203: _ErrCode_name[2407:2421],
+	125204: _ErrCode_name[2421:2435],
+	125205: _ErrCode_name[2435:2443],
+	125206: _ErrCode_name[2443:2459],
+	125207: _ErrCode_name[2459:2480],
+	125208: _ErrCode_name[2480:2494],
+	125209: _ErrCode_name[2494:2514],
+	125210: _ErrCode_name[2514:2532],
+	125211: _ErrCode_name[2532:2556],
+	125212: _ErrCode_name[2556:2578],
+	125213: _ErrCode_name[2578:2601],
+	125214: _ErrCode_name[2601:2617],
+	125215: _ErrCode_name[2617:2644],
+	125216: _ErrCode_name[2644:2664],
+	125217: _ErrCode_name[2664:2675],
+	125218: _ErrCode_name[2675:2701],
+	125219: _ErrCode_name[2701:2724],
+	125220: _ErrCode_name[2724:2751],
+	131001: _ErrCode_name[2751:2766],
+	131002: _ErrCode_name[2766:2785],
+	131003: _ErrCode_name[2785:2804],
+	131004: _ErrCode_name[2804:2823],
+	131005: _ErrCode_name[2823:2842],
+	131006: _ErrCode_name[2842:2854],
+	131101: _ErrCode_name[2854:2868],
+	131102: _ErrCode_name[2868:2886],
+	131103: _ErrCode_name[2886:2904],
+	131104: _ErrCode_name[2904:2922],
+	131105: _ErrCode_name[2922:2942],
+	131106: _ErrCode_name[2942:2960],
+	131107: _ErrCode_name[2960:2980],
+	131108: _ErrCode_name[2980:3002],
+	131109: _ErrCode_name[3002:3030],
+	131110: _ErrCode_name[3030:3053],
+	131201: _ErrCode_name[3053:3070],
+	131202: _ErrCode_name[3070:3091],
+	131203: _ErrCode_name[3091:3112],
+	131204: _ErrCode_name[3112:3141],
+	131205: _ErrCode_name[3141:3162],
+	131206: _ErrCode_name[3162:3190],
+	131207: _ErrCode_name[3190:3207],
+	131208: _ErrCode_name[3207:3229],
+	131209: _ErrCode_name[3229:3237],
+	131210: _ErrCode_name[3237:3257],
+	131211: _ErrCode_name[3257:3276],
+	131212: _ErrCode_name[3276:3289],
+	131301: _ErrCode_name[3289:3303],
+	131302: _ErrCode_name[3303:3321],
+	131303: _ErrCode_name[3321:3339],
+	131304: _ErrCode_name[3339:3366],
+	131305: _ErrCode_name[3366:3384],
+	131306: _ErrCode_name[3384:3395],
+	131307: _ErrCode_name[3395:3415],
+	131308: _ErrCode_name[3415:3431],
+	131309: _ErrCode_name[3431:3448],
+	131310: _ErrCode_name[3448:3468],
+	131401: _ErrCode_name[3468:3483],
+	131402: _ErrCode_name[3483:3502],
+	131403: _ErrCode_name[3502:3521],
+	131404: _ErrCode_name[3521:3540],
+	131405: _ErrCode_name[3540:3552],
+	131601: _ErrCode_name[3552:3567],
+	131602: _ErrCode_name[3567:3586],
+	131603: _ErrCode_name[3586:3605],
+	131604: _ErrCode_name[3605:3624],
+	131605: _ErrCode_name[3624:3643],
+	131701: _ErrCode_name[3643:3661],
+	131702: _ErrCode_name[3661:3683],
+	131703: _ErrCode_name[3683:3705],
+	131704: _ErrCode_name[3705:3727],
+	131705: _ErrCode_name[3727:3742],
+	131706: _ErrCode_name[3742:3754],
+	131707: _ErrCode_name[3754:3773],
+	131801: _ErrCode_name[3773:3791],
+	131802: _ErrCode_name[3791:3813],
+	131803: _ErrCode_name[3813:3835],
+	131804: _ErrCode_name[3835:3857],
+	131805: _ErrCode_name[3857:3878],
+	131806: _ErrCode_name[3878:3900],
+	131807: _ErrCode_name[3900:3923],
+	131901: _ErrCode_name[3923:3941],
+	131902: _ErrCode_name[3941:3963],
+	131903: _ErrCode_name[3963:3985],
+	131904: _ErrCode_name[3985:4007],
+	131905: _ErrCode_name[4007:4023],
+	132001: _ErrCode_name[4023:4038],
+	132002: _ErrCode_name[4038:4057],
+	132003: _ErrCode_name[4057:4076],
+	132004: _ErrCode_name[4076:4089],
+	132005: _ErrCode_name[4089:4106],
+	132006: _ErrCode_name[4106:4127],
+	132007: _ErrCode_name[4127:4148],
+	132008: _ErrCode_name[4148:4169],
+	132009: _ErrCode_name[4169:4185],
+	141001: _ErrCode_name[4185:4206],
+	141002: _ErrCode_name[4206:4231],
+	141003: _ErrCode_name[4231:4256],
+	141004: _ErrCode_name[4256:4281],
+	141005: _ErrCode_name[4281:4308],
+	141006: _ErrCode_name[4308:4328],
+	141007: _ErrCode_name[4328:4349],
+	141008: _ErrCode_name[4349:4364],
+	141009: _ErrCode_name[4364:4384],
+	141010: _ErrCode_name[4384:4403],
+	141011: _ErrCode_name[4403:4427],
+	141012: _ErrCode_name[4427:4451],
+	141013: _ErrCode_name[4451:4475],
+	151000: _ErrCode_name[4475:4488],
+	151001: _ErrCode_name[4488:4498],
+	151002: _ErrCode_name[4498:4507],
+	151003: _ErrCode_name[4507:4524],
+	151004: _ErrCode_name[4524:4541],
+	151005: _ErrCode_name[4541:4558],
+	151006: _ErrCode_name[4558:4575],
+	151007: _ErrCode_name[4575:4599],
+	151008: _ErrCode_name[4599:4623],
+	151009: _ErrCode_name[4623:4647],
+	151010: _ErrCode_name[4647:4667],
+	151011: _ErrCode_name[4667:4686],
+	161001: _ErrCode_name[4686:4700],
+	161002: _ErrCode_name[4700:4718],
+	161003: _ErrCode_name[4718:4736],
+	161004: _ErrCode_name[4736:4754],
+	161005: _ErrCode_name[4754:4774],
+	161006: _ErrCode_name[4774:4785],
+	171001: _ErrCode_name[4785:4806],
+	171002: _ErrCode_name[4806:4824],
+	171003: _ErrCode_name[4824:4846],
+	171004: _ErrCode_name[4846:4868],
+	171005: _ErrCode_name[4868:4890],
+	171006: _ErrCode_name[4890:4902],
+	171007: _ErrCode_name[4902:4924],
+	171008: _ErrCode_name[4924:4942],
+	171009: _ErrCode_name[4942:4958],
+	171010: _ErrCode_name[4958:4974],
+	171011: _ErrCode_name[4974:4986],
+	181001: _ErrCode_name[4986:4998],
+	199801: _ErrCode_name[4998:5023],
+	199802: _ErrCode_name[5023:5045],
+	199803: _ErrCode_name[5045:5067],
+	199804: _ErrCode_name[5067:5089],
 }
 
 func (i ErrCode) String() string {
@@ -10,6 +10,9 @@ package common
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -59,6 +62,62 @@ func ErrorResponse(c *gin.Context, code int, errorMsg string, err error) {
 	})
 }
 
+// ETag identifies the revision of a resource by its last update, in microseconds as stored by the database
+func ETag(updatedAt time.Time) string {
+	return fmt.Sprintf("\"%x\"", updatedAt.UnixMicro())
+}
+
+// SetETag adds the revision of the resource to the response so that clients can send it back with If-Match
+func SetETag(c *gin.Context, updatedAt time.Time) {
+	c.Header("ETag", ETag(updatedAt))
+}
+
+// IfMatch checks the If-Match header of a mutating request against the current revision of the resource,
+// it responds 412 and returns false if the resource changed since the client read it, requests without the header pass
+func IfMatch(c *gin.Context, updatedAt time.Time) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	current := ETag(updatedAt)
+	for _, etag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(etag), "W/") == current {
+			return true
+		}
+	}
+	c.Header("ETag", current)
+	ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed", NewCLError(ErrPreconditionFailed, "Resource was modified, get it again and retry", nil))
+	return false
+}
+
+// ClaimIfMatch checks the If-Match header like IfMatch and then claims the revision for the request by moving
+// updated_at of the resource on only if it is still the one checked, of concurrent requests with the same ETag
+// the first one wins and the others get 412 as if they had come after it
+func ClaimIfMatch(c *gin.Context, resource interface{}, updatedAt time.Time) bool {
+	if !IfMatch(c, updatedAt) {
+		return false
+	}
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	db := DB()
+	if db.NewScope(resource).PrimaryKeyZero() {
+		ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed", NewCLError(ErrPreconditionFailed, "Resource was modified, get it again and retry", nil))
+		return false
+	}
+	result := db.Model(resource).Where("updated_at = ?", updatedAt).UpdateColumn("updated_at", time.Now())
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to check the revision", NewCLError(ErrDatabaseError, "Failed to check the revision", result.Error))
+		return false
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed", NewCLError(ErrPreconditionFailed, "Resource was modified, get it again and retry", nil))
+		return false
+	}
+	return true
+}
+
 func (e *APIError) Error() string {
 	return fmt.Sprintf("CLError: code=%d, message=%s", e.ErrorCode, e.ErrorMessage)
 }
@@ -0,0 +1,43 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	updatedAt := time.Now()
+	cases := map[string]bool{
+		"":                                true,
+		"*":                               true,
+		ETag(updatedAt):                   true,
+		"W/" + ETag(updatedAt):            true,
+		`"0", ` + ETag(updatedAt):         true,
+		ETag(updatedAt.Add(-time.Second)): false,
+	}
+	for header, expected := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPatch, "/api/v1/subnets/1", nil)
+		if header != "" {
+			c.Request.Header.Set("If-Match", header)
+		}
+		if IfMatch(c, updatedAt) != expected {
+			t.Errorf("If-Match %q should be %v", header, expected)
+		}
+		if !expected && w.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %q should get 412, got %d", header, w.Code)
+		}
+	}
+}
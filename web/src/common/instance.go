@@ -10,6 +10,7 @@ package common
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"web/src/model"
 )
@@ -54,3 +55,50 @@ func GetHyperGroup(ctx context.Context, zoneID int64, skipHyper int32) (hyperGro
 	}
 	return
 }
+
+// HyperSupports checks the capabilities reported by hyper_status against the extra specs,
+// memory is in MB as in flavors
+func HyperSupports(hyper *model.Hyper, specs *model.ExtraSpecs, cpu, memory int32) bool {
+	if specs.IsEmpty() {
+		return true
+	}
+	if specs.CpuPolicy == model.CpuPolicyDedicated {
+		if hyper.FreePinCpus < cpu || hyper.NumaNodes < specs.NumaNodes {
+			return false
+		}
+	}
+	switch specs.HugepageSize {
+	case "2M":
+		if hyper.Hugepages2M*2 < int64(memory) {
+			return false
+		}
+	case "1G":
+		if hyper.Hugepages1G*1024 < int64(memory) {
+			return false
+		}
+	}
+	if specs.CpuModel != "" && specs.CpuModel != "host-model" && specs.CpuModel != "host-passthrough" {
+		if !hasItem(hyper.CpuModels, specs.CpuModel) {
+			return false
+		}
+	}
+	for _, feature := range strings.Split(specs.CpuFeatures, ",") {
+		if feature == "" || strings.HasPrefix(feature, "-") {
+			continue
+		}
+		// /proc/cpuinfo spells sse4.2 as sse4_2
+		if !hasItem(hyper.CpuFlags, strings.ReplaceAll(feature, ".", "_")) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasItem(list, item string) bool {
+	for _, i := range strings.Split(list, ",") {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
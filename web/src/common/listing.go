@@ -0,0 +1,351 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"web/src/dbs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// The list grammar shared by the /api/v1 list endpoints:
+//
+//	filter[status]=running                        equality
+//	filter[status]=in:running,stopped             one of the values
+//	filter[created_at]=gte:2024-01-01;lt:2024-02-01  ranges with eq, ne, gt, gte, lt and lte
+//	sort=-created_at,name                         order, - for descending
+//	cursor=<token>                                continue after the last item of the previous page
+//	tag[env]=prod                                 tagged with the key and value, tag[env]= for any value
+//
+// Filters and sort keys are columns of the listed table, values are bound as query arguments.
+
+const listQueryKey = "list_query"
+
+var listOperators = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+// columns that can never be filtered or sorted even though they are stored in the table
+var listHiddenColumns = []string{"password", "secret", "hash", "token", "deleted_at"}
+
+type listCondition struct {
+	clause string
+	args   []interface{}
+}
+
+type listSortKey struct {
+	field  *gorm.StructField
+	column string
+	desc   bool
+}
+
+// ListQuery is the parsed filter, sort and cursor of a list request
+type ListQuery struct {
+	scope      *gorm.Scope
+	table      string
+	conditions []*listCondition
+	sort       []*listSortKey
+	cursor     []interface{}
+}
+
+type listCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func listColumn(scope *gorm.Scope, field *gorm.StructField) string {
+	return scope.QuotedTableName() + "." + scope.Quote(field.DBName)
+}
+
+// listField finds a filterable column of the model
+func listField(scope *gorm.Scope, name string) (field *gorm.StructField, err error) {
+	lower := strings.ToLower(name)
+	for _, hidden := range listHiddenColumns {
+		if strings.Contains(lower, hidden) {
+			err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Field %s can not be listed by", name), nil)
+			return
+		}
+	}
+	for _, f := range scope.GetModelStruct().StructFields {
+		if f.DBName == name && f.IsNormal && !f.IsIgnored && f.Tag.Get("json") != "-" {
+			field = f
+			return
+		}
+	}
+	err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Unknown field %s", name), nil)
+	return
+}
+
+// listValue converts a query value to the type of the column
+func listValue(field *gorm.StructField, value string) (result interface{}, err error) {
+	fieldType := field.Struct.Type
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		var t time.Time
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err = time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid time %s of %s", value, field.DBName), err)
+	}
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result, err = strconv.ParseInt(value, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		result, err = strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		result, err = strconv.ParseFloat(value, 64)
+	case reflect.Bool:
+		result, err = strconv.ParseBool(value)
+	case reflect.String:
+		result = value
+	default:
+		err = fmt.Errorf("unsupported type")
+	}
+	if err != nil {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid value %s of %s", value, field.DBName), err)
+	}
+	return
+}
+
+// NewListQuery parses the list grammar of the request for the model of the scope, order is the default sort
+// of the endpoint and taggable tells whether the resources can be filtered by tags
+func NewListQuery(c *gin.Context, scope *gorm.Scope, order string, taggable bool) (query *ListQuery, err error) {
+	query = &ListQuery{scope: scope, table: scope.TableName()}
+	for name, expr := range c.QueryMap("filter") {
+		var field *gorm.StructField
+		field, err = listField(scope, name)
+		if err != nil {
+			return
+		}
+		for _, term := range strings.Split(expr, ";") {
+			op := "eq"
+			if i := strings.Index(term, ":"); i > 0 {
+				if _, ok := listOperators[term[:i]]; ok || term[:i] == "in" {
+					op, term = term[:i], term[i+1:]
+				}
+			}
+			column := listColumn(scope, field)
+			if op == "in" {
+				values := []interface{}{}
+				for _, item := range strings.Split(term, ",") {
+					var v interface{}
+					if v, err = listValue(field, item); err != nil {
+						return
+					}
+					values = append(values, v)
+				}
+				query.conditions = append(query.conditions, &listCondition{clause: column + " IN (?)", args: []interface{}{values}})
+			} else {
+				var v interface{}
+				if v, err = listValue(field, term); err != nil {
+					return
+				}
+				query.conditions = append(query.conditions, &listCondition{clause: column + " " + listOperators[op] + " ?", args: []interface{}{v}})
+			}
+		}
+	}
+	tags := c.QueryMap("tag")
+	if len(tags) > 0 && !taggable {
+		err = NewCLError(ErrNotTaggable, fmt.Sprintf("Resource %s can not be filtered by tags", query.table), nil)
+		return
+	}
+	for key, value := range tags {
+		clause := scope.QuotedTableName() + ".id IN (SELECT resource_id FROM tags WHERE resource_type = ? AND key = ?"
+		args := []interface{}{query.table, key}
+		if value != "" {
+			clause += " AND value = ?"
+			args = append(args, value)
+		}
+		query.conditions = append(query.conditions, &listCondition{clause: clause + ")", args: args})
+	}
+	sort := strings.TrimSpace(c.Query("sort"))
+	if sort == "" {
+		sort = order
+	}
+	if sort == "" {
+		sort = "created_at"
+	}
+	for _, item := range strings.Split(sort, ",") {
+		item = strings.TrimSpace(item)
+		key := &listSortKey{}
+		if strings.HasPrefix(item, "-") {
+			key.desc = true
+		}
+		item = strings.TrimLeft(item, "+-")
+		if key.field, err = listField(scope, item); err != nil {
+			return
+		}
+		if key.field.Struct.Type.Kind() == reflect.Ptr {
+			err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Field %s can not be sorted by", item), nil)
+			return
+		}
+		key.column = listColumn(scope, key.field)
+		query.sort = append(query.sort, key)
+	}
+	// id breaks ties so that the order and the cursor are stable
+	idField, _ := scope.FieldByName("id")
+	if idField != nil && query.sort[len(query.sort)-1].field.DBName != "id" {
+		query.sort = append(query.sort, &listSortKey{field: idField.StructField, column: listColumn(scope, idField.StructField)})
+	}
+	if token := c.Query("cursor"); token != "" {
+		err = query.decodeCursor(token)
+	}
+	return
+}
+
+func (q *ListQuery) sortSpec() string {
+	keys := []string{}
+	for _, key := range q.sort {
+		if key.desc {
+			keys = append(keys, "-"+key.field.DBName)
+		} else {
+			keys = append(keys, key.field.DBName)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+func (q *ListQuery) decodeCursor(token string) (err error) {
+	invalid := NewCLError(ErrInvalidParameter, "Invalid cursor", nil)
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return invalid
+	}
+	cursor := &listCursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.Sort != q.sortSpec() || len(cursor.Values) != len(q.sort) {
+		return NewCLError(ErrInvalidParameter, "Invalid cursor, it must be used with the same sort", nil)
+	}
+	for i, key := range q.sort {
+		var v interface{}
+		if v, err = listValue(key.field, cursor.Values[i]); err != nil {
+			return invalid
+		}
+		q.cursor = append(q.cursor, v)
+	}
+	return
+}
+
+// NextCursor returns the cursor after the last item of a full page, empty if there are no more items
+func (q *ListQuery) NextCursor(items interface{}, limit int) string {
+	list := reflect.Indirect(reflect.ValueOf(items))
+	if q == nil || list.Kind() != reflect.Slice || limit <= 0 || list.Len() < limit {
+		return ""
+	}
+	last := list.Index(list.Len() - 1).Interface()
+	scope := q.scope.New(last)
+	cursor := &listCursor{Sort: q.sortSpec()}
+	for _, key := range q.sort {
+		field, ok := scope.FieldByName(key.field.Name)
+		if !ok {
+			return ""
+		}
+		switch v := field.Field.Interface().(type) {
+		case time.Time:
+			cursor.Values = append(cursor.Values, v.Format(time.RFC3339Nano))
+		default:
+			cursor.Values = append(cursor.Values, fmt.Sprint(v))
+		}
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// SetNextCursor returns the cursor of the next page in the X-Next-Cursor and Link headers
+func (q *ListQuery) SetNextCursor(c *gin.Context, items interface{}, limit int) {
+	cursor := q.NextCursor(items, limit)
+	if cursor == "" {
+		return
+	}
+	next := *c.Request.URL
+	values := next.Query()
+	values.Set("cursor", cursor)
+	values.Del("offset")
+	next.RawQuery = values.Encode()
+	c.Header("X-Next-Cursor", cursor)
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", (&url.URL{Path: next.Path, RawQuery: next.RawQuery}).String()))
+}
+
+// WithListQuery passes the list query to the List function of the admin
+func WithListQuery(ctx context.Context, query *ListQuery) context.Context {
+	return context.WithValue(ctx, listQueryKey, query)
+}
+
+func getListQuery(ctx context.Context, value interface{}) *ListQuery {
+	query, ok := ctx.Value(listQueryKey).(*ListQuery)
+	if !ok || query == nil || query.scope.New(value).TableName() != query.table {
+		return nil
+	}
+	return query
+}
+
+func (q *ListQuery) filter(db *gorm.DB) *gorm.DB {
+	for _, condition := range q.conditions {
+		db = db.Where(condition.clause, condition.args...)
+	}
+	return db
+}
+
+// ListFilter applies the filters of the request to the count query of the model
+func ListFilter(ctx context.Context, db *gorm.DB) *gorm.DB {
+	query := getListQuery(ctx, db.Value)
+	if query == nil {
+		return db
+	}
+	return query.filter(db)
+}
+
+// ListPage applies the filters, sort and cursor of the request to the find query of the model,
+// without a list query it sorts by order like dbs.Sortby
+func ListPage(ctx context.Context, db *gorm.DB, value interface{}, offset, limit int64, order string) *gorm.DB {
+	query := getListQuery(ctx, value)
+	if query == nil {
+		return dbs.Sortby(db.Offset(offset).Limit(limit), order)
+	}
+	db = query.filter(db)
+	if len(query.cursor) > 0 {
+		// keyset condition, (k1 > v1) or (k1 = v1 and k2 > v2) ...
+		clauses := []string{}
+		args := []interface{}{}
+		for i, key := range query.sort {
+			parts := []string{}
+			for j := 0; j < i; j++ {
+				parts = append(parts, query.sort[j].column+" = ?")
+				args = append(args, query.cursor[j])
+			}
+			op := ">"
+			if key.desc {
+				op = "<"
+			}
+			parts = append(parts, key.column+" "+op+" ?")
+			args = append(args, query.cursor[i])
+			clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		}
+		db = db.Where(strings.Join(clauses, " OR "), args...)
+		offset = 0
+	}
+	for _, key := range query.sort {
+		if key.desc {
+			db = db.Order(key.column + " DESC")
+		} else {
+			db = db.Order(key.column)
+		}
+	}
+	return db.Offset(offset).Limit(limit)
+}
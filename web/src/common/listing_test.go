@@ -0,0 +1,191 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"web/src/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// listConnection stands in for the database, the parser only needs the dialect to quote the columns
+type listConnection struct {
+	gorm.SQLCommon
+}
+
+func listScope(value interface{}) *gorm.Scope {
+	db, _ := gorm.Open("postgres", listConnection{})
+	return db.NewScope(value)
+}
+
+func listContext(query url.Values) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tokens?"+query.Encode(), nil)
+	return c
+}
+
+func TestNewListQueryFilter(t *testing.T) {
+	query, err := NewListQuery(listContext(url.Values{
+		"filter[user_id]":    {"in:1,2"},
+		"filter[created_at]": {"gte:2024-01-01;lt:2024-02-01"},
+		"filter[family]":     {"login:abc"},
+		"filter[revoked]":    {"ne:true"},
+	}), listScope(&model.Token{}), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clauses := map[string][]interface{}{}
+	for _, condition := range query.conditions {
+		clauses[condition.clause[strings.LastIndex(condition.clause, ".")+1:]] = condition.args
+	}
+	from, _ := time.Parse("2006-01-02", "2024-01-01")
+	to, _ := time.Parse("2006-01-02", "2024-02-01")
+	expected := map[string]interface{}{
+		`"user_id" IN (?)`:  []interface{}{int64(1), int64(2)},
+		`"created_at" >= ?`: from,
+		`"created_at" < ?`:  to,
+		// an unknown operator is part of the value
+		`"family" = ?`:   "login:abc",
+		`"revoked" <> ?`: true,
+	}
+	if len(clauses) != len(expected) {
+		t.Fatalf("expected %d conditions, got %v", len(expected), clauses)
+	}
+	for clause, value := range expected {
+		args, ok := clauses[clause]
+		if !ok || len(args) != 1 {
+			t.Errorf("condition %s is missing, got %v", clause, clauses)
+			continue
+		}
+		switch v := value.(type) {
+		case []interface{}:
+			list, _ := args[0].([]interface{})
+			if len(list) != len(v) || list[0] != v[0] || list[1] != v[1] {
+				t.Errorf("condition %s should have %v, got %v", clause, v, args[0])
+			}
+		case time.Time:
+			if got, _ := args[0].(time.Time); !got.Equal(v) {
+				t.Errorf("condition %s should have %v, got %v", clause, v, args[0])
+			}
+		default:
+			if args[0] != v {
+				t.Errorf("condition %s should have %v, got %v", clause, v, args[0])
+			}
+		}
+	}
+}
+
+func TestNewListQueryInvalid(t *testing.T) {
+	cases := []url.Values{
+		{"filter[hash]": {"abc"}},
+		{"filter[deleted_at]": {"2024-01-01"}},
+		{"filter[password]": {"abc"}},
+		{"filter[unknown]": {"abc"}},
+		{"filter[user_id]": {"gt:abc"}},
+		{"filter[created_at]": {"yesterday"}},
+		{"filter[revoked]": {"in:true,maybe"}},
+		{"sort": {"-hash"}},
+		{"sort": {"unknown"}},
+		{"sort": {"created_at,"}},
+	}
+	for _, query := range cases {
+		_, err := NewListQuery(listContext(query), listScope(&model.Token{}), "", false)
+		if clErr, ok := err.(*CLError); !ok || clErr.Code != ErrInvalidParameter {
+			t.Errorf("query %s should be rejected, got %v", query.Encode(), err)
+		}
+	}
+}
+
+func TestListQueryCursor(t *testing.T) {
+	sort := url.Values{"sort": {"-created_at,family"}}
+	query, err := NewListQuery(listContext(sort), listScope(&model.Token{}), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// id is appended to break ties
+	if spec := query.sortSpec(); spec != "-created_at,family,id" {
+		t.Fatalf("unexpected sort %s", spec)
+	}
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 678900000, time.UTC)
+	items := []*model.Token{
+		{Model: model.Model{ID: 9, CreatedAt: createdAt.Add(time.Hour)}, Family: "b"},
+		{Model: model.Model{ID: 7, CreatedAt: createdAt}, Family: "a"},
+	}
+	if cursor := query.NextCursor(items, 3); cursor != "" {
+		t.Fatalf("a short page should have no cursor, got %s", cursor)
+	}
+	cursor := query.NextCursor(items, 2)
+	if cursor == "" {
+		t.Fatal("a full page should have a cursor")
+	}
+	sort.Set("cursor", cursor)
+	next, err := NewListQuery(listContext(sort), listScope(&model.Token{}), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.cursor) != 3 {
+		t.Fatalf("cursor should have 3 values, got %v", next.cursor)
+	}
+	if v, _ := next.cursor[0].(time.Time); !v.Equal(createdAt) {
+		t.Errorf("cursor should continue after %v, got %v", createdAt, next.cursor[0])
+	}
+	if next.cursor[1] != "a" || next.cursor[2] != int64(7) {
+		t.Errorf("cursor should continue after a and 7, got %v", next.cursor[1:])
+	}
+	if !next.sort[0].desc || next.sort[1].desc || next.sort[2].desc {
+		t.Error("only created_at should be sorted descending")
+	}
+	// the cursor can not be used with another sort
+	_, err = NewListQuery(listContext(url.Values{"sort": {"created_at,family"}, "cursor": {cursor}}), listScope(&model.Token{}), "", false)
+	if err == nil {
+		t.Error("cursor of another sort is accepted")
+	}
+}
+
+func TestListQueryMalformedCursor(t *testing.T) {
+	encode := func(data string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(data))
+	}
+	cursors := []string{
+		"not base64!",
+		encode("{"),
+		encode(`{"s":"created_at,id","v":["2024-01-01T00:00:00Z"]}`),
+		encode(`{"s":"created_at,id","v":["2024-01-01T00:00:00Z","abc"]}`),
+		encode(`{"s":"created_at,id","v":["yesterday","1"]}`),
+	}
+	for _, cursor := range cursors {
+		_, err := NewListQuery(listContext(url.Values{"cursor": {cursor}}), listScope(&model.Token{}), "", false)
+		if clErr, ok := err.(*CLError); !ok || clErr.Code != ErrInvalidParameter {
+			t.Errorf("cursor %s should be rejected, got %v", cursor, err)
+		}
+	}
+}
+
+func TestNewListQueryTags(t *testing.T) {
+	tags := url.Values{"tag[env]": {"prod"}}
+	_, err := NewListQuery(listContext(tags), listScope(&model.Token{}), "", false)
+	if clErr, ok := err.(*CLError); !ok || clErr.Code != ErrNotTaggable {
+		t.Fatalf("tags of a resource that is not taggable should be rejected, got %v", err)
+	}
+	query, err := NewListQuery(listContext(tags), listScope(&model.Token{}), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(query.conditions) != 1 || len(query.conditions[0].args) != 3 || query.conditions[0].args[2] != "prod" {
+		t.Errorf("tag should be filtered by key and value, got %v", query.conditions)
+	}
+}
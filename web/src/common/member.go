@@ -19,6 +19,9 @@ type MemberShip struct {
 	OrgID    int64
 	OrgName  string
 	Role     model.Role
+	Actions  []string /* Granted by the custom roles bound to the member */
+	MfaAt    int64    /* Unix time of the last second factor verification, for step-up */
+	action   string
 }
 
 func (m *MemberShip) GetWhere() (where string) {
@@ -32,7 +35,7 @@ func (m *MemberShip) GetWhere() (where string) {
 
 func (m *MemberShip) CheckPermission(reqRole model.Role) (permit bool) {
 	permit = false
-	if m.Role >= reqRole || (m.OrgName == "admin" && m.Role == model.Admin) ||
+	if m.allowed(reqRole) || (m.OrgName == "admin" && m.Role == model.Admin) ||
 		(m.OrgName == "admin" && m.UserName == "admin") {
 		permit = true
 	}
@@ -77,7 +80,7 @@ func (m *MemberShip) CheckOwner(reqRole model.Role, table string, id int64) (isO
 	if id == 0 {
 		return
 	}
-	if m.Role < reqRole {
+	if !m.allowed(reqRole) {
 		return
 	}
 	type Result struct {
@@ -101,7 +104,7 @@ func (m *MemberShip) ValidateOwner(reqRole model.Role, owner int64) (isOwner boo
 	if owner == 0 {
 		return
 	}
-	if m.Role < reqRole {
+	if !m.allowed(reqRole) {
 		return
 	}
 	if m.OrgID == owner || m.Role == model.Admin {
@@ -147,5 +150,6 @@ func GetDBMemberShip(userID, orgID int64) (m *MemberShip, err error) {
 	m.UserName = member.UserName
 	m.OrgName = member.OrgName
 	m.Role = member.Role
+	err = m.LoadActions()
 	return
 }
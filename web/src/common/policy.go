@@ -0,0 +1,74 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"regexp"
+	"strings"
+
+	"web/src/model"
+)
+
+var actionRegex = regexp.MustCompile(`^([a-z_]+|\*):([a-z_]+|\*)$`)
+
+// ValidAction checks the resource:verb form of an action, either part may be *
+func ValidAction(action string) bool {
+	return actionRegex.MatchString(action)
+}
+
+// MatchAction tells if the action is granted by one of the granted actions
+func MatchAction(granted []string, action string) bool {
+	resource, verb, ok := strings.Cut(action, ":")
+	if !ok {
+		return false
+	}
+	for _, grant := range granted {
+		grantResource, grantVerb, ok := strings.Cut(grant, ":")
+		if !ok {
+			continue
+		}
+		if (grantResource == "*" || grantResource == resource) && (grantVerb == "*" || grantVerb == verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns a copy of the membership checking the action, the checks of the copy pass
+// if the role is high enough or a custom role of the member grants the action
+func (m *MemberShip) For(action string) *MemberShip {
+	membership := *m
+	membership.action = action
+	return &membership
+}
+
+// allowed checks the role ladder and then the custom roles, custom roles never stand in for admin
+func (m *MemberShip) allowed(reqRole model.Role) bool {
+	if m.Role >= reqRole {
+		return true
+	}
+	return reqRole < model.Admin && m.action != "" && MatchAction(m.Actions, m.action)
+}
+
+// LoadActions collects the actions of the custom roles bound to the member in its organization
+func (m *MemberShip) LoadActions() (err error) {
+	db := DB()
+	bindings := []*model.RoleBinding{}
+	err = db.Preload("Role").Where("user_id = ? and owner = ?", m.UserID, m.OrgID).Find(&bindings).Error
+	if err != nil {
+		logger.Error("Failed to query role bindings", err)
+		return NewCLError(ErrDatabaseError, "Failed to query role bindings", err)
+	}
+	m.Actions = nil
+	for _, binding := range bindings {
+		if binding.Role == nil || binding.Role.Actions == "" {
+			continue
+		}
+		m.Actions = append(m.Actions, strings.Split(binding.Role.Actions, ",")...)
+	}
+	return
+}
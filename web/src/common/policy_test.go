@@ -0,0 +1,52 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"testing"
+
+	"web/src/model"
+)
+
+func TestMatchAction(t *testing.T) {
+	granted := []string{"instance:reboot", "volume:*", "*:get"}
+	cases := map[string]bool{
+		"instance:reboot": true,
+		"instance:delete": false,
+		"volume:delete":   true,
+		"subnet:get":      true,
+		"subnet:list":     false,
+		"instance":        false,
+	}
+	for action, expected := range cases {
+		if MatchAction(granted, action) != expected {
+			t.Errorf("MatchAction(%s) should be %v", action, expected)
+		}
+	}
+	if !ValidAction("floating_ip:create") || !ValidAction("*:*") || ValidAction("instance") || ValidAction("Instance:reboot") {
+		t.Error("ValidAction failed")
+	}
+}
+
+func TestMemberShipFor(t *testing.T) {
+	operator := &MemberShip{OrgID: 2, OrgName: "dev", Role: model.Reader, Actions: []string{"instance:reboot", "instance:list"}}
+	if !operator.For("instance:reboot").ValidateOwner(model.Writer, 2) {
+		t.Error("operator should reboot instances of its org")
+	}
+	if operator.For("instance:reboot").ValidateOwner(model.Writer, 3) {
+		t.Error("operator should not reboot instances of other orgs")
+	}
+	if operator.For("instance:delete").ValidateOwner(model.Writer, 2) {
+		t.Error("operator should not delete instances")
+	}
+	if operator.ValidateOwner(model.Writer, 2) {
+		t.Error("checks without an action should use the role only")
+	}
+	if operator.For("instance:list").CheckPermission(model.Admin) {
+		t.Error("custom roles should never grant admin")
+	}
+}
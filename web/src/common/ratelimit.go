@@ -0,0 +1,103 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"web/src/model"
+)
+
+// RateStore keeps token buckets, a request takes one token and rate tokens per second are refilled up to burst
+type RateStore interface {
+	Take(key string, rate float64, burst int, now time.Time) (retryAfter time.Duration, ok bool)
+}
+
+// refill takes one token from the bucket, it returns the tokens left or how long until a token is available
+func refill(tokens float64, stamp int64, rate float64, burst int, now time.Time) (left float64, retryAfter time.Duration, ok bool) {
+	elapsed := float64(now.UnixNano()-stamp) / float64(time.Second)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	left = math.Min(float64(burst), tokens+elapsed*rate)
+	if left >= 1 {
+		return left - 1, 0, true
+	}
+	retryAfter = time.Duration((1 - left) / rate * float64(time.Second))
+	return left, retryAfter, false
+}
+
+type memoryBucket struct {
+	tokens float64
+	stamp  int64
+}
+
+// MemoryRateStore keeps the buckets in this process, each node limits on its own
+type MemoryRateStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+func (s *MemoryRateStore) Take(key string, rate float64, burst int, now time.Time) (retryAfter time.Duration, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.buckets == nil {
+		s.buckets = make(map[string]*memoryBucket)
+	}
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &memoryBucket{tokens: float64(burst), stamp: now.UnixNano()}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens, retryAfter, ok = refill(bucket.tokens, bucket.stamp, rate, burst, now)
+	bucket.stamp = now.UnixNano()
+	return
+}
+
+// DBRateStore keeps the buckets in the database so that all nodes share the limits, requests are allowed if the database fails
+type DBRateStore struct{}
+
+func (s *DBRateStore) Take(key string, rate float64, burst int, now time.Time) (retryAfter time.Duration, ok bool) {
+	db := DB().Begin()
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Error("Rate limit bucket failed, allow the request", err)
+			db.Rollback()
+			retryAfter, ok = 0, true
+			return
+		}
+		db.Commit()
+	}()
+	err = db.Set("gorm:insert_option", "ON CONFLICT DO NOTHING").Create(&model.RateBucket{Key: key, Tokens: float64(burst), Stamp: now.UnixNano()}).Error
+	if err != nil {
+		return
+	}
+	bucket := &model.RateBucket{}
+	err = db.Set("gorm:query_option", "FOR UPDATE").Take(bucket, "key = ?", key).Error
+	if err != nil {
+		return
+	}
+	bucket.Tokens, retryAfter, ok = refill(bucket.Tokens, bucket.Stamp, rate, burst, now)
+	err = db.Model(bucket).UpdateColumns(map[string]interface{}{"tokens": bucket.Tokens, "stamp": now.UnixNano()}).Error
+	return
+}
+
+var (
+	memoryRateStore = &MemoryRateStore{}
+	dbRateStore     = &DBRateStore{}
+)
+
+// GetRateStore returns the shared database store for multi-node deployments, otherwise the in-process one
+func GetRateStore(shared bool) RateStore {
+	if shared {
+		return dbRateStore
+	}
+	return memoryRateStore
+}
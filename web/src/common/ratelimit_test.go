@@ -0,0 +1,32 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRateStore(t *testing.T) {
+	store := &MemoryRateStore{}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, ok := store.Take("org:1", 1, 3, now); !ok {
+			t.Fatalf("request %d within the burst should pass", i)
+		}
+	}
+	retryAfter, ok := store.Take("org:1", 1, 3, now)
+	if ok || retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("request beyond the burst should wait up to a second, got %v %v", ok, retryAfter)
+	}
+	if _, ok = store.Take("org:2", 1, 3, now); !ok {
+		t.Error("buckets of other keys should be independent")
+	}
+	if _, ok = store.Take("org:1", 1, 3, now.Add(time.Second)); !ok {
+		t.Error("a token should be refilled after a second")
+	}
+}
@@ -0,0 +1,563 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultClaimTimeout = 300
+	maxRejectReasons    = 3
+)
+
+var (
+	defaultSchedulerFilters  = []string{"status", "zone", "capacity", "capabilities", "aggregates", "affinity", "hyper_tags"}
+	defaultSchedulerWeighers = []string{"spread"}
+)
+
+// SchedulerHints constrain where a guest may go beyond its size
+type SchedulerHints struct {
+	SameHost      []int32           /* hostids of hypervisors the guest must run on */
+	DifferentHost []int32           /* hostids of hypervisors the guest must avoid */
+	HyperTags     map[string]string /* tags a hypervisor must have */
+}
+
+// ScheduleRequest describes a guest to place, memory is in MB and disk in GB as in flavors
+type ScheduleRequest struct {
+	ZoneID int64
+	Cpu    int32
+	Memory int32
+	Disk   int32
+	Specs  *model.ExtraSpecs
+	Hints  *SchedulerHints
+	// AggregateRules gather the rules of the flavor, image and organization,
+	// each is key=value for aggregate metadata to require or !key=value to forbid
+	AggregateRules []string
+}
+
+// HostState is a candidate hypervisor, the free resources are the last report
+// adjusted to the current over-commit rates and less the claims made since
+type HostState struct {
+	Hyper       *model.Hyper
+	Resource    *model.Resource
+	Tags        map[string]string
+	Aggregates  map[string]bool     /* key=value metadata of the aggregates of the hyper */
+	Isolated    map[string][]string /* metadata of the isolated aggregates of the hyper by name */
+	Instances   int
+	Cpu         int64 /* free vcpus */
+	CpuTotal    int64
+	Memory      int64 /* free memory in KiB */
+	MemoryTotal int64
+	Disk        int64 /* free disk in bytes */
+	DiskTotal   int64
+}
+
+// SchedulerFilter returns why the host can not take the guest, or an empty string if it can
+type SchedulerFilter func(req *ScheduleRequest, host *HostState) string
+
+// SchedulerWeigher scores a host in [0, 1] for the guest, the higher the better
+type SchedulerWeigher func(req *ScheduleRequest, host *HostState) float64
+
+var (
+	schedulerFilters  = map[string]SchedulerFilter{}
+	schedulerWeighers = map[string]SchedulerWeigher{}
+)
+
+// RegisterSchedulerFilter makes a filter available to scheduler.filters
+func RegisterSchedulerFilter(name string, filter SchedulerFilter) {
+	schedulerFilters[name] = filter
+}
+
+// RegisterSchedulerWeigher makes a weigher available to scheduler.weighers
+func RegisterSchedulerWeigher(name string, weigher SchedulerWeigher) {
+	schedulerWeighers[name] = weigher
+}
+
+func init() {
+	RegisterSchedulerFilter("status", statusFilter)
+	RegisterSchedulerFilter("zone", zoneFilter)
+	RegisterSchedulerFilter("capacity", capacityFilter)
+	RegisterSchedulerFilter("capabilities", capabilitiesFilter)
+	RegisterSchedulerFilter("aggregates", aggregatesFilter)
+	RegisterSchedulerFilter("affinity", affinityFilter)
+	RegisterSchedulerFilter("hyper_tags", hyperTagsFilter)
+	RegisterSchedulerWeigher("spread", spreadWeigher)
+	RegisterSchedulerWeigher("pack", packWeigher)
+	RegisterSchedulerWeigher("least_loaded", leastLoadedWeigher)
+}
+
+func (req *ScheduleRequest) needs() (cpu, memory, disk int64) {
+	return int64(req.Cpu), int64(req.Memory) * 1024, int64(req.Disk) * 1024 * 1024 * 1024
+}
+
+func statusFilter(req *ScheduleRequest, host *HostState) string {
+	if host.Hyper.Status != 1 {
+		return fmt.Sprintf("hyper %d is %s", host.Hyper.Hostid, host.Hyper.GetStatus())
+	}
+	return ""
+}
+
+func zoneFilter(req *ScheduleRequest, host *HostState) string {
+	if req.ZoneID > 0 && host.Hyper.ZoneID != req.ZoneID {
+		return fmt.Sprintf("hyper %d is in another zone", host.Hyper.Hostid)
+	}
+	return ""
+}
+
+func capacityFilter(req *ScheduleRequest, host *HostState) string {
+	if host.Resource == nil {
+		return fmt.Sprintf("hyper %d has not reported its resources", host.Hyper.Hostid)
+	}
+	cpu, memory, disk := req.needs()
+	if host.Cpu < cpu {
+		return fmt.Sprintf("hyper %d has %d free vcpus, %d needed", host.Hyper.Hostid, host.Cpu, cpu)
+	}
+	if host.Memory < memory {
+		return fmt.Sprintf("hyper %d has %d MB free memory, %d needed", host.Hyper.Hostid, host.Memory/1024, req.Memory)
+	}
+	if host.Disk < disk {
+		return fmt.Sprintf("hyper %d has %d GB free disk, %d needed", host.Hyper.Hostid, host.Disk>>30, req.Disk)
+	}
+	return ""
+}
+
+func capabilitiesFilter(req *ScheduleRequest, host *HostState) string {
+	if !HyperSupports(host.Hyper, req.Specs, req.Cpu, req.Memory) {
+		return fmt.Sprintf("hyper %d does not support the extra specs", host.Hyper.Hostid)
+	}
+	return ""
+}
+
+func aggregatesFilter(req *ScheduleRequest, host *HostState) string {
+	required := map[string]bool{}
+	for _, rule := range req.AggregateRules {
+		if strings.HasPrefix(rule, "!") {
+			if host.Aggregates[rule[1:]] {
+				return fmt.Sprintf("hyper %d is in an aggregate with %s", host.Hyper.Hostid, rule[1:])
+			}
+			continue
+		}
+		if !host.Aggregates[rule] {
+			return fmt.Sprintf("hyper %d is in no aggregate with %s", host.Hyper.Hostid, rule)
+		}
+		required[rule] = true
+	}
+	for name, metadata := range host.Isolated {
+		isolated := true
+		for _, pair := range metadata {
+			if required[pair] {
+				isolated = false
+				break
+			}
+		}
+		if isolated {
+			return fmt.Sprintf("hyper %d is isolated by aggregate %s", host.Hyper.Hostid, name)
+		}
+	}
+	return ""
+}
+
+func affinityFilter(req *ScheduleRequest, host *HostState) string {
+	if req.Hints == nil {
+		return ""
+	}
+	hostid := host.Hyper.Hostid
+	for _, id := range req.Hints.SameHost {
+		if id != hostid {
+			return fmt.Sprintf("hyper %d is not the host of the same_host guests", hostid)
+		}
+	}
+	for _, id := range req.Hints.DifferentHost {
+		if id == hostid {
+			return fmt.Sprintf("hyper %d hosts a different_host guest", hostid)
+		}
+	}
+	return ""
+}
+
+func hyperTagsFilter(req *ScheduleRequest, host *HostState) string {
+	if req.Hints == nil {
+		return ""
+	}
+	for key, value := range req.Hints.HyperTags {
+		if v, ok := host.Tags[key]; !ok || v != value {
+			return fmt.Sprintf("hyper %d is not tagged %s=%s", host.Hyper.Hostid, key, value)
+		}
+	}
+	return ""
+}
+
+// freeRatio is the share of cpu and memory left on the host once the guest is placed
+func freeRatio(req *ScheduleRequest, host *HostState) float64 {
+	cpu, memory, _ := req.needs()
+	ratio := 0.0
+	if host.CpuTotal > 0 {
+		ratio += float64(host.Cpu-cpu) / float64(host.CpuTotal)
+	}
+	if host.MemoryTotal > 0 {
+		ratio += float64(host.Memory-memory) / float64(host.MemoryTotal)
+	}
+	ratio /= 2
+	if ratio < 0 {
+		ratio = 0
+	}
+	return ratio
+}
+
+func spreadWeigher(req *ScheduleRequest, host *HostState) float64 {
+	return freeRatio(req, host)
+}
+
+func packWeigher(req *ScheduleRequest, host *HostState) float64 {
+	return 1 - freeRatio(req, host)
+}
+
+func leastLoadedWeigher(req *ScheduleRequest, host *HostState) float64 {
+	return 1 / float64(1+host.Instances)
+}
+
+type schedulerClaim struct {
+	hostid int32
+	cpu    int64
+	memory int64
+	disk   int64
+	at     time.Time
+}
+
+var (
+	claimMutex sync.Mutex
+	claims     []*schedulerClaim
+)
+
+// claimTimeout bounds how long a claim counts if the hypervisor does not report again
+func claimTimeout() time.Duration {
+	seconds := viper.GetInt("scheduler.claim_timeout")
+	if seconds <= 0 {
+		seconds = defaultClaimTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ClaimHost holds the resources of the guest on the host until the hypervisor reports them,
+// so that guests scheduled in the meantime do not count on the same free resources
+func ClaimHost(req *ScheduleRequest, host *HostState) {
+	cpu, memory, disk := req.needs()
+	host.Cpu -= cpu
+	host.Memory -= memory
+	host.Disk -= disk
+	host.Instances++
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+	claims = append(claims, &schedulerClaim{hostid: host.Hyper.Hostid, cpu: cpu, memory: memory, disk: disk, at: time.Now()})
+}
+
+// applyClaims drops claims the hypervisors have reported since or that timed out
+// and takes the others off the free resources of the hosts
+func applyClaims(hosts map[int32]*HostState) {
+	now := time.Now()
+	timeout := claimTimeout()
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+	kept := claims[:0]
+	for _, claim := range claims {
+		if now.Sub(claim.at) > timeout {
+			continue
+		}
+		host, ok := hosts[claim.hostid]
+		if ok && host.Resource != nil && host.Resource.UpdatedAt.After(claim.at) {
+			continue
+		}
+		kept = append(kept, claim)
+		if ok {
+			host.Cpu -= claim.cpu
+			host.Memory -= claim.memory
+			host.Disk -= claim.disk
+			host.Instances++
+		}
+	}
+	claims = kept
+}
+
+// overCommit rescales a reported total to the current over-commit rate of the hyper,
+// so a rate changed by the admin counts before the hypervisor reports with it
+func overCommit(total int64, reportedRate, rate float32) int64 {
+	if reportedRate <= 0 || rate <= 0 || reportedRate == rate {
+		return total
+	}
+	return int64(float64(total) / float64(reportedRate) * float64(rate))
+}
+
+func newHostState(hyper *model.Hyper, resource *model.Resource) *HostState {
+	host := &HostState{Hyper: hyper, Resource: resource, Tags: map[string]string{}, Aggregates: map[string]bool{}, Isolated: map[string][]string{}}
+	if resource == nil {
+		return host
+	}
+	host.CpuTotal = overCommit(resource.CpuTotal, resource.CpuOverRate, hyper.CpuOverRate)
+	host.MemoryTotal = overCommit(resource.MemoryTotal, resource.MemOverRate, hyper.MemOverRate)
+	host.DiskTotal = overCommit(resource.DiskTotal, resource.DiskOverRate, hyper.DiskOverRate)
+	host.Cpu = host.CpuTotal - (resource.CpuTotal - resource.Cpu)
+	host.Memory = host.MemoryTotal - (resource.MemoryTotal - resource.Memory)
+	host.Disk = host.DiskTotal - (resource.DiskTotal - resource.Disk)
+	return host
+}
+
+// loadHosts builds the state of every hypervisor, the filters decide which ones qualify
+func loadHosts(ctx context.Context) (hosts []*HostState, err error) {
+	ctx, db := GetContextDB(ctx)
+	hypers := []*model.Hyper{}
+	if err = db.Where("hostid >= 0").Order("hostid").Find(&hypers).Error; err != nil {
+		logger.Error("Hypers query failed", err)
+		return nil, NewCLError(ErrSQLSyntaxError, "Failed to query hypervisors", err)
+	}
+	resources := []*model.Resource{}
+	if err = db.Find(&resources).Error; err != nil {
+		logger.Error("Resources query failed", err)
+		return nil, NewCLError(ErrSQLSyntaxError, "Failed to query hypervisor resources", err)
+	}
+	byHostid := make(map[int32]*model.Resource, len(resources))
+	for _, resource := range resources {
+		byHostid[resource.Hostid] = resource
+	}
+	hostMap := make(map[int32]*HostState, len(hypers))
+	byID := make(map[int64]*HostState, len(hypers))
+	for _, hyper := range hypers {
+		host := newHostState(hyper, byHostid[hyper.Hostid])
+		hosts = append(hosts, host)
+		hostMap[hyper.Hostid] = host
+		byID[hyper.ID] = host
+	}
+	tags := []*model.Tag{}
+	if err = db.Where("resource_type = ?", "hypers").Find(&tags).Error; err != nil {
+		logger.Error("Hyper tags query failed", err)
+		return nil, NewCLError(ErrSQLSyntaxError, "Failed to query hypervisor tags", err)
+	}
+	for _, tag := range tags {
+		if host, ok := byID[tag.ResourceID]; ok {
+			host.Tags[tag.Key] = tag.Value
+		}
+	}
+	if err = loadAggregates(ctx, byID); err != nil {
+		return
+	}
+	counts := []*struct {
+		Hyper int32
+		Total int
+	}{}
+	err = db.Model(&model.Instance{}).Select("hyper, count(*) as total").Group("hyper").Scan(&counts).Error
+	if err != nil {
+		logger.Error("Instance count query failed", err)
+		return nil, NewCLError(ErrSQLSyntaxError, "Failed to count instances of hypervisors", err)
+	}
+	for _, count := range counts {
+		if host, ok := hostMap[count.Hyper]; ok {
+			host.Instances = count.Total
+		}
+	}
+	applyClaims(hostMap)
+	return
+}
+
+// loadAggregates adds the metadata of the aggregates to their hypervisors
+func loadAggregates(ctx context.Context, byID map[int64]*HostState) (err error) {
+	ctx, db := GetContextDB(ctx)
+	aggregates := []*model.Aggregate{}
+	if err = db.Preload("Hypers").Find(&aggregates).Error; err != nil {
+		logger.Error("Aggregates query failed", err)
+		return NewCLError(ErrSQLSyntaxError, "Failed to query aggregates", err)
+	}
+	tags := []*model.Tag{}
+	if err = db.Where("resource_type = ?", "aggregates").Find(&tags).Error; err != nil {
+		logger.Error("Aggregate metadata query failed", err)
+		return NewCLError(ErrSQLSyntaxError, "Failed to query aggregate metadata", err)
+	}
+	metadata := map[int64][]string{}
+	for _, tag := range tags {
+		metadata[tag.ResourceID] = append(metadata[tag.ResourceID], tag.Key+"="+tag.Value)
+	}
+	for _, aggregate := range aggregates {
+		for _, hyper := range aggregate.Hypers {
+			host, ok := byID[hyper.ID]
+			if !ok {
+				continue
+			}
+			for _, pair := range metadata[aggregate.ID] {
+				host.Aggregates[pair] = true
+			}
+			if aggregate.Isolated {
+				host.Isolated[aggregate.Name] = metadata[aggregate.ID]
+			}
+		}
+	}
+	return
+}
+
+// AggregateRules splits and gathers comma separated aggregate rules
+func AggregateRules(rules ...string) (gathered []string) {
+	for _, list := range rules {
+		for _, rule := range strings.Split(list, ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				gathered = append(gathered, rule)
+			}
+		}
+	}
+	return
+}
+
+// configured returns the names in scheduler.<kind> with their multipliers, written as name or name:multiplier
+func configured(kind string, defaults []string) (names []string, multipliers []float64) {
+	items := viper.GetStringSlice("scheduler." + kind)
+	if len(items) == 0 {
+		items = defaults
+	}
+	for _, item := range items {
+		name, multiplier := item, 1.0
+		if i := strings.Index(item, ":"); i > 0 {
+			name = item[:i]
+			m, err := strconv.ParseFloat(item[i+1:], 64)
+			if err != nil {
+				logger.Errorf("Invalid multiplier of scheduler %s %s", kind, item)
+				continue
+			}
+			multiplier = m
+		}
+		names = append(names, name)
+		multipliers = append(multipliers, multiplier)
+	}
+	return
+}
+
+// Schedule runs the configured filters over all hypervisors and ranks the ones left
+// by the configured weighers, the best host comes first. With no host left the error
+// tells how many hosts each filter rejected and why
+func Schedule(ctx context.Context, req *ScheduleRequest) (ranked []*HostState, err error) {
+	hosts, err := loadHosts(ctx)
+	if err != nil {
+		return
+	}
+	return scheduleHosts(req, hosts)
+}
+
+func scheduleHosts(req *ScheduleRequest, hosts []*HostState) (ranked []*HostState, err error) {
+	filterNames, _ := configured("filters", defaultSchedulerFilters)
+	rejects := map[string][]string{}
+	order := []string{}
+	for _, host := range hosts {
+		passed := true
+		for _, name := range filterNames {
+			filter, ok := schedulerFilters[name]
+			if !ok {
+				continue
+			}
+			if reason := filter(req, host); reason != "" {
+				if _, seen := rejects[name]; !seen {
+					order = append(order, name)
+				}
+				rejects[name] = append(rejects[name], reason)
+				passed = false
+				break
+			}
+		}
+		if passed {
+			ranked = append(ranked, host)
+		}
+	}
+	if len(ranked) == 0 {
+		explains := []string{}
+		for _, name := range order {
+			reasons := rejects[name]
+			explain := fmt.Sprintf("%s rejected %d", name, len(reasons))
+			if len(reasons) > maxRejectReasons {
+				reasons = append(reasons[:maxRejectReasons:maxRejectReasons], "...")
+			}
+			explains = append(explains, fmt.Sprintf("%s (%s)", explain, strings.Join(reasons, "; ")))
+		}
+		message := "No valid hypervisor found"
+		if len(explains) > 0 {
+			message = fmt.Sprintf("%s: %s", message, strings.Join(explains, ", "))
+		}
+		logger.Errorf("%s for %+v", message, req)
+		err = NewCLError(ErrNoQualifiedHypervisor, message, nil)
+		return
+	}
+	weigherNames, multipliers := configured("weighers", defaultSchedulerWeighers)
+	scores := make(map[*HostState]float64, len(ranked))
+	for _, host := range ranked {
+		for i, name := range weigherNames {
+			if weigher, ok := schedulerWeighers[name]; ok {
+				scores[host] += multipliers[i] * weigher(req, host)
+			}
+		}
+	}
+	// ties go to the lower hostid so that the same state always gives the same placement
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i].Hyper.Hostid < ranked[j].Hyper.Hostid
+	})
+	for _, host := range ranked {
+		logger.Debugf("Hyper %d scored %.3f for %+v", host.Hyper.Hostid, scores[host], req)
+	}
+	return
+}
+
+// SelectHyperGroup schedules the guest and returns the ranked hosts as a select= group, best first,
+// with the resources claimed on the best one. The dispatcher sends the guest to a member that has
+// room for it by its own reports, so a host filled up since the last report of the control plane
+// is passed over instead of failing the launch
+func SelectHyperGroup(ctx context.Context, req *ScheduleRequest) (hyperGroup string, err error) {
+	ranked, err := Schedule(ctx, req)
+	if err != nil {
+		return
+	}
+	ClaimHost(req, ranked[0])
+	return hyperGroupOf(req.ZoneID, ranked), nil
+}
+
+// hyperGroupOf names the group after its members, the dispatcher keeps groups by name
+// and the members differ from one request to the next
+func hyperGroupOf(zoneID int64, ranked []*HostState) string {
+	hostids := make([]string, len(ranked))
+	for i, host := range ranked {
+		hostids[i] = strconv.Itoa(int(host.Hyper.Hostid))
+	}
+	hyperList := strings.Join(hostids, ",")
+	return fmt.Sprintf("group-zone-%d-%08x:%s", zoneID, crc32.ChecksumIEEE([]byte(hyperList)), hyperList)
+}
+
+// ClaimOnHyper claims the resources on the given hypervisor if it has room for them,
+// a guest growing in place asks for the difference to its current size
+func ClaimOnHyper(ctx context.Context, hostid int32, req *ScheduleRequest) (err error) {
+	hosts, err := loadHosts(ctx)
+	if err != nil {
+		return
+	}
+	for _, host := range hosts {
+		if host.Hyper.Hostid != hostid {
+			continue
+		}
+		if reason := capacityFilter(req, host); reason != "" {
+			logger.Errorf("Hyper %d can not take %+v, %s", hostid, req, reason)
+			return NewCLError(ErrNoQualifiedHypervisor, reason, nil)
+		}
+		ClaimHost(req, host)
+		return
+	}
+	return NewCLError(ErrHypervisorNotFound, fmt.Sprintf("Hyper %d not found", hostid), nil)
+}
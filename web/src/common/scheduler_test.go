@@ -0,0 +1,90 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+func testHost(hostid int32, cpu, memory int64) *HostState {
+	hyper := &model.Hyper{Hostid: hostid, Status: 1, ZoneID: 1, CpuOverRate: 2, MemOverRate: 1, DiskOverRate: 1}
+	resource := &model.Resource{
+		Hostid: hostid, Cpu: cpu, CpuTotal: 32, Memory: memory, MemoryTotal: 64 << 20, Disk: 1 << 40, DiskTotal: 1 << 40,
+		CpuOverRate: 2, MemOverRate: 1, DiskOverRate: 1,
+	}
+	return newHostState(hyper, resource)
+}
+
+func TestScheduleHosts(t *testing.T) {
+	req := &ScheduleRequest{ZoneID: 1, Cpu: 4, Memory: 8192, Disk: 40}
+	hosts := []*HostState{testHost(1, 8, 16<<20), testHost(2, 24, 48<<20), testHost(3, 2, 48<<20)}
+	ranked, err := scheduleHosts(req, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].Hyper.Hostid != 2 {
+		t.Errorf("spread should prefer the emptier hyper 2, got %d hosts", len(ranked))
+	}
+
+	viper.Set("scheduler.weighers", []string{"pack"})
+	defer viper.Set("scheduler.weighers", nil)
+	ranked, _ = scheduleHosts(req, hosts)
+	if ranked[0].Hyper.Hostid != 1 {
+		t.Errorf("pack should prefer the fuller hyper 1, got %d", ranked[0].Hyper.Hostid)
+	}
+
+	ClaimHost(req, ranked[0])
+	if ranked[0].Cpu != 4 {
+		t.Errorf("claim should take 4 vcpus off hyper 1, %d left", ranked[0].Cpu)
+	}
+
+	if group := hyperGroupOf(1, ranked); !strings.HasSuffix(group, ":1,2") || !strings.HasPrefix(group, "group-zone-1-") {
+		t.Errorf("group should list the hypers in score order, got %s", group)
+	}
+	if hyperGroupOf(1, ranked) == hyperGroupOf(1, []*HostState{ranked[1], ranked[0]}) {
+		t.Error("groups of different members or order should have different names")
+	}
+
+	req.Cpu = 64
+	_, err = scheduleHosts(req, hosts)
+	if err == nil || !strings.Contains(err.Error(), "capacity rejected 3") {
+		t.Errorf("error should explain the capacity rejections, got %v", err)
+	}
+}
+
+func TestOverCommit(t *testing.T) {
+	hyper := &model.Hyper{Hostid: 1, CpuOverRate: 4, MemOverRate: 1, DiskOverRate: 1}
+	resource := &model.Resource{Hostid: 1, Cpu: 8, CpuTotal: 32, CpuOverRate: 2}
+	host := newHostState(hyper, resource)
+	if host.CpuTotal != 64 || host.Cpu != 40 {
+		t.Errorf("a raised rate should count before it is reported, got %d/%d", host.Cpu, host.CpuTotal)
+	}
+}
+
+func TestAggregatesFilter(t *testing.T) {
+	host := testHost(1, 8, 16<<20)
+	host.Aggregates["ssd=true"] = true
+	host.Aggregates["tenant=finance"] = true
+	host.Isolated["finance"] = []string{"tenant=finance"}
+	req := &ScheduleRequest{AggregateRules: AggregateRules("ssd=true")}
+	if reason := aggregatesFilter(req, host); reason == "" {
+		t.Error("isolated hyper should reject guests not requiring its metadata")
+	}
+	req.AggregateRules = AggregateRules("ssd=true, tenant=finance")
+	if reason := aggregatesFilter(req, host); reason != "" {
+		t.Errorf("hyper should pass, got %s", reason)
+	}
+	req.AggregateRules = AggregateRules("tenant=finance,!ssd=true")
+	if reason := aggregatesFilter(req, host); reason == "" {
+		t.Error("forbidden metadata should reject the hyper")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultLoginMaxFailures = 20
+	defaultLoginWindow      = 15
+)
+
+// LoginThrottle limits failed logins per client IP for both the REST API and the UI,
+// login.ip_max_failures failures within login.ip_window_minutes block the address until the window passes
+var LoginThrottle = &FailureThrottle{}
+
+type FailureThrottle struct {
+	mutex    sync.Mutex
+	failures map[string][]time.Time
+}
+
+func (t *FailureThrottle) limits() (maxFailures int, window time.Duration) {
+	maxFailures = viper.GetInt("login.ip_max_failures")
+	if maxFailures == 0 {
+		maxFailures = defaultLoginMaxFailures
+	}
+	minutes := viper.GetInt("login.ip_window_minutes")
+	if minutes <= 0 {
+		minutes = defaultLoginWindow
+	}
+	window = time.Duration(minutes) * time.Minute
+	return
+}
+
+// recent drops failures out of the window, the caller holds the mutex
+func (t *FailureThrottle) recent(key string, now time.Time, window time.Duration) []time.Time {
+	kept := t.failures[key][:0]
+	for _, at := range t.failures[key] {
+		if now.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.failures, key)
+		return nil
+	}
+	t.failures[key] = kept
+	return kept
+}
+
+// Allow tells if the key may try to login, otherwise how long to wait, a negative ip_max_failures disables the throttle
+func (t *FailureThrottle) Allow(key string) (retryAfter time.Duration, ok bool) {
+	maxFailures, window := t.limits()
+	if maxFailures < 0 || key == "" {
+		return 0, true
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.failures == nil {
+		return 0, true
+	}
+	now := time.Now()
+	failures := t.recent(key, now, window)
+	if len(failures) < maxFailures {
+		return 0, true
+	}
+	// blocked until enough failures fall out of the window
+	retryAfter = failures[len(failures)-maxFailures].Add(window).Sub(now)
+	return retryAfter, false
+}
+
+// Fail records a failed login of the key
+func (t *FailureThrottle) Fail(key string) {
+	maxFailures, window := t.limits()
+	if maxFailures < 0 || key == "" {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.failures == nil {
+		t.failures = make(map[string][]time.Time)
+	}
+	now := time.Now()
+	failures := t.recent(key, now, window)
+	if len(failures) >= maxFailures {
+		failures = failures[len(failures)-maxFailures+1:]
+	}
+	t.failures[key] = append(failures, now)
+}
@@ -0,0 +1,32 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFailureThrottle(t *testing.T) {
+	viper.Set("login.ip_max_failures", 3)
+	defer viper.Set("login.ip_max_failures", nil)
+	throttle := &FailureThrottle{}
+	for i := 0; i < 3; i++ {
+		if _, ok := throttle.Allow("10.0.0.1"); !ok {
+			t.Fatalf("attempt %d should be allowed", i)
+		}
+		throttle.Fail("10.0.0.1")
+	}
+	retryAfter, ok := throttle.Allow("10.0.0.1")
+	if ok || retryAfter <= 0 {
+		t.Error("address should be throttled after 3 failures")
+	}
+	if _, ok = throttle.Allow("10.0.0.2"); !ok {
+		t.Error("other addresses should not be throttled")
+	}
+}
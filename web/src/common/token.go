@@ -17,5 +17,7 @@ type TokenClaim struct {
 	Role       model.Role
 	InstanceID int    `json:"instanceID"`
 	Secret     string `json:"secret"`
+	UserID     int64  `json:"userID,omitempty"`
+	Type       string `json:"type,omitempty"` /* console type, vnc if empty */
 	jwt.RegisteredClaims
 }
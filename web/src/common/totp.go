@@ -0,0 +1,92 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TotpPeriod = 30
+	TotpDigits = 6
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTotpSecret returns a random base32 secret of 160 bits as recommended by RFC 4226
+func NewTotpSecret() (secret string, err error) {
+	buf := make([]byte, 20)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return
+	}
+	secret = totpEncoding.EncodeToString(buf)
+	return
+}
+
+// TotpURL is the otpauth URI shown as a QR code to authenticator apps
+func TotpURL(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("period", fmt.Sprintf("%d", TotpPeriod))
+	values.Set("digits", fmt.Sprintf("%d", TotpDigits))
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(account), values.Encode())
+}
+
+// TotpCode computes the RFC 6238 code of the time step
+func TotpCode(secret string, step int64) (code string, err error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TotpDigits; i++ {
+		mod *= 10
+	}
+	code = fmt.Sprintf("%0*d", TotpDigits, value%mod)
+	return
+}
+
+// VerifyTotp accepts the code of the current time step or one step around it for clock drift,
+// steps not after lastStep are rejected so a code can be used only once
+func VerifyTotp(secret, code string, now time.Time, lastStep int64) (step int64, ok bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TotpDigits {
+		return
+	}
+	current := now.Unix() / TotpPeriod
+	for _, candidate := range []int64{current - 1, current, current + 1} {
+		if candidate <= lastStep {
+			continue
+		}
+		expected, err := TotpCode(secret, candidate)
+		if err != nil {
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return candidate, true
+		}
+	}
+	return
+}
@@ -0,0 +1,58 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+	// RFC 6238 SHA1 test vectors truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range vectors {
+		code, err := TotpCode(secret, ts/TotpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("code at %d is %s, expected %s", ts, code, expected)
+		}
+	}
+}
+
+func TestVerifyTotp(t *testing.T) {
+	secret, err := NewTotpSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current := now.Unix() / TotpPeriod
+	code, _ := TotpCode(secret, current)
+	step, ok := VerifyTotp(secret, code, now, 0)
+	if !ok || step != current {
+		t.Fatal("current code should be accepted")
+	}
+	if _, ok = VerifyTotp(secret, code, now, step); ok {
+		t.Error("used code should be rejected")
+	}
+	previous, _ := TotpCode(secret, current-1)
+	if _, ok = VerifyTotp(secret, previous, now, 0); !ok {
+		t.Error("code of the previous step should be accepted")
+	}
+	stale, _ := TotpCode(secret, current-3)
+	if _, ok = VerifyTotp(secret, stale, now, 0); ok {
+		t.Error("stale code should be rejected")
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+// Package identity talks to external identity providers, it knows nothing about the users and organizations of cloudland,
+// routes provisions them from the identities returned here
+package identity
+
+import (
+	"strings"
+)
+
+const (
+	SourceLocal = "local"
+	SourceLdap  = "ldap"
+	SourceOidc  = "oidc"
+)
+
+// Identity is a user authenticated by an external provider
+type Identity struct {
+	Username string
+	Email    string
+	Groups   []string
+	Source   string
+}
+
+// Mapping grants a role in an organization to the members of a group
+type Mapping struct {
+	Group string `mapstructure:"group"`
+	Org   string `mapstructure:"org"`
+	Role  string `mapstructure:"role"`
+}
+
+var roleRanks = map[string]int{
+	"reader": 1,
+	"writer": 2,
+	"owner":  3,
+	"admin":  4,
+}
+
+// MapGroups returns the highest role per organization granted by the groups, groups are compared case insensitively
+func MapGroups(groups []string, mappings []*Mapping) (roles map[string]string) {
+	roles = make(map[string]string)
+	for _, mapping := range mappings {
+		role := strings.ToLower(mapping.Role)
+		if _, ok := roleRanks[role]; !ok || mapping.Org == "" {
+			continue
+		}
+		for _, group := range groups {
+			if !strings.EqualFold(group, mapping.Group) {
+				continue
+			}
+			if roleRanks[role] > roleRanks[roles[mapping.Org]] {
+				roles[mapping.Org] = role
+			}
+		}
+	}
+	return
+}
+
+// ManagedOrgs returns the organizations whose membership is controlled by the mappings
+func ManagedOrgs(mappings []*Mapping) (orgs []string) {
+	orgSet := make(map[string]struct{})
+	for _, mapping := range mappings {
+		if _, ok := orgSet[mapping.Org]; ok || mapping.Org == "" {
+			continue
+		}
+		orgSet[mapping.Org] = struct{}{}
+		orgs = append(orgs, mapping.Org)
+	}
+	return
+}
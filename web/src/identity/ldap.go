@@ -0,0 +1,147 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package identity
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+type LdapConfig struct {
+	URL                string `mapstructure:"url"`
+	StartTLS           bool   `mapstructure:"start_tls"`
+	InsecureTLS        bool   `mapstructure:"insecure_tls"`
+	BindDN             string `mapstructure:"bind_dn"`
+	BindPassword       string `mapstructure:"bind_password"`
+	BaseDN             string `mapstructure:"base_dn"`
+	UserFilter         string `mapstructure:"user_filter"`          /* (uid=%s) by default */
+	UsernameAttribute  string `mapstructure:"username_attribute"`   /* uid by default */
+	EmailAttribute     string `mapstructure:"email_attribute"`      /* mail by default */
+	GroupAttribute     string `mapstructure:"group_attribute"`      /* memberOf by default */
+	GroupBaseDN        string `mapstructure:"group_base_dn"`        /* search groups here if set, besides the group attribute */
+	GroupFilter        string `mapstructure:"group_filter"`         /* (member=%s) by default, %s is the escaped user dn */
+	GroupNameAttribute string `mapstructure:"group_name_attribute"` /* cn by default */
+}
+
+func (cfg *LdapConfig) setDefaults() {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(member=%s)"
+	}
+	if cfg.GroupNameAttribute == "" {
+		cfg.GroupNameAttribute = "cn"
+	}
+}
+
+func (cfg *LdapConfig) dial() (conn *ldap.Conn, err error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureTLS}
+	conn, err = ldap.DialURL(cfg.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return
+	}
+	if cfg.StartTLS {
+		err = conn.StartTLS(tlsConfig)
+		if err != nil {
+			conn.Close()
+			return
+		}
+	}
+	return
+}
+
+// LdapAuthenticate finds the user with the service account, binds as the user to check the password
+// and collects the groups from the group attribute of the user and the groups under GroupBaseDN
+func LdapAuthenticate(cfg LdapConfig, username, password string) (ident *Identity, err error) {
+	cfg.setDefaults()
+	if username == "" || password == "" {
+		// An empty password is an unauthenticated bind which always succeeds
+		err = fmt.Errorf("Empty username or password")
+		return
+	}
+	conn, err := cfg.dial()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if cfg.BindDN != "" {
+		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+		if err != nil {
+			err = fmt.Errorf("Failed to bind service account, %v", err)
+			return
+		}
+	}
+	filter := fmt.Sprintf(cfg.UserFilter, ldap.EscapeFilter(username))
+	request := ldap.NewSearchRequest(cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		filter, []string{"dn", cfg.UsernameAttribute, cfg.EmailAttribute, cfg.GroupAttribute}, nil)
+	result, err := conn.Search(request)
+	if err != nil {
+		return
+	}
+	if len(result.Entries) != 1 {
+		err = fmt.Errorf("Found %d entries for user %s", len(result.Entries), username)
+		return
+	}
+	entry := result.Entries[0]
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		return
+	}
+	ident = &Identity{
+		Username: entry.GetAttributeValue(cfg.UsernameAttribute),
+		Email:    entry.GetAttributeValue(cfg.EmailAttribute),
+		Source:   SourceLdap,
+	}
+	if ident.Username == "" {
+		ident.Username = username
+	}
+	for _, groupDN := range entry.GetAttributeValues(cfg.GroupAttribute) {
+		ident.Groups = append(ident.Groups, groupDN, groupName(groupDN))
+	}
+	if cfg.GroupBaseDN != "" {
+		if cfg.BindDN != "" {
+			err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+			if err != nil {
+				return
+			}
+		}
+		filter = fmt.Sprintf(cfg.GroupFilter, ldap.EscapeFilter(entry.DN))
+		request = ldap.NewSearchRequest(cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 10, false,
+			filter, []string{"dn", cfg.GroupNameAttribute}, nil)
+		result, err = conn.Search(request)
+		if err != nil {
+			return
+		}
+		for _, group := range result.Entries {
+			ident.Groups = append(ident.Groups, group.DN, group.GetAttributeValue(cfg.GroupNameAttribute))
+		}
+	}
+	return
+}
+
+// groupName returns the first rdn value of a group dn, so mappings can use either "cn=dev,ou=groups,dc=example,dc=com" or "dev"
+func groupName(groupDN string) string {
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return strings.SplitN(strings.TrimPrefix(groupDN, "cn="), ",", 2)[0]
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,155 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type OidcConfig struct {
+	Issuer         string   `mapstructure:"issuer"`
+	ClientID       string   `mapstructure:"client_id"`
+	ClientSecret   string   `mapstructure:"client_secret"`
+	RedirectURL    string   `mapstructure:"redirect_url"`
+	Scopes         []string `mapstructure:"scopes"`          /* openid profile email by default */
+	UsernameClaim  string   `mapstructure:"username_claim"`  /* preferred_username by default */
+	GroupsClaim    string   `mapstructure:"groups_claim"`    /* groups by default */
+	ExchangeClient []string `mapstructure:"exchange_client"` /* other audiences accepted for token exchange */
+}
+
+type OidcProvider struct {
+	cfg      OidcConfig
+	provider *oidc.Provider
+	oauth    *oauth2.Config
+}
+
+// NewOidcProvider fetches the discovery document of the issuer
+func NewOidcProvider(ctx context.Context, cfg OidcConfig) (p *OidcProvider, err error) {
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return
+	}
+	p = &OidcProvider{
+		cfg:      cfg,
+		provider: provider,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+	}
+	return
+}
+
+// NewState returns a random value for the state and the nonce of an authorization request
+func NewState() (state string, err error) {
+	buf := make([]byte, 16)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return
+	}
+	state = hex.EncodeToString(buf)
+	return
+}
+
+func (p *OidcProvider) AuthCodeURL(state, nonce string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+// Exchange redeems the authorization code and returns the identity in the id token
+func (p *OidcProvider) Exchange(ctx context.Context, code, nonce string) (ident *Identity, err error) {
+	token, err := p.oauth.Exchange(ctx, code)
+	if err != nil {
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		err = fmt.Errorf("No id_token in token response")
+		return
+	}
+	verifier := p.provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return
+	}
+	if idToken.Nonce != nonce {
+		err = fmt.Errorf("Invalid nonce in id_token")
+		return
+	}
+	ident, err = p.identity(idToken)
+	return
+}
+
+// VerifyToken checks an id token obtained by a client itself, for the token exchange of the REST API
+func (p *OidcProvider) VerifyToken(ctx context.Context, rawIDToken string) (ident *Identity, err error) {
+	verifier := p.provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return
+	}
+	allowed := false
+	for _, aud := range idToken.Audience {
+		if aud == p.cfg.ClientID {
+			allowed = true
+		}
+		for _, client := range p.cfg.ExchangeClient {
+			if aud == client {
+				allowed = true
+			}
+		}
+	}
+	if !allowed {
+		err = fmt.Errorf("Audience %v of id_token is not accepted", idToken.Audience)
+		return
+	}
+	ident, err = p.identity(idToken)
+	return
+}
+
+func (p *OidcProvider) identity(idToken *oidc.IDToken) (ident *Identity, err error) {
+	claims := map[string]interface{}{}
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return
+	}
+	ident = &Identity{Source: SourceOidc}
+	ident.Username, _ = claims[p.cfg.UsernameClaim].(string)
+	if ident.Username == "" {
+		err = fmt.Errorf("No %s claim in id_token", p.cfg.UsernameClaim)
+		return
+	}
+	ident.Email, _ = claims["email"].(string)
+	switch groups := claims[p.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				ident.Groups = append(ident.Groups, name)
+			}
+		}
+	case string:
+		ident.Groups = append(ident.Groups, groups)
+	}
+	return
+}
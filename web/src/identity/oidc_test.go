@@ -0,0 +1,167 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// standInIdP is a minimal oidc provider serving discovery, keys and a token endpoint which returns the id token of the code
+type standInIdP struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	codes map[string]string
+}
+
+func newStandInIdP(t *testing.T) (idp *standInIdP) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp = &standInIdP{key: key, codes: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/auth",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idToken, ok := idp.codes[r.Form.Get("code")]
+		if !ok {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	return
+}
+
+func (idp *standInIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	now := time.Now()
+	claims["iss"] = idp.URL
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOidcExchange(t *testing.T) {
+	idp := newStandInIdP(t)
+	defer idp.Close()
+	ctx := context.Background()
+	p, err := NewOidcProvider(ctx, OidcConfig{Issuer: idp.URL, ClientID: "cloudland", ClientSecret: "secret", RedirectURL: "https://cloudland/login/oidc/callback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.codes["good"] = idp.sign(t, jwt.MapClaims{"aud": "cloudland", "sub": "1", "nonce": "n1", "preferred_username": "alice", "groups": []string{"dev", "ops"}})
+	ident, err := p.Exchange(ctx, "good", "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ident.Username != "alice" || len(ident.Groups) != 2 || ident.Source != SourceOidc {
+		t.Fatal(ident)
+	}
+	if _, err = p.Exchange(ctx, "good", "n2"); err == nil {
+		t.Fatal("nonce mismatch accepted")
+	}
+	if _, err = p.Exchange(ctx, "bad", "n1"); err == nil {
+		t.Fatal("unknown code accepted")
+	}
+}
+
+func TestOidcVerifyToken(t *testing.T) {
+	idp := newStandInIdP(t)
+	defer idp.Close()
+	ctx := context.Background()
+	p, err := NewOidcProvider(ctx, OidcConfig{Issuer: idp.URL, ClientID: "cloudland", ExchangeClient: []string{"ci"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []struct {
+		aud string
+		ok  bool
+	}{
+		{"cloudland", true},
+		{"ci", true},
+		{"other", false},
+	}
+	for _, f := range fixtures {
+		raw := idp.sign(t, jwt.MapClaims{"aud": f.aud, "sub": "2", "preferred_username": "bob"})
+		ident, err := p.VerifyToken(ctx, raw)
+		if (err == nil) != f.ok {
+			t.Fatal(f.aud, err)
+		}
+		if f.ok && ident.Username != "bob" {
+			t.Fatal(ident)
+		}
+	}
+	raw := idp.sign(t, jwt.MapClaims{"aud": "cloudland", "sub": "3"})
+	if _, err = p.VerifyToken(ctx, raw); err == nil {
+		t.Fatal("token without username accepted")
+	}
+}
+
+func TestMapGroups(t *testing.T) {
+	mappings := []*Mapping{
+		{Group: "dev", Org: "engineering", Role: "writer"},
+		{Group: "leads", Org: "engineering", Role: "owner"},
+		{Group: "ops", Org: "infra", Role: "reader"},
+		{Group: "ops", Org: "broken", Role: "root"},
+	}
+	roles := MapGroups([]string{"DEV", "leads"}, mappings)
+	if len(roles) != 1 || roles["engineering"] != "owner" {
+		t.Fatal(roles)
+	}
+	roles = MapGroups([]string{"ops"}, mappings)
+	if len(roles) != 1 || roles["infra"] != "reader" {
+		t.Fatal(roles)
+	}
+	if orgs := ManagedOrgs(mappings); len(orgs) != 3 {
+		t.Fatal(orgs)
+	}
+}
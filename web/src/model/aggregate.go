@@ -0,0 +1,25 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// Aggregate is a named set of hypervisors with key value metadata kept as tags of the aggregate,
+// flavors, images and organizations place guests on them with their aggregate rules
+type Aggregate struct {
+	Model
+	Name     string   `gorm:"type:varchar(64);index"`
+	Remark   string   `gorm:"type:varchar(512);default:''"`
+	Isolated bool     /* the hypervisors only take guests whose rules require some metadata of the aggregate */
+	Hypers   []*Hyper `gorm:"many2many:aggregate_hypers;"`
+}
+
+func init() {
+	dbs.AutoMigrate(&Aggregate{})
+}
@@ -0,0 +1,31 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"time"
+
+	"web/src/dbs"
+)
+
+type AppCredential struct {
+	Model
+	Owner      int64  `gorm:"default:1"` /* The organization ID of the resource */
+	Name       string `gorm:"type:varchar(64)"`
+	UserID     int64  `gorm:"index"`
+	User       *User  `gorm:"foreignkey:UserID"`
+	KeyID      string `gorm:"type:varchar(32);unique_index"`
+	SecretHash string `gorm:"type:varchar(64)"` /* The sha256 of the secret, the secret itself is only shown at creation */
+	Role       Role   /* The ceiling of the role, the role of the member is used if it is lower */
+	Scopes     string `gorm:"type:varchar(1024)"` /* Comma separated API path prefixes or resource types, empty for all */
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func init() {
+	dbs.AutoMigrate(&AppCredential{})
+}
@@ -7,9 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package model
 
 import (
+	"time"
+
 	"web/src/dbs"
 )
 
+const (
+	ConsoleTypeVnc    = "vnc"
+	ConsoleTypeSerial = "serial"
+)
+
 type Console struct {
 	Model
 	Owner      int64 `gorm:"default:1"` /* The organization ID of the resource */
@@ -18,6 +25,29 @@ type Console struct {
 	Type       string
 }
 
+// ConsoleLog caches the tail of the serial log of an instance as the hypervisor last reported it
+type ConsoleLog struct {
+	Model
+	InstanceID int64  `gorm:"unique_index"`
+	Lines      int32  /* lines asked for, the output may have fewer */
+	Output     string `gorm:"type:text"`
+}
+
+// ConsoleSession records who used a serial console of an instance, from where and for how long
+type ConsoleSession struct {
+	Model
+	Owner        int64 `gorm:"default:1"` /* The organization ID of the resource */
+	InstanceID   int64 `gorm:"index"`
+	UserID       int64
+	Type         string `gorm:"type:varchar(16)"`
+	RemoteAddr   string `gorm:"type:varchar(64)"`
+	LocalAddress string `gorm:"type:varchar(64)"` /* where the hypervisor exposes the console */
+	LocalPort    int32
+	BytesIn      int64 /* keystrokes from the user */
+	BytesOut     int64 /* output of the guest */
+	EndedAt      *time.Time
+}
+
 func init() {
-	dbs.AutoMigrate(&Console{})
+	dbs.AutoMigrate(&Console{}, &ConsoleLog{}, &ConsoleSession{})
 }
@@ -0,0 +1,41 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+const (
+	DnsRecordA     = "A"
+	DnsRecordPTR   = "PTR"
+	DnsRecordCNAME = "CNAME"
+	DnsRecordTXT   = "TXT"
+)
+
+type DnsZone struct {
+	Model
+	Owner    int64        `gorm:"default:1"` /* The organization ID of the resource */
+	Name     string       `gorm:"unique_index:idx_router_zone;type:varchar(128)"`
+	RouterID int64        `gorm:"unique_index:idx_router_zone"`
+	Router   *Router      `gorm:"foreignkey:RouterID"`
+	Records  []*DnsRecord `gorm:"foreignkey:ZoneID"`
+}
+
+type DnsRecord struct {
+	Model
+	Owner      int64  `gorm:"default:1"` /* The organization ID of the resource */
+	ZoneID     int64  `gorm:"index"`
+	Name       string `gorm:"type:varchar(255)"`
+	Type       string `gorm:"type:varchar(16)"`
+	Value      string `gorm:"type:varchar(255)"`
+	InstanceID int64  `gorm:"index"` /* Set for the records created for instances automatically */
+}
+
+func init() {
+	dbs.AutoMigrate(&DnsZone{}, &DnsRecord{})
+}
@@ -19,6 +19,34 @@ type Flavor struct {
 	Disk      int32
 	Swap      int32
 	Ephemeral int32
+	ExtraSpecs
+}
+
+const (
+	CpuPolicyShared    = "shared"
+	CpuPolicyDedicated = "dedicated"
+)
+
+// ExtraSpecs tune the guest beyond its size, flavors keep them and instances get a copy at creation,
+// launch_vm.sh and resize_vm.sh apply them to the domain xml
+type ExtraSpecs struct {
+	CpuPolicy    string `gorm:"type:varchar(16);default:''" json:"cpu_policy,omitempty"`    /* shared or dedicated, dedicated pins every vcpu to a host cpu */
+	NumaNodes    int32  `gorm:"default:0" json:"numa_nodes,omitempty"`                      /* guest numa nodes, cpu and memory are split evenly */
+	HugepageSize string `gorm:"type:varchar(8);default:''" json:"hugepage_size,omitempty"`  /* 2M or 1G */
+	CpuModel     string `gorm:"type:varchar(64);default:''" json:"cpu_model,omitempty"`     /* host-model by default, host-passthrough or a libvirt cpu model */
+	CpuFeatures  string `gorm:"type:varchar(512);default:''" json:"cpu_features,omitempty"` /* comma separated cpu flags to require, -flag to disable */
+	Watchdog     string `gorm:"type:varchar(16);default:''" json:"watchdog,omitempty"`      /* watchdog action, reset, poweroff, pause or none */
+	VirtioQueues int32  `gorm:"default:0" json:"virtio_queues,omitempty"`                   /* queues per virtio nic, 0 for half of the vcpus */
+	MaxCpu       int32  `gorm:"default:0" json:"max_cpu,omitempty"`                         /* vcpus the guest can hot-add up to, 0 for none */
+	MaxMemory    int32  `gorm:"default:0" json:"max_memory,omitempty"`                      /* memory in MB the guest can hot-add up to, 0 for none */
+
+	// AggregateRules place the guest rather than tune it, comma separated key=value aggregate metadata
+	// the hypervisor must have, !key=value for metadata it must not have
+	AggregateRules string `gorm:"type:varchar(512);default:''" json:"aggregate_rules,omitempty"`
+}
+
+func (specs *ExtraSpecs) IsEmpty() bool {
+	return specs == nil || *specs == (ExtraSpecs{})
 }
 
 func init() {
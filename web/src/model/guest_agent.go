@@ -0,0 +1,39 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+const (
+	GuestCommandTypeExec = "exec"
+	GuestCommandTypeFile = "file"
+)
+
+const (
+	GuestCommandStatusRunning = "running"
+	GuestCommandStatusDone    = "done"
+	GuestCommandStatusError   = "error"
+	GuestCommandStatusTimeout = "timeout"
+)
+
+// GuestCommand records a command run or a file written inside an instance through its guest agent
+type GuestCommand struct {
+	Model
+	Owner      int64  `gorm:"default:1"` /* The organization ID of the resource */
+	InstanceID int64  `gorm:"index"`
+	Type       string `gorm:"type:varchar(16)"`
+	Command    string `gorm:"type:text"` /* the command line, or the path of the file */
+	Status     string `gorm:"type:varchar(16)"`
+	ExitCode   int32
+	Output     string `gorm:"type:text"`
+}
+
+func init() {
+	dbs.AutoMigrate(&GuestCommand{})
+}
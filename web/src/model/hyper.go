@@ -35,6 +35,12 @@ type Hyper struct {
 	RouteIP      string
 	VirtType     string
 	CpuModel     string
+	CpuModels    string  `gorm:"type:text"` /* libvirt cpu models usable on the host, comma separated */
+	CpuFlags     string  `gorm:"type:text"` /* host cpu flags, comma separated */
+	NumaNodes    int32   `gorm:"default:1"`
+	FreePinCpus  int32   /* host cpus of dedicated_cpu_set not pinned yet */
+	Hugepages2M  int64   `gorm:"column:hugepages_2m"` /* free 2M hugepages */
+	Hugepages1G  int64   `gorm:"column:hugepages_1g"` /* free 1G hugepages */
 	CpuOverRate  float32 `gorm:"default:1.0"`
 	MemOverRate  float32 `gorm:"default:1.0"`
 	DiskOverRate float32 `gorm:"default:1.0"`
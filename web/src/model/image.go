@@ -58,6 +58,7 @@ type Image struct {
 	RescueImage           int64     `gorm:"default:0"`
 	StorageType           string    `gorm:"type:varchar(36);"`
 	OsFamily              string    `gorm:"type:varchar(128);"`
+	AggregateRules        string    `gorm:"type:varchar(512);default:''"` /* aggregate metadata the hypervisors must or must not have as in flavors */
 }
 
 type ImageStorage struct {
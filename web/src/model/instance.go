@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package model
 
 import (
+	"time"
+
 	"web/src/dbs"
 )
 
@@ -32,6 +34,12 @@ func (s InstanceStatus) String() string {
 	return string(s)
 }
 
+// resize modes, online hot-adds to the running guest and offline restarts it with the new size
+const (
+	ResizeModeOffline = "offline"
+	ResizeModeOnline  = "online"
+)
+
 // UserDataType 用户数据类型常量
 const (
 	UserDataTypePlain  = "plain"
@@ -78,6 +86,15 @@ type Instance struct {
 	Zone           *Zone `gorm:"foreignkey:ZoneID"`
 	RouterID       int64 `gorm:"unique_index:idx_router_instance"`
 	Router         *Router
+	ExtraSpecs
+	GuestInfo
+}
+
+// GuestInfo is what the guest agent last reported from inside the instance
+type GuestInfo struct {
+	GuestOS     string     `gorm:"type:varchar(128)"`
+	GuestIps    string     `gorm:"type:varchar(512)"` /* comma separated addresses seen by the guest */
+	GuestInfoAt *time.Time /* when the agent last answered */
 }
 
 func init() {
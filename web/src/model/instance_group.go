@@ -0,0 +1,62 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"time"
+
+	"web/src/dbs"
+)
+
+// InstanceGroup keeps DesiredCount instances of a launch template, the members are registered
+// as backends of Listener on BackendPort when a listener is chosen
+type InstanceGroup struct {
+	Model
+	Owner           int64           `gorm:"unique_index:idx_account_instance_group;default:1"` /* The organization ID of the resource */
+	Name            string          `gorm:"unique_index:idx_account_instance_group;type:varchar(64)"`
+	TemplateID      int64           `gorm:"index"`
+	Template        *LaunchTemplate `gorm:"foreignkey:TemplateID"`
+	TemplateVersion int32           /* 0 follows the default version of the template */
+	MinCount        int32
+	MaxCount        int32
+	DesiredCount    int32
+	ListenerID      int64
+	Listener        *Listener `gorm:"foreignkey:ListenerID"`
+	BackendPort     int32
+	Status          string                 `gorm:"type:varchar(32)"`
+	Reason          string                 `gorm:"type:varchar(512)"`
+	Members         []*InstanceGroupMember `gorm:"foreignkey:GroupID"`
+	ReconcileUntil  *time.Time             /* Lease of the API node reconciling the group */
+}
+
+// InstanceGroupMember is an instance created for a group and its backend in the listener of the group
+type InstanceGroupMember struct {
+	Model
+	Owner      int64     `gorm:"default:1;index"` /* The organization ID of the resource */
+	GroupID    int64     `gorm:"index"`
+	InstanceID int64     `gorm:"unique_index"`
+	Instance   *Instance `gorm:"foreignkey:InstanceID"`
+	BackendID  int64
+}
+
+// ScalingPolicy changes the desired count of a group by Adjustment when an alert carrying
+// its UUID and token fires, at most once per Cooldown seconds
+type ScalingPolicy struct {
+	Model
+	Owner         int64  `gorm:"default:1;index"` /* The organization ID of the resource */
+	GroupID       int64  `gorm:"index"`
+	Name          string `gorm:"type:varchar(64)"`
+	Action        string `gorm:"type:varchar(32)"`
+	Adjustment    int32
+	Cooldown      int32
+	Token         string `gorm:"type:varchar(64)" json:"-"`
+	LastTriggered *time.Time
+}
+
+func init() {
+	dbs.AutoMigrate(&InstanceGroup{}, &InstanceGroupMember{}, &ScalingPolicy{})
+}
@@ -0,0 +1,35 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// LaunchTemplate is a named instance create payload, every change is kept as a new version
+type LaunchTemplate struct {
+	Model
+	Owner          int64  `gorm:"unique_index:idx_account_launch_template;default:1"` /* The organization ID of the resource */
+	Name           string `gorm:"unique_index:idx_account_launch_template;type:varchar(64)"`
+	Description    string `gorm:"type:varchar(256)"`
+	DefaultVersion int32
+	LatestVersion  int32
+}
+
+// LaunchTemplateVersion is an immutable version of a launch template, Payload is the instance payload in JSON
+type LaunchTemplateVersion struct {
+	Model
+	Owner       int64  `gorm:"default:1;index"` /* The organization ID of the resource */
+	TemplateID  int64  `gorm:"unique_index:idx_launch_template_version"`
+	Version     int32  `gorm:"unique_index:idx_launch_template_version"`
+	Description string `gorm:"type:varchar(256)"`
+	Payload     string `gorm:"type:text"`
+}
+
+func init() {
+	dbs.AutoMigrate(&LaunchTemplate{}, &LaunchTemplateVersion{})
+}
@@ -0,0 +1,23 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// MfaRecoveryCode is a one time code to login without the authenticator, only its hash is kept
+type MfaRecoveryCode struct {
+	Model
+	UserID int64  `gorm:"index"`
+	Hash   string `gorm:"type:varchar(64)"`
+	Used   bool
+}
+
+func init() {
+	dbs.AutoMigrate(&MfaRecoveryCode{})
+}
@@ -0,0 +1,31 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+type NatGateway struct {
+	Model
+	Owner        int64   `gorm:"default:1"` /* The organization ID of the resource */
+	Name         string  `gorm:"type:varchar(64)"`
+	Status       string  `gorm:"type:varchar(32)"`
+	RouterID     int64   `gorm:"index"`
+	Router       *Router `gorm:"foreignkey:RouterID"`
+	FloatingIpID int64
+	FloatingIp   *FloatingIp `gorm:"foreignkey:FloatingIpID"`
+	Hyper        int32       `gorm:"default:-1"` /* The hypervisor hosting the public address */
+	Inbound      int32
+	Outbound     int32
+	AllSubnets   bool         `gorm:"default:false"`
+	Interfaces   []*Interface `gorm:"-"` /* One interface per translated subnet, the next hop of the subnet */
+}
+
+func init() {
+	dbs.AutoMigrate(&NatGateway{})
+}
@@ -56,11 +56,14 @@ func (r Role) String() string {
 
 type Organization struct {
 	Model
-	Owner     int64     `gorm:"default:1"` /* The organization ID of the resource */
-	Name      string    `gorm:"size:255;unique_index" json:"name,omitempty"`
-	Members   []*Member `gorm:"foreignkey:OrgID"`
-	OwnerUser *User     `gorm:"foreignkey:ID";AssociationForeignKey:Owner`
-	DefaultSG int64
+	Owner      int64     `gorm:"default:1"` /* The organization ID of the resource */
+	Name       string    `gorm:"size:255;unique_index" json:"name,omitempty"`
+	Members    []*Member `gorm:"foreignkey:OrgID"`
+	OwnerUser  *User     `gorm:"foreignkey:ID";AssociationForeignKey:Owner`
+	DefaultSG  int64
+	RequireMfa bool /* Members must login with a second factor to use the organization */
+	// AggregateRules keep the guests of the organization on or off hypervisors by aggregate metadata as in flavors
+	AggregateRules string `gorm:"type:varchar(512);default:''"`
 }
 
 func (Organization) TableName() string {
@@ -0,0 +1,22 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// PasswordHistory keeps the hashes of previous passwords so that they can not be reused
+type PasswordHistory struct {
+	Model
+	UserID int64  `gorm:"index"`
+	Hash   string `gorm:"size:255"`
+}
+
+func init() {
+	dbs.AutoMigrate(&PasswordHistory{})
+}
@@ -0,0 +1,34 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// CustomRole is a named set of actions like instance:reboot within an organization
+type CustomRole struct {
+	Model
+	Owner       int64  `gorm:"default:1;index"` /* The organization ID of the resource */
+	Name        string `gorm:"type:varchar(64)"`
+	Description string `gorm:"type:varchar(255)"`
+	Actions     string `gorm:"type:text"` /* Comma separated actions, resource:verb with * as wildcard */
+}
+
+// RoleBinding grants the actions of a custom role to a user in the organization of the role
+type RoleBinding struct {
+	Model
+	Owner  int64       `gorm:"default:1;index"` /* The organization ID of the resource */
+	RoleID int64       `gorm:"index"`
+	Role   *CustomRole `gorm:"foreignkey:RoleID"`
+	UserID int64       `gorm:"index"`
+	User   *User       `gorm:"foreignkey:UserID"`
+}
+
+func init() {
+	dbs.AutoMigrate(&CustomRole{}, &RoleBinding{})
+}
@@ -22,6 +22,7 @@ type Portmap struct {
 	RouterID      int64
 	Router        *Router `gorm:"foreignkey:RouterID"`
 	InstanceID    int64
+	Instance      *Instance `gorm:"foreignkey:InstanceID"`
 }
 
 func init() {
@@ -27,6 +27,7 @@ type Quota struct {
 	Secrule   int32
 	Instance  int32
 	Openshift int32
+	Portmap   int32
 }
 
 func init() {
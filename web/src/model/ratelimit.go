@@ -0,0 +1,26 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// RateBucket is a token bucket of the API rate limit shared by all nodes when ratelimit.shared is set
+type RateBucket struct {
+	Key    string  `gorm:"type:varchar(128);primary_key;not null"`
+	Tokens float64 `gorm:"not null"`
+	Stamp  int64   `gorm:"not null"` /* Unix nano time of the last refill */
+}
+
+func (RateBucket) TableName() string {
+	return "rate_buckets"
+}
+
+func init() {
+	dbs.AutoMigrate(&RateBucket{})
+}
@@ -23,6 +23,10 @@ type Resource struct {
 	MemoryTotal int64
 	Disk        int64
 	DiskTotal   int64
+	/* over-commit rates the hypervisor reported the totals with, they lag behind the rates of the hyper after an update */
+	CpuOverRate  float32
+	MemOverRate  float32
+	DiskOverRate float32
 }
 
 func init() {
@@ -0,0 +1,42 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+const (
+	RouteNexthopInstance  = "instance"
+	RouteNexthopInterface = "interface"
+	RouteNexthopIP        = "ip"
+	RouteNexthopVPC       = "vpc"
+)
+
+type RouteTable struct {
+	Model
+	Owner    int64         `gorm:"default:1"` /* The organization ID of the resource */
+	Name     string        `gorm:"type:varchar(64)"`
+	RouterID int64         `gorm:"index"`
+	Router   *Router       `gorm:"foreignkey:RouterID"`
+	Entries  []*RouteEntry `gorm:"foreignkey:RouteTableID"`
+	Subnets  []*Subnet     `gorm:"foreignkey:RouteTableID"`
+}
+
+type RouteEntry struct {
+	Model
+	Owner        int64  `gorm:"default:1"` /* The organization ID of the resource */
+	RouteTableID int64  `gorm:"index"`
+	Destination  string `gorm:"type:varchar(64)"`
+	NexthopType  string `gorm:"type:varchar(32)"`
+	NexthopID    int64  /* ID of the instance, interface or router when the next hop is not an IP */
+	Nexthop      string `gorm:"type:varchar(64)"` /* Resolved next hop address */
+}
+
+func init() {
+	dbs.AutoMigrate(&RouteTable{}, &RouteEntry{})
+}
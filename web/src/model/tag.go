@@ -0,0 +1,25 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+// Tag is a key value label of a resource, ResourceType is the table of the resource
+type Tag struct {
+	Model
+	Owner        int64  `gorm:"default:1;index"` /* The organization ID of the resource */
+	ResourceType string `gorm:"type:varchar(32);unique_index:idx_resource_tag"`
+	ResourceID   int64  `gorm:"unique_index:idx_resource_tag"`
+	Key          string `gorm:"type:varchar(128);unique_index:idx_resource_tag"`
+	Value        string `gorm:"type:varchar(256)"`
+}
+
+func init() {
+	dbs.AutoMigrate(&Tag{})
+}
@@ -0,0 +1,34 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"time"
+
+	"web/src/dbs"
+)
+
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+)
+
+type Token struct {
+	Model
+	Jti       string `gorm:"type:varchar(64);unique_index"`
+	Kind      string `gorm:"type:varchar(16)"`
+	Family    string `gorm:"type:varchar(64);index"` /* The jti of the access token issued at login, shared by all rotated tokens */
+	UserID    int64  `gorm:"index"`
+	OrgID     int64  `gorm:"index"`
+	Hash      string `gorm:"type:varchar(64);index"` /* The sha256 of a refresh token, the token itself is never saved */
+	ExpiresAt time.Time
+	Revoked   bool `gorm:"default:false"`
+}
+
+func init() {
+	dbs.AutoMigrate(&Token{})
+}
@@ -13,15 +13,24 @@ History:
 package model
 
 import (
+	"time"
+
 	"web/src/dbs"
 )
 
 type User struct {
 	Model
-	Owner    int64     `gorm:"default:1"` /* The organization ID of the resource */
-	Username string    `gorm:"size:255;unique_index" json:"username,omitempty"`
-	Password string    `gorm:"size:255" json:"password,omitempty"`
-	Members  []*Member `gorm:"foreignkey:UserID"`
+	Owner             int64      `gorm:"default:1"` /* The organization ID of the resource */
+	Username          string     `gorm:"size:255;unique_index" json:"username,omitempty"`
+	Password          string     `gorm:"size:255" json:"password,omitempty"`
+	Source            string     `gorm:"type:varchar(16);default:'local'"` /* local, ldap or oidc */
+	MfaSecret         string     `gorm:"type:varchar(64)" json:"-"`        /* Base32 TOTP secret, pending until MfaEnabled */
+	MfaEnabled        bool       `json:"-"`
+	MfaLastStep       int64      `json:"-"` /* The last accepted TOTP time step, a code is only accepted once */
+	FailedLogins      int        `json:"-"` /* Consecutive failed logins, reset by a successful login or unlock */
+	LockedUntil       *time.Time `json:"-"`
+	PasswordChangedAt *time.Time `json:"-"`
+	Members           []*Member  `gorm:"foreignkey:UserID"`
 }
 
 func (User) TableName() string {
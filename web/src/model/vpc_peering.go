@@ -0,0 +1,33 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"web/src/dbs"
+)
+
+const (
+	VpcPeeringStatusPending  = "pending"
+	VpcPeeringStatusActive   = "active"
+	VpcPeeringStatusRejected = "rejected"
+)
+
+type VpcPeering struct {
+	Model
+	Owner        int64   `gorm:"default:1"` /* The organization ID of the requester */
+	Name         string  `gorm:"type:varchar(64)"`
+	Status       string  `gorm:"type:varchar(32)"`
+	RouterID     int64   `gorm:"index"`
+	Router       *Router `gorm:"foreignkey:RouterID"`
+	PeerOwner    int64   `gorm:"index"` /* The organization ID of the accepter */
+	PeerRouterID int64   `gorm:"index"`
+	PeerRouter   *Router `gorm:"foreignkey:PeerRouterID"`
+}
+
+func init() {
+	dbs.AutoMigrate(&VpcPeering{})
+}
@@ -54,13 +54,19 @@ func (a *AddressAdmin) Update(ctx context.Context, addr *model.Address) (err err
 	return
 }
 
-func (a *AddressAdmin) ListBySubnetID(ctx context.Context, subnetID int64) (addresses []*model.Address, err error) {
+// List returns a page of the addresses of a subnet, a negative limit returns all of them
+func (a *AddressAdmin) List(ctx context.Context, offset, limit int64, order string, subnetID int64) (total int64, addresses []*model.Address, err error) {
 	ctx, db := GetContextDB(ctx)
+	if err = ListFilter(ctx, db.Model(&model.Address{})).Where("subnet_id = ?", subnetID).Count(&total).Error; err != nil {
+		logger.Error("Failed to count addresses for subnet, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count addresses", err)
+		return
+	}
 	addresses = []*model.Address{}
-	err = db.Preload("Subnet").Where("subnet_id = ?", subnetID).Order("id").Find(&addresses).Error
-	if err != nil {
+	if err = ListPage(ctx, db, &model.Address{}, offset, limit, order).Preload("Subnet").Where("subnet_id = ?", subnetID).Find(&addresses).Error; err != nil {
 		logger.Error("Failed to query addresses for subnet, %v", err)
-		return nil, NewCLError(ErrDatabaseError, "Failed to list addresses", err)
+		err = NewCLError(ErrDatabaseError, "Failed to list addresses", err)
+		return
 	}
 	return
 }
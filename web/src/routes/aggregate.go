@@ -0,0 +1,280 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+const maxAggregateRules = 16
+
+var (
+	aggregateAdmin = &AggregateAdmin{}
+
+	aggregateRuleRegexp = regexp.MustCompile(`^!?[^=,!\s][^=,\s]*=[^,\s]*$`)
+)
+
+type AggregateAdmin struct{}
+
+// CheckRules validates comma separated aggregate rules, key=value to require and !key=value to forbid
+func (a *AggregateAdmin) CheckRules(rules string) (err error) {
+	list := AggregateRules(rules)
+	if len(list) > maxAggregateRules {
+		return NewCLError(ErrInvalidAggregateRules, fmt.Sprintf("At most %d aggregate rules are allowed", maxAggregateRules), nil)
+	}
+	for _, rule := range list {
+		if len(rule) > maxTagKeyLength+maxTagValueLength || !aggregateRuleRegexp.MatchString(rule) {
+			return NewCLError(ErrInvalidAggregateRules, fmt.Sprintf("Invalid aggregate rule %s, it must be key=value or !key=value", rule), nil)
+		}
+	}
+	return
+}
+
+// checkMetadata keeps the metadata matchable by rules, the tag limits apply as well
+func (a *AggregateAdmin) checkMetadata(metadata map[string]string) (err error) {
+	for key, value := range metadata {
+		if strings.ContainsAny(key, "=,! \t") || strings.ContainsAny(value, ", \t") {
+			return NewCLError(ErrInvalidAggregate, fmt.Sprintf("Aggregate metadata %s=%s must not contain commas or spaces, nor = and ! in keys", key, value), nil)
+		}
+	}
+	return
+}
+
+// Rules gathers the aggregate rules for a guest of the organization with the flavor specs and image
+func (a *AggregateAdmin) Rules(ctx context.Context, specs *model.ExtraSpecs, imageID, orgID int64) (rules []string, err error) {
+	ctx, db := GetContextDB(ctx)
+	org := &model.Organization{Model: model.Model{ID: orgID}}
+	if err = db.Take(org).Error; err != nil {
+		logger.Error("Failed to query organization", err)
+		err = NewCLError(ErrOrgNotFound, "Failed to find the organization", err)
+		return
+	}
+	lists := []string{org.AggregateRules}
+	if specs != nil {
+		lists = append(lists, specs.AggregateRules)
+	}
+	// the image may be gone by the time its guests migrate
+	image := &model.Image{Model: model.Model{ID: imageID}}
+	if err = db.Unscoped().Take(image).Error; err != nil {
+		logger.Error("Failed to query image", err)
+		err = NewCLError(ErrImageNotFound, "Failed to find the image", err)
+		return
+	}
+	lists = append(lists, image.AggregateRules)
+	rules = AggregateRules(lists...)
+	return
+}
+
+// SetRules replaces the aggregate rules of an image or organization, only admins place guests
+func (a *AggregateAdmin) SetRules(ctx context.Context, resource interface{}, rules []string) (joined string, err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	joined = strings.Join(AggregateRules(rules...), ",")
+	if err = a.CheckRules(joined); err != nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	if err = db.Model(resource).Update("aggregate_rules", joined).Error; err != nil {
+		logger.Error("DB failed to update aggregate rules", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update aggregate rules", err)
+		return
+	}
+	return
+}
+
+func (a *AggregateAdmin) checkAdmin(ctx context.Context) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		logger.Error("Not authorized for this operation")
+		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
+	}
+	return
+}
+
+func (a *AggregateAdmin) List(ctx context.Context, offset, limit int64, order, query string) (total int64, aggregates []*model.Aggregate, err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+	if order == "" {
+		order = "name"
+	}
+	if query != "" {
+		db = db.Where("name like ?", "%"+query+"%")
+	}
+	aggregates = []*model.Aggregate{}
+	if err = ListFilter(ctx, db.Model(&model.Aggregate{})).Count(&total).Error; err != nil {
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count aggregates", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.Aggregate{}, offset, limit, order).Preload("Hypers").Find(&aggregates).Error; err != nil {
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query aggregates", err)
+		return
+	}
+	return
+}
+
+func (a *AggregateAdmin) GetAggregateByUUID(ctx context.Context, uuID string) (aggregate *model.Aggregate, err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	aggregate = &model.Aggregate{}
+	err = db.Preload("Hypers").Where("uuid = ?", uuID).Take(aggregate).Error
+	if err != nil {
+		logger.Error("Failed to query aggregate", err)
+		err = NewCLError(ErrAggregateNotFound, "Aggregate not found", err)
+		return
+	}
+	return
+}
+
+// Metadata returns the metadata of the aggregate
+func (a *AggregateAdmin) Metadata(ctx context.Context, aggregate *model.Aggregate) (metadata map[string]string, err error) {
+	return tagAdmin.Tags(ctx, "aggregates", aggregate.ID)
+}
+
+func (a *AggregateAdmin) getHypers(ctx context.Context, hostids []int32) (hypers []*model.Hyper, err error) {
+	ctx, db := GetContextDB(ctx)
+	hypers = []*model.Hyper{}
+	if len(hostids) == 0 {
+		return
+	}
+	if err = db.Where("hostid in (?)", hostids).Find(&hypers).Error; err != nil {
+		logger.Error("Failed to query hypervisors", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query hypervisors", err)
+		return
+	}
+	if len(hypers) != len(hostids) {
+		err = NewCLError(ErrHypervisorNotFound, "Some of the hypervisors are not found", nil)
+		return
+	}
+	return
+}
+
+func (a *AggregateAdmin) Create(ctx context.Context, name, remark string, isolated bool, metadata map[string]string, hostids []int32) (aggregate *model.Aggregate, err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	if err = a.checkMetadata(metadata); err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	count := 0
+	if err = db.Model(&model.Aggregate{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		logger.Error("Failed to count aggregates", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count aggregates", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrInvalidAggregate, fmt.Sprintf("Aggregate %s already exists", name), nil)
+		return
+	}
+	hypers, err := a.getHypers(ctx, hostids)
+	if err != nil {
+		return
+	}
+	aggregate = &model.Aggregate{
+		Name:     name,
+		Remark:   remark,
+		Isolated: isolated,
+		Hypers:   hypers,
+	}
+	if err = db.Create(aggregate).Error; err != nil {
+		logger.Error("DB failed to create aggregate", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create aggregate", err)
+		return
+	}
+	err = tagAdmin.replace(db, "aggregates", aggregate.ID, 1, metadata)
+	return
+}
+
+// Update changes the aggregate, nil metadata or hostids are left as they are
+func (a *AggregateAdmin) Update(ctx context.Context, aggregate *model.Aggregate, remark string, isolated bool, metadata map[string]string, hostids []int32) (err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	if err = a.checkMetadata(metadata); err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = db.Model(aggregate).Updates(map[string]interface{}{
+		"remark":   remark,
+		"isolated": isolated,
+	}).Error
+	if err != nil {
+		logger.Error("DB failed to update aggregate", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update aggregate", err)
+		return
+	}
+	aggregate.Remark = remark
+	aggregate.Isolated = isolated
+	if hostids != nil {
+		var hypers []*model.Hyper
+		hypers, err = a.getHypers(ctx, hostids)
+		if err != nil {
+			return
+		}
+		if err = db.Model(aggregate).Association("Hypers").Replace(hypers).Error; err != nil {
+			logger.Error("DB failed to update aggregate hypervisors", err)
+			err = NewCLError(ErrDatabaseError, "Failed to update aggregate hypervisors", err)
+			return
+		}
+		aggregate.Hypers = hypers
+	}
+	if metadata != nil {
+		err = tagAdmin.replace(db, "aggregates", aggregate.ID, 1, metadata)
+	}
+	return
+}
+
+func (a *AggregateAdmin) Delete(ctx context.Context, aggregate *model.Aggregate) (err error) {
+	if err = a.checkAdmin(ctx); err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if err = db.Model(aggregate).Association("Hypers").Clear().Error; err != nil {
+		logger.Error("DB failed to clear aggregate hypervisors", err)
+		err = NewCLError(ErrDatabaseError, "Failed to clear aggregate hypervisors", err)
+		return
+	}
+	if err = tagAdmin.replace(db, "aggregates", aggregate.ID, 1, nil); err != nil {
+		return
+	}
+	if err = db.Delete(aggregate).Error; err != nil {
+		logger.Error("DB failed to delete aggregate", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete aggregate", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,233 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+const (
+	AppCredentialPrefix = "clak_"
+)
+
+var (
+	appCredentialAdmin = &AppCredentialAdmin{}
+	scopeRegex         = regexp.MustCompile(`^(/[a-zA-Z0-9_/:.-]*|[a-z_]+)$`)
+)
+
+type AppCredentialAdmin struct{}
+
+func randomHex(n int) (str string, err error) {
+	buf := make([]byte, n)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return
+	}
+	str = hex.EncodeToString(buf)
+	return
+}
+
+func secretHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ScopeAllows checks an API path against the allow list of a credential, a scope starting with "/" is the path
+// or a parent of it, otherwise it is a resource type matching the first path segment after /api/v1, for example "instances"
+func ScopeAllows(scopes, path string) bool {
+	if strings.TrimSpace(scopes) == "" {
+		return true
+	}
+	resource := strings.SplitN(strings.TrimPrefix(path, "/api/v1/"), "/", 2)[0]
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if strings.HasPrefix(scope, "/") {
+			// whole segments only, /api/v1/subnets/1 does not allow /api/v1/subnets/12
+			prefix := strings.TrimSuffix(scope, "/")
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		} else if scope == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// Create generates a credential for the current member, the key is returned only once
+func (a *AppCredentialAdmin) Create(ctx context.Context, name string, role model.Role, scopes []string, expiresAt *time.Time) (cred *model.AppCredential, key string, err error) {
+	memberShip := GetMemberShip(ctx)
+	if memberShip.UserID == 0 || memberShip.OrgID == 0 {
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create application credential", nil)
+		return
+	}
+	if role == model.None {
+		role = memberShip.Role
+	}
+	if role > memberShip.Role {
+		err = NewCLError(ErrPermissionDenied, fmt.Sprintf("Role %s exceeds the role of the member", role), nil)
+		return
+	}
+	for i, scope := range scopes {
+		scopes[i] = strings.TrimSpace(scope)
+		if !scopeRegex.MatchString(scopes[i]) {
+			err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid scope %s", scope), nil)
+			return
+		}
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		err = NewCLError(ErrInvalidParameter, "Expiry is in the past", nil)
+		return
+	}
+	keyID, err := randomHex(8)
+	if err != nil {
+		logger.Error("Failed to generate key id", err)
+		err = NewCLError(ErrAppCredentialCreateFailed, "Failed to generate key", err)
+		return
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		logger.Error("Failed to generate secret", err)
+		err = NewCLError(ErrAppCredentialCreateFailed, "Failed to generate key", err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	cred = &model.AppCredential{
+		Model:      model.Model{Creater: memberShip.UserID},
+		Owner:      memberShip.OrgID,
+		Name:       name,
+		UserID:     memberShip.UserID,
+		KeyID:      keyID,
+		SecretHash: secretHash(secret),
+		Role:       role,
+		Scopes:     strings.Join(scopes, ","),
+		ExpiresAt:  expiresAt,
+	}
+	err = db.Create(cred).Error
+	if err != nil {
+		logger.Error("DB failed to create application credential", err)
+		err = NewCLError(ErrAppCredentialCreateFailed, "Failed to create application credential", err)
+		return
+	}
+	key = AppCredentialPrefix + keyID + "_" + secret
+	return
+}
+
+// Authenticate finds the credential of a key and checks its expiry and its allow list against the requested path
+func (a *AppCredentialAdmin) Authenticate(ctx context.Context, key, path string) (cred *model.AppCredential, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(key, AppCredentialPrefix), "_", 2)
+	if !strings.HasPrefix(key, AppCredentialPrefix) || len(parts) != 2 {
+		err = NewCLError(ErrAppCredentialInvalid, "Invalid application credential", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	cred = &model.AppCredential{}
+	err = db.Where("key_id = ?", parts[0]).Take(cred).Error
+	if err != nil {
+		logger.Error("Failed to query application credential", err)
+		err = NewCLError(ErrAppCredentialInvalid, "Invalid application credential", err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(secretHash(parts[1])), []byte(cred.SecretHash)) != 1 {
+		err = NewCLError(ErrAppCredentialInvalid, "Invalid application credential", nil)
+		return
+	}
+	now := time.Now()
+	if cred.ExpiresAt != nil && cred.ExpiresAt.Before(now) {
+		err = NewCLError(ErrAppCredentialExpired, "Application credential expired", nil)
+		return
+	}
+	if !ScopeAllows(cred.Scopes, path) {
+		err = NewCLError(ErrAppCredentialScopeDenied, fmt.Sprintf("Application credential is not allowed to access %s", path), nil)
+		return
+	}
+	err = db.Model(cred).UpdateColumn("last_used_at", now).Error
+	if err != nil {
+		logger.Error("DB failed to update application credential", err)
+		err = nil
+	}
+	return
+}
+
+func (a *AppCredentialAdmin) getWhere(memberShip *MemberShip) (where string) {
+	where = memberShip.GetWhere()
+	if where != "" && memberShip.Role < model.Owner {
+		where = fmt.Sprintf("%s and user_id = %d", where, memberShip.UserID)
+	}
+	return
+}
+
+func (a *AppCredentialAdmin) GetAppCredentialByUUID(ctx context.Context, uuID string) (cred *model.AppCredential, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := a.getWhere(memberShip)
+	cred = &model.AppCredential{}
+	err = db.Preload("User").Where(where).Where("uuid = ?", uuID).Take(cred).Error
+	if err != nil {
+		logger.Error("Failed to query application credential, %v", err)
+		err = NewCLError(ErrAppCredentialNotFound, "Failed to find application credential", err)
+		return
+	}
+	return
+}
+
+// Delete revokes a credential, members can revoke their own credentials and owners all credentials of the organization
+func (a *AppCredentialAdmin) Delete(ctx context.Context, cred *model.AppCredential) (err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	if cred.UserID != memberShip.UserID && !memberShip.ValidateOwner(model.Owner, cred.Owner) {
+		logger.Error("Not authorized to delete the application credential")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the application credential", nil)
+		return
+	}
+	if err = db.Delete(cred).Error; err != nil {
+		logger.Error("DB failed to delete application credential", err)
+		err = NewCLError(ErrAppCredentialDeleteFailed, "Failed to delete application credential", err)
+		return
+	}
+	return
+}
+
+func (a *AppCredentialAdmin) List(ctx context.Context, offset, limit int64, order string) (total int64, creds []*model.AppCredential, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := a.getWhere(memberShip)
+	creds = []*model.AppCredential{}
+	if err = ListFilter(ctx, db.Model(&model.AppCredential{})).Where(where).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count application credentials, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count application credentials", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.AppCredential{}, offset, limit, order).Preload("User").Where(where).Find(&creds).Error; err != nil {
+		logger.Error("DB failed to query application credentials, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query application credentials", err)
+		return
+	}
+	return
+}
@@ -15,7 +15,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -82,7 +81,7 @@ func (a *BackendAdmin) CreateHaproxyConf(ctx context.Context, updatedlistener *m
 
 func (a *BackendAdmin) Create(ctx context.Context, name, backendAddr string, ssl bool, listener *model.Listener, loadBalancer *model.LoadBalancer) (backend *model.Backend, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("backend:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create backend")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create backend", nil)
@@ -126,7 +125,7 @@ func (a *BackendAdmin) Get(ctx context.Context, id int64, listener *model.Listen
 		err = NewCLError(ErrBackendNotFound, "Failed to find backend", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, backend.Owner)
+	permit := memberShip.For("backend:get").ValidateOwner(model.Reader, backend.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the backend")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the backend", nil)
@@ -146,7 +145,7 @@ func (a *BackendAdmin) GetBackendByUUID(ctx context.Context, uuID string) (backe
 		err = NewCLError(ErrRouterNotFound, "Failed to find backend", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, backend.Owner)
+	permit := memberShip.For("backend:get").ValidateOwner(model.Reader, backend.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the backend")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the backend", nil)
@@ -166,7 +165,7 @@ func (a *BackendAdmin) GetBackendByName(ctx context.Context, name string) (backe
 		err = NewCLError(ErrRouterNotFound, "Failed to find backend", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, backend.Owner)
+	permit := memberShip.For("backend:get").ValidateOwner(model.Reader, backend.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the backend")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the backend", nil)
@@ -213,7 +212,7 @@ func (a *BackendAdmin) Delete(ctx context.Context, backend *model.Backend, liste
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, backend.Owner)
+	permit := memberShip.For("backend:delete").ValidateOwner(model.Writer, backend.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the backend")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the router", nil)
@@ -263,13 +262,12 @@ func (a *BackendAdmin) List(ctx context.Context, offset, limit int64, order stri
 		where = fmt.Sprintf("%s and %s", where, wm)
 	}
 	backends = []*model.Backend{}
-	if err = db.Model(&model.Backend{}).Where(where).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Backend{})).Where(where).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count backends, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count backends", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(where).Find(&backends).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Backend{}, offset, limit, order).Where(where).Find(&backends).Error; err != nil {
 		logger.Error("DB failed to query backends, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query backends", err)
 		return
@@ -438,7 +436,7 @@ func (v *BackendView) Delete(c *macaron.Context, store session.Store) (err error
 func (v *BackendView) New(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("backend:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -13,7 +13,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -55,7 +54,7 @@ func (a *BackupAdmin) CreateBackupByUUID(ctx context.Context, uuid string, poolI
 
 func (a *BackupAdmin) createBackup(ctx context.Context, volume *model.Volume, poolID string, name string) (backup *model.VolumeBackup, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("backup:create").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to backup volume(%s)", volume.UUID)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to backup the volume", nil)
@@ -115,9 +114,10 @@ func (a *BackupAdmin) createBackup(ctx context.Context, volume *model.Volume, po
 	if vol_driver != "local" {
 		wdsUUID := volume.GetOriginVolumeID()
 		wdsOriginPoolID := volume.GetVolumePoolID()
+		quiesce := guestAgentAdmin.quiesceGuests(ctx, []int64{volume.InstanceID})
 		if poolID != "" && poolID != wdsOriginPoolID {
 			logger.Debugf("Backup volume %s from pool %s to pool %s", volume.UUID, wdsOriginPoolID, poolID)
-			command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s' '%s'", vol_driver, task.ID, backup.ID, backup.UUID, backup.Name, volume.ID, wdsUUID, wdsOriginPoolID, poolID)
+			command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s' '%s' '%s'", vol_driver, task.ID, backup.ID, backup.UUID, backup.Name, volume.ID, wdsUUID, wdsOriginPoolID, poolID, quiesce)
 			err = HyperExecute(ctx, control, command)
 			if err != nil {
 				logger.Error("Backup volume execution failed", err)
@@ -126,7 +126,7 @@ func (a *BackupAdmin) createBackup(ctx context.Context, volume *model.Volume, po
 			return
 		} else {
 			logger.Debugf("Backup volume %s to same pool %s, use snapshot", volume.UUID, poolID)
-			command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s'", vol_driver, task.ID, backup.ID, backup.UUID, backup.Name, volume.ID, wdsUUID, wdsOriginPoolID)
+			command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s' '%s' '%s'", vol_driver, task.ID, backup.ID, backup.UUID, backup.Name, volume.ID, wdsUUID, wdsOriginPoolID, wdsOriginPoolID, quiesce)
 			err = HyperExecute(ctx, control, command)
 			if err != nil {
 				logger.Error("Backup volume execution failed", err)
@@ -164,7 +164,7 @@ func (a *BackupAdmin) CreateSnapshotByUUID(ctx context.Context, uuid, name strin
 
 func (a *BackupAdmin) createSnapshot(ctx context.Context, name string, volume *model.Volume) (snapshot *model.VolumeBackup, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("backup:create_snapshot").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to snapshot volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to snapshot the volume", nil)
@@ -194,7 +194,8 @@ func (a *BackupAdmin) createSnapshot(ctx context.Context, name string, volume *m
 	if vol_driver != "local" {
 		wdsUUID := volume.GetOriginVolumeID()
 		wdsOriginPoolID := volume.GetVolumePoolID()
-		command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s'", vol_driver, task.ID, snapshot.ID, snapshot.UUID, snapshot.Name, volume.ID, wdsUUID, wdsOriginPoolID)
+		quiesce := guestAgentAdmin.quiesceGuests(ctx, []int64{volume.InstanceID})
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_snapshot_%s.sh '%d' '%d' '%s' '%s' '%d' '%s' '%s' '%s' '%s'", vol_driver, task.ID, snapshot.ID, snapshot.UUID, snapshot.Name, volume.ID, wdsUUID, wdsOriginPoolID, wdsOriginPoolID, quiesce)
 		err = HyperExecute(ctx, control, command)
 		if err != nil {
 			logger.Error("Backup volume execution failed", err)
@@ -271,7 +272,7 @@ func (a *BackupAdmin) GetBackupByID(ctx context.Context, backupID int64) (backup
 		err = NewCLError(ErrBackupNotFound, "Backup/Snapshot not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, backup.Owner)
+	permit := memberShip.For("backup:get").ValidateOwner(model.Reader, backup.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the backup")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the backup", nil)
@@ -292,7 +293,7 @@ func (a *BackupAdmin) GetBackupByUUID(ctx context.Context, uuID string) (backup
 		err = NewCLError(ErrVolumeNotFound, "Volume not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, backup.Owner)
+	permit := memberShip.For("backup:get").ValidateOwner(model.Reader, backup.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the backup")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the backup", nil)
@@ -317,7 +318,7 @@ func (a *BackupAdmin) Delete(ctx context.Context, backup *model.VolumeBackup) (e
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, backup.Owner)
+	permit := memberShip.For("backup:delete").ValidateOwner(model.Writer, backup.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the backup")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the backup", nil)
@@ -384,7 +385,7 @@ func (a *BackupAdmin) Restore(ctx context.Context, backupID int64) (backup *mode
 		return
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("backup:restore").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to restore volume(%s)", volume.UUID)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to restore the volume", nil)
@@ -494,20 +495,19 @@ func (a *BackupAdmin) List(ctx context.Context, offset, limit int64, order, quer
 		}
 	}
 	if whereSQL != "" {
-		if err = db.Model(&model.VolumeBackup{}).Where(memberShipSQL).Where(whereSQL).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.VolumeBackup{})).Where(memberShipSQL).Where(whereSQL).Count(&total).Error; err != nil {
 			logger.Error("DB: query backup count failed", err)
 			err = NewCLError(ErrSQLSyntaxError, "Failed to query backup count", err)
 			return
 		}
 	} else {
-		if err = db.Model(&model.VolumeBackup{}).Where(memberShipSQL).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.VolumeBackup{})).Where(memberShipSQL).Count(&total).Error; err != nil {
 			logger.Error("DB: query backup count failed", err)
 			err = NewCLError(ErrSQLSyntaxError, "Failed to query backup count", err)
 			return
 		}
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Volume").Preload("Task").Where(memberShipSQL).Where(whereSQL).Find(&backups).Error; err != nil {
+	if err = ListPage(ctx, db, &model.VolumeBackup{}, offset, limit, order).Preload("Volume").Preload("Task").Where(memberShipSQL).Where(whereSQL).Find(&backups).Error; err != nil {
 		logger.Error("DB: query backup failed", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query backup", err)
 		return
@@ -541,7 +541,7 @@ func (a *BackupAdmin) Update(ctx context.Context, id int64, name, path string, s
 		return
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, backup.Owner)
+	permit := memberShip.For("backup:update").ValidateOwner(model.Writer, backup.Owner)
 	if !permit {
 		logger.Error("Not authorized to update the backup")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to update the backup", nil)
@@ -567,7 +567,7 @@ func (a *BackupAdmin) Update(ctx context.Context, id int64, name, path string, s
 
 func (v *BackupView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("backup:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -601,7 +601,7 @@ func (v *BackupView) List(c *macaron.Context, store session.Store) {
 
 func (v *BackupView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("backup:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -634,7 +634,7 @@ func (v *BackupView) New(c *macaron.Context, store session.Store) {
 
 func (v *BackupView) Create(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("backup:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -704,7 +704,7 @@ func (v *BackupView) Delete(c *macaron.Context, store session.Store) (err error)
 
 func (v *BackupView) Restore(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("backup:restore").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
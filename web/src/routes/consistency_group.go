@@ -14,7 +14,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -96,7 +95,7 @@ func (a *ConsistencyGroupAdmin) List(ctx context.Context, offset, limit int64, o
 	}
 
 	// Get total count
-	if err = query.Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, query).Count(&total).Error; err != nil {
 		logger.Errorf("Failed to count consistency groups: %+v", err)
 		err = NewCLError(ErrDatabaseError, "Failed to count consistency groups", err)
 		return
@@ -104,8 +103,7 @@ func (a *ConsistencyGroupAdmin) List(ctx context.Context, offset, limit int64, o
 
 	// Get paginated results
 	cgs = []*model.ConsistencyGroup{}
-	query = dbs.Sortby(query.Offset(int(offset)).Limit(int(limit)), order)
-	if err = query.Find(&cgs).Error; err != nil {
+	if err = ListPage(ctx, query, &model.ConsistencyGroup{}, offset, limit, order).Find(&cgs).Error; err != nil {
 		logger.Errorf("Failed to list consistency groups: %+v", err)
 		err = NewCLError(ErrDatabaseError, "Failed to list consistency groups", err)
 		return
@@ -122,7 +120,7 @@ func (a *ConsistencyGroupAdmin) Create(ctx context.Context, name, description st
 
 	// Get membership for permission check
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Errorf("Not authorized to create consistency group")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create consistency group", nil)
@@ -270,7 +268,7 @@ func (a *ConsistencyGroupAdmin) Update(ctx context.Context, id int64, name, desc
 
 	// Permission check
 	// 权限检查
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:update").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to update consistency group ID %d", id)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to update consistency group", nil)
@@ -352,7 +350,7 @@ func (a *ConsistencyGroupAdmin) Delete(ctx context.Context, id int64) (err error
 
 	// Permission check
 	// 权限检查
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:delete").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to delete consistency group ID %d", id)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete consistency group", nil)
@@ -430,7 +428,7 @@ func (a *ConsistencyGroupAdmin) GetVolumes(ctx context.Context, id int64) (volum
 
 	// Permission check
 	// 权限检查
-	permit := memberShip.ValidateOwner(model.Reader, cg.Owner)
+	permit := memberShip.For("consistency_group:get").ValidateOwner(model.Reader, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to get volumes of consistency group ID %d", id)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to get volumes of consistency group", nil)
@@ -469,7 +467,7 @@ func (a *ConsistencyGroupAdmin) AddVolumes(ctx context.Context, id int64, volume
 
 	// Permission check
 	// 权限检查
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:add_volumes").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to add volumes to consistency group ID %d", id)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to add volumes to consistency group", nil)
@@ -647,7 +645,7 @@ func (a *ConsistencyGroupAdmin) RemoveVolume(ctx context.Context, id int64, volu
 
 	// Permission check
 	// 权限检查
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:remove_volume").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to remove volume from consistency group ID %d", id)
 		err = NewCLError(ErrPermissionDenied, "Not authorized to remove volume from consistency group", nil)
@@ -780,7 +778,7 @@ func (a *ConsistencyGroupAdmin) ListSnapshots(ctx context.Context, cgID int64, o
 
 	// Get total count
 	// 获取总数
-	if err = query.Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, query).Count(&total).Error; err != nil {
 		logger.Errorf("Failed to count snapshots: %+v", err)
 		err = NewCLError(ErrDatabaseError, "Failed to count snapshots", err)
 		return
@@ -792,8 +790,7 @@ func (a *ConsistencyGroupAdmin) ListSnapshots(ctx context.Context, cgID int64, o
 	if order == "" {
 		order = "-created_at"
 	}
-	query = dbs.Sortby(query.Offset(int(offset)).Limit(int(limit)), order)
-	if err = query.Find(&snapshots).Error; err != nil {
+	if err = ListPage(ctx, query, &model.ConsistencyGroupSnapshot{}, offset, limit, order).Find(&snapshots).Error; err != nil {
 		logger.Errorf("Failed to list snapshots: %+v", err)
 		err = NewCLError(ErrDatabaseError, "Failed to list snapshots", err)
 		return
@@ -816,7 +813,7 @@ func (a *ConsistencyGroupAdmin) CreateSnapshot(ctx context.Context, cgUUID strin
 
 	// 2. 权限检查
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:create_snapshot").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to create snapshot for CG %s", cg.UUID)
 		return nil, NewCLError(ErrPermissionDenied, "Not authorized to create snapshot for this consistency group", nil)
@@ -897,11 +894,18 @@ func (a *ConsistencyGroupAdmin) CreateSnapshot(ctx context.Context, cgUUID strin
 	}
 
 	// 10. 调用 shell 脚本创建 WDS 快照
-	// Parameters: cg_ID, cg_snapshot_ID, cg_snapshot_Name, wds_cg_id
+	// Parameters: cg_ID, cg_snapshot_ID, cg_snapshot_Name, wds_cg_id, quiesce_guests
 	cg_snapshot_Name := fmt.Sprintf("cg_snap_%s", snapshot.UUID)
+	instanceIDs := []int64{}
+	for _, cgv := range cgVolumes {
+		if cgv.Volume.InstanceID > 0 {
+			instanceIDs = append(instanceIDs, cgv.Volume.InstanceID)
+		}
+	}
+	quiesce := guestAgentAdmin.quiesceGuests(ctx, instanceIDs)
 	control := fmt.Sprintf("inter=")
-	command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_cg_snapshot_wds.sh '%d' '%d' '%s' '%s'",
-		cg.ID, snapshot.ID, cg_snapshot_Name, cg.WdsCgID)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_cg_snapshot_wds.sh '%d' '%d' '%s' '%s' '%s'",
+		cg.ID, snapshot.ID, cg_snapshot_Name, cg.WdsCgID, quiesce)
 	err = HyperExecute(ctx, control, command)
 	if err != nil {
 		logger.Errorf("Failed to execute create CG snapshot script: %v", err)
@@ -928,7 +932,7 @@ func (a *ConsistencyGroupAdmin) DeleteSnapshot(ctx context.Context, cgUUID, snap
 
 	// 2. 权限检查
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:delete_snapshot").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to delete snapshot for CG %s", cg.UUID)
 		return NewCLError(ErrPermissionDenied, "Not authorized to delete snapshot for this consistency group", nil)
@@ -1022,7 +1026,7 @@ func (a *ConsistencyGroupAdmin) RestoreSnapshot(ctx context.Context, cgUUID, sna
 
 	// 2. 权限检查
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit := memberShip.For("consistency_group:restore_snapshot").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Errorf("Not authorized to restore snapshot for CG %s", cg.UUID)
 		return nil, NewCLError(ErrPermissionDenied, "Not authorized to restore snapshot for this consistency group", nil)
@@ -1191,7 +1195,7 @@ func (a *ConsistencyGroupAdmin) RestoreSnapshot(ctx context.Context, cgUUID, sna
 // 显示一致性组列表页面
 func (v *ConsistencyGroupView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("consistency_group:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1262,7 +1266,7 @@ func (v *ConsistencyGroupView) List(c *macaron.Context, store session.Store) {
 // 显示创建一致性组页面
 func (v *ConsistencyGroupView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1294,7 +1298,7 @@ func (v *ConsistencyGroupView) New(c *macaron.Context, store session.Store) {
 // 处理创建新一致性组
 func (v *ConsistencyGroupView) Create(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1336,7 +1340,7 @@ func (v *ConsistencyGroupView) Create(c *macaron.Context, store session.Store) {
 // 显示一致性组详情页面
 func (v *ConsistencyGroupView) Get(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("consistency_group:get").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1368,7 +1372,7 @@ func (v *ConsistencyGroupView) Get(c *macaron.Context, store session.Store) {
 
 	// Permission check
 	// 权限检查
-	permit = memberShip.ValidateOwner(model.Reader, cg.Owner)
+	permit = memberShip.For("consistency_group:get").ValidateOwner(model.Reader, cg.Owner)
 	if !permit {
 		logger.Error("Not authorized to view this consistency group")
 		c.Data["ErrorMsg"] = "Not authorized to view this consistency group"
@@ -1409,7 +1413,7 @@ func (v *ConsistencyGroupView) Get(c *macaron.Context, store session.Store) {
 // 显示编辑一致性组页面
 func (v *ConsistencyGroupView) Edit(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1435,7 +1439,7 @@ func (v *ConsistencyGroupView) Edit(c *macaron.Context, store session.Store) {
 
 	// Permission check
 	// 权限检查
-	permit = memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit = memberShip.For("consistency_group:update").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Error("Not authorized to edit this consistency group")
 		c.Data["ErrorMsg"] = "Not authorized to edit this consistency group"
@@ -1451,7 +1455,7 @@ func (v *ConsistencyGroupView) Edit(c *macaron.Context, store session.Store) {
 // 处理更新一致性组
 func (v *ConsistencyGroupView) Patch(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1515,7 +1519,7 @@ func (v *ConsistencyGroupView) Delete(c *macaron.Context, store session.Store) e
 // 显示一致性组的卷管理页面
 func (v *ConsistencyGroupView) Volumes(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("consistency_group:get").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1541,7 +1545,7 @@ func (v *ConsistencyGroupView) Volumes(c *macaron.Context, store session.Store)
 
 	// Permission check
 	// 权限检查
-	permit = memberShip.ValidateOwner(model.Reader, cg.Owner)
+	permit = memberShip.For("consistency_group:get").ValidateOwner(model.Reader, cg.Owner)
 	if !permit {
 		logger.Error("Not authorized to view this consistency group")
 		c.Data["ErrorMsg"] = "Not authorized to view this consistency group"
@@ -1586,7 +1590,7 @@ func (v *ConsistencyGroupView) Volumes(c *macaron.Context, store session.Store)
 // 处理向一致性组添加卷
 func (v *ConsistencyGroupView) AddVolumes(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:add_volumes").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1674,7 +1678,7 @@ func (v *ConsistencyGroupView) RemoveVolume(c *macaron.Context, store session.St
 // 显示一致性组的快照列表页面
 func (v *ConsistencyGroupView) ListSnapshots(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("consistency_group:list_snapshots").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1700,7 +1704,7 @@ func (v *ConsistencyGroupView) ListSnapshots(c *macaron.Context, store session.S
 
 	// Permission check
 	// 权限检查
-	permit = memberShip.ValidateOwner(model.Reader, cg.Owner)
+	permit = memberShip.For("consistency_group:list_snapshots").ValidateOwner(model.Reader, cg.Owner)
 	if !permit {
 		logger.Error("Not authorized to view this consistency group")
 		c.Data["ErrorMsg"] = "Not authorized to view this consistency group"
@@ -1750,7 +1754,7 @@ func (v *ConsistencyGroupView) ListSnapshots(c *macaron.Context, store session.S
 // 显示创建快照页面
 func (v *ConsistencyGroupView) NewSnapshot(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:create_snapshot").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1776,7 +1780,7 @@ func (v *ConsistencyGroupView) NewSnapshot(c *macaron.Context, store session.Sto
 
 	// Permission check
 	// 权限检查
-	permit = memberShip.ValidateOwner(model.Writer, cg.Owner)
+	permit = memberShip.For("consistency_group:create_snapshot").ValidateOwner(model.Writer, cg.Owner)
 	if !permit {
 		logger.Error("Not authorized to create snapshot for this consistency group")
 		c.Data["ErrorMsg"] = "Not authorized to create snapshot for this consistency group"
@@ -1799,7 +1803,7 @@ func (v *ConsistencyGroupView) NewSnapshot(c *macaron.Context, store session.Sto
 // 处理为一致性组创建快照
 func (v *ConsistencyGroupView) CreateSnapshot(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:create_snapshot").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1904,7 +1908,7 @@ func (v *ConsistencyGroupView) DeleteSnapshot(c *macaron.Context, store session.
 // 处理从快照恢复一致性组
 func (v *ConsistencyGroupView) Restore(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("consistency_group:restore").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
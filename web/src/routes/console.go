@@ -46,11 +46,21 @@ func RandomStr() string {
 }
 
 func MakeToken(ctx context.Context, instance *model.Instance) (token string, err error) {
+	return MakeConsoleToken(ctx, instance, model.ConsoleTypeVnc)
+}
+
+// MakeConsoleToken issues a token for a console of the instance, a new vnc token replaces the
+// previous one. Serial console tokens expire sooner and each is good for one connection, so every
+// serial token has a record of its own and tokens of other users are left alone
+func MakeConsoleToken(ctx context.Context, instance *model.Instance, consoleType string) (token string, err error) {
 	memberShip := GetMemberShip(ctx)
 	permit := memberShip.CheckPermission(model.Writer)
+	if consoleType == model.ConsoleTypeSerial {
+		permit = memberShip.For("instance:serial_console").ValidateOwner(model.Writer, instance.Owner)
+	}
 	if !permit {
-		logger.Error("Not authorized to create interface in public subnet")
-		return "", NewCLError(ErrPermissionDenied, "Not authorized to create interface in public subnet", nil)
+		logger.Error("Not authorized to make the console token")
+		return "", NewCLError(ErrPermissionDenied, "Not authorized to make the console token", nil)
 	}
 	secret := RandomStr()
 	tkClaim := TokenClaim{
@@ -58,20 +68,29 @@ func MakeToken(ctx context.Context, instance *model.Instance) (token string, err
 		Role:       memberShip.Role,
 		InstanceID: int(instance.ID),
 		Secret:     secret,
+		UserID:     memberShip.UserID,
+		Type:       consoleType,
 	}
-	tkClaim.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(TokenExpireDuration))
-	tokenHash := make([]byte, 32)
-	data := sha3.NewShake256()
-	data.Write([]byte(secret))
-	data.Read(tokenHash)
-	hashSecret := fmt.Sprintf("%x", tokenHash)
+	expire := TokenExpireDuration
+	if consoleType == model.ConsoleTypeSerial {
+		expire = serialTokenExpire()
+	}
+	tkClaim.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expire))
 	ctx, db := GetContextDB(ctx)
 	console := &model.Console{
 		Instance:   instance.ID,
-		Type:       "vnc",
-		HashSecret: hashSecret,
+		Type:       consoleType,
+		HashSecret: consoleSecretHash(secret),
+	}
+	if consoleType == model.ConsoleTypeSerial {
+		// tokens that expired unused go with the next one
+		err = db.Where("instance = ? and type = ? and created_at < ?", instance.ID, consoleType, time.Now().Add(-expire)).Delete(&model.Console{}).Error
+		if err == nil {
+			err = db.Create(console).Error
+		}
+	} else {
+		err = db.Where("instance = ? and type = ?", instance.ID, consoleType).Assign(console).FirstOrCreate(&model.Console{}).Error
 	}
-	err = db.Where("instance = ?", instance.ID).Assign(console).FirstOrCreate(&model.Console{}).Error
 	if err != nil {
 		logger.Error("Failed to make console record ", err)
 		return "", NewCLError(ErrConsoleCreateFailed, "Failed to make console record", err)
@@ -81,37 +100,44 @@ func MakeToken(ctx context.Context, instance *model.Instance) (token string, err
 	return
 }
 
-func ResolveToken(ctx context.Context, tokenString string) (int, *MemberShip, error) {
+func consoleSecretHash(secret string) string {
+	tokenHash := make([]byte, 32)
+	data := sha3.NewShake256()
+	data.Write([]byte(secret))
+	data.Read(tokenHash)
+	return fmt.Sprintf("%x", tokenHash)
+}
+
+// ResolveToken finds the console record of the token and returns it with the member the token was issued to
+func ResolveToken(ctx context.Context, tokenString, consoleType string) (*model.Console, *MemberShip, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaim{}, func(token *jwt.Token) (interface{}, error) {
 		return SignedSeret, nil
 	})
 	if err != nil || token == nil {
-		return 0, nil, err
+		return nil, nil, err
 	}
 	claims, ok := token.Claims.(*TokenClaim)
 	if !ok || !token.Valid {
-		return 0, nil, NewCLError(ErrInvalidConsoleToken, "Token is invalid", nil)
+		return nil, nil, NewCLError(ErrInvalidConsoleToken, "Token is invalid", nil)
+	}
+	if claims.Type == "" {
+		claims.Type = model.ConsoleTypeVnc
+	}
+	if claims.Type != consoleType {
+		return nil, nil, NewCLError(ErrInvalidConsoleToken, "Token is for another console type", nil)
 	}
 	ctx, db := GetContextDB(ctx)
-	instanceID := claims.InstanceID
-	console := &model.Console{Instance: int64(instanceID)}
-	err = db.Where(console).Take(console).Error
+	console := &model.Console{}
+	err = db.Where("instance = ? and type = ? and hash_secret = ?", claims.InstanceID, consoleType, consoleSecretHash(claims.Secret)).Take(console).Error
 	if err != nil {
-		return 0, nil, NewCLError(ErrConsoleNotFound, "Failed to retrieve console record", err)
-	}
-	tokenHash := make([]byte, 32)
-	data := sha3.NewShake256()
-	data.Write([]byte(claims.Secret))
-	data.Read(tokenHash)
-	hashSecret := fmt.Sprintf("%x", tokenHash)
-	if hashSecret != console.HashSecret {
-		return 0, nil, NewCLError(ErrInvalidConsoleToken, "Secret can not pass validation", nil)
+		return nil, nil, NewCLError(ErrInvalidConsoleToken, "Secret can not pass validation", err)
 	}
 	memberShip := &MemberShip{
-		OrgID: claims.OrgID,
-		Role:  claims.Role,
+		OrgID:  claims.OrgID,
+		UserID: claims.UserID,
+		Role:   claims.Role,
 	}
-	return instanceID, memberShip, nil
+	return console, memberShip, nil
 }
 
 func (a *ConsoleView) ConsoleURL(c *macaron.Context, store session.Store) {
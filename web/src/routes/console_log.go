@@ -0,0 +1,101 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DefaultConsoleLogLines = 100
+	MaxConsoleLogLines     = 1000
+	consoleLogCacheTTL     = 10 * time.Second
+	consoleLogWait         = 5 * time.Second
+	consoleLogPoll         = 500 * time.Millisecond
+)
+
+var (
+	consoleLogAdmin = &ConsoleLogAdmin{}
+)
+
+type ConsoleLogAdmin struct{}
+
+// Get returns the last lines of the serial log of the instance. A cached log that is fresh and long
+// enough comes back right away, otherwise the hypervisor is asked for it and answers through the
+// console_log callback, which Get waits for a while before it falls back to the cached log
+func (a *ConsoleLogAdmin) Get(ctx context.Context, instance *model.Instance, lines int32) (consoleLog *model.ConsoleLog, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("instance:console_log").ValidateOwner(model.Writer, instance.Owner)
+	if !permit {
+		logger.Error("Not authorized to get the console log")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to get the console log", nil)
+		return
+	}
+	if lines <= 0 {
+		lines = DefaultConsoleLogLines
+	}
+	if lines > MaxConsoleLogLines {
+		lines = MaxConsoleLogLines
+	}
+	ctx, db := GetContextDB(ctx)
+	consoleLog = &model.ConsoleLog{}
+	err = db.Where("instance_id = ?", instance.ID).Take(consoleLog).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		logger.Error("Failed to query console log", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query console log", err)
+		return
+	}
+	err = nil
+	if consoleLog.ID > 0 && consoleLog.Lines >= lines && time.Since(consoleLog.UpdatedAt) < consoleLogCacheTTL {
+		// a longer cached log serves shorter requests too
+		consoleLog.Output = tailLines(consoleLog.Output, lines)
+		consoleLog.Lines = lines
+		return
+	}
+	requested := time.Now()
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/console_log.sh '%d' '%d'", instance.ID, lines)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Console log remote exec failed", err)
+		return
+	}
+	for waited := time.Duration(0); waited < consoleLogWait; waited += consoleLogPoll {
+		time.Sleep(consoleLogPoll)
+		fetched := &model.ConsoleLog{}
+		if db.Where("instance_id = ? and updated_at > ?", instance.ID, requested).Take(fetched).Error == nil {
+			return fetched, nil
+		}
+	}
+	if consoleLog.ID == 0 {
+		err = NewCLError(ErrConsoleLogNotReady, "Console log is not ready yet, retry later", nil)
+		return
+	}
+	logger.Debugf("Hypervisor %d has not answered for the console log of instance %d, returning the cached one", instance.Hyper, instance.ID)
+	return
+}
+
+func tailLines(output string, lines int32) string {
+	trimmed := strings.TrimSuffix(output, "\n")
+	for i := len(trimmed) - 1; i >= 0; i-- {
+		if trimmed[i] == '\n' {
+			lines--
+			if lines == 0 {
+				return output[i+1:]
+			}
+		}
+	}
+	return output
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"strconv"
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -80,13 +79,12 @@ func (a *DictionaryAdmin) List(ctx context.Context, offset, limit int64, order s
 	}
 
 	dictionaries = []*model.Dictionary{}
-	if err = db.Model(&model.Dictionary{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Dictionary{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Debugf("DictionaryAdmin.List: count error, err=%v", err)
 		logger.Debugf("Exit DictionaryAdmin.List with error")
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to count dictionaries", err)
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(where).Where(query).Find(&dictionaries).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Dictionary{}, offset, limit, order).Where(where).Where(query).Find(&dictionaries).Error; err != nil {
 		logger.Errorf("DictionaryAdmin.List: find error, err=%v", err)
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to find dictionaries", err)
 	}
@@ -199,7 +197,7 @@ type DictionaryView struct{}
 
 func (v *DictionaryView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("dictionary:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -0,0 +1,604 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var (
+	dnsAdmin     = &DnsAdmin{}
+	dnsNameRegex = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?$`)
+	// printable ascii but ", $, ` and \ so that text records stay literal in the dnsmasq config
+	dnsTextRegex = regexp.MustCompile(`^[ !#%-\[\]-_a-~]*$`)
+)
+
+type DnsAdmin struct{}
+
+type DnsRecordData struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type DnsData struct {
+	Zones   []string         `json:"zones"`
+	Records []*DnsRecordData `json:"records"`
+}
+
+// RecordFQDN returns the full name of a record, the apex of the zone is written as "@"
+func RecordFQDN(zone *model.DnsZone, record *model.DnsRecord) string {
+	if record.Name == "@" || record.Name == "" {
+		return zone.Name
+	}
+	return record.Name + "." + zone.Name
+}
+
+// subnetDomain returns the first domain of the search list of a subnet
+func subnetDomain(subnet *model.Subnet) string {
+	domains := strings.FieldsFunc(subnet.DomainSearch, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';'
+	})
+	if len(domains) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(domains[0], "."))
+}
+
+func (a *DnsAdmin) validateRecord(name, rtype, value string) (err error) {
+	switch rtype {
+	case model.DnsRecordA:
+		if name != "@" && !dnsNameRegex.MatchString(name) {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid record name %s", name), nil)
+			return
+		}
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid ipv4 address %s", value), nil)
+			return
+		}
+	case model.DnsRecordPTR:
+		ip := net.ParseIP(name)
+		if ip == nil || ip.To4() == nil {
+			err = NewCLError(ErrDnsRecordInvalid, "Name of PTR record must be an ipv4 address", nil)
+			return
+		}
+		if !dnsNameRegex.MatchString(strings.TrimSuffix(value, ".")) {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid host name %s", value), nil)
+			return
+		}
+	case model.DnsRecordCNAME:
+		if name == "@" || !dnsNameRegex.MatchString(name) {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid record name %s", name), nil)
+			return
+		}
+		if !dnsNameRegex.MatchString(strings.TrimSuffix(value, ".")) {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid host name %s", value), nil)
+			return
+		}
+	case model.DnsRecordTXT:
+		if name != "@" && !dnsNameRegex.MatchString(name) {
+			err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Invalid record name %s", name), nil)
+			return
+		}
+		if len(value) > 255 || !dnsTextRegex.MatchString(value) {
+			err = NewCLError(ErrDnsRecordInvalid, "Text record must be printable characters other than \", $, ` and \\", nil)
+			return
+		}
+	default:
+		err = NewCLError(ErrDnsRecordInvalid, fmt.Sprintf("Unsupported record type %s", rtype), nil)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) getDnsData(ctx context.Context, routerID int64) (dnsData *DnsData, err error) {
+	ctx, db := GetContextDB(ctx)
+	zones := []*model.DnsZone{}
+	err = db.Preload("Records").Where("router_id = ?", routerID).Find(&zones).Error
+	if err != nil {
+		logger.Error("Failed to query dns zones", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query dns zones", err)
+		return
+	}
+	dnsData = &DnsData{Zones: []string{}, Records: []*DnsRecordData{}}
+	for _, zone := range zones {
+		dnsData.Zones = append(dnsData.Zones, zone.Name)
+		for _, record := range zone.Records {
+			name := RecordFQDN(zone, record)
+			value := strings.TrimSuffix(record.Value, ".")
+			if record.Type == model.DnsRecordPTR {
+				name = record.Name
+			}
+			dnsData.Records = append(dnsData.Records, &DnsRecordData{Name: name, Type: record.Type, Value: value})
+		}
+	}
+	return
+}
+
+// Apply installs the records of all zones of a router on one hypervisor, or all if hyper is negative
+func (a *DnsAdmin) Apply(ctx context.Context, routerID int64, hyper int32) (err error) {
+	dnsData, err := a.getDnsData(ctx, routerID)
+	if err != nil {
+		return
+	}
+	if hyper >= 0 && len(dnsData.Zones) == 0 {
+		return
+	}
+	jsonData, err := json.Marshal(dnsData)
+	if err != nil {
+		logger.Error("Failed to marshal dns data", err)
+		return
+	}
+	control := "toall="
+	if hyper >= 0 {
+		control = fmt.Sprintf("inter=%d", hyper)
+	}
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/set_dns_zone.sh '%d'<<EOF\n%s\nEOF", routerID, base64.StdEncoding.EncodeToString(jsonData))
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Set dns zone execution failed", err)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) SyncRouter(ctx context.Context, routerID int64, hyper int32) (err error) {
+	return a.Apply(ctx, routerID, hyper)
+}
+
+func (a *DnsAdmin) getOrCreateZone(ctx context.Context, router *model.Router, name string) (zone *model.DnsZone, err error) {
+	ctx, db := GetContextDB(ctx)
+	zone = &model.DnsZone{}
+	err = db.Where("router_id = ? and name = ?", router.ID, name).Take(zone).Error
+	if err == nil {
+		return
+	}
+	zone = &model.DnsZone{Model: model.Model{Creater: router.Creater}, Owner: router.Owner, Name: name, RouterID: router.ID}
+	err = db.Create(zone).Error
+	if err != nil {
+		logger.Error("Failed to create dns zone", err)
+		err = NewCLError(ErrDnsZoneCreateFailed, "Failed to create dns zone", err)
+		return
+	}
+	return
+}
+
+// AddInstanceRecords creates the A and PTR records of an instance in the zones of its subnet domains,
+// the records of all interfaces are replaced together and the hypervisors are left alone if nothing changed
+func (a *DnsAdmin) AddInstanceRecords(ctx context.Context, instance *model.Instance) (err error) {
+	ctx, db := GetContextDB(ctx)
+	hostname := strings.ToLower(instance.Hostname)
+	if !dnsNameRegex.MatchString(hostname) || strings.Contains(hostname, ".") {
+		logger.Debugf("Hostname %s is not a valid dns label", instance.Hostname)
+		return
+	}
+	records := []*model.DnsRecord{}
+	wanted := make(map[string]struct{})
+	routerSet := make(map[int64]struct{})
+	for _, iface := range instance.Interfaces {
+		if iface.Address == nil || iface.Address.Subnet == nil {
+			continue
+		}
+		subnet := iface.Address.Subnet
+		domain := subnetDomain(subnet)
+		if subnet.RouterID == 0 || subnet.Type != string(Internal) || domain == "" || !dnsNameRegex.MatchString(domain) {
+			continue
+		}
+		router := subnet.Router
+		if router == nil {
+			router = &model.Router{Model: model.Model{ID: subnet.RouterID}}
+			err = db.Take(router).Error
+			if err != nil {
+				logger.Error("Failed to query router", err)
+				return
+			}
+		}
+		var zone *model.DnsZone
+		zone, err = a.getOrCreateZone(ctx, router, domain)
+		if err != nil {
+			return
+		}
+		routerSet[router.ID] = struct{}{}
+		ip := strings.Split(iface.Address.Address, "/")[0]
+		fqdn := hostname + "." + zone.Name
+		for _, record := range []*model.DnsRecord{
+			{Name: hostname, Type: model.DnsRecordA, Value: ip},
+			{Name: ip, Type: model.DnsRecordPTR, Value: fqdn},
+		} {
+			record.ZoneID = zone.ID
+			key := fmt.Sprintf("%d %s %s %s", record.ZoneID, record.Type, record.Name, record.Value)
+			if _, ok := wanted[key]; ok {
+				continue
+			}
+			wanted[key] = struct{}{}
+			records = append(records, record)
+		}
+	}
+	existing := []*model.DnsRecord{}
+	err = db.Where("instance_id = ?", instance.ID).Find(&existing).Error
+	if err != nil {
+		logger.Error("Failed to query dns records", err)
+		return
+	}
+	changed := len(existing) != len(records)
+	zoneIDs := []int64{}
+	found := make(map[string]struct{})
+	for _, record := range existing {
+		key := fmt.Sprintf("%d %s %s %s", record.ZoneID, record.Type, record.Name, record.Value)
+		if _, ok := wanted[key]; !ok {
+			changed = true
+		}
+		found[key] = struct{}{}
+		zoneIDs = append(zoneIDs, record.ZoneID)
+	}
+	if !changed && len(found) == len(wanted) {
+		return
+	}
+	err = db.Where("instance_id = ?", instance.ID).Delete(&model.DnsRecord{}).Error
+	if err != nil {
+		logger.Error("Failed to delete dns records", err)
+		err = NewCLError(ErrDnsRecordDeleteFailed, "Failed to delete dns records", err)
+		return
+	}
+	for _, record := range records {
+		record.Model = model.Model{Creater: instance.Creater}
+		record.Owner = instance.Owner
+		record.InstanceID = instance.ID
+		err = db.Create(record).Error
+		if err != nil {
+			logger.Error("Failed to create dns record", err)
+			err = NewCLError(ErrDnsRecordCreateFailed, "Failed to create dns record", err)
+			return
+		}
+	}
+	// the zones of records that are gone need the update as well
+	if len(zoneIDs) > 0 {
+		zones := []*model.DnsZone{}
+		err = db.Where("id in (?)", zoneIDs).Find(&zones).Error
+		if err != nil {
+			logger.Error("Failed to query dns zones", err)
+			return
+		}
+		for _, zone := range zones {
+			routerSet[zone.RouterID] = struct{}{}
+		}
+	}
+	for routerID := range routerSet {
+		err = a.Apply(ctx, routerID, -1)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// RemoveInstanceRecords deletes the records created automatically for an instance
+func (a *DnsAdmin) RemoveInstanceRecords(ctx context.Context, instance *model.Instance) (err error) {
+	ctx, db := GetContextDB(ctx)
+	records := []*model.DnsRecord{}
+	err = db.Where("instance_id = ?", instance.ID).Find(&records).Error
+	if err != nil {
+		logger.Error("Failed to query dns records", err)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+	err = db.Where("instance_id = ?", instance.ID).Delete(&model.DnsRecord{}).Error
+	if err != nil {
+		logger.Error("Failed to delete dns records", err)
+		err = NewCLError(ErrDnsRecordDeleteFailed, "Failed to delete dns records", err)
+		return
+	}
+	zoneIDs := []int64{}
+	for _, record := range records {
+		zoneIDs = append(zoneIDs, record.ZoneID)
+	}
+	zones := []*model.DnsZone{}
+	err = db.Where("id in (?)", zoneIDs).Find(&zones).Error
+	if err != nil {
+		logger.Error("Failed to query dns zones", err)
+		return
+	}
+	routerSet := make(map[int64]struct{})
+	for _, zone := range zones {
+		routerSet[zone.RouterID] = struct{}{}
+	}
+	for routerID := range routerSet {
+		err = a.Apply(ctx, routerID, -1)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DeleteRouterZones removes all dns zones and records of a router being deleted
+func (a *DnsAdmin) DeleteRouterZones(ctx context.Context, routerID int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Where("zone_id in (select id from dns_zones where router_id = ?)", routerID).Delete(&model.DnsRecord{}).Error
+	if err != nil {
+		logger.Error("Failed to delete dns records", err)
+		err = NewCLError(ErrDnsRecordDeleteFailed, "Failed to delete dns records", err)
+		return
+	}
+	err = db.Where("router_id = ?", routerID).Delete(&model.DnsZone{}).Error
+	if err != nil {
+		logger.Error("Failed to delete dns zones", err)
+		err = NewCLError(ErrDnsZoneDeleteFailed, "Failed to delete dns zones", err)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) CreateZone(ctx context.Context, router *model.Router, name string) (zone *model.DnsZone, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("dns:create_zone").ValidateOwner(model.Writer, router.Owner)
+	if !permit {
+		logger.Error("Not authorized to create dns zone")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create dns zone", nil)
+		return
+	}
+	name = strings.ToLower(strings.Trim(name, "."))
+	if !dnsNameRegex.MatchString(name) || strings.HasPrefix(name, "*") {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid zone name %s", name), nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	count := 0
+	err = db.Model(&model.DnsZone{}).Where("router_id = ? and name = ?", router.ID, name).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count dns zones", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count dns zones", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrDnsZoneExists, fmt.Sprintf("Dns zone %s exists in the vpc", name), nil)
+		return
+	}
+	zone = &model.DnsZone{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, RouterID: router.ID}
+	err = db.Create(zone).Error
+	if err != nil {
+		logger.Error("DB failed to create dns zone", err)
+		err = NewCLError(ErrDnsZoneCreateFailed, "Failed to create dns zone", err)
+		return
+	}
+	zone.Router = router
+	err = a.Apply(ctx, router.ID, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) GetZoneByUUID(ctx context.Context, uuID string) (zone *model.DnsZone, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	zone = &model.DnsZone{}
+	err = db.Preload("Router").Where(where).Where("uuid = ?", uuID).Take(zone).Error
+	if err != nil {
+		logger.Error("Failed to query dns zone, %v", err)
+		err = NewCLError(ErrDnsZoneNotFound, "Failed to find dns zone", err)
+		return
+	}
+	permit := memberShip.For("dns:get").ValidateOwner(model.Reader, zone.Owner)
+	if !permit {
+		logger.Error("Not authorized to read the dns zone")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the dns zone", nil)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) DeleteZone(ctx context.Context, zone *model.DnsZone) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("dns:delete_zone").ValidateOwner(model.Writer, zone.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the dns zone")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the dns zone", nil)
+		return
+	}
+	err = db.Where("zone_id = ?", zone.ID).Delete(&model.DnsRecord{}).Error
+	if err != nil {
+		logger.Error("DB failed to delete dns records", err)
+		err = NewCLError(ErrDnsRecordDeleteFailed, "Failed to delete dns records", err)
+		return
+	}
+	if err = db.Delete(zone).Error; err != nil {
+		logger.Error("DB failed to delete dns zone", err)
+		err = NewCLError(ErrDnsZoneDeleteFailed, "Failed to delete dns zone", err)
+		return
+	}
+	err = a.Apply(ctx, zone.RouterID, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) ListZones(ctx context.Context, offset, limit int64, order string, router *model.Router) (total int64, zones []*model.DnsZone, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := memberShip.GetWhere()
+	zones = []*model.DnsZone{}
+	if err = ListFilter(ctx, db.Model(&model.DnsZone{})).Where(where).Where("router_id = ?", router.ID).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count dns zones, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count dns zones", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.DnsZone{}, offset, limit, order).Where(where).Where("router_id = ?", router.ID).Find(&zones).Error; err != nil {
+		logger.Error("DB failed to query dns zones, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query dns zones", err)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) CreateRecord(ctx context.Context, zone *model.DnsZone, name, rtype, value string) (record *model.DnsRecord, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("dns:create_record").ValidateOwner(model.Writer, zone.Owner)
+	if !permit {
+		logger.Error("Not authorized to create dns record")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create dns record", nil)
+		return
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."+zone.Name))
+	rtype = strings.ToUpper(rtype)
+	err = a.validateRecord(name, rtype, value)
+	if err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	record = &model.DnsRecord{Model: model.Model{Creater: memberShip.UserID}, Owner: zone.Owner, ZoneID: zone.ID, Name: name, Type: rtype, Value: value}
+	err = db.Create(record).Error
+	if err != nil {
+		logger.Error("DB failed to create dns record", err)
+		err = NewCLError(ErrDnsRecordCreateFailed, "Failed to create dns record", err)
+		return
+	}
+	err = a.Apply(ctx, zone.RouterID, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) GetRecordByUUID(ctx context.Context, zone *model.DnsZone, uuID string) (record *model.DnsRecord, err error) {
+	ctx, db := GetContextDB(ctx)
+	record = &model.DnsRecord{}
+	err = db.Where("zone_id = ? and uuid = ?", zone.ID, uuID).Take(record).Error
+	if err != nil {
+		logger.Error("Failed to query dns record, %v", err)
+		err = NewCLError(ErrDnsRecordNotFound, "Failed to find dns record", err)
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) UpdateRecord(ctx context.Context, zone *model.DnsZone, record *model.DnsRecord, value string) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("dns:update_record").ValidateOwner(model.Writer, record.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the dns record")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the dns record", nil)
+		return
+	}
+	if record.InstanceID > 0 {
+		err = NewCLError(ErrDnsRecordInvalid, "Records of instances are managed automatically", nil)
+		return
+	}
+	err = a.validateRecord(record.Name, record.Type, value)
+	if err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	record.Value = value
+	err = db.Model(record).Update("value", value).Error
+	if err != nil {
+		logger.Error("DB failed to update dns record", err)
+		err = NewCLError(ErrDnsRecordUpdateFailed, "Failed to update dns record", err)
+		return
+	}
+	err = a.Apply(ctx, zone.RouterID, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) DeleteRecord(ctx context.Context, zone *model.DnsZone, record *model.DnsRecord) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("dns:delete_record").ValidateOwner(model.Writer, record.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the dns record")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the dns record", nil)
+		return
+	}
+	if record.InstanceID > 0 {
+		err = NewCLError(ErrDnsRecordInvalid, "Records of instances are managed automatically", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if err = db.Delete(record).Error; err != nil {
+		logger.Error("DB failed to delete dns record", err)
+		err = NewCLError(ErrDnsRecordDeleteFailed, "Failed to delete dns record", err)
+		return
+	}
+	err = a.Apply(ctx, zone.RouterID, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *DnsAdmin) ListRecords(ctx context.Context, offset, limit int64, order string, zone *model.DnsZone) (total int64, records []*model.DnsRecord, err error) {
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	records = []*model.DnsRecord{}
+	if err = ListFilter(ctx, db.Model(&model.DnsRecord{})).Where("zone_id = ?", zone.ID).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count dns records, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count dns records", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.DnsRecord{}, offset, limit, order).Where("zone_id = ?", zone.ID).Find(&records).Error; err != nil {
+		logger.Error("DB failed to query dns records, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query dns records", err)
+		return
+	}
+	return
+}
@@ -11,10 +11,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -29,7 +30,77 @@ var (
 type FlavorAdmin struct{}
 type FlavorView struct{}
 
-func (a *FlavorAdmin) Create(ctx context.Context, name string, cpu, memory, disk int32) (flavor *model.Flavor, err error) {
+var (
+	cpuModelRegexp   = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+	cpuFeatureRegexp = regexp.MustCompile(`^-?[a-z0-9._-]{1,32}$`)
+	watchdogActions  = map[string]bool{"reset": true, "shutdown": true, "poweroff": true, "pause": true, "none": true, "inject-nmi": true}
+)
+
+// CheckExtraSpecs validates the extra specs for a guest of the given size, memory is in MB
+func (a *FlavorAdmin) CheckExtraSpecs(specs *model.ExtraSpecs, cpu, memory int32) (err error) {
+	if specs.IsEmpty() {
+		return
+	}
+	if specs.CpuPolicy != "" && specs.CpuPolicy != model.CpuPolicyShared && specs.CpuPolicy != model.CpuPolicyDedicated {
+		return NewCLError(ErrInvalidParameter, "CPU policy must be shared or dedicated", nil)
+	}
+	cells := int32(1)
+	if specs.NumaNodes < 0 || specs.NumaNodes > 8 {
+		return NewCLError(ErrInvalidParameter, "NUMA nodes must not exceed 8", nil)
+	}
+	if specs.NumaNodes > 1 {
+		cells = specs.NumaNodes
+		if cpu%cells != 0 || memory%cells != 0 {
+			return NewCLError(ErrInvalidParameter, "CPU and memory must split evenly over the NUMA nodes", nil)
+		}
+	}
+	switch specs.HugepageSize {
+	case "":
+	case "2M":
+		if (memory/cells)%2 != 0 {
+			return NewCLError(ErrInvalidParameter, "Memory of each NUMA node must be a multiple of the 2M hugepage size", nil)
+		}
+	case "1G":
+		if (memory/cells)%1024 != 0 {
+			return NewCLError(ErrInvalidParameter, "Memory of each NUMA node must be a multiple of the 1G hugepage size", nil)
+		}
+	default:
+		return NewCLError(ErrInvalidParameter, "Hugepage size must be 2M or 1G", nil)
+	}
+	if specs.CpuModel != "" && !cpuModelRegexp.MatchString(specs.CpuModel) {
+		return NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid CPU model %s", specs.CpuModel), nil)
+	}
+	if specs.CpuFeatures != "" {
+		for _, feature := range strings.Split(specs.CpuFeatures, ",") {
+			if !cpuFeatureRegexp.MatchString(feature) {
+				return NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid CPU feature %s", feature), nil)
+			}
+		}
+	}
+	if specs.Watchdog != "" && !watchdogActions[specs.Watchdog] {
+		return NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid watchdog action %s", specs.Watchdog), nil)
+	}
+	if specs.VirtioQueues < 0 || specs.VirtioQueues > 64 {
+		return NewCLError(ErrInvalidParameter, "Virtio queues must not exceed 64", nil)
+	}
+	if specs.MaxCpu != 0 {
+		if specs.MaxCpu < cpu || specs.MaxCpu > 256 {
+			return NewCLError(ErrInvalidParameter, "Max CPU must be between the CPU and 256", nil)
+		}
+		if specs.MaxCpu%cells != 0 {
+			return NewCLError(ErrInvalidParameter, "Max CPU must split evenly over the NUMA nodes", nil)
+		}
+		if specs.MaxCpu > cpu && specs.CpuPolicy == model.CpuPolicyDedicated {
+			return NewCLError(ErrInvalidParameter, "Dedicated CPUs can not be hot-added", nil)
+		}
+	}
+	if specs.MaxMemory != 0 && specs.MaxMemory < memory {
+		return NewCLError(ErrInvalidParameter, "Max memory must not be less than the memory", nil)
+	}
+	return aggregateAdmin.CheckRules(specs.AggregateRules)
+}
+
+func (a *FlavorAdmin) Create(ctx context.Context, name string, cpu, memory, disk int32, specs *model.ExtraSpecs) (flavor *model.Flavor, err error) {
 	memberShip := GetMemberShip(ctx)
 	permit := memberShip.CheckPermission(model.Admin)
 	if !permit {
@@ -37,6 +108,11 @@ func (a *FlavorAdmin) Create(ctx context.Context, name string, cpu, memory, disk
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
 		return
 	}
+	err = a.CheckExtraSpecs(specs, cpu, memory)
+	if err != nil {
+		logger.Errorf("Invalid extra specs %+v, %v", specs, err)
+		return
+	}
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
@@ -49,6 +125,9 @@ func (a *FlavorAdmin) Create(ctx context.Context, name string, cpu, memory, disk
 		Disk:   disk,
 		Memory: memory,
 	}
+	if specs != nil {
+		flavor.ExtraSpecs = *specs
+	}
 	err = db.Create(flavor).Error
 	if err != nil {
 		logger.Error("Failed to create flavor, %v", err)
@@ -130,11 +209,10 @@ func (a *FlavorAdmin) List(ctx context.Context, offset, limit int64, order, quer
 	}
 
 	flavors = []*model.Flavor{}
-	if err = db.Model(&model.Flavor{}).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Flavor{})).Where(query).Count(&total).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to count flavors", err)
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(query).Find(&flavors).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Flavor{}, offset, limit, order).Where(query).Find(&flavors).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to list flavors", err)
 	}
 
@@ -227,7 +305,7 @@ func (v *FlavorView) Create(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	_, err = flavorAdmin.Create(c.Req.Context(), name, int32(cpu), int32(memory), int32(disk))
+	_, err = flavorAdmin.Create(c.Req.Context(), name, int32(cpu), int32(memory), int32(disk), nil)
 	if err != nil {
 		logger.Error("Create flavor failed", err)
 		c.HTML(500, "500")
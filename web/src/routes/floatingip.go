@@ -122,7 +122,7 @@ func (a *FloatingIpAdmin) createDummyFloatingIp(ctx context.Context, instance *m
 
 func (a *FloatingIpAdmin) Create(ctx context.Context, instance *model.Instance, pubSubnets []*model.Subnet, publicIp string, name string, inbound, outbound, activationCount int32, siteSubnets []*model.Subnet, group *model.IpGroup, loadBalancer *model.LoadBalancer) (floatingIps []*model.FloatingIp, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -253,7 +253,7 @@ func (a *FloatingIpAdmin) Attach(ctx context.Context, floatingIp *model.Floating
 		return
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:attach").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -433,6 +433,9 @@ func (a *FloatingIpAdmin) Detach(ctx context.Context, floatingIp *model.Floating
 			logger.Error("DB: delete native fip failed", err)
 			return NewCLError(ErrDeleteNativeFIPFailed, "Failed to delete native floating IP", err)
 		}
+		if err = tagAdmin.replace(db, "floating_ips", floatingIp.ID, floatingIp.Owner, nil); err != nil {
+			return
+		}
 		floatingIp.Instance = nil
 		return
 	}
@@ -633,12 +636,11 @@ func (a *FloatingIpAdmin) List(ctx context.Context, offset, limit int64, order,
 	_, db := GetContextDB(ctx)
 	where := memberShip.GetWhere()
 	floatingIps = []*model.FloatingIp{}
-	if err = db.Model(&model.FloatingIp{}).Where(where).Where(query).Where(intQuery).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.FloatingIp{})).Where(where).Where(query).Where(intQuery).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count floating ip(s), %v", err)
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to count floating IPs", err)
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Group").Preload("Interface").Preload("Interface.Address").Preload("Interface.Address.Subnet").Preload("Subnet").Where(where).Where(query).Where(intQuery).Find(&floatingIps).Error; err != nil {
+	if err = ListPage(ctx, db, &model.FloatingIp{}, offset, limit, order).Preload("Group").Preload("Interface").Preload("Interface.Address").Preload("Interface.Address.Subnet").Preload("Subnet").Where(where).Where(query).Where(intQuery).Find(&floatingIps).Error; err != nil {
 		logger.Error("DB failed to query floating ip(s), %v", err)
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to query floating IPs", err)
 	}
@@ -787,7 +789,7 @@ func (a *FloatingIpAdmin) List4View(ctx context.Context, offset, limit int64, or
 
 func (v *FloatingIpView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("floating_ip:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -893,7 +895,7 @@ func (v *FloatingIpView) Delete(c *macaron.Context, store session.Store) (err er
 
 func (v *FloatingIpView) LBNew(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -922,7 +924,7 @@ func (v *FloatingIpView) LBNew(c *macaron.Context, store session.Store) {
 
 func (v *FloatingIpView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1017,7 +1019,7 @@ func (v *FloatingIpView) Edit(c *macaron.Context, store session.Store) {
 	}
 
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1085,7 +1087,7 @@ func (v *FloatingIpView) Patch(c *macaron.Context, store session.Store) {
 	}
 
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("floating_ip:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1388,6 +1390,9 @@ func (a *FloatingIpAdmin) DeallocateFloatingIp(ctx context.Context, floatingIpID
 		logger.Error("Failed to delete floating ip, %v", err)
 		return NewCLError(ErrFIPDeleteFailed, "Failed to delete floating ip", err)
 	}
+	if err = tagAdmin.replace(db, "floating_ips", floatingIpID, 0, nil); err != nil {
+		return
+	}
 	return
 }
 
@@ -0,0 +1,239 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+const (
+	DefaultGuestExecTimeout = 30
+	MaxGuestExecTimeout     = 300
+	MaxGuestFileSize        = 64 * 1024
+	guestExecWait           = 30 * time.Second
+	guestExecPoll           = 500 * time.Millisecond
+)
+
+var (
+	guestAgentAdmin = &GuestAgentAdmin{}
+)
+
+type GuestAgentAdmin struct{}
+
+func (a *GuestAgentAdmin) checkAgent(instance *model.Instance) (err error) {
+	if instance.Image == nil || !instance.Image.QAEnabled {
+		err = NewCLError(ErrGuestAgentNotEnabled, "Guest Agent is not enabled for the image of instance", nil)
+		logger.Error(err)
+		return
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		err = NewCLError(ErrInstanceInvalidState, "Instance is not in running state", nil)
+		logger.Error(err)
+		return
+	}
+	return
+}
+
+// Exec runs a command inside the instance through its guest agent and waits a while for it to finish,
+// a command still running by then is returned as is and can be looked up later by its ID
+func (a *GuestAgentAdmin) Exec(ctx context.Context, instance *model.Instance, path string, args []string, timeout int32) (guestCmd *model.GuestCommand, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		logger.Error("Not authorized to execute commands in the instance")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to execute commands in the instance", nil)
+		return
+	}
+	if err = a.checkAgent(instance); err != nil {
+		return
+	}
+	if timeout <= 0 {
+		timeout = DefaultGuestExecTimeout
+	}
+	if timeout > MaxGuestExecTimeout {
+		timeout = MaxGuestExecTimeout
+	}
+	ctx, db := GetContextDB(ctx)
+	guestCmd = &model.GuestCommand{
+		Model:      model.Model{Creater: memberShip.UserID},
+		Owner:      instance.Owner,
+		InstanceID: instance.ID,
+		Type:       model.GuestCommandTypeExec,
+		Command:    strings.Join(append([]string{path}, args...), " "),
+		Status:     model.GuestCommandStatusRunning,
+	}
+	if err = db.Create(guestCmd).Error; err != nil {
+		logger.Error("Failed to create guest command", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create guest command", err)
+		return
+	}
+	logger.Infof("User %d executes '%s' in instance %d as guest command %d", memberShip.UserID, guestCmd.Command, instance.ID, guestCmd.ID)
+	// path and args go in a heredoc so nothing in them is ever seen by a shell
+	execArgs, err := json.Marshal(map[string]interface{}{"path": path, "arg": args})
+	if err != nil {
+		logger.Error("Failed to marshal guest command", err)
+		return
+	}
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/guest_exec.sh '%d' '%d' '%d' <<EOF\n%s\nEOF", instance.ID, guestCmd.ID, timeout, base64.StdEncoding.EncodeToString(execArgs))
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Guest exec command execution failed", err)
+		db.Model(guestCmd).Update("status", model.GuestCommandStatusError)
+		return
+	}
+	wait := time.Duration(timeout)*time.Second + 5*time.Second
+	if wait > guestExecWait {
+		wait = guestExecWait
+	}
+	for waited := time.Duration(0); waited < wait; waited += guestExecPoll {
+		time.Sleep(guestExecPoll)
+		fetched := &model.GuestCommand{Model: model.Model{ID: guestCmd.ID}}
+		if db.Take(fetched).Error == nil && fetched.Status != model.GuestCommandStatusRunning {
+			return fetched, nil
+		}
+	}
+	return
+}
+
+// InjectFile writes content to a file inside the instance through its guest agent, the file is
+// created or truncated, and the result comes back on the guest command later
+func (a *GuestAgentAdmin) InjectFile(ctx context.Context, instance *model.Instance, path string, content []byte) (guestCmd *model.GuestCommand, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit, err := memberShip.For("instance:guest_file").CheckOwner(model.Writer, "instances", instance.ID)
+	if err != nil {
+		logger.Error("Failed to check owner")
+		return
+	}
+	if !permit {
+		logger.Error("Not authorized to inject files into the instance")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to inject files into the instance", nil)
+		return
+	}
+	if err = a.checkAgent(instance); err != nil {
+		return
+	}
+	if len(content) > MaxGuestFileSize {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("File content is limited to %d bytes", MaxGuestFileSize), nil)
+		logger.Error(err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	guestCmd = &model.GuestCommand{
+		Model:      model.Model{Creater: memberShip.UserID},
+		Owner:      instance.Owner,
+		InstanceID: instance.ID,
+		Type:       model.GuestCommandTypeFile,
+		Command:    path,
+		Status:     model.GuestCommandStatusRunning,
+	}
+	if err = db.Create(guestCmd).Error; err != nil {
+		logger.Error("Failed to create guest command", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create guest command", err)
+		return
+	}
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/guest_file.sh '%d' '%d' '%s' <<EOF\n%s\nEOF", instance.ID, guestCmd.ID, base64.StdEncoding.EncodeToString([]byte(path)), base64.StdEncoding.EncodeToString(content))
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Guest file command execution failed", err)
+		db.Model(guestCmd).Update("status", model.GuestCommandStatusError)
+		return
+	}
+	return
+}
+
+// RefreshInfo asks the guest agent for the OS and the addresses of the instance, they land on the
+// instance record when the hypervisor calls back
+func (a *GuestAgentAdmin) RefreshInfo(ctx context.Context, instance *model.Instance) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit, err := memberShip.For("instance:guest_info").CheckOwner(model.Reader, "instances", instance.ID)
+	if err != nil {
+		logger.Error("Failed to check owner")
+		return
+	}
+	if !permit {
+		logger.Error("Not authorized to refresh the guest info of the instance")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to refresh the guest info of the instance", nil)
+		return
+	}
+	if err = a.checkAgent(instance); err != nil {
+		return
+	}
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/guest_info.sh '%d'", instance.ID)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Guest info command execution failed", err)
+		return
+	}
+	return
+}
+
+func (a *GuestAgentAdmin) GetCommand(ctx context.Context, instance *model.Instance, uuID string) (guestCmd *model.GuestCommand, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit, err := memberShip.For("instance:guest_command").CheckOwner(model.Writer, "instances", instance.ID)
+	if err != nil {
+		logger.Error("Failed to check owner")
+		return
+	}
+	if !permit {
+		logger.Error("Not authorized to get the guest command")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to get the guest command", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	guestCmd = &model.GuestCommand{}
+	if err = db.Where("uuid = ? and instance_id = ?", uuID, instance.ID).Take(guestCmd).Error; err != nil {
+		logger.Error("Failed to query guest command", err)
+		err = NewCLError(ErrGuestCommandNotFound, "Guest command not found", err)
+		return
+	}
+	// what an admin ran stays with admins
+	if guestCmd.Type == model.GuestCommandTypeExec && !memberShip.CheckPermission(model.Admin) {
+		logger.Error("Not authorized to get the guest command")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to get the guest command", nil)
+		return
+	}
+	return
+}
+
+// quiesceGuests lists the running instances that have a guest agent as hypervisor:ID pairs, the
+// snapshot scripts freeze their filesystems around the snapshot. Failures only cost consistency,
+// so they are logged and the snapshot goes on
+func (a *GuestAgentAdmin) quiesceGuests(ctx context.Context, instanceIDs []int64) string {
+	if len(instanceIDs) == 0 {
+		return ""
+	}
+	ctx, db := GetContextDB(ctx)
+	instances := []*model.Instance{}
+	if err := db.Preload("Image").Where("id in (?) and status = ?", instanceIDs, model.InstanceStatusRunning).Find(&instances).Error; err != nil {
+		logger.Error("Failed to query instances to quiesce", err)
+		return ""
+	}
+	guests := []string{}
+	for _, instance := range instances {
+		if instance.Image == nil || !instance.Image.QAEnabled {
+			continue
+		}
+		hyper := &model.Hyper{}
+		if err := db.Where("hostid = ?", instance.Hyper).Take(hyper).Error; err != nil {
+			logger.Errorf("Failed to query hypervisor %d of instance %d, %v", instance.Hyper, instance.ID, err)
+			continue
+		}
+		guests = append(guests, fmt.Sprintf("%s:%d", hyper.Hostname, instance.ID))
+	}
+	return strings.Join(guests, ",")
+}
@@ -13,7 +13,6 @@ import (
 	"net/http"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -42,11 +41,10 @@ func (a *HyperAdmin) List(ctx context.Context, offset, limit int64, order, query
 	}
 
 	hypers = []*model.Hyper{}
-	if err = db.Model(&model.Hyper{}).Where("hostid >= 0").Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Hyper{})).Where("hostid >= 0").Where(query).Count(&total).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to count hypervisors", err)
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Zone").Where("hostid >= 0").Where(query).Find(&hypers).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Hyper{}, offset, limit, order).Preload("Zone").Where("hostid >= 0").Where(query).Find(&hypers).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to retrieve hypervisors", err)
 	}
 	db = db.Offset(0).Limit(-1)
@@ -93,6 +91,9 @@ func (a *HyperAdmin) Update(ctx context.Context, hyper *model.Hyper) (err error)
 	callScript := false
 	restartCloudlet := 0
 	if hyper.Status != hyperInDB.Status {
+		if err = mfaAdmin.StepUp(ctx, "change the hypervisor status"); err != nil {
+			return
+		}
 		logger.Info("Updating hypervisor status from", hyperInDB.GetStatus(), "to", hyper.GetStatus())
 		hyperInDB.Status = hyper.Status
 		callScript = true
@@ -154,6 +155,29 @@ func (a *HyperAdmin) Update(ctx context.Context, hyper *model.Hyper) (err error)
 	return
 }
 
+// SetTags replaces the tags of the hypervisor, the hyper_tags scheduler hint places guests by them
+func (a *HyperAdmin) SetTags(ctx context.Context, hyper *model.Hyper, tags map[string]string) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
+		logger.Error("Not authorized for this operation", err)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	return tagAdmin.replace(db, "hypers", hyper.ID, 1, tags)
+}
+
+// Tags returns the tags of the hypervisor
+func (a *HyperAdmin) Tags(ctx context.Context, hyper *model.Hyper) (tags map[string]string, err error) {
+	return tagAdmin.Tags(ctx, "hypers", hyper.ID)
+}
+
 func (a *HyperAdmin) GetHyperByHostid(ctx context.Context, hostid int32) (hyper *model.Hyper, err error) {
 	memberShip := GetMemberShip(ctx)
 	permit := memberShip.CheckPermission(model.Admin)
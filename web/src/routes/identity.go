@@ -0,0 +1,276 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	. "web/src/common"
+	"web/src/identity"
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	identityAdmin = &IdentityAdmin{}
+	identityRoles = map[string]model.Role{
+		"reader": model.Reader,
+		"writer": model.Writer,
+		"owner":  model.Owner,
+		"admin":  model.Admin,
+	}
+)
+
+type IdentityAdmin struct {
+	mutex sync.Mutex
+	oidc  *identity.OidcProvider
+}
+
+// LdapEnabled tells if ldap.url is set in the config
+func (a *IdentityAdmin) LdapEnabled() bool {
+	return viper.GetString("ldap.url") != ""
+}
+
+// OidcEnabled tells if oidc.issuer is set in the config
+func (a *IdentityAdmin) OidcEnabled() bool {
+	return viper.GetString("oidc.issuer") != ""
+}
+
+func (a *IdentityAdmin) mappings(source string) (mappings []*identity.Mapping) {
+	err := viper.UnmarshalKey(source+".group_mappings", &mappings)
+	if err != nil {
+		logger.Error("Invalid group mappings of ", source, err)
+	}
+	return
+}
+
+// Oidc returns the provider of the configured issuer, the discovery document is fetched at the first use
+func (a *IdentityAdmin) Oidc(ctx context.Context) (provider *identity.OidcProvider, err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.oidc != nil {
+		provider = a.oidc
+		return
+	}
+	if !a.OidcEnabled() {
+		err = NewCLError(ErrIdentityNotConfigured, "OIDC is not configured", nil)
+		return
+	}
+	cfg := identity.OidcConfig{}
+	err = viper.UnmarshalKey("oidc", &cfg)
+	if err != nil {
+		logger.Error("Invalid oidc config", err)
+		err = NewCLError(ErrIdentityNotConfigured, "Invalid OIDC config", err)
+		return
+	}
+	provider, err = identity.NewOidcProvider(ctx, cfg)
+	if err != nil {
+		logger.Error("Failed to discover oidc issuer", err)
+		err = NewCLError(ErrIdentityProviderFailed, "Failed to discover OIDC issuer", err)
+		return
+	}
+	a.oidc = provider
+	return
+}
+
+// LdapLogin authenticates the user against the directory and provisions it
+func (a *IdentityAdmin) LdapLogin(ctx context.Context, username, password string) (user *model.User, err error) {
+	cfg := identity.LdapConfig{}
+	err = viper.UnmarshalKey("ldap", &cfg)
+	if err != nil {
+		logger.Error("Invalid ldap config", err)
+		err = NewCLError(ErrIdentityNotConfigured, "Invalid LDAP config", err)
+		return
+	}
+	ident, err := identity.LdapAuthenticate(cfg, username, password)
+	if err != nil {
+		logger.Error("LDAP authentication failed", err)
+		err = NewCLError(ErrPasswordMismatch, "Invalid username or password", err)
+		return
+	}
+	user, err = a.Provision(ctx, ident)
+	return
+}
+
+// OidcLogin exchanges the authorization code of the callback and provisions the user
+func (a *IdentityAdmin) OidcLogin(ctx context.Context, code, nonce string) (user *model.User, err error) {
+	provider, err := a.Oidc(ctx)
+	if err != nil {
+		return
+	}
+	ident, err := provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		logger.Error("OIDC code exchange failed", err)
+		err = NewCLError(ErrIdentityProviderFailed, "OIDC login failed", err)
+		return
+	}
+	user, err = a.Provision(ctx, ident)
+	return
+}
+
+// Exchange verifies an ID token issued to this or a trusted client and provisions the user
+func (a *IdentityAdmin) Exchange(ctx context.Context, rawIDToken string) (user *model.User, err error) {
+	provider, err := a.Oidc(ctx)
+	if err != nil {
+		return
+	}
+	ident, err := provider.VerifyToken(ctx, rawIDToken)
+	if err != nil {
+		logger.Error("Failed to verify subject token", err)
+		err = NewCLError(ErrTokenInvalid, "Invalid subject token", err)
+		return
+	}
+	user, err = a.Provision(ctx, ident)
+	return
+}
+
+func (a *IdentityAdmin) adminContext(ctx context.Context) (adminCtx context.Context, err error) {
+	user, err := userAdmin.GetUserByName("admin")
+	if err != nil {
+		return
+	}
+	org, err := orgAdmin.GetOrgByName(ctx, "admin")
+	if err != nil {
+		return
+	}
+	memberShip, err := GetDBMemberShip(user.ID, org.ID)
+	if err != nil {
+		return
+	}
+	memberShip.Role = model.Admin
+	adminCtx = memberShip.SetContext(ctx)
+	return
+}
+
+// Provision creates the user with its personal organization at the first login, then aligns the memberships
+// of the organizations in the group mappings with the groups of the identity
+func (a *IdentityAdmin) Provision(ctx context.Context, ident *identity.Identity) (user *model.User, err error) {
+	if ident.Username == "" || ident.Username == "admin" {
+		err = NewCLError(ErrIdentityProvisionFailed, fmt.Sprintf("Username %q can not be provisioned", ident.Username), nil)
+		return
+	}
+	ctx, err = a.adminContext(ctx)
+	if err != nil {
+		logger.Error("Failed to get admin context", err)
+		err = NewCLError(ErrIdentityProvisionFailed, "Failed to provision user", err)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	user = &model.User{}
+	err = db.Where("username = ?", ident.Username).Take(user).Error
+	if err == nil {
+		if user.Source == identity.SourceLocal && viper.GetBool("identity.link_local_users") {
+			err = db.Model(user).Update("source", ident.Source).Error
+			if err != nil {
+				logger.Error("DB failed to link user", err)
+				err = NewCLError(ErrIdentityProvisionFailed, "Failed to link user", err)
+				return
+			}
+		} else if user.Source != ident.Source {
+			logger.Errorf("User %s exists with source %s, not %s", user.Username, user.Source, ident.Source)
+			err = NewCLError(ErrIdentitySourceMismatch, fmt.Sprintf("User %s exists with another identity source", user.Username), nil)
+			return
+		}
+	} else {
+		var password string
+		password, err = randomHex(32)
+		if err != nil {
+			logger.Error("Failed to generate password", err)
+			err = NewCLError(ErrIdentityProvisionFailed, "Failed to provision user", err)
+			return
+		}
+		logger.Infof("Provisioning user %s from %s", ident.Username, ident.Source)
+		user, err = userAdmin.Create(ctx, ident.Username, password, "")
+		if err != nil {
+			return
+		}
+		err = db.Model(user).Update("source", ident.Source).Error
+		if err != nil {
+			logger.Error("DB failed to update user source", err)
+			err = NewCLError(ErrIdentityProvisionFailed, "Failed to provision user", err)
+			return
+		}
+		_, err = orgAdmin.Create(ctx, ident.Username, ident.Username, "")
+		if err != nil {
+			return
+		}
+	}
+	err = a.syncMembers(ctx, user, ident)
+	return
+}
+
+// syncMembers grants the mapped roles and removes the memberships the groups no longer grant,
+// organizations not in the mappings and the personal organization are left alone
+func (a *IdentityAdmin) syncMembers(ctx context.Context, user *model.User, ident *identity.Identity) (err error) {
+	ctx, db := GetContextDB(ctx)
+	mappings := a.mappings(ident.Source)
+	roles := identity.MapGroups(ident.Groups, mappings)
+	for _, orgName := range identity.ManagedOrgs(mappings) {
+		if orgName == user.Username {
+			continue
+		}
+		org := &model.Organization{}
+		err = db.Where("name = ?", orgName).Take(org).Error
+		if err != nil {
+			logger.Errorf("Organization %s of group mappings not found, %v", orgName, err)
+			err = nil
+			continue
+		}
+		member := &model.Member{}
+		found := db.Where("user_id = ? and org_id = ?", user.ID, org.ID).Take(member).Error == nil
+		role, granted := identityRoles[strings.ToLower(roles[orgName])]
+		switch {
+		case granted && !found:
+			member = &model.Member{
+				Owner:    org.ID,
+				UserName: user.Username,
+				UserID:   user.ID,
+				OrgName:  org.Name,
+				OrgID:    org.ID,
+				Role:     role,
+			}
+			err = db.Create(member).Error
+			if err != nil {
+				logger.Error("DB failed to create member", err)
+				err = NewCLError(ErrMemberCreationFailed, "Failed to create organization member", err)
+				return
+			}
+			continue
+		case granted && member.Role != role:
+			err = db.Model(member).Update("role", role).Error
+			if err != nil {
+				logger.Error("DB failed to update member", err)
+				err = NewCLError(ErrMemberUpdateFailed, "Failed to update organization member", err)
+				return
+			}
+		case !granted && found:
+			err = db.Delete(member).Error
+			if err != nil {
+				logger.Error("DB failed to delete member", err)
+				err = NewCLError(ErrMemberDeleteFailed, "Failed to delete organization member", err)
+				return
+			}
+		default:
+			continue
+		}
+		err = tokenAdmin.RevokeMember(ctx, user.ID, org.ID)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -152,7 +152,7 @@ func (a *ImageAdmin) GetImageByUUID(ctx context.Context, uuID string) (image *mo
 		return nil, NewCLError(ErrImageNotFound, "Image not found", err)
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("image:get").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized to get image")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to get image", nil)
@@ -170,7 +170,7 @@ func (a *ImageAdmin) GetImageByName(ctx context.Context, name string) (image *mo
 		return nil, NewCLError(ErrImageNotFound, "Image not found", err)
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("image:get").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized to get image")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to get image", nil)
@@ -193,7 +193,7 @@ func (a *ImageAdmin) Get(ctx context.Context, id int64) (image *model.Image, err
 		return nil, NewCLError(ErrImageNotFound, "Image not found", err)
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("image:get").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized to get image")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to get image", nil)
@@ -226,7 +226,7 @@ func (a *ImageAdmin) Delete(ctx context.Context, image *model.Image) (err error)
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, image.Owner)
+	permit := memberShip.For("image:delete").ValidateOwner(model.Writer, image.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete image")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete image", nil)
@@ -271,6 +271,9 @@ func (a *ImageAdmin) Delete(ctx context.Context, image *model.Image) (err error)
 	if err = db.Delete(image).Error; err != nil {
 		return NewCLError(ErrImageDeleteFailed, "Failed to delete image record", err)
 	}
+	if err = tagAdmin.replace(db, "images", image.ID, image.Owner, nil); err != nil {
+		return
+	}
 	if err = db.Where("image_id = ?", image.ID).Delete(&model.ImageStorage{}).Error; err != nil {
 		return NewCLError(ErrImageStorageDeleteFailed, "Failed to delete image storage records", err)
 	}
@@ -291,11 +294,10 @@ func (a *ImageAdmin) List(ctx context.Context, offset, limit int64, order, query
 		query = fmt.Sprintf("name like '%%%s%%'", query)
 	}
 	images = []*model.Image{}
-	if err = db.Model(&model.Image{}).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Image{})).Where(query).Count(&total).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to count images", err)
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(query).Find(&images).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Image{}, offset, limit, order).Where(query).Find(&images).Error; err != nil {
 		return 0, nil, NewCLError(ErrSQLSyntaxError, "Failed to find images", err)
 	}
 
@@ -434,7 +436,7 @@ func (a *ImageAdmin) Update(ctx context.Context, image *model.Image, osCode, nam
 
 func (v *ImageView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("image:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -525,7 +527,7 @@ func (v *ImageView) Delete(c *macaron.Context, store session.Store) (err error)
 
 func (v *ImageView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("image:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -558,7 +560,7 @@ func (v *ImageView) New(c *macaron.Context, store session.Store) {
 func (v *ImageView) Create(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("image:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -618,7 +620,7 @@ func (v *ImageView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "images", int64(imageID))
+	permit, err := memberShip.For("image:update").CheckOwner(model.Writer, "images", int64(imageID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
@@ -702,7 +704,7 @@ func (v *ImageView) Patch(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "images", int64(imageID))
+	permit, err := memberShip.For("image:update").CheckOwner(model.Writer, "images", int64(imageID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
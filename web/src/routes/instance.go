@@ -82,6 +82,8 @@ type InstanceData struct {
 	DiskIopsLimit       int32                       `json:"disk_iops_limit"`
 	DiskBpsLimit        int32                       `json:"disk_bps_limit"`
 	StoragePoolRelation map[string]PoolRelationItem `json:"storage_pool_relation,omitempty"`
+	Tags                map[string]string           `json:"tags,omitempty"`
+	ExtraSpecs          *model.ExtraSpecs           `json:"extra_specs,omitempty"`
 }
 
 type InstancesData struct {
@@ -91,7 +93,7 @@ type InstancesData struct {
 
 func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata string, userdataType string, vendorData string, vendorDataType string, image *model.Image,
 	zone *model.Zone, routerID int64, primaryIface *InterfaceInfo, secondaryIfaces []*InterfaceInfo,
-	keys []*model.Key, rootPasswd string, loginPort, hyperID int, cpu int32, memory int32, disk int32, diskIopsLimit int32, diskBpsLimit int32, nestedEnable bool, poolID string) (instances []*model.Instance, err error) {
+	keys []*model.Key, rootPasswd string, loginPort, hyperID int, cpu int32, memory int32, disk int32, specs *model.ExtraSpecs, hints *SchedulerHints, diskIopsLimit int32, diskBpsLimit int32, nestedEnable bool, poolID string, tags map[string]string) (instances []*model.Instance, err error) {
 	logger.Debugf("Create %d instances with image %s, zone %s, router %d, primary interface %v, secondary interfaces %v, keys %v, root password %s, hyper %d, cpu %d, memory %d, disk %d, disk_iops_limit %d, disk_bps_limit %d, nestedEnable %t, poolID %s",
 		count, image.Name, zone.Name, routerID, primaryIface, secondaryIfaces, keys, "********", hyperID, cpu, memory, disk, diskIopsLimit, diskBpsLimit, nestedEnable, poolID)
 	if count > 1 && len(primaryIface.PublicIps) > 0 {
@@ -115,6 +117,11 @@ func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata
 		logger.Error(err)
 		return
 	}
+	// cpu and memory may differ from the flavor the specs come from
+	err = flavorAdmin.CheckExtraSpecs(specs, cpu, memory)
+	if err != nil {
+		return
+	}
 	zoneID := zone.ID
 	if hyperID >= 0 {
 		hyper := &model.Hyper{}
@@ -128,6 +135,11 @@ func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata
 			err = NewCLError(ErrInvalidParameter, "Hypervisor is not in this zone", nil)
 			return
 		}
+		if !HyperSupports(hyper, specs, cpu, memory) {
+			logger.Errorf("Hypervisor %d does not support extra specs %+v", hyperID, specs)
+			err = NewCLError(ErrNoQualifiedHypervisor, "Hypervisor does not support the extra specs of the flavor", nil)
+			return
+		}
 	}
 	if loginPort <= 0 {
 		if image.OSCode == "linux" {
@@ -136,11 +148,18 @@ func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata
 			loginPort = 3389
 		}
 	}
-	hyperGroup, err := GetHyperGroup(ctx, zoneID, -1)
+	aggregateRules, err := aggregateAdmin.Rules(ctx, specs, image.ID, memberShip.OrgID)
 	if err != nil {
-		logger.Error("No valid hypervisor", err)
 		return
 	}
+	scheduleReq := &ScheduleRequest{ZoneID: zoneID, Cpu: cpu, Memory: memory, Disk: disk, Specs: specs, Hints: hints, AggregateRules: aggregateRules}
+	if hyperID < 0 {
+		// fail early with the reasons before any record is made, each instance is placed below
+		_, err = Schedule(ctx, scheduleReq)
+		if err != nil {
+			return
+		}
+	}
 	passwdLogin := false
 	if rootPasswd != "" {
 		passwdLogin = true
@@ -230,11 +249,19 @@ func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata
 			Memory:         memory,
 			Disk:           disk,
 		}
+		if specs != nil {
+			instance.ExtraSpecs = *specs
+		}
 		err = db.Create(instance).Error
 		if err != nil {
 			logger.Error("DB create instance failed", err)
 			return nil, NewCLError(ErrInstanceCreationFailed, "Failed to create instance record", err)
 		}
+		err = tagAdmin.replace(db, "instances", instance.ID, instance.Owner, tags)
+		if err != nil {
+			logger.Error("Failed to tag instance", err)
+			return
+		}
 		instance.Image = image
 		instance.Zone = zone
 		var bootVolume *model.Volume
@@ -270,9 +297,17 @@ func (a *InstanceAdmin) Create(ctx context.Context, count int, prefix, userdata
 		}
 		instance.Interfaces = ifaces
 		rcNeeded := fmt.Sprintf("cpu=%d memory=%d disk=%d network=%d", instance.Cpu, instance.Memory*1024, int64(instance.Disk)*1024*1024, 0)
-		control := "select=" + hyperGroup + " " + rcNeeded
+		var control string
 		if i == 0 && hyperID >= 0 {
 			control = fmt.Sprintf("inter=%d %s", hyperID, rcNeeded)
+		} else {
+			// the scheduler of the control plane ranks the hosts, the claim keeps the next instances off the best one
+			var hyperGroup string
+			hyperGroup, err = SelectHyperGroup(ctx, scheduleReq)
+			if err != nil {
+				return
+			}
+			control = "select=" + hyperGroup + " " + rcNeeded
 		}
 		command := fmt.Sprintf("/opt/cloudland/scripts/backend/launch_vm.sh '%d' '%s.%s' '%t' '%s' '%d' '%d' '%d' '%d' '%t' '%s' '%s' <<EOF\n%s\nEOF", instance.ID, imagePrefix, image.Format, image.QAEnabled, hostname, instance.Cpu, instance.Memory, instance.Disk, bootVolume.ID, nestedEnable, image.BootLoader, instance.UUID, base64.StdEncoding.EncodeToString([]byte(metadata)))
 		execCommands = append(execCommands, &ExecutionCommand{
@@ -404,21 +439,43 @@ func (a *InstanceAdmin) ChangeInstanceStatus(ctx context.Context, instance *mode
 	return
 }
 
+// powerActionPolicy maps a power action to the policy action, both restarts are instance:reboot and both stops instance:stop
+func powerActionPolicy(action PowerAction) string {
+	switch action {
+	case Restart, HardRestart:
+		return "instance:reboot"
+	case Stop, HardStop:
+		return "instance:stop"
+	default:
+		return "instance:" + string(action)
+	}
+}
+
 func (a *InstanceAdmin) Update(ctx context.Context, instance *model.Instance, hostname string, action PowerAction, hyperID int) (err error) {
 	if instance.Status == model.InstanceStatusMigrating {
 		err = fmt.Errorf("Instance is not in a valid state")
 		return
 	}
 	memberShip := GetMemberShip(ctx)
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", instance.ID)
-	if err != nil {
-		logger.Error("Failed to check owner")
-		return
+	actions := []string{}
+	if string(action) != "" {
+		actions = append(actions, powerActionPolicy(action))
 	}
-	if !permit {
-		logger.Error("Not authorized to update the instance")
-		err = NewCLError(ErrPermissionDenied, "Not authorized to update the instance", nil)
-		return
+	if hostname != instance.Hostname || len(actions) == 0 {
+		actions = append(actions, "instance:update")
+	}
+	permit := false
+	for _, policyAction := range actions {
+		permit, err = memberShip.For(policyAction).CheckOwner(model.Writer, "instances", instance.ID)
+		if err != nil {
+			logger.Error("Failed to check owner")
+			return
+		}
+		if !permit {
+			logger.Errorf("Not authorized to %s the instance", policyAction)
+			err = NewCLError(ErrPermissionDenied, "Not authorized to update the instance", nil)
+			return
+		}
 	}
 
 	ctx, db, newTransaction := StartTransaction(ctx)
@@ -453,8 +510,10 @@ func (a *InstanceAdmin) Update(ctx context.Context, instance *model.Instance, ho
 	return
 }
 
-func (a *InstanceAdmin) Resize(ctx context.Context, instance *model.Instance, cpu int32, memory int32) (err error) {
-	logger.Debugf("Resize instance %d with cpu %d, memory %d", instance.ID, cpu, memory)
+// Resize changes the size of the instance to the flavor or to the given size, online by hot-adding to the running guest,
+// offline by restarting it, migrate moves it to another hypervisor when its own lacks the capacity
+func (a *InstanceAdmin) Resize(ctx context.Context, instance *model.Instance, flavor *model.Flavor, cpu, memory, disk int32, mode string, migrate bool) (err error) {
+	logger.Debugf("Resize instance %d with flavor %+v, cpu %d, memory %d, disk %d, mode %s, migrate %t", instance.ID, flavor, cpu, memory, disk, mode, migrate)
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
@@ -462,7 +521,7 @@ func (a *InstanceAdmin) Resize(ctx context.Context, instance *model.Instance, cp
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", instance.ID)
+	permit, err := memberShip.For("instance:resize").CheckOwner(model.Writer, "instances", instance.ID)
 	if err != nil {
 		logger.Error("Failed to check owner")
 		return
@@ -495,26 +554,172 @@ func (a *InstanceAdmin) Resize(ctx context.Context, instance *model.Instance, cp
 		logger.Error("Instance has volume restoring", err)
 		return
 	}
-	instance.Status = status
+	if mode == "" {
+		mode = model.ResizeModeOffline
+	}
+	var flavorID int64
+	specs := instance.ExtraSpecs
+	if disk != 0 && disk < bootVolume.Size {
+		return NewCLError(ErrInvalidParameter, "The boot disk can not shrink", nil)
+	}
+	if disk > bootVolume.Size && mode != model.ResizeModeOnline {
+		return NewCLError(ErrInvalidParameter, "The boot disk grows online only, resize the volume to grow it offline", nil)
+	}
+	if flavor != nil {
+		flavorID = flavor.ID
+		cpu, memory, specs = flavor.Cpu, flavor.Memory, flavor.ExtraSpecs
+		if disk == 0 && mode == model.ResizeModeOnline {
+			disk = flavor.Disk
+		}
+	}
+	if disk < bootVolume.Size {
+		disk = bootVolume.Size
+	}
+	if mode == model.ResizeModeOnline {
+		if instance.Status != model.InstanceStatusRunning {
+			return NewCLError(ErrInstanceInvalidState, "Online resize needs a running instance", nil)
+		}
+		if specs != instance.ExtraSpecs {
+			return NewCLError(ErrInvalidParameter, "Online resize can not change the extra specs, resize offline instead", nil)
+		}
+		if cpu < instance.Cpu || memory < instance.Memory {
+			return NewCLError(ErrInvalidParameter, "Online resize can only grow the instance", nil)
+		}
+		maxCpu, maxMemory := specs.MaxCpu, specs.MaxMemory
+		if maxCpu < instance.Cpu {
+			maxCpu = instance.Cpu
+		}
+		if maxMemory < instance.Memory {
+			maxMemory = instance.Memory
+		}
+		if cpu > maxCpu {
+			return NewCLError(ErrInvalidParameter, fmt.Sprintf("CPU can be hot-added up to %d, resize offline instead", maxCpu), nil)
+		}
+		if memory > maxMemory {
+			return NewCLError(ErrInvalidParameter, fmt.Sprintf("Memory can be hot-added up to %d MB, resize offline instead", maxMemory), nil)
+		}
+		// linux onlines hot-added memory in blocks of 128M
+		step := int32(128)
+		if specs.HugepageSize == "1G" {
+			step = 1024
+		}
+		if (memory-instance.Memory)%step != 0 {
+			return NewCLError(ErrInvalidParameter, fmt.Sprintf("Hot-added memory must be a multiple of %d MB", step), nil)
+		}
+	} else {
+		// a guest restarting with a size beyond its max gets the new size as the max
+		if specs.MaxCpu != 0 && (specs.MaxCpu < cpu || specs.CpuPolicy == model.CpuPolicyDedicated) {
+			specs.MaxCpu = cpu
+		}
+		if specs.MaxMemory != 0 && specs.MaxMemory < memory {
+			specs.MaxMemory = memory
+		}
+	}
+	err = flavorAdmin.CheckExtraSpecs(&specs, cpu, memory)
+	if err != nil {
+		return
+	}
+	hyper := &model.Hyper{}
+	err = db.Where("hostid = ?", instance.Hyper).Take(hyper).Error
+	if err != nil {
+		logger.Error("Failed to query hypervisor", err)
+		return NewCLError(ErrHypervisorNotFound, "Failed to find the hypervisor of the instance", err)
+	}
+	// the instance gives its pinned cpus and hugepages back before it is resized
+	needCpu, needMemory := cpu, memory
+	if instance.CpuPolicy == model.CpuPolicyDedicated {
+		needCpu -= instance.Cpu
+	}
+	if instance.HugepageSize == specs.HugepageSize {
+		needMemory -= instance.Memory
+	}
+	var lacking error
+	if !HyperSupports(hyper, &specs, needCpu, needMemory) {
+		logger.Errorf("Hypervisor %d does not support extra specs %+v", hyper.Hostid, specs)
+		lacking = NewCLError(ErrNoQualifiedHypervisor, "Hypervisor of the instance does not support the extra specs", nil)
+	} else {
+		// the instance already holds its current size on the hypervisor, so only growth needs room
+		grow := &ScheduleRequest{Cpu: cpu - instance.Cpu, Memory: memory - instance.Memory, Disk: disk - bootVolume.Size}
+		if grow.Cpu < 0 {
+			grow.Cpu = 0
+		}
+		if grow.Memory < 0 {
+			grow.Memory = 0
+		}
+		if grow.Cpu > 0 || grow.Memory > 0 || grow.Disk > 0 {
+			lacking = ClaimOnHyper(ctx, instance.Hyper, grow)
+		}
+	}
+	if lacking != nil && (!migrate || mode == model.ResizeModeOnline) {
+		return NewCLError(ErrNoQualifiedHypervisor, "Hypervisor of the instance lacks the capacity for the new size, resize offline with migrate to move it to another hypervisor", lacking)
+	}
+	instance.FlavorID = flavorID
+	instance.Flavor = flavor
 	instance.Cpu = cpu
 	instance.Memory = memory
-	if instance.Disk == 0 {
-		instance.Disk = bootVolume.Size
-	}
-	err = db.Model(&model.Instance{}).Where("id = ?", instance.ID).Updates(map[string]interface{}{
-		"flavor_id": 0,
-		"status":    instance.Status,
-		"memory":    instance.Memory,
-		"cpu":       instance.Cpu,
-		"disk":      instance.Disk,
-	}).Error
+	instance.Disk = disk
+	instance.ExtraSpecs = specs
+	values := map[string]interface{}{
+		"flavor_id":       instance.FlavorID,
+		"memory":          instance.Memory,
+		"cpu":             instance.Cpu,
+		"disk":            instance.Disk,
+		"cpu_policy":      instance.CpuPolicy,
+		"numa_nodes":      instance.NumaNodes,
+		"hugepage_size":   instance.HugepageSize,
+		"cpu_model":       instance.CpuModel,
+		"cpu_features":    instance.CpuFeatures,
+		"watchdog":        instance.Watchdog,
+		"virtio_queues":   instance.VirtioQueues,
+		"max_cpu":         instance.MaxCpu,
+		"max_memory":      instance.MaxMemory,
+		"aggregate_rules": instance.AggregateRules,
+	}
+	if lacking == nil {
+		instance.Status = status
+		values["status"] = instance.Status
+	}
+	err = db.Model(&model.Instance{}).Where("id = ?", instance.ID).Updates(values).Error
 	if err != nil {
 		logger.Error("Failed to save instance", err)
 		return NewCLError(ErrInstanceUpdateFailed, "Failed to save instance", err)
 	}
+	if lacking != nil {
+		// a cold migration defines the guest with the new size on a hypervisor that has the room
+		var migrations []*model.Migration
+		migrations, err = migrationAdmin.create(ctx, fmt.Sprintf("resize-%s", instance.Hostname), []*model.Instance{instance}, true, -1)
+		if err == nil && len(migrations) == 0 {
+			err = NewCLError(ErrNoQualifiedHypervisor, "No hypervisor has the capacity for the new size", lacking)
+		}
+		return
+	}
 
 	control := fmt.Sprintf("inter=%d", instance.Hyper)
-	command := fmt.Sprintf("/opt/cloudland/scripts/backend/resize_vm.sh '%d' '%d' '%d'", instance.ID, cpu, memory)
+	if mode == model.ResizeModeOnline {
+		// hot-add before growing the disk, a grown disk can not shrink back if the hotplug fails while
+		// the callback of the hotplug records the size the guest really has whatever happens to the disk
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/hotplug_vm.sh '%d' '%d' '%d'", instance.ID, cpu, memory)
+		err = HyperExecute(ctx, control, command)
+		if err != nil {
+			logger.Error("Hotplug remote exec failed", err)
+			return
+		}
+		if disk > bootVolume.Size {
+			bootVolume.Instance = instance
+			err = volumeAdmin.resize(ctx, bootVolume, disk, true)
+			if err != nil {
+				logger.Error("Failed to grow the boot volume", err)
+				return
+			}
+		}
+		return
+	}
+	extraSpecs, err := json.Marshal(&instance.ExtraSpecs)
+	if err != nil {
+		logger.Errorf("Failed to marshal extra specs, %v", err)
+		return NewCLError(ErrJSONMarshalFailed, "Failed to marshal extra specs", err)
+	}
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/resize_vm.sh '%d' '%d' '%d' <<EOF\n%s\nEOF", instance.ID, cpu, memory, base64.StdEncoding.EncodeToString(extraSpecs))
 	err = HyperExecute(ctx, control, command)
 	if err != nil {
 		logger.Error("Resize remote exec failed", err)
@@ -537,7 +742,7 @@ func (a *InstanceAdmin) Reinstall(ctx context.Context, instance *model.Instance,
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", instance.ID)
+	permit, err := memberShip.For("instance:reinstall").CheckOwner(model.Writer, "instances", instance.ID)
 	if err != nil {
 		logger.Error("Failed to check owner")
 		return
@@ -708,7 +913,7 @@ func (a *InstanceAdmin) SetUserPassword(ctx context.Context, id int64, user, pas
 		return NewCLError(ErrInstanceNotFound, "Failed to get instance", err)
 	}
 	memberShip := GetMemberShip(ctx)
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", instance.ID)
+	permit, err := memberShip.For("instance:set_user_password").CheckOwner(model.Writer, "instances", instance.ID)
 	if err != nil {
 		logger.Error("Failed to check owner")
 		return
@@ -954,6 +1159,10 @@ func (a *InstanceAdmin) buildMetadata(ctx context.Context, primaryIface *Interfa
 	if dns == primaryIP {
 		dns = ""
 	}
+	tags, err := tagAdmin.Tags(ctx, "instances", instance.ID)
+	if err != nil {
+		return
+	}
 	instData := &InstanceData{
 		Userdata:            instance.Userdata,
 		UserdataType:        instance.UserdataType,
@@ -970,6 +1179,10 @@ func (a *InstanceAdmin) buildMetadata(ctx context.Context, primaryIface *Interfa
 		DiskIopsLimit:       diskIopsLimit,
 		DiskBpsLimit:        diskBpsLimit,
 		StoragePoolRelation: poolRelation,
+		Tags:                tags,
+	}
+	if !instance.ExtraSpecs.IsEmpty() {
+		instData.ExtraSpecs = &instance.ExtraSpecs
 	}
 	jsonData, err := json.Marshal(instData)
 	if err != nil {
@@ -980,6 +1193,20 @@ func (a *InstanceAdmin) buildMetadata(ctx context.Context, primaryIface *Interfa
 }
 
 func (a *InstanceAdmin) GetMetadata(ctx context.Context, instance *model.Instance, rootPasswd string) (metadata string, err error) {
+	instData, err := a.GetInstanceData(ctx, instance, rootPasswd)
+	if err != nil {
+		return
+	}
+	jsonData, err := json.Marshal(instData)
+	if err != nil {
+		logger.Errorf("Failed to marshal instance json data, %v", err)
+		return "", NewCLError(ErrJSONMarshalFailed, "Failed to marshal instance json data", err)
+	}
+	return string(jsonData), nil
+}
+
+// GetInstanceData builds the metadata of an instance loaded by Get from the current state in the DB
+func (a *InstanceAdmin) GetInstanceData(ctx context.Context, instance *model.Instance, rootPasswd string) (instData *InstanceData, err error) {
 	vlans := []*VlanInfo{}
 	instLinks := []*NetworkLink{}
 	volumes := []*VolumeInfo{}
@@ -1004,6 +1231,10 @@ func (a *InstanceAdmin) GetMetadata(ctx context.Context, instance *model.Instanc
 		}
 	}
 	dns := ""
+	tags, err := tagAdmin.Tags(ctx, "instances", instance.ID)
+	if err != nil {
+		return
+	}
 	instNetworks, moreAddresses, err = GetInstanceNetworks(ctx, instance, nil)
 	if err != nil {
 		logger.Errorf("Failed to get instance networks, %v", err)
@@ -1028,7 +1259,7 @@ func (a *InstanceAdmin) GetMetadata(ctx context.Context, instance *model.Instanc
 			MoreAddresses: moreAddresses,
 		})
 	}
-	instData := &InstanceData{
+	instData = &InstanceData{
 		Userdata:       instance.Userdata,
 		UserdataType:   instance.UserdataType,
 		Vendordata:     instance.Vendordata,
@@ -1044,13 +1275,12 @@ func (a *InstanceAdmin) GetMetadata(ctx context.Context, instance *model.Instanc
 		OSCode:         GetImageOSCode(ctx, instance),
 		DiskIopsLimit:  diskIopsLimit,
 		DiskBpsLimit:   diskBpsLimit,
+		Tags:           tags,
 	}
-	jsonData, err := json.Marshal(instData)
-	if err != nil {
-		logger.Errorf("Failed to marshal instance json data, %v", err)
-		return "", NewCLError(ErrJSONMarshalFailed, "Failed to marshal instance json data", err)
+	if !instance.ExtraSpecs.IsEmpty() {
+		instData.ExtraSpecs = &instance.ExtraSpecs
 	}
-	return string(jsonData), nil
+	return
 }
 
 // CleanupInstanceRuleLinks cleans up all rule links for an instance
@@ -1126,7 +1356,7 @@ func (a *InstanceAdmin) Delete(ctx context.Context, instance *model.Instance) (e
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, instance.Owner)
+	permit := memberShip.For("instance:delete").ValidateOwner(model.Writer, instance.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the instance")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the instance", nil)
@@ -1167,6 +1397,11 @@ func (a *InstanceAdmin) Delete(ctx context.Context, instance *model.Instance) (e
 		}
 		instance.FloatingIps = nil
 	}
+	err = portmapAdmin.DeleteByInstance(ctx, instance)
+	if err != nil {
+		logger.Errorf("Failed to delete port forwardings, %v", err)
+		return
+	}
 	if err = db.Where("instance_id = ?", instance.ID).Find(&instance.Volumes).Error; err != nil {
 		logger.Errorf("Failed to query volumes, %v", err)
 		return NewCLError(ErrSQLSyntaxError, "Failed to query volumes for instance", err)
@@ -1197,6 +1432,9 @@ func (a *InstanceAdmin) Delete(ctx context.Context, instance *model.Instance) (e
 					logger.Error("DB: delete boot volume failed", err)
 					return NewCLError(ErrBootVolumeDeleteFailed, "Delete boot volume failed", err)
 				}
+				if err = tagAdmin.replace(db, "volumes", volume.ID, volume.Owner, nil); err != nil {
+					return
+				}
 			}
 		}
 		instance.Volumes = nil
@@ -1269,7 +1507,7 @@ func (a *InstanceAdmin) Get(ctx context.Context, id int64) (instance *model.Inst
 			return
 		}
 	}
-	permit := memberShip.ValidateOwner(model.Reader, instance.Owner)
+	permit := memberShip.For("instance:get").ValidateOwner(model.Reader, instance.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the instance")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the instance", nil)
@@ -1367,7 +1605,7 @@ func GetDomainByInstanceUUID(ctx context.Context, uuid string) (string, error) {
 
 func (a *InstanceAdmin) List(ctx context.Context, offset, limit int64, order, query string) (total int64, instances []*model.Instance, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("instance:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -1385,12 +1623,11 @@ func (a *InstanceAdmin) List(ctx context.Context, offset, limit int64, order, qu
 
 	where := memberShip.GetWhere()
 	instances = []*model.Instance{}
-	if err = db.Model(&model.Instance{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Instance{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count instance(s)", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Volumes").Preload("Image").Preload("Zone").Preload("Flavor").Preload("Keys").Where(where).Where(query).Find(&instances).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Instance{}, offset, limit, order).Preload("Volumes").Preload("Image").Preload("Zone").Preload("Flavor").Preload("Keys").Where(where).Where(query).Find(&instances).Error; err != nil {
 		logger.Errorf("Failed to query instance(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query instance(s)", err)
 		return
@@ -1437,7 +1674,7 @@ func (a *InstanceAdmin) List(ctx context.Context, offset, limit int64, order, qu
 // It does not modify the original List method used by the API layer.
 func (a *InstanceAdmin) List4View(ctx context.Context, offset, limit int64, order string, params *InstanceSearchParams) (total int64, instances []*model.Instance, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("instance:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -1588,7 +1825,7 @@ func (v *InstanceView) List(c *macaron.Context, store session.Store) {
 
 func (v *InstanceView) UpdateTable(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("instance:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1653,7 +1890,7 @@ func (v *InstanceView) SearchJSON(c *macaron.Context, store session.Store) {
 
 func (v *InstanceView) Status(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("instance:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1720,7 +1957,7 @@ func (v *InstanceView) Delete(c *macaron.Context, store session.Store) (err erro
 func (v *InstanceView) New(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("instance:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1816,7 +2053,7 @@ func (v *InstanceView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", int64(instanceID))
+	permit, err := memberShip.For("instance:update").CheckOwner(model.Writer, "instances", int64(instanceID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -1937,7 +2174,7 @@ func (v *InstanceView) SetUserPassword(c *macaron.Context, store session.Store)
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "instances", int64(instanceID))
+	permit, err := memberShip.For("instance:set_user_password").CheckOwner(model.Writer, "instances", int64(instanceID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -2158,7 +2395,7 @@ func (v *InstanceView) Resize(c *macaron.Context, store session.Store) {
 			memory = int32(newMemory)
 		}
 
-		err = instanceAdmin.Resize(ctx, instance, cpu, memory)
+		err = instanceAdmin.Resize(ctx, instance, nil, cpu, memory, 0, model.ResizeModeOffline, false)
 		if err != nil {
 			logger.Error("Resize failed", err)
 			c.Data["ErrorMsg"] = err.Error()
@@ -2375,7 +2612,7 @@ func (v *InstanceView) getSecurityGroups(ctx context.Context, routerID int64, sg
 func (v *InstanceView) Create(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("instance:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Need Write permissions")
 		c.Data["ErrorMsg"] = "Need Write permissions"
@@ -2688,7 +2925,7 @@ func (v *InstanceView) Create(c *macaron.Context, store session.Store) {
 		}
 	}
 	poolID := c.QueryTrim("pool")
-	_, err = instanceAdmin.Create(ctx, count, hostname, userdata, userdataType, vendordata, vendordataType, image, zone, routerID, primaryIface, secondaryIfaces, instKeys, rootPasswd, loginPort, hyperID, flavor.Cpu, flavor.Memory, flavor.Disk, int32(diskIopsLimit), int32(diskBpsLimit), nestedEnable, poolID)
+	_, err = instanceAdmin.Create(ctx, count, hostname, userdata, userdataType, vendordata, vendordataType, image, zone, routerID, primaryIface, secondaryIfaces, instKeys, rootPasswd, loginPort, hyperID, flavor.Cpu, flavor.Memory, flavor.Disk, &flavor.ExtraSpecs, nil, int32(diskIopsLimit), int32(diskBpsLimit), nestedEnable, poolID, nil)
 	if err != nil {
 		logger.Error("Create instance failed", err)
 		c.Data["ErrorMsg"] = err.Error()
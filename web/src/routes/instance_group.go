@@ -0,0 +1,487 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ScaleOut = "scale_out"
+	ScaleIn  = "scale_in"
+)
+
+var instanceGroupAdmin = &InstanceGroupAdmin{}
+
+// instanceGroupLease bounds a reconciliation, a group claimed by a node which died is reconciled again after it
+const instanceGroupLease = 10 * time.Minute
+
+// InstanceGroupAdmin keeps the instance groups, their members and scaling policies,
+// the REST API reconciles the members with the desired count
+type InstanceGroupAdmin struct{}
+
+// checkCounts requires min <= desired <= max
+func (a *InstanceGroupAdmin) checkCounts(minCount, maxCount, desiredCount int32) (err error) {
+	if minCount < 0 || maxCount < minCount || desiredCount < minCount || desiredCount > maxCount {
+		return NewCLError(ErrInvalidInstanceGroup, fmt.Sprintf("Counts must satisfy 0 <= min(%d) <= desired(%d) <= max(%d)", minCount, desiredCount, maxCount), nil)
+	}
+	return
+}
+
+// GroupContext returns a context acting for the organization of the group, the reconciler creates and
+// deletes the members with it whether it runs for a request, an alert or in the background
+func (a *InstanceGroupAdmin) GroupContext(ctx context.Context, group *model.InstanceGroup) context.Context {
+	memberShip := &MemberShip{UserID: group.Creater, OrgID: group.Owner, Role: model.Writer}
+	return memberShip.SetContext(ctx)
+}
+
+func (a *InstanceGroupAdmin) Create(ctx context.Context, name string, template *model.LaunchTemplate, templateVersion, minCount, maxCount, desiredCount int32, listener *model.Listener, backendPort int32) (group *model.InstanceGroup, err error) {
+	memberShip := GetMemberShip(ctx)
+	// the group creates instances on behalf of the organization, so its creator must be allowed to create them
+	permit := memberShip.For("instance_group:create").CheckPermission(model.Writer) && memberShip.For("instance:create").CheckPermission(model.Writer)
+	if !permit {
+		logger.Error("Not authorized to create instance groups")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create instance groups", nil)
+		return
+	}
+	if err = a.checkCounts(minCount, maxCount, desiredCount); err != nil {
+		return
+	}
+	if templateVersion > 0 {
+		if _, err = launchTemplateAdmin.GetVersion(ctx, template, templateVersion); err != nil {
+			return
+		}
+	}
+	listenerID := int64(0)
+	if listener != nil {
+		if backendPort <= 0 {
+			err = NewCLError(ErrInvalidInstanceGroup, "Backend port is required with a listener", nil)
+			return
+		}
+		listenerID = listener.ID
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	group = &model.InstanceGroup{
+		Model:           model.Model{Creater: memberShip.UserID},
+		Owner:           memberShip.OrgID,
+		Name:            name,
+		TemplateID:      template.ID,
+		Template:        template,
+		TemplateVersion: templateVersion,
+		MinCount:        minCount,
+		MaxCount:        maxCount,
+		DesiredCount:    desiredCount,
+		ListenerID:      listenerID,
+		Listener:        listener,
+		BackendPort:     backendPort,
+		Status:          "pending",
+	}
+	err = db.Create(group).Error
+	if err != nil {
+		logger.Errorf("DB failed to create instance group, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create instance group", err)
+		return
+	}
+	return
+}
+
+// Update changes the counts and the template version of the group, nil values are left unchanged
+func (a *InstanceGroupAdmin) Update(ctx context.Context, group *model.InstanceGroup, minCount, maxCount, desiredCount, templateVersion *int32) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("instance_group:update").ValidateOwner(model.Writer, group.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the instance group")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the instance group", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	updated := *group
+	if minCount != nil {
+		updated.MinCount = *minCount
+	}
+	if maxCount != nil {
+		updated.MaxCount = *maxCount
+	}
+	if desiredCount != nil {
+		updated.DesiredCount = *desiredCount
+	}
+	if err = a.checkCounts(updated.MinCount, updated.MaxCount, updated.DesiredCount); err != nil {
+		return
+	}
+	if templateVersion != nil {
+		if *templateVersion > 0 {
+			if _, err = launchTemplateAdmin.GetVersion(ctx, group.Template, *templateVersion); err != nil {
+				return
+			}
+		}
+		updated.TemplateVersion = *templateVersion
+	}
+	err = db.Model(group).Updates(map[string]interface{}{
+		"min_count":        updated.MinCount,
+		"max_count":        updated.MaxCount,
+		"desired_count":    updated.DesiredCount,
+		"template_version": updated.TemplateVersion,
+	}).Error
+	if err != nil {
+		logger.Errorf("DB failed to update instance group, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update instance group", err)
+		return
+	}
+	group.MinCount, group.MaxCount, group.DesiredCount, group.TemplateVersion = updated.MinCount, updated.MaxCount, updated.DesiredCount, updated.TemplateVersion
+	return
+}
+
+// SetStatus records the outcome of the last reconciliation
+func (a *InstanceGroupAdmin) SetStatus(ctx context.Context, group *model.InstanceGroup, status, reason string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	group.Status, group.Reason = status, reason
+	err = db.Model(group).Updates(map[string]interface{}{"status": status, "reason": reason}).Error
+	if err != nil {
+		logger.Errorf("DB failed to update instance group status, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update instance group status", err)
+	}
+	return
+}
+
+// Claim leases the reconciliation of the group to the caller so that the API nodes do not reconcile
+// it at the same time, the lease expires after instanceGroupLease in case the node dies, release it when done
+func (a *InstanceGroupAdmin) Claim(ctx context.Context, groupID int64) (release func(), claimed bool, err error) {
+	ctx, db := GetContextDB(ctx)
+	now := time.Now()
+	until := now.Add(instanceGroupLease).Truncate(time.Microsecond)
+	result := db.Model(&model.InstanceGroup{}).Where("id = ? AND (reconcile_until IS NULL OR reconcile_until < ?)", groupID, now).
+		UpdateColumn("reconcile_until", until)
+	if result.Error != nil {
+		logger.Errorf("DB failed to claim instance group %d, %v", groupID, result.Error)
+		err = NewCLError(ErrDatabaseError, "Failed to claim instance group", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	claimed = true
+	release = func() {
+		// only our own lease, another node may have taken over an expired one
+		err := db.Model(&model.InstanceGroup{}).Where("id = ? AND reconcile_until = ?", groupID, until).
+			UpdateColumn("reconcile_until", gorm.Expr("NULL")).Error
+		if err != nil {
+			logger.Errorf("DB failed to release instance group %d, %v", groupID, err)
+		}
+	}
+	return
+}
+
+// Delete removes the group and its policies, the caller removes the members first
+func (a *InstanceGroupAdmin) Delete(ctx context.Context, group *model.InstanceGroup) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("instance_group:delete").ValidateOwner(model.Writer, group.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the instance group")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the instance group", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	count := 0
+	err = db.Model(&model.InstanceGroupMember{}).Where("group_id = ?", group.ID).Count(&count).Error
+	if err != nil {
+		logger.Errorf("DB failed to count instance group members, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count instance group members", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrInvalidInstanceGroup, fmt.Sprintf("Instance group still has %d members", count), nil)
+		return
+	}
+	err = db.Where("group_id = ?", group.ID).Delete(&model.ScalingPolicy{}).Error
+	if err != nil {
+		logger.Errorf("DB failed to delete scaling policies, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete scaling policies", err)
+		return
+	}
+	if err = db.Delete(group).Error; err != nil {
+		logger.Errorf("DB failed to delete instance group, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete instance group", err)
+		return
+	}
+	// free the name for new groups
+	group.Name = fmt.Sprintf("%s-%d", group.Name, group.CreatedAt.Unix())
+	err = db.Model(&model.InstanceGroup{}).Unscoped().Where("id = ?", group.ID).Update("name", group.Name).Error
+	if err != nil {
+		logger.Errorf("DB failed to update instance group name, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update instance group name", err)
+		return
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) Get(ctx context.Context, id int64) (group *model.InstanceGroup, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	group = &model.InstanceGroup{Model: model.Model{ID: id}}
+	err = db.Preload("Template").Preload("Listener").Where(where).Take(group).Error
+	if err != nil {
+		logger.Errorf("Failed to query instance group, %v", err)
+		err = NewCLError(ErrInstanceGroupNotFound, "Instance group not found", err)
+		return
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) GetInstanceGroupByUUID(ctx context.Context, uuID string) (group *model.InstanceGroup, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	group = &model.InstanceGroup{}
+	err = db.Preload("Template").Preload("Listener").Where(where).Where("uuid = ?", uuID).Take(group).Error
+	if err != nil {
+		logger.Errorf("Failed to query instance group, %v", err)
+		err = NewCLError(ErrInstanceGroupNotFound, "Instance group not found", err)
+		return
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) List(ctx context.Context, offset, limit int64, order, query string) (total int64, groups []*model.InstanceGroup, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+	if order == "" {
+		order = "created_at"
+	}
+	if query != "" {
+		db = db.Where("name like ?", "%"+query+"%")
+	}
+	where := memberShip.GetWhere()
+	groups = []*model.InstanceGroup{}
+	if err = ListFilter(ctx, db.Model(&model.InstanceGroup{})).Where(where).Count(&total).Error; err != nil {
+		logger.Errorf("DB failed to count instance groups, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count instance groups", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.InstanceGroup{}, offset, limit, order).Preload("Template").Preload("Listener").Where(where).Find(&groups).Error; err != nil {
+		logger.Errorf("DB failed to query instance groups, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query instance groups", err)
+		return
+	}
+	return
+}
+
+// ListAll returns the groups of all organizations for the reconciler, without permission checks
+func (a *InstanceGroupAdmin) ListAll(ctx context.Context) (groups []*model.InstanceGroup, err error) {
+	ctx, db := GetContextDB(ctx)
+	groups = []*model.InstanceGroup{}
+	if err = db.Preload("Template").Preload("Listener").Order("id").Find(&groups).Error; err != nil {
+		logger.Errorf("DB failed to query instance groups, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query instance groups", err)
+	}
+	return
+}
+
+// Members returns the members of the group oldest first, members whose instance was deleted
+// outside of the group are returned in gone for the caller to clean up
+func (a *InstanceGroupAdmin) Members(ctx context.Context, group *model.InstanceGroup) (members, gone []*model.InstanceGroupMember, err error) {
+	ctx, db := GetContextDB(ctx)
+	all := []*model.InstanceGroupMember{}
+	if err = db.Preload("Instance").Where("group_id = ?", group.ID).Order("id").Find(&all).Error; err != nil {
+		logger.Errorf("DB failed to query instance group members, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query instance group members", err)
+		return
+	}
+	for _, member := range all {
+		if member.Instance == nil {
+			gone = append(gone, member)
+			continue
+		}
+		members = append(members, member)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) AddMember(ctx context.Context, group *model.InstanceGroup, instance *model.Instance, backendID int64) (member *model.InstanceGroupMember, err error) {
+	ctx, db := GetContextDB(ctx)
+	member = &model.InstanceGroupMember{Owner: group.Owner, GroupID: group.ID, InstanceID: instance.ID, Instance: instance, BackendID: backendID}
+	if err = db.Create(member).Error; err != nil {
+		logger.Errorf("DB failed to create instance group member, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create instance group member", err)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) SetMemberBackend(ctx context.Context, member *model.InstanceGroupMember, backendID int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	member.BackendID = backendID
+	if err = db.Model(member).Update("backend_id", backendID).Error; err != nil {
+		logger.Errorf("DB failed to update instance group member, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update instance group member", err)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) RemoveMember(ctx context.Context, member *model.InstanceGroupMember) (err error) {
+	ctx, db := GetContextDB(ctx)
+	if err = db.Unscoped().Delete(member).Error; err != nil {
+		logger.Errorf("DB failed to delete instance group member, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete instance group member", err)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) CreatePolicy(ctx context.Context, group *model.InstanceGroup, name, action string, adjustment, cooldown int32) (policy *model.ScalingPolicy, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("instance_group:update").ValidateOwner(model.Writer, group.Owner)
+	if !permit {
+		logger.Error("Not authorized to add scaling policies")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to add scaling policies", nil)
+		return
+	}
+	if action != ScaleOut && action != ScaleIn {
+		err = NewCLError(ErrInvalidInstanceGroup, fmt.Sprintf("Scaling action must be %s or %s", ScaleOut, ScaleIn), nil)
+		return
+	}
+	secret := make([]byte, 16)
+	if _, err = rand.Read(secret); err != nil {
+		logger.Errorf("Failed to generate scaling token, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to generate scaling token", err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	policy = &model.ScalingPolicy{
+		Model:      model.Model{Creater: memberShip.UserID},
+		Owner:      group.Owner,
+		GroupID:    group.ID,
+		Name:       name,
+		Action:     action,
+		Adjustment: adjustment,
+		Cooldown:   cooldown,
+		Token:      hex.EncodeToString(secret),
+	}
+	if err = db.Create(policy).Error; err != nil {
+		logger.Errorf("DB failed to create scaling policy, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create scaling policy", err)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) ListPolicies(ctx context.Context, group *model.InstanceGroup) (policies []*model.ScalingPolicy, err error) {
+	ctx, db := GetContextDB(ctx)
+	policies = []*model.ScalingPolicy{}
+	if err = db.Where("group_id = ?", group.ID).Order("id").Find(&policies).Error; err != nil {
+		logger.Errorf("DB failed to query scaling policies, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query scaling policies", err)
+	}
+	return
+}
+
+func (a *InstanceGroupAdmin) DeletePolicy(ctx context.Context, group *model.InstanceGroup, uuID string) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("instance_group:update").ValidateOwner(model.Writer, group.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete scaling policies")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete scaling policies", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	result := db.Where("group_id = ? AND uuid = ?", group.ID, uuID).Delete(&model.ScalingPolicy{})
+	if result.Error != nil {
+		logger.Errorf("DB failed to delete scaling policy, %v", result.Error)
+		return NewCLError(ErrDatabaseError, "Failed to delete scaling policy", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return NewCLError(ErrScalingPolicyNotFound, "Scaling policy not found", nil)
+	}
+	return
+}
+
+// TriggerPolicy applies a scaling policy for an alert, the token of the policy authenticates the alert,
+// triggered is false when the policy is cooling down or the desired count is already at the limit
+func (a *InstanceGroupAdmin) TriggerPolicy(ctx context.Context, uuID, token string) (group *model.InstanceGroup, triggered bool, err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	policy := &model.ScalingPolicy{}
+	err = db.Set("gorm:query_option", "FOR UPDATE").Where("uuid = ?", uuID).Take(policy).Error
+	if err != nil {
+		logger.Errorf("Failed to query scaling policy %s, %v", uuID, err)
+		err = NewCLError(ErrScalingPolicyNotFound, "Scaling policy not found", err)
+		return
+	}
+	if token == "" || !hmac.Equal([]byte(policy.Token), []byte(token)) {
+		err = NewCLError(ErrInvalidScalingToken, "Invalid scaling token", nil)
+		return
+	}
+	now := time.Now()
+	if policy.LastTriggered != nil && now.Before(policy.LastTriggered.Add(time.Duration(policy.Cooldown)*time.Second)) {
+		logger.Infof("Scaling policy %s is cooling down", uuID)
+		return
+	}
+	group = &model.InstanceGroup{Model: model.Model{ID: policy.GroupID}}
+	err = db.Set("gorm:query_option", "FOR UPDATE").Take(group).Error
+	if err != nil {
+		logger.Errorf("Failed to query instance group %d, %v", policy.GroupID, err)
+		err = NewCLError(ErrInstanceGroupNotFound, "Instance group not found", err)
+		return
+	}
+	desired := group.DesiredCount + policy.Adjustment
+	if policy.Action == ScaleIn {
+		desired = group.DesiredCount - policy.Adjustment
+	}
+	if desired > group.MaxCount {
+		desired = group.MaxCount
+	}
+	if desired < group.MinCount {
+		desired = group.MinCount
+	}
+	if desired == group.DesiredCount {
+		logger.Infof("Instance group %s is at %d instances, scaling policy %s has no effect", group.Name, desired, uuID)
+		return
+	}
+	logger.Infof("Scaling policy %s scales instance group %s from %d to %d", uuID, group.Name, group.DesiredCount, desired)
+	group.DesiredCount = desired
+	if err = db.Model(group).Update("desired_count", desired).Error; err != nil {
+		logger.Errorf("DB failed to update instance group, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update instance group", err)
+		return
+	}
+	if err = db.Model(policy).Update("last_triggered", now).Error; err != nil {
+		logger.Errorf("DB failed to update scaling policy, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update scaling policy", err)
+		return
+	}
+	triggered = true
+	return
+}
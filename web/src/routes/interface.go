@@ -15,7 +15,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -63,7 +62,7 @@ func (a *InterfaceAdmin) Get(ctx context.Context, id int64) (iface *model.Interf
 		err = NewCLError(ErrInterfaceNotFound, "Interface not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, iface.Owner)
+	permit := memberShip.For("interface:get").ValidateOwner(model.Reader, iface.Owner)
 	if !permit {
 		logger.Debug("Not authorized to read the interface")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the interface", nil)
@@ -85,7 +84,7 @@ func (a *InterfaceAdmin) GetInterfaceByUUID(ctx context.Context, uuID string) (i
 		err = NewCLError(ErrInterfaceNotFound, "Interface not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, iface.Owner)
+	permit := memberShip.For("interface:get").ValidateOwner(model.Reader, iface.Owner)
 	if !permit {
 		logger.Debug("Not authorized to read the subnet")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the interface", nil)
@@ -100,7 +99,7 @@ func (a *InterfaceAdmin) Delete(ctx context.Context, instance *model.Instance, i
 		return
 	}
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, iface.Owner)
+	permit := memberShip.For("interface:delete").ValidateOwner(model.Writer, iface.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the interface")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the interface", nil)
@@ -118,7 +117,7 @@ func (a *InterfaceAdmin) Delete(ctx context.Context, instance *model.Instance, i
 
 func (a *InterfaceAdmin) List(ctx context.Context, offset, limit int64, order string, instance *model.Instance) (total int64, interfaces []*model.Interface, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Reader, instance.Owner)
+	permit := memberShip.For("interface:list").ValidateOwner(model.Reader, instance.Owner)
 	if !permit {
 		logger.Debug("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -139,13 +138,12 @@ func (a *InterfaceAdmin) List(ctx context.Context, offset, limit int64, order st
 		where = fmt.Sprintf("%s and %s", where, wm)
 	}
 	interfaces = []*model.Interface{}
-	if err = db.Model(&model.Interface{}).Where(where).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Interface{})).Where(where).Count(&total).Error; err != nil {
 		logger.Debug("DB failed to count security rule(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count interfaces", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("SiteSubnets").Preload("SecurityGroups").Preload("Address").Preload("Address.Subnet").Preload("SecondAddresses", func(db *gorm.DB) *gorm.DB {
+	if err = ListPage(ctx, db, &model.Interface{}, offset, limit, order).Preload("SiteSubnets").Preload("SecurityGroups").Preload("Address").Preload("Address.Subnet").Preload("SecondAddresses", func(db *gorm.DB) *gorm.DB {
 		return db.Order("addresses.updated_at")
 	}).Preload("SecondAddresses.Subnet").Where(where).Find(&interfaces).Error; err != nil {
 		logger.Debug("DB failed to query interface(s), %v", err)
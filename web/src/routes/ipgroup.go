@@ -39,7 +39,7 @@ type IpGroupView struct{}
 func (a *IpGroupAdmin) Create(ctx context.Context, name string, typeName string, ipGroupType int) (ipGroup *model.IpGroup, err error) {
 	logger.Debugf("Enter IpGroupAdmin.Create, name=%s,typeName=%s, ipGroupType=%d", name, typeName, ipGroupType)
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("ip_group:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -106,7 +106,7 @@ func (a *IpGroupAdmin) Delete(ctx context.Context, ipGroup *model.IpGroup) (err
 		logger.Debugf("Exit IpGroupAdmin.Delete, id=%d, err=%v", ipGroup.ID, err)
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, ipGroup.Owner)
+	permit := memberShip.For("ip_group:delete").ValidateOwner(model.Writer, ipGroup.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the ip group")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the ip group", nil)
@@ -190,7 +190,7 @@ func (a *IpGroupAdmin) Update(ctx context.Context, ipGroup *model.IpGroup, name
 		logger.Debugf("Exit IpGroupAdmin.Update, id=%d, err=%v", ipGroup.ID, err)
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, ipGroup.Owner)
+	permit := memberShip.For("ip_group:update").ValidateOwner(model.Writer, ipGroup.Owner)
 	if !permit {
 		logger.Error("Not authorized to update the ip group")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to update the ip group", nil)
@@ -224,13 +224,12 @@ func (a *IpGroupAdmin) List(ctx context.Context, offset, limit int64, order, que
 		order = "created_at"
 	}
 	ipGroups = []*model.IpGroup{}
-	if err = db.Model(&model.IpGroup{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.IpGroup{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Errorf("IpGroupAdmin.List: count error, err=%v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count ip groups", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Subnets").Preload("DictionaryType").Preload("FloatingIPs", func(db *gorm.DB) *gorm.DB {
+	if err = ListPage(ctx, db, &model.IpGroup{}, offset, limit, order).Preload("Subnets").Preload("DictionaryType").Preload("FloatingIPs", func(db *gorm.DB) *gorm.DB {
 		return db.Order("floating_ips.updated_at")
 	}).Preload("FloatingIPs.Subnet").Preload("FloatingIPs.Subnet.Group").Preload("FloatingIPs.Subnet.Group.DictionaryType").Preload("FloatingIPs.Group").Preload("FloatingIPs.Group.DictionaryType").Where(where).Where(query).Find(&ipGroups).Error; err != nil {
 		logger.Errorf("IpGroupAdmin.List: find error, err=%v", err)
@@ -255,7 +254,7 @@ func (a *IpGroupAdmin) List(ctx context.Context, offset, limit int64, order, que
 
 func (v *IpGroupView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("ip_group:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -37,11 +37,6 @@ type CustomClaims struct {
 	Role model.Role `json:"r,omitempty"`
 }
 
-func (*CustomClaims) verifyPrivilege(resource interface{}) (result bool) {
-	// TODO:  checkout authority
-	return true
-}
-
 func NewClaims(u, o, uid, oid string, role model.Role) (claims jwt.Claims, issuedAt, ExpiresAt int64) {
 	now := time.Now()
 	issuedAt = now.Unix()
@@ -103,8 +98,15 @@ func privateKey() *rsa.PrivateKey {
 }
 
 func NewToken(u, o, uid, oid string, role model.Role) (signed string, issueAt, expiresAt int64, err error) {
+	signed, _, issueAt, expiresAt, err = newToken(u, o, uid, oid, role)
+	return
+}
+
+// newToken also returns the jti of the token so that it can be revoked later
+func newToken(u, o, uid, oid string, role model.Role) (signed, jti string, issueAt, expiresAt int64, err error) {
 	var claims jwt.Claims
 	claims, issueAt, expiresAt = NewClaims(u, o, uid, oid, role)
+	jti = claims.(*CustomClaims).ID
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	signed, err = token.SignedString(privateKey())
 	return
@@ -18,7 +18,6 @@ import (
 	"golang.org/x/crypto/ssh"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -35,7 +34,7 @@ type KeyView struct{}
 
 func (a *KeyAdmin) CreateKeyPair(ctx context.Context) (publicKey, fingerPrint, privateKey string, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("key:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create keys")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create keys", nil)
@@ -64,7 +63,7 @@ func (a *KeyAdmin) CreateKeyPair(ctx context.Context) (publicKey, fingerPrint, p
 
 func (a *KeyAdmin) Create(ctx context.Context, name, publicKey, uuid string) (key *model.Key, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("key:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create keys")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create keys", nil)
@@ -105,7 +104,7 @@ func (a *KeyAdmin) Delete(ctx context.Context, key *model.Key) (err error) {
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, key.Owner)
+	permit := memberShip.For("key:delete").ValidateOwner(model.Writer, key.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the key")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the key", nil)
@@ -214,13 +213,12 @@ func (a *KeyAdmin) List(ctx context.Context, offset, limit int64, order, query s
 	}
 	where := memberShip.GetWhere()
 	keys = []*model.Key{}
-	if err = db.Model(&model.Key{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Key{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count keys, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count keys", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(where).Where(query).Find(&keys).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Key{}, offset, limit, order).Where(where).Where(query).Find(&keys).Error; err != nil {
 		logger.Error("DB failed to query keys, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query keys", err)
 		return
@@ -242,7 +240,7 @@ func (a *KeyAdmin) List(ctx context.Context, offset, limit int64, order, query s
 
 func (v *KeyView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("key:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -311,7 +309,7 @@ func (v *KeyView) Delete(c *macaron.Context, store session.Store) (err error) {
 
 func (v *KeyView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("key:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -0,0 +1,268 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var launchTemplateAdmin = &LaunchTemplateAdmin{}
+
+// LaunchTemplateAdmin keeps the launch templates, the payload of a version is opaque JSON here,
+// the REST API validates it as an instance payload before it is stored
+type LaunchTemplateAdmin struct{}
+
+func (a *LaunchTemplateAdmin) Create(ctx context.Context, name, description, versionDescription, payload string) (template *model.LaunchTemplate, version *model.LaunchTemplateVersion, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("launch_template:create").CheckPermission(model.Writer)
+	if !permit {
+		logger.Error("Not authorized to create launch templates")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create launch templates", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	template = &model.LaunchTemplate{Model: model.Model{Creater: memberShip.UserID}, Owner: memberShip.OrgID, Name: name, Description: description, DefaultVersion: 1, LatestVersion: 1}
+	err = db.Create(template).Error
+	if err != nil {
+		logger.Errorf("DB failed to create launch template, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create launch template", err)
+		return
+	}
+	version = &model.LaunchTemplateVersion{Model: model.Model{Creater: memberShip.UserID}, Owner: template.Owner, TemplateID: template.ID, Version: 1, Description: versionDescription, Payload: payload}
+	err = db.Create(version).Error
+	if err != nil {
+		logger.Errorf("DB failed to create launch template version, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create launch template version", err)
+		return
+	}
+	return
+}
+
+// CreateVersion adds the payload as the next version of the template, the default version is moved to it if asked
+func (a *LaunchTemplateAdmin) CreateVersion(ctx context.Context, template *model.LaunchTemplate, description, payload string, setDefault bool) (version *model.LaunchTemplateVersion, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("launch_template:update").ValidateOwner(model.Writer, template.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the launch template")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the launch template", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	// lock the template so concurrent versions are numbered one after another
+	err = db.Set("gorm:query_option", "FOR UPDATE").Take(template, "id = ?", template.ID).Error
+	if err != nil {
+		logger.Errorf("DB failed to lock launch template, %v", err)
+		err = NewCLError(ErrLaunchTemplateNotFound, "Failed to query launch template", err)
+		return
+	}
+	version = &model.LaunchTemplateVersion{Model: model.Model{Creater: memberShip.UserID}, Owner: template.Owner, TemplateID: template.ID, Version: template.LatestVersion + 1, Description: description, Payload: payload}
+	err = db.Create(version).Error
+	if err != nil {
+		logger.Errorf("DB failed to create launch template version, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create launch template version", err)
+		return
+	}
+	template.LatestVersion = version.Version
+	if setDefault {
+		template.DefaultVersion = version.Version
+	}
+	err = db.Model(template).Updates(map[string]interface{}{"latest_version": template.LatestVersion, "default_version": template.DefaultVersion}).Error
+	if err != nil {
+		logger.Errorf("DB failed to update launch template, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update launch template", err)
+		return
+	}
+	return
+}
+
+// Update changes the name, the description or the default version, empty values are left unchanged
+func (a *LaunchTemplateAdmin) Update(ctx context.Context, template *model.LaunchTemplate, name, description string, defaultVersion int32) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("launch_template:update").ValidateOwner(model.Writer, template.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the launch template")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the launch template", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if defaultVersion > 0 {
+		if _, err = a.GetVersion(ctx, template, defaultVersion); err != nil {
+			return
+		}
+		template.DefaultVersion = defaultVersion
+	}
+	if name != "" {
+		template.Name = name
+	}
+	if description != "" {
+		template.Description = description
+	}
+	err = db.Model(template).Updates(map[string]interface{}{"name": template.Name, "description": template.Description, "default_version": template.DefaultVersion}).Error
+	if err != nil {
+		logger.Errorf("DB failed to update launch template, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update launch template", err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) Delete(ctx context.Context, template *model.LaunchTemplate) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("launch_template:delete").ValidateOwner(model.Writer, template.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the launch template")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the launch template", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = db.Where("template_id = ?", template.ID).Delete(&model.LaunchTemplateVersion{}).Error
+	if err != nil {
+		logger.Errorf("DB failed to delete launch template versions, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete launch template versions", err)
+		return
+	}
+	if err = db.Delete(template).Error; err != nil {
+		logger.Errorf("DB failed to delete launch template, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to delete launch template", err)
+		return
+	}
+	// free the name for new templates
+	template.Name = fmt.Sprintf("%s-%d", template.Name, template.CreatedAt.Unix())
+	err = db.Model(&model.LaunchTemplate{}).Unscoped().Where("id = ?", template.ID).Update("name", template.Name).Error
+	if err != nil {
+		logger.Errorf("DB failed to update launch template name, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to update launch template name", err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) GetLaunchTemplateByUUID(ctx context.Context, uuID string) (template *model.LaunchTemplate, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	template = &model.LaunchTemplate{}
+	err = db.Where(where).Where("uuid = ?", uuID).Take(template).Error
+	if err != nil {
+		logger.Errorf("Failed to query launch template, %v", err)
+		err = NewCLError(ErrLaunchTemplateNotFound, "Launch template not found", err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) GetLaunchTemplateByName(ctx context.Context, name string) (template *model.LaunchTemplate, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	template = &model.LaunchTemplate{}
+	err = db.Where(where).Where("name = ?", name).Take(template).Error
+	if err != nil {
+		logger.Errorf("Failed to query launch template, %v", err)
+		err = NewCLError(ErrLaunchTemplateNotFound, "Launch template not found", err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) GetLaunchTemplate(ctx context.Context, reference *BaseReference) (template *model.LaunchTemplate, err error) {
+	if reference == nil || (reference.ID == "" && reference.Name == "") {
+		err = NewCLError(ErrInvalidParameter, "Launch template base reference must be provided with either uuid or name", nil)
+		return
+	}
+	if reference.ID != "" {
+		return a.GetLaunchTemplateByUUID(ctx, reference.ID)
+	}
+	return a.GetLaunchTemplateByName(ctx, reference.Name)
+}
+
+// GetVersion returns a version of the template, version 0 is the default version
+func (a *LaunchTemplateAdmin) GetVersion(ctx context.Context, template *model.LaunchTemplate, version int32) (templateVersion *model.LaunchTemplateVersion, err error) {
+	if version == 0 {
+		version = template.DefaultVersion
+	}
+	ctx, db := GetContextDB(ctx)
+	templateVersion = &model.LaunchTemplateVersion{}
+	err = db.Where("template_id = ? AND version = ?", template.ID, version).Take(templateVersion).Error
+	if err != nil {
+		logger.Errorf("Failed to query launch template version, %v", err)
+		err = NewCLError(ErrLaunchTemplateVersionNotFound, fmt.Sprintf("Version %d of launch template %s not found", version, template.Name), err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) ListVersions(ctx context.Context, template *model.LaunchTemplate, offset, limit int64) (total int64, versions []*model.LaunchTemplateVersion, err error) {
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+	versions = []*model.LaunchTemplateVersion{}
+	db = db.Model(&model.LaunchTemplateVersion{}).Where("template_id = ?", template.ID)
+	if err = db.Count(&total).Error; err != nil {
+		logger.Errorf("DB failed to count launch template versions, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count launch template versions", err)
+		return
+	}
+	if err = db.Order("version desc").Offset(offset).Limit(limit).Find(&versions).Error; err != nil {
+		logger.Errorf("DB failed to query launch template versions, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query launch template versions", err)
+		return
+	}
+	return
+}
+
+func (a *LaunchTemplateAdmin) List(ctx context.Context, offset, limit int64, order, query string) (total int64, templates []*model.LaunchTemplate, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+	if order == "" {
+		order = "created_at"
+	}
+	if query != "" {
+		db = db.Where("name like ?", "%"+query+"%")
+	}
+	where := memberShip.GetWhere()
+	templates = []*model.LaunchTemplate{}
+	if err = ListFilter(ctx, db.Model(&model.LaunchTemplate{})).Where(where).Count(&total).Error; err != nil {
+		logger.Errorf("DB failed to count launch templates, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count launch templates", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.LaunchTemplate{}, offset, limit, order).Where(where).Find(&templates).Error; err != nil {
+		logger.Errorf("DB failed to query launch templates, %v", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query launch templates", err)
+		return
+	}
+	return
+}
@@ -15,7 +15,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -32,7 +31,7 @@ type ListenerView struct{}
 
 func (a *ListenerAdmin) Create(ctx context.Context, name, mode, key, cert string, port int32, loadBalancer *model.LoadBalancer) (listener *model.Listener, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("listener:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create listener")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create listener", nil)
@@ -76,7 +75,7 @@ func (a *ListenerAdmin) Get(ctx context.Context, id int64, loadBalancer *model.L
 		err = NewCLError(ErrListenerNotFound, "Failed to find listener", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, listener.Owner)
+	permit := memberShip.For("listener:get").ValidateOwner(model.Reader, listener.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the listener")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the listener", nil)
@@ -96,7 +95,7 @@ func (a *ListenerAdmin) GetListenerByUUID(ctx context.Context, uuID string) (lis
 		err = NewCLError(ErrListenerNotFound, "Failed to find listener", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, listener.Owner)
+	permit := memberShip.For("listener:get").ValidateOwner(model.Reader, listener.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the listener")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the listener", nil)
@@ -116,7 +115,7 @@ func (a *ListenerAdmin) GetListenerByName(ctx context.Context, name string) (lis
 		err = NewCLError(ErrListenerNotFound, "Failed to find listener", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, listener.Owner)
+	permit := memberShip.For("listener:get").ValidateOwner(model.Reader, listener.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the listener")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the listener", nil)
@@ -163,7 +162,7 @@ func (a *ListenerAdmin) Delete(ctx context.Context, listener *model.Listener, lo
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, listener.Owner)
+	permit := memberShip.For("listener:delete").ValidateOwner(model.Writer, listener.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the listener")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the router", nil)
@@ -227,13 +226,12 @@ func (a *ListenerAdmin) List(ctx context.Context, offset, limit int64, order str
 		where = fmt.Sprintf("%s and %s", where, wm)
 	}
 	listeners = []*model.Listener{}
-	if err = db.Model(&model.Listener{}).Where(where).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Listener{})).Where(where).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count listeners, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count listeners", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Backends").Where(where).Find(&listeners).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Listener{}, offset, limit, order).Preload("Backends").Where(where).Find(&listeners).Error; err != nil {
 		logger.Error("DB failed to query listeners, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query listeners", err)
 		return
@@ -360,7 +358,7 @@ func (v *ListenerView) Delete(c *macaron.Context, store session.Store) (err erro
 func (v *ListenerView) New(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("listener:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
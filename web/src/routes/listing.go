@@ -0,0 +1,19 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	. "web/src/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseListQuery parses the list grammar of the request for the model, order is the default sort of the endpoint
+func ParseListQuery(c *gin.Context, value interface{}, order string) (query *ListQuery, err error) {
+	scope := DB().NewScope(value)
+	return NewListQuery(c, scope, order, Taggable(scope.TableName()))
+}
@@ -16,7 +16,6 @@ import (
 	"time"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -165,7 +164,7 @@ func CreateVrrpInstance(ctx context.Context, name string, router *model.Router,
 
 func (a *LoadBalancerAdmin) Create(ctx context.Context, name string, router *model.Router, zone *model.Zone) (loadBalancer *model.LoadBalancer, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("load_balancer:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create routers")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create routers", nil)
@@ -208,7 +207,7 @@ func (a *LoadBalancerAdmin) Get(ctx context.Context, id int64) (loadBalancer *mo
 		err = NewCLError(ErrLoadBalancerNotFound, "Failed to find load balancer", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, loadBalancer.Owner)
+	permit := memberShip.For("load_balancer:get").ValidateOwner(model.Reader, loadBalancer.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the load balancer")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the load balancer", nil)
@@ -228,7 +227,7 @@ func (a *LoadBalancerAdmin) GetLoadBalancerByUUID(ctx context.Context, uuID stri
 		err = NewCLError(ErrRouterNotFound, "Failed to find load balancer", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, loadBalancer.Owner)
+	permit := memberShip.For("load_balancer:get").ValidateOwner(model.Reader, loadBalancer.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the load balancer")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the load balancer", nil)
@@ -248,7 +247,7 @@ func (a *LoadBalancerAdmin) GetLoadBalancerByName(ctx context.Context, name stri
 		err = NewCLError(ErrRouterNotFound, "Failed to find load balancer", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, loadBalancer.Owner)
+	permit := memberShip.For("load_balancer:get").ValidateOwner(model.Reader, loadBalancer.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the load balancer")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the load balancer", nil)
@@ -295,7 +294,7 @@ func (a *LoadBalancerAdmin) Delete(ctx context.Context, loadBalancer *model.Load
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, loadBalancer.Owner)
+	permit := memberShip.For("load_balancer:delete").ValidateOwner(model.Writer, loadBalancer.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the load balancer")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the router", nil)
@@ -381,6 +380,9 @@ func (a *LoadBalancerAdmin) Delete(ctx context.Context, loadBalancer *model.Load
 		err = NewCLError(ErrLoadBalancerDeleteFailed, "Failed to delete load balancer", err)
 		return
 	}
+	if err = tagAdmin.replace(db, "load_balancers", loadBalancer.ID, loadBalancer.Owner, nil); err != nil {
+		return
+	}
 	loadBalancer.Name = fmt.Sprintf("%s-%d", loadBalancer.Name, loadBalancer.CreatedAt.Unix())
 	err = db.Model(&model.LoadBalancer{}).Unscoped().Where("id = ?", loadBalancer.ID).Update("name", loadBalancer.Name).Error
 	if err != nil {
@@ -407,13 +409,12 @@ func (a *LoadBalancerAdmin) List(ctx context.Context, offset, limit int64, order
 	}
 	where := memberShip.GetWhere()
 	loadBalancers = []*model.LoadBalancer{}
-	if err = db.Model(&model.LoadBalancer{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.LoadBalancer{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count load balancers, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count load balancers", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("FloatingIps").Preload("VrrpInstance").Preload("VrrpInstance.VrrpSubnet").Preload("Listeners").Preload("Listeners.Backends").Preload("Router").Where(where).Where(query).Find(&loadBalancers).Error; err != nil {
+	if err = ListPage(ctx, db, &model.LoadBalancer{}, offset, limit, order).Preload("FloatingIps").Preload("VrrpInstance").Preload("VrrpInstance.VrrpSubnet").Preload("Listeners").Preload("Listeners.Backends").Preload("Router").Where(where).Where(query).Find(&loadBalancers).Error; err != nil {
 		logger.Error("DB failed to query load balancers, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query load balancers", err)
 		return
@@ -506,7 +507,7 @@ func (v *LoadBalancerView) Delete(c *macaron.Context, store session.Store) (err
 func (v *LoadBalancerView) New(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("load_balancer:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
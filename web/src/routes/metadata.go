@@ -0,0 +1,86 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+// MetadataAdmin looks up the instances for the metadata service, a guest is identified by the router
+// its request comes through and its source address, so there is no membership of a user
+type MetadataAdmin struct{}
+
+// RouterToken is the token the metadata proxy of a router sends along, the HMAC-SHA256 of the router ID keyed by metadata.secret
+func (a *MetadataAdmin) RouterToken(routerID int64) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("metadata.secret")))
+	mac.Write([]byte(strconv.FormatInt(routerID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRouter checks the token of the metadata proxy, nothing is trusted without metadata.secret
+func (a *MetadataAdmin) VerifyRouter(routerID int64, token string) bool {
+	if viper.GetString("metadata.secret") == "" || routerID <= 0 {
+		return false
+	}
+	return hmac.Equal([]byte(a.RouterToken(routerID)), []byte(token))
+}
+
+// GetInstanceByAddress finds the instance owning the address in the subnets of the router
+func (a *MetadataAdmin) GetInstanceByAddress(ctx context.Context, routerID int64, ip string) (instance *model.Instance, err error) {
+	ctx, db := GetContextDB(ctx)
+	address := &model.Address{}
+	err = db.Joins("JOIN subnets ON subnets.id = addresses.subnet_id AND subnets.deleted_at IS NULL").
+		Where("subnets.router_id = ? AND addresses.address LIKE ? AND addresses.allocated = ?", routerID, ip+"/%", true).
+		Take(address).Error
+	if err != nil {
+		logger.Errorf("Failed to query address %s in router %d, %v", ip, routerID, err)
+		err = NewCLError(ErrResourceNotFound, fmt.Sprintf("No instance has address %s", ip), err)
+		return
+	}
+	ifaceID := address.Interface
+	if ifaceID == 0 {
+		ifaceID = address.SecondInterface
+	}
+	iface := &model.Interface{}
+	err = db.Where("id = ? AND type = ? AND instance > 0", ifaceID, "instance").Take(iface).Error
+	if err != nil {
+		logger.Errorf("Failed to query instance interface of address %s, %v", ip, err)
+		err = NewCLError(ErrResourceNotFound, fmt.Sprintf("No instance has address %s", ip), err)
+		return
+	}
+	// read the instance as its own organization
+	owner := &model.Instance{}
+	err = db.Select("id, owner").Where("id = ?", iface.Instance).Take(owner).Error
+	if err != nil {
+		logger.Errorf("Failed to query instance %d, %v", iface.Instance, err)
+		err = NewCLError(ErrInstanceNotFound, "Instance not found", err)
+		return
+	}
+	memberShip := &MemberShip{OrgID: owner.Owner, Role: model.Reader}
+	return instanceAdmin.Get(memberShip.SetContext(ctx), owner.ID)
+}
+
+// GetInstanceData returns the instance of the address with its metadata built live from the DB,
+// the root password is never served
+func (a *MetadataAdmin) GetInstanceData(ctx context.Context, routerID int64, ip string) (instance *model.Instance, instData *InstanceData, err error) {
+	instance, err = a.GetInstanceByAddress(ctx, routerID, ip)
+	if err != nil {
+		return
+	}
+	instData, err = instanceAdmin.GetInstanceData(ctx, instance, "")
+	return
+}
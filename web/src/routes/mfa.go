@@ -0,0 +1,457 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/go-macaron/session"
+	"github.com/spf13/viper"
+	macaron "gopkg.in/macaron.v1"
+)
+
+const (
+	mfaIssuer         = "CloudLand"
+	mfaRecoveryCodes  = 10
+	mfaDefaultStepUp  = 5
+	mfaRecoveryLength = 10
+)
+
+var (
+	mfaAdmin = &MfaAdmin{}
+	mfaView  = &MfaView{}
+)
+
+type MfaAdmin struct{}
+
+type MfaView struct{}
+
+// stepUpWindow is how long a second factor verification allows destructive operations, mfa.step_up_minutes in the config
+func stepUpWindow() time.Duration {
+	minutes := viper.GetInt("mfa.step_up_minutes")
+	if minutes <= 0 {
+		minutes = mfaDefaultStepUp
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (a *MfaAdmin) currentUser(ctx context.Context) (user *model.User, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	user = &model.User{Model: model.Model{ID: memberShip.UserID}}
+	if memberShip.UserID == 0 {
+		err = NewCLError(ErrPermissionDenied, "Not authorized to manage multi-factor authentication", nil)
+		return
+	}
+	err = db.Take(user).Error
+	if err != nil {
+		logger.Error("DB failed to query user", err)
+		err = NewCLError(ErrUserNotFound, "User not found", err)
+		return
+	}
+	return
+}
+
+// Status tells if the second factor of the current user is enabled or waiting for activation and how many recovery codes are left
+func (a *MfaAdmin) Status(ctx context.Context) (enabled, pending bool, recoveryCodes int, err error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return
+	}
+	enabled = user.MfaEnabled
+	pending = !user.MfaEnabled && user.MfaSecret != ""
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(&model.MfaRecoveryCode{}).Where("user_id = ? and used = ?", user.ID, false).Count(&recoveryCodes).Error
+	if err != nil {
+		logger.Error("DB failed to count recovery codes", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count recovery codes", err)
+		return
+	}
+	return
+}
+
+// Enroll starts the enrollment of the current user with a new secret, it takes effect after Activate
+func (a *MfaAdmin) Enroll(ctx context.Context) (secret, otpURL string, err error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return
+	}
+	if user.MfaEnabled {
+		err = NewCLError(ErrMfaAlreadyEnabled, "Multi-factor authentication is already enabled", nil)
+		return
+	}
+	secret, err = NewTotpSecret()
+	if err != nil {
+		logger.Error("Failed to generate totp secret", err)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to generate secret", err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(user).Updates(map[string]interface{}{"mfa_secret": secret, "mfa_last_step": 0}).Error
+	if err != nil {
+		logger.Error("DB failed to update user", err)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to save secret", err)
+		return
+	}
+	otpURL = TotpURL(mfaIssuer, user.Username, secret)
+	return
+}
+
+// Activate confirms the enrollment with a code of the authenticator and returns the recovery codes
+func (a *MfaAdmin) Activate(ctx context.Context, code string) (recoveryCodes []string, err error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return
+	}
+	if user.MfaEnabled {
+		err = NewCLError(ErrMfaAlreadyEnabled, "Multi-factor authentication is already enabled", nil)
+		return
+	}
+	if user.MfaSecret == "" {
+		err = NewCLError(ErrMfaNotEnrolled, "Multi-factor authentication is not enrolled", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.verifyTotp(ctx, user, code)
+	if err != nil {
+		return
+	}
+	err = db.Model(user).Update("mfa_enabled", true).Error
+	if err != nil {
+		logger.Error("DB failed to enable mfa", err)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to enable multi-factor authentication", err)
+		return
+	}
+	recoveryCodes, err = a.newRecoveryCodes(ctx, user)
+	return
+}
+
+// Disable removes the second factor of the current user, a valid code is required
+func (a *MfaAdmin) Disable(ctx context.Context, code string) (err error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return
+	}
+	ctx, _, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.Verify(ctx, user, code)
+	if err != nil {
+		return
+	}
+	err = a.reset(ctx, user)
+	return
+}
+
+// Reset removes the second factor of a user who lost the authenticator and the recovery codes, admin only
+func (a *MfaAdmin) Reset(ctx context.Context, user *model.User) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		logger.Error("Not authorized to reset multi-factor authentication")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to reset multi-factor authentication", nil)
+		return
+	}
+	ctx, _, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.reset(ctx, user)
+	if err != nil {
+		return
+	}
+	err = tokenAdmin.RevokeMember(ctx, user.ID, 0)
+	return
+}
+
+func (a *MfaAdmin) reset(ctx context.Context, user *model.User) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(user).Updates(map[string]interface{}{"mfa_secret": "", "mfa_enabled": false, "mfa_last_step": 0}).Error
+	if err != nil {
+		logger.Error("DB failed to disable mfa", err)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to disable multi-factor authentication", err)
+		return
+	}
+	err = a.DeleteRecoveryCodes(ctx, user.ID)
+	return
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user, a valid code is required
+func (a *MfaAdmin) RegenerateRecoveryCodes(ctx context.Context, code string) (recoveryCodes []string, err error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return
+	}
+	ctx, _, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.Verify(ctx, user, code)
+	if err != nil {
+		return
+	}
+	recoveryCodes, err = a.newRecoveryCodes(ctx, user)
+	return
+}
+
+func (a *MfaAdmin) newRecoveryCodes(ctx context.Context, user *model.User) (recoveryCodes []string, err error) {
+	err = a.DeleteRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	for i := 0; i < mfaRecoveryCodes; i++ {
+		var code string
+		code, err = randomHex(mfaRecoveryLength / 2)
+		if err != nil {
+			logger.Error("Failed to generate recovery code", err)
+			err = NewCLError(ErrMfaUpdateFailed, "Failed to generate recovery codes", err)
+			return
+		}
+		err = db.Create(&model.MfaRecoveryCode{UserID: user.ID, Hash: secretHash(code)}).Error
+		if err != nil {
+			logger.Error("DB failed to create recovery code", err)
+			err = NewCLError(ErrMfaUpdateFailed, "Failed to save recovery codes", err)
+			return
+		}
+		recoveryCodes = append(recoveryCodes, code)
+	}
+	return
+}
+
+func (a *MfaAdmin) DeleteRecoveryCodes(ctx context.Context, userID int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Unscoped().Where("user_id = ?", userID).Delete(&model.MfaRecoveryCode{}).Error
+	if err != nil {
+		logger.Error("DB failed to delete recovery codes", err)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to delete recovery codes", err)
+		return
+	}
+	return
+}
+
+func (a *MfaAdmin) verifyTotp(ctx context.Context, user *model.User, code string) (err error) {
+	step, ok := VerifyTotp(user.MfaSecret, code, time.Now(), user.MfaLastStep)
+	if !ok {
+		err = NewCLError(ErrMfaInvalidCode, "Invalid multi-factor authentication code", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	// The condition makes two concurrent requests with the same code accept only one of them
+	result := db.Model(&model.User{}).Where("id = ? and mfa_last_step < ?", user.ID, step).Update("mfa_last_step", step)
+	if result.Error != nil {
+		logger.Error("DB failed to update mfa step", result.Error)
+		err = NewCLError(ErrMfaUpdateFailed, "Failed to verify code", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		err = NewCLError(ErrMfaInvalidCode, "Multi-factor authentication code was already used", nil)
+		return
+	}
+	user.MfaLastStep = step
+	return
+}
+
+// Verify checks a code of the authenticator or an unused recovery code of the user
+func (a *MfaAdmin) Verify(ctx context.Context, user *model.User, code string) (err error) {
+	if !user.MfaEnabled {
+		err = NewCLError(ErrMfaNotEnrolled, "Multi-factor authentication is not enabled", nil)
+		return
+	}
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) == TotpDigits {
+		err = a.verifyTotp(ctx, user, code)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	recoveryCodes := []*model.MfaRecoveryCode{}
+	err = db.Where("user_id = ? and used = ?", user.ID, false).Find(&recoveryCodes).Error
+	if err != nil {
+		logger.Error("DB failed to query recovery codes", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query recovery codes", err)
+		return
+	}
+	hash := secretHash(code)
+	for _, recoveryCode := range recoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(recoveryCode.Hash)) != 1 {
+			continue
+		}
+		result := db.Model(recoveryCode).Where("used = ?", false).Update("used", true)
+		if result.Error != nil || result.RowsAffected == 0 {
+			logger.Error("Failed to use recovery code", result.Error)
+			break
+		}
+		logger.Infof("User %s used a recovery code", user.Username)
+		return
+	}
+	err = NewCLError(ErrMfaInvalidCode, "Invalid multi-factor authentication code", nil)
+	return
+}
+
+// Check enforces the second factor of a login to the organization, code is the TOTP or recovery code of the login request
+func (a *MfaAdmin) Check(ctx context.Context, user *model.User, org *model.Organization, code string) (err error) {
+	if !user.MfaEnabled {
+		if org != nil && org.RequireMfa && user.Username != "admin" {
+			err = NewCLError(ErrMfaEnrollmentRequired, fmt.Sprintf("Organization %s requires multi-factor authentication, enroll it first", org.Name), nil)
+		}
+		return
+	}
+	if strings.TrimSpace(code) == "" {
+		err = NewCLError(ErrMfaRequired, "Multi-factor authentication code is required", nil)
+		return
+	}
+	err = a.Verify(ctx, user, code)
+	return
+}
+
+// StepUp guards destructive operations, users with a second factor must have verified it recently,
+// users without one are let through unless mfa.enforce_step_up is set in the config
+func (a *MfaAdmin) StepUp(ctx context.Context, operation string) (err error) {
+	memberShip := GetMemberShip(ctx)
+	if memberShip.MfaAt > 0 && time.Since(time.Unix(memberShip.MfaAt, 0)) <= stepUpWindow() {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	user := &model.User{Model: model.Model{ID: memberShip.UserID}}
+	err = db.Take(user).Error
+	if err != nil {
+		logger.Error("DB failed to query user", err)
+		err = NewCLError(ErrUserNotFound, "User not found", err)
+		return
+	}
+	if user.MfaEnabled || viper.GetBool("mfa.enforce_step_up") {
+		logger.Errorf("Step-up authentication is required to %s", operation)
+		err = NewCLError(ErrMfaStepUpRequired, fmt.Sprintf("Verify multi-factor authentication again to %s", operation), nil)
+		return
+	}
+	return
+}
+
+// SetOrgRequirement makes the second factor mandatory for the members of an organization, admin only
+func (a *MfaAdmin) SetOrgRequirement(ctx context.Context, org *model.Organization, required bool) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		logger.Error("Not authorized to change multi-factor requirement")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to change multi-factor requirement", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(org).Update("require_mfa", required).Error
+	if err != nil {
+		logger.Error("DB failed to update organization", err)
+		err = NewCLError(ErrOrgUpdateFailed, "Failed to update organization", err)
+		return
+	}
+	org.RequireMfa = required
+	return
+}
+
+func (v *MfaView) render(c *macaron.Context) {
+	enabled, pending, recoveryCodes, err := mfaAdmin.Status(c.Req.Context())
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	c.Data["MfaEnabled"] = enabled
+	c.Data["MfaPending"] = pending
+	c.Data["RecoveryCodesLeft"] = recoveryCodes
+	c.Data["RedirectTo"] = c.QueryTrim("redirect_to")
+	c.HTML(200, "mfa")
+}
+
+func (v *MfaView) Show(c *macaron.Context, store session.Store) {
+	v.render(c)
+}
+
+func (v *MfaView) Enroll(c *macaron.Context, store session.Store) {
+	secret, otpURL, err := mfaAdmin.Enroll(c.Req.Context())
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	c.Data["Secret"] = secret
+	c.Data["OtpURL"] = otpURL
+	v.render(c)
+}
+
+func (v *MfaView) Activate(c *macaron.Context, store session.Store) {
+	recoveryCodes, err := mfaAdmin.Activate(c.Req.Context(), c.QueryTrim("passcode"))
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	store.Set("mfa_at", time.Now().Unix())
+	c.Data["RecoveryCodes"] = recoveryCodes
+	v.render(c)
+}
+
+func (v *MfaView) Disable(c *macaron.Context, store session.Store) {
+	err := mfaAdmin.Disable(c.Req.Context(), c.QueryTrim("passcode"))
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	store.Delete("mfa_at")
+	c.Redirect("/mfa")
+}
+
+func (v *MfaView) RecoveryCodes(c *macaron.Context, store session.Store) {
+	recoveryCodes, err := mfaAdmin.RegenerateRecoveryCodes(c.Req.Context(), c.QueryTrim("passcode"))
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	c.Data["RecoveryCodes"] = recoveryCodes
+	v.render(c)
+}
+
+// Verify is the step-up of the session before destructive operations
+func (v *MfaView) Verify(c *macaron.Context, store session.Store) {
+	ctx := c.Req.Context()
+	user, err := mfaAdmin.currentUser(ctx)
+	if err == nil {
+		err = mfaAdmin.Verify(ctx, user, c.QueryTrim("passcode"))
+	}
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	store.Set("mfa_at", time.Now().Unix())
+	redirectTo := c.QueryTrim("redirect_to")
+	if !strings.HasPrefix(redirectTo, "/") || strings.HasPrefix(redirectTo, "//") {
+		redirectTo = "/mfa"
+	}
+	c.Redirect(redirectTo)
+}
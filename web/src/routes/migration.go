@@ -16,7 +16,6 @@ import (
 	"strings"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -40,6 +39,13 @@ func (a *MigrationAdmin) Create(ctx context.Context, name string, instances []*m
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
 		return
 	}
+	return a.create(ctx, name, instances, force, tgtHyper)
+}
+
+// create migrates the instances without checking for admin, for operations that move
+// guests on behalf of their owners such as resize
+func (a *MigrationAdmin) create(ctx context.Context, name string, instances []*model.Instance, force bool, tgtHyper int32) (migrations []*model.Migration, err error) {
+	memberShip := GetMemberShip(ctx)
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
@@ -125,8 +131,21 @@ func (a *MigrationAdmin) Create(ctx context.Context, name string, instances []*m
 		poolID := bootVolume.GetVolumePoolID()
 		control := fmt.Sprintf("inter=%d", tgtHyper)
 		if tgtHyper == -1 {
+			var aggregateRules []string
+			aggregateRules, err = aggregateAdmin.Rules(ctx, &instance.ExtraSpecs, instance.ImageID, instance.Owner)
+			if err != nil {
+				return
+			}
 			var hyperGroup string
-			hyperGroup, err = GetHyperGroup(ctx, instance.ZoneID, instance.Hyper)
+			hyperGroup, err = SelectHyperGroup(ctx, &ScheduleRequest{
+				ZoneID:         instance.ZoneID,
+				Cpu:            instance.Cpu,
+				Memory:         instance.Memory,
+				Disk:           instance.Disk,
+				Specs:          &instance.ExtraSpecs,
+				Hints:          &SchedulerHints{DifferentHost: []int32{instance.Hyper}},
+				AggregateRules: aggregateRules,
+			})
 			if err != nil {
 				task1.Summary = "No qualified target"
 				task1.Status = "not_doing"
@@ -142,6 +161,7 @@ func (a *MigrationAdmin) Create(ctx context.Context, name string, instances []*m
 				err = nil
 				continue
 			}
+			// the target is recorded when the hypervisor that took the guest calls back
 			rcNeeded := fmt.Sprintf("cpu=%d memory=%d disk=%d network=%d", instance.Cpu, instance.Memory*1024, int64(instance.Disk)*1024*1024, 0)
 			control = "select=" + hyperGroup + " " + rcNeeded
 		}
@@ -267,12 +287,11 @@ func (a *MigrationAdmin) List(ctx context.Context, offset, limit int64, order, q
 		query = fmt.Sprintf("name like '%%%s%%'", query)
 	}
 	migrations = []*model.Migration{}
-	if err = db.Model(&model.Migration{}).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Migration{})).Where(query).Count(&total).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count migrations", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Instance").Preload("Phases").Where(query).Find(&migrations).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Migration{}, offset, limit, order).Preload("Instance").Preload("Phases").Where(query).Find(&migrations).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query migrations", err)
 		return
 	}
@@ -314,7 +333,7 @@ func (v *MigrationView) List(c *macaron.Context, store session.Store) {
 
 func (v *MigrationView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("migration:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
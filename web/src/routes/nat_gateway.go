@@ -0,0 +1,615 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var (
+	natGatewayAdmin = &NatGatewayAdmin{}
+)
+
+type NatGatewayAdmin struct{}
+
+type NatSubnet struct {
+	Vni     int64  `json:"vni"`
+	Gateway string `json:"gateway"`
+	Network string `json:"network"`
+	NatIP   string `json:"nat_ip"`
+	NatMac  string `json:"nat_mac"`
+}
+
+// natGatewayMac is the mac address set_subnet_gw.sh gives to the subnet gateway device on a hypervisor,
+// the nat address of a subnet lives on that device so the other hypervisors can reach it through the fdb
+func natGatewayMac(vlan int64, hyper int32) string {
+	return fmt.Sprintf("52:%02x:%02x:%02x:%02x:%02x", (vlan>>16)&0xff, (vlan>>8)&0xff, vlan&0xff, (hyper>>8)&0xff, hyper&0xff)
+}
+
+func (a *NatGatewayAdmin) chooseHyper(ctx context.Context, router *model.Router) (hyperID int32, err error) {
+	ctx, db := GetContextDB(ctx)
+	iface := &model.Interface{}
+	err = db.Where("router_id = ? and type = ? and hyper >= 0", router.ID, "instance").Order("created_at").Take(iface).Error
+	if err == nil {
+		hyperID = iface.Hyper
+		return
+	}
+	hyper := &model.Hyper{}
+	err = db.Where("status = ? and hostid >= 0", 1).Order("hostid").Take(hyper).Error
+	if err != nil {
+		logger.Error("No active hypervisor for nat gateway", err)
+		err = NewCLError(ErrInsufficientResource, "No active hypervisor for nat gateway", err)
+		return
+	}
+	hyperID = hyper.Hostid
+	return
+}
+
+func (a *NatGatewayAdmin) loadInterfaces(ctx context.Context, natGateway *model.NatGateway) (err error) {
+	ctx, db := GetContextDB(ctx)
+	natGateway.Interfaces = []*model.Interface{}
+	err = db.Preload("Address").Preload("Address.Subnet").Where("device = ? and type = ?", natGateway.ID, "nat").Find(&natGateway.Interfaces).Error
+	if err != nil {
+		logger.Error("Failed to query nat gateway interfaces", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query nat gateway interfaces", err)
+		return
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) getNatData(ifaces []*model.Interface) (data []byte, err error) {
+	natSubnets := []*NatSubnet{}
+	for _, iface := range ifaces {
+		if iface.Address == nil || iface.Address.Subnet == nil {
+			continue
+		}
+		subnet := iface.Address.Subnet
+		ipNet := subnetCIDR(subnet)
+		if ipNet == nil {
+			continue
+		}
+		natSubnets = append(natSubnets, &NatSubnet{Vni: subnet.Vlan, Gateway: subnet.Gateway, Network: ipNet.String(), NatIP: iface.Address.Address, NatMac: iface.MacAddr})
+	}
+	data, err = json.Marshal(natSubnets)
+	if err != nil {
+		logger.Error("Failed to marshal nat gateway data", err)
+		return
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) addSubnet(ctx context.Context, natGateway *model.NatGateway, subnet *model.Subnet) (iface *model.Interface, err error) {
+	ctx, db := GetContextDB(ctx)
+	if subnet.RouterID != natGateway.RouterID || subnet.Type != string(Internal) {
+		logger.Errorf("Subnet %d is not an internal subnet of router %d", subnet.ID, natGateway.RouterID)
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Subnet %s is not an internal subnet of the vpc", subnet.Name), nil)
+		return
+	}
+	mac := natGatewayMac(subnet.Vlan, natGateway.Hyper)
+	iface, err = CreateInterface(ctx, subnet, natGateway.ID, natGateway.Owner, natGateway.Hyper, 0, 0, "", mac, "nat", "nat", nil, false)
+	if err != nil {
+		logger.Error("Failed to create nat gateway interface", err)
+		err = NewCLError(ErrInterfaceCreateFailed, "Failed to create nat gateway interface", err)
+		return
+	}
+	iface.Device = natGateway.ID
+	err = db.Model(iface).Update("device", natGateway.ID).Error
+	if err != nil {
+		logger.Error("Failed to update nat gateway interface", err)
+		err = NewCLError(ErrInterfaceUpdateFailed, "Failed to update nat gateway interface", err)
+		return
+	}
+	iface.Address.Subnet = subnet
+	natGateway.Interfaces = append(natGateway.Interfaces, iface)
+	return
+}
+
+// syncFdb installs the fdb entries of the nat addresses on the hypervisors hosting the router,
+// and the fdb entries of all the router interfaces on the hypervisor hosting the nat gateway
+func (a *NatGatewayAdmin) syncFdb(ctx context.Context, natGateway *model.NatGateway) (err error) {
+	ctx, db := GetContextDB(ctx)
+	hyper := &model.Hyper{}
+	err = db.Where("hostid = ?", natGateway.Hyper).Take(hyper).Error
+	if err != nil {
+		logger.Error("Failed to query hypervisor", err)
+		return
+	}
+	natRules := []*FdbRule{}
+	for _, iface := range natGateway.Interfaces {
+		if iface.Address == nil || iface.Address.Subnet == nil {
+			continue
+		}
+		natRules = append(natRules, &FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
+	}
+	ifaces := []*model.Interface{}
+	err = db.Preload("Address").Preload("Address.Subnet").Where("router_id = ? and type <> 'gateway' and type <> 'nat' and hyper >= 0 and hyper <> ?", natGateway.RouterID, natGateway.Hyper).Find(&ifaces).Error
+	if err != nil {
+		logger.Error("Failed to query interfaces of router", err)
+		return
+	}
+	localRules := []*FdbRule{}
+	hyperSet := make(map[int32]struct{})
+	for _, iface := range ifaces {
+		if iface.Address == nil || iface.Address.Subnet == nil || iface.Address.Subnet.Type == string(Public) {
+			continue
+		}
+		ifaceHyper := &model.Hyper{}
+		hyperErr := db.Where("hostid = ?", iface.Hyper).Take(ifaceHyper).Error
+		if hyperErr != nil {
+			logger.Error("Failed to query hypervisor", hyperErr)
+			continue
+		}
+		hyperSet[iface.Hyper] = struct{}{}
+		localRules = append(localRules, &FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: ifaceHyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
+	}
+	if len(natRules) > 0 {
+		fdbJson, _ := json.Marshal(natRules)
+		for hyperID := range hyperSet {
+			control := fmt.Sprintf("inter=%d", hyperID)
+			command := fmt.Sprintf("/opt/cloudland/scripts/backend/add_fwrule.sh <<EOF\n%s\nEOF", fdbJson)
+			err = HyperExecute(ctx, control, command)
+			if err != nil {
+				logger.Error("Add_fwrule execution failed", err)
+				return
+			}
+		}
+	}
+	if len(localRules) > 0 {
+		fdbJson, _ := json.Marshal(localRules)
+		control := fmt.Sprintf("inter=%d", natGateway.Hyper)
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/add_fwrule.sh <<EOF\n%s\nEOF", fdbJson)
+		err = HyperExecute(ctx, control, command)
+		if err != nil {
+			logger.Error("Add_fwrule execution failed", err)
+			return
+		}
+	}
+	return
+}
+
+// Apply sets up the public address and the translation on the hosting hypervisor,
+// and points the translated subnets to the nat addresses on the others
+func (a *NatGatewayAdmin) Apply(ctx context.Context, natGateway *model.NatGateway) (err error) {
+	fip := natGateway.FloatingIp
+	if fip == nil || fip.Interface == nil || fip.Interface.Address == nil || fip.Interface.Address.Subnet == nil {
+		logger.Errorf("Invalid public address of nat gateway %d", natGateway.ID)
+		err = NewCLError(ErrNatGatewayUpdateFailed, "Invalid public address of nat gateway", nil)
+		return
+	}
+	data, err := a.getNatData(natGateway.Interfaces)
+	if err != nil {
+		return
+	}
+	pubSubnet := fip.Interface.Address.Subnet
+	control := fmt.Sprintf("inter=%d", natGateway.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_nat_gateway.sh '%d' '%d' '%s' '%s' '%d' '%d' '%d' '%d'<<EOF\n%s\nEOF", natGateway.RouterID, natGateway.ID, fip.FipAddress, pubSubnet.Gateway, pubSubnet.Vlan, fip.ID, natGateway.Inbound, natGateway.Outbound, data)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Create nat gateway failed", err)
+		return
+	}
+	err = a.syncFdb(ctx, natGateway)
+	if err != nil {
+		return
+	}
+	control = "toall="
+	command = fmt.Sprintf("/opt/cloudland/scripts/backend/set_nat_route.sh '%d' '%d'<<EOF\n%s\nEOF", natGateway.RouterID, natGateway.ID, data)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Set nat route failed", err)
+		return
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) clear(ctx context.Context, natGateway *model.NatGateway, ifaces []*model.Interface) (err error) {
+	fip := natGateway.FloatingIp
+	if fip == nil {
+		return
+	}
+	data, err := a.getNatData(ifaces)
+	if err != nil {
+		return
+	}
+	control := "toall="
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/clear_nat_gateway.sh '%d' '%d' '%s' '%d'<<EOF\n%s\nEOF", natGateway.RouterID, natGateway.ID, fip.FipAddress, fip.ID, data)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Clear nat gateway failed", err)
+		return
+	}
+	return
+}
+
+// SyncRouter points the translated subnets to the nat gateway on one hypervisor
+func (a *NatGatewayAdmin) SyncRouter(ctx context.Context, routerID int64, hyper int32) (err error) {
+	ctx, db := GetContextDB(ctx)
+	natGateways := []*model.NatGateway{}
+	err = db.Where("router_id = ?", routerID).Find(&natGateways).Error
+	if err != nil {
+		logger.Error("Failed to query nat gateways", err)
+		return
+	}
+	for _, natGateway := range natGateways {
+		if natGateway.Hyper == hyper {
+			continue
+		}
+		err = a.loadInterfaces(ctx, natGateway)
+		if err != nil {
+			return
+		}
+		err = a.syncFdb(ctx, natGateway)
+		if err != nil {
+			return
+		}
+		var data []byte
+		data, err = a.getNatData(natGateway.Interfaces)
+		if err != nil {
+			return
+		}
+		control := fmt.Sprintf("inter=%d", hyper)
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/set_nat_route.sh '%d' '%d'<<EOF\n%s\nEOF", natGateway.RouterID, natGateway.ID, data)
+		err = HyperExecute(ctx, control, command)
+		if err != nil {
+			logger.Error("Set nat route failed", err)
+			return
+		}
+	}
+	return
+}
+
+// AttachSubnet adds a new subnet of a router to its nat gateway translating all subnets
+func (a *NatGatewayAdmin) AttachSubnet(ctx context.Context, subnet *model.Subnet) (err error) {
+	ctx, db := GetContextDB(ctx)
+	natGateway := &model.NatGateway{}
+	err = db.Preload("FloatingIp").Preload("FloatingIp.Interface").Preload("FloatingIp.Interface.Address").Preload("FloatingIp.Interface.Address.Subnet").Where("router_id = ? and all_subnets = ?", subnet.RouterID, true).Take(natGateway).Error
+	if err != nil {
+		err = nil
+		return
+	}
+	err = a.loadInterfaces(ctx, natGateway)
+	if err != nil {
+		return
+	}
+	_, err = a.addSubnet(ctx, natGateway, subnet)
+	if err != nil {
+		return
+	}
+	err = a.Apply(ctx, natGateway)
+	return
+}
+
+func (a *NatGatewayAdmin) getSubnets(ctx context.Context, router *model.Router, subnets []*model.Subnet) (natSubnets []*model.Subnet, allSubnets bool, err error) {
+	ctx, db := GetContextDB(ctx)
+	if len(subnets) > 0 {
+		natSubnets = subnets
+		return
+	}
+	allSubnets = true
+	natSubnets = []*model.Subnet{}
+	err = db.Where("router_id = ? and type = ?", router.ID, string(Internal)).Find(&natSubnets).Error
+	if err != nil {
+		logger.Error("Failed to query subnets of router", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query subnets of the vpc", err)
+		return
+	}
+	return
+}
+
+// Create allocates a public address for the router and translates the given subnets,
+// or all internal subnets of the router including the ones created later if none is given
+func (a *NatGatewayAdmin) Create(ctx context.Context, name string, router *model.Router, pubSubnets []*model.Subnet, publicIp string, inbound, outbound int32, subnets []*model.Subnet) (natGateway *model.NatGateway, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("nat_gateway:create").ValidateOwner(model.Writer, router.Owner)
+	if !permit {
+		logger.Error("Not authorized to create nat gateway")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create nat gateway", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	count := 0
+	err = db.Model(&model.NatGateway{}).Where("router_id = ?", router.ID).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count nat gateways", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count nat gateways", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrNatGatewayExists, "The vpc already has a nat gateway", nil)
+		return
+	}
+	subnets, allSubnets, err := a.getSubnets(ctx, router, subnets)
+	if err != nil {
+		return
+	}
+	hyper, err := a.chooseHyper(ctx, router)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = router.Name + "-nat"
+	}
+	fip := &model.FloatingIp{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, Inbound: inbound, Outbound: outbound, Type: string(PublicNatGateway), RouterID: router.ID}
+	err = db.Create(fip).Error
+	if err != nil {
+		logger.Error("DB failed to create floating ip", err)
+		err = NewCLError(ErrFIPCreateFailed, "Failed to create floating ip", err)
+		return
+	}
+	fipIface, err := AllocateFloatingIp(ctx, fip.ID, router.Owner, pubSubnets, publicIp)
+	if err != nil || fipIface == nil {
+		logger.Error("Failed to allocate public address for nat gateway", err)
+		err = NewCLError(ErrInsufficientAddress, "Failed to allocate public address for nat gateway", err)
+		return
+	}
+	fip.FipAddress = fipIface.Address.Address
+	fip.IPAddress = strings.Split(fip.FipAddress, "/")[0]
+	fip.SubnetID = fipIface.Address.Subnet.ID
+	fip.Interface = fipIface
+	err = db.Model(&model.FloatingIp{}).Where("id = ?", fip.ID).Updates(map[string]interface{}{"fip_address": fip.FipAddress, "ip_address": fip.IPAddress, "subnet_id": fip.SubnetID}).Error
+	if err != nil {
+		logger.Error("DB failed to update floating ip", err)
+		err = NewCLError(ErrFIPUpdateFailed, "Failed to update floating ip", err)
+		return
+	}
+	natGateway = &model.NatGateway{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, Status: "available", RouterID: router.ID, FloatingIpID: fip.ID, Hyper: hyper, Inbound: inbound, Outbound: outbound, AllSubnets: allSubnets}
+	err = db.Create(natGateway).Error
+	if err != nil {
+		logger.Error("DB failed to create nat gateway", err)
+		err = NewCLError(ErrNatGatewayCreateFailed, "Failed to create nat gateway", err)
+		return
+	}
+	natGateway.FloatingIp = fip
+	natGateway.Router = router
+	natGateway.Interfaces = []*model.Interface{}
+	for _, subnet := range subnets {
+		_, err = a.addSubnet(ctx, natGateway, subnet)
+		if err != nil {
+			return
+		}
+	}
+	err = a.Apply(ctx, natGateway)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) Get(ctx context.Context, id int64) (natGateway *model.NatGateway, err error) {
+	if id <= 0 {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid nat gateway ID: %d", id), nil)
+		logger.Error(err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	natGateway = &model.NatGateway{Model: model.Model{ID: id}}
+	err = db.Preload("Router").Preload("FloatingIp").Preload("FloatingIp.Interface").Preload("FloatingIp.Interface.Address").Preload("FloatingIp.Interface.Address.Subnet").Where(where).Take(natGateway).Error
+	if err != nil {
+		logger.Error("Failed to query nat gateway", err)
+		err = NewCLError(ErrNatGatewayNotFound, "Failed to find nat gateway", err)
+		return
+	}
+	err = a.loadInterfaces(ctx, natGateway)
+	return
+}
+
+func (a *NatGatewayAdmin) GetNatGatewayByUUID(ctx context.Context, uuID string) (natGateway *model.NatGateway, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	natGateway = &model.NatGateway{}
+	err = db.Preload("Router").Preload("FloatingIp").Preload("FloatingIp.Interface").Preload("FloatingIp.Interface.Address").Preload("FloatingIp.Interface.Address.Subnet").Where(where).Where("uuid = ?", uuID).Take(natGateway).Error
+	if err != nil {
+		logger.Error("Failed to query nat gateway, %v", err)
+		err = NewCLError(ErrNatGatewayNotFound, "Failed to find nat gateway", err)
+		return
+	}
+	permit := memberShip.For("nat_gateway:get").ValidateOwner(model.Reader, natGateway.Owner)
+	if !permit {
+		logger.Error("Not authorized to read the nat gateway")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the nat gateway", nil)
+		return
+	}
+	err = a.loadInterfaces(ctx, natGateway)
+	return
+}
+
+// Update changes the name and the bandwidth limits, and replaces the translated subnets if subnets is not nil
+func (a *NatGatewayAdmin) Update(ctx context.Context, natGateway *model.NatGateway, name string, inbound, outbound int32, subnets []*model.Subnet) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("nat_gateway:update").ValidateOwner(model.Writer, natGateway.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the nat gateway")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the nat gateway", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if name != "" && natGateway.Name != name {
+		natGateway.Name = name
+		if err = db.Model(natGateway).Update("name", natGateway.Name).Error; err != nil {
+			logger.Error("Failed to save nat gateway", err)
+			err = NewCLError(ErrNatGatewayUpdateFailed, "Failed to update nat gateway", err)
+			return
+		}
+	}
+	changed := false
+	if inbound >= 0 && outbound >= 0 && (inbound != natGateway.Inbound || outbound != natGateway.Outbound) {
+		natGateway.Inbound = inbound
+		natGateway.Outbound = outbound
+		err = db.Model(natGateway).Updates(map[string]interface{}{"inbound": inbound, "outbound": outbound}).Error
+		if err != nil {
+			logger.Error("Failed to save nat gateway", err)
+			err = NewCLError(ErrNatGatewayUpdateFailed, "Failed to update nat gateway", err)
+			return
+		}
+		err = db.Model(&model.FloatingIp{}).Where("id = ?", natGateway.FloatingIpID).Updates(map[string]interface{}{"inbound": inbound, "outbound": outbound}).Error
+		if err != nil {
+			logger.Error("Failed to save floating ip", err)
+			err = NewCLError(ErrFIPUpdateFailed, "Failed to update floating ip", err)
+			return
+		}
+		changed = true
+	}
+	if subnets != nil {
+		var router *model.Router
+		router = natGateway.Router
+		if router == nil {
+			router = &model.Router{Model: model.Model{ID: natGateway.RouterID}}
+		}
+		var allSubnets bool
+		subnets, allSubnets, err = a.getSubnets(ctx, router, subnets)
+		if err != nil {
+			return
+		}
+		wanted := make(map[int64]*model.Subnet)
+		for _, subnet := range subnets {
+			wanted[subnet.ID] = subnet
+		}
+		kept := []*model.Interface{}
+		removed := []*model.Interface{}
+		for _, iface := range natGateway.Interfaces {
+			if _, ok := wanted[iface.Subnet]; ok {
+				delete(wanted, iface.Subnet)
+				kept = append(kept, iface)
+			} else {
+				removed = append(removed, iface)
+			}
+		}
+		if len(removed) > 0 {
+			err = a.clear(ctx, natGateway, removed)
+			if err != nil {
+				return
+			}
+			for _, iface := range removed {
+				err = DeleteInterface(ctx, iface)
+				if err != nil {
+					logger.Error("Failed to delete nat gateway interface", err)
+					err = NewCLError(ErrInterfaceDeleteFailed, "Failed to delete nat gateway interface", err)
+					return
+				}
+			}
+		}
+		natGateway.Interfaces = kept
+		for _, subnet := range subnets {
+			if _, ok := wanted[subnet.ID]; !ok {
+				continue
+			}
+			_, err = a.addSubnet(ctx, natGateway, subnet)
+			if err != nil {
+				return
+			}
+		}
+		if allSubnets != natGateway.AllSubnets {
+			natGateway.AllSubnets = allSubnets
+			err = db.Model(natGateway).Update("all_subnets", allSubnets).Error
+			if err != nil {
+				logger.Error("Failed to save nat gateway", err)
+				err = NewCLError(ErrNatGatewayUpdateFailed, "Failed to update nat gateway", err)
+				return
+			}
+		}
+		changed = true
+	}
+	if changed {
+		err = a.Apply(ctx, natGateway)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) Delete(ctx context.Context, natGateway *model.NatGateway) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("nat_gateway:delete").ValidateOwner(model.Writer, natGateway.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the nat gateway")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the nat gateway", nil)
+		return
+	}
+	err = a.clear(ctx, natGateway, natGateway.Interfaces)
+	if err != nil {
+		return
+	}
+	for _, iface := range natGateway.Interfaces {
+		err = DeleteInterface(ctx, iface)
+		if err != nil {
+			logger.Error("Failed to delete nat gateway interface", err)
+			err = NewCLError(ErrInterfaceDeleteFailed, "Failed to delete nat gateway interface", err)
+			return
+		}
+	}
+	if natGateway.FloatingIpID > 0 {
+		err = floatingIpAdmin.DeallocateFloatingIp(ctx, natGateway.FloatingIpID)
+		if err != nil {
+			logger.Error("Failed to deallocate public address of nat gateway", err)
+			return
+		}
+	}
+	if err = db.Delete(natGateway).Error; err != nil {
+		logger.Error("DB failed to delete nat gateway", err)
+		err = NewCLError(ErrNatGatewayDeleteFailed, "Failed to delete nat gateway", err)
+		return
+	}
+	return
+}
+
+func (a *NatGatewayAdmin) List(ctx context.Context, offset, limit int64, order string, router *model.Router) (total int64, natGateways []*model.NatGateway, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := memberShip.GetWhere()
+	natGateways = []*model.NatGateway{}
+	if err = ListFilter(ctx, db.Model(&model.NatGateway{})).Where(where).Where("router_id = ?", router.ID).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count nat gateways, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count nat gateways", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.NatGateway{}, offset, limit, order).Preload("FloatingIp").Where(where).Where("router_id = ?", router.ID).Find(&natGateways).Error; err != nil {
+		logger.Error("DB failed to query nat gateways, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query nat gateways", err)
+		return
+	}
+	for _, natGateway := range natGateways {
+		err = a.loadInterfaces(ctx, natGateway)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
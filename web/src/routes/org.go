@@ -20,7 +20,6 @@ import (
 	"strings"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -163,14 +162,40 @@ func (a *OrgAdmin) Update(ctx context.Context, orgID int64, members, users []str
 			err = nil
 			continue
 		}
+		err = tokenAdmin.RevokeMember(ctx, em.UserID, orgID)
+		if err != nil {
+			logger.Error("Failed to revoke tokens of member", err)
+			err = nil
+		}
+		err = policyAdmin.DeleteMemberBindings(ctx, em.UserID, orgID)
+		if err != nil {
+			logger.Error("Failed to delete role bindings of member", err)
+			err = nil
+		}
 	}
 	for i, user := range users {
-		err = db.Model(&model.Member{}).Where("user_name = ? and org_id = ?", user, orgID).Update("role", roles[i]).Error
+		member := &model.Member{}
+		err = db.Where("user_name = ? and org_id = ?", user, orgID).Take(member).Error
+		if err != nil {
+			logger.Error("Failed to query member", err)
+			err = nil
+			continue
+		}
+		if member.Role == roles[i] {
+			continue
+		}
+		err = db.Model(member).Update("role", roles[i]).Error
 		if err != nil {
 			logger.Error("Failed to update member", err)
 			err = nil
 			continue
 		}
+		// Tokens carry the role, the member has to login again with the new one
+		err = tokenAdmin.RevokeMember(ctx, member.UserID, orgID)
+		if err != nil {
+			logger.Error("Failed to revoke tokens of member", err)
+			err = nil
+		}
 	}
 	return
 }
@@ -237,6 +262,10 @@ func (a *OrgAdmin) Delete(ctx context.Context, org *model.Organization) (err err
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the org", nil)
 		return
 	}
+	err = mfaAdmin.StepUp(ctx, "delete the org")
+	if err != nil {
+		return
+	}
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
@@ -271,6 +300,18 @@ func (a *OrgAdmin) Delete(ctx context.Context, org *model.Organization) (err err
 		err = NewCLError(ErrMemberDeleteFailed, "Failed to delete organization member", err)
 		return
 	}
+	err = db.Delete(&model.RoleBinding{}, `owner = ?`, org.ID).Error
+	if err != nil {
+		logger.Error("DB failed to delete role bindings, %v", err)
+		err = NewCLError(ErrRoleBindingDeleteFailed, "Failed to delete role bindings", err)
+		return
+	}
+	err = db.Delete(&model.CustomRole{}, `owner = ?`, org.ID).Error
+	if err != nil {
+		logger.Error("DB failed to delete custom roles, %v", err)
+		err = NewCLError(ErrCustomRoleDeleteFailed, "Failed to delete custom roles", err)
+		return
+	}
 	keys := []*model.Key{}
 	err = db.Where("owner = ?", org.ID).Find(&keys).Error
 	if err != nil {
@@ -343,13 +384,12 @@ func (a *OrgAdmin) List(ctx context.Context, offset, limit int64, order, query s
 		where = fmt.Sprintf("id = %d", memberShip.OrgID)
 	}
 	orgs = []*model.Organization{}
-	if err = db.Model(&orgs).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&orgs)).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count organizations, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count organizations", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	err = db.Preload("Members").Where(where).Where(query).Find(&orgs).Error
+	err = ListPage(ctx, db, &orgs, offset, limit, order).Preload("Members").Where(where).Where(query).Find(&orgs).Error
 	if err != nil {
 		logger.Error("DB failed to query organizations, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query organizations", err)
@@ -507,13 +547,23 @@ func (v *OrgView) Patch(c *macaron.Context, store session.Store) {
 		}
 		roleList = append(roleList, model.Role(role))
 	}
-	_, err = orgAdmin.Update(c.Req.Context(), int64(orgID), memberList, userList, roleList)
+	org, err := orgAdmin.Update(c.Req.Context(), int64(orgID), memberList, userList, roleList)
 	if err != nil {
 		logger.Error("Failed to update organization, %v", err)
 		c.Data["ErrorMsg"] = err.Error()
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
+	requireMfa := c.QueryTrim("require_mfa") != ""
+	if memberShip.Role == model.Admin && requireMfa != org.RequireMfa {
+		err = mfaAdmin.SetOrgRequirement(c.Req.Context(), org, requireMfa)
+		if err != nil {
+			logger.Error("Failed to update multi-factor requirement, %v", err)
+			c.Data["ErrorMsg"] = err.Error()
+			c.HTML(http.StatusBadRequest, "error")
+			return
+		}
+	}
 	c.Redirect(redirectTo)
 }
 
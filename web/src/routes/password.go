@@ -0,0 +1,287 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	. "web/src/common"
+	"web/src/identity"
+	"web/src/model"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	defaultLockoutThreshold  = 5
+	defaultLockoutMinutes    = 15
+)
+
+// passwordPolicy is read from the [password] section of the config on every use
+type passwordPolicy struct {
+	MinLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSpecial   bool
+	History          int           /* Number of previous passwords that can not be reused */
+	MaxAge           time.Duration /* Passwords older than this must be changed at login, 0 never expires */
+	LockoutThreshold int           /* Consecutive failures to lock the account, negative disables lockout */
+	LockoutDuration  time.Duration /* 0 locks until an admin unlocks the account */
+}
+
+func getPasswordPolicy() (policy *passwordPolicy) {
+	policy = &passwordPolicy{
+		MinLength:        viper.GetInt("password.min_length"),
+		RequireUpper:     viper.GetBool("password.require_upper"),
+		RequireLower:     viper.GetBool("password.require_lower"),
+		RequireDigit:     viper.GetBool("password.require_digit"),
+		RequireSpecial:   viper.GetBool("password.require_special"),
+		History:          viper.GetInt("password.history"),
+		MaxAge:           time.Duration(viper.GetInt("password.max_age_days")) * 24 * time.Hour,
+		LockoutThreshold: viper.GetInt("password.lockout_threshold"),
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = defaultPasswordMinLength
+	}
+	if policy.LockoutThreshold == 0 {
+		policy.LockoutThreshold = defaultLockoutThreshold
+	}
+	minutes := defaultLockoutMinutes
+	if viper.IsSet("password.lockout_minutes") {
+		minutes = viper.GetInt("password.lockout_minutes")
+	}
+	if minutes > 0 {
+		policy.LockoutDuration = time.Duration(minutes) * time.Minute
+	}
+	return
+}
+
+// CheckPassword validates the complexity of a new password
+func (a *UserAdmin) CheckPassword(username, password string) (err error) {
+	policy := getPasswordPolicy()
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			special = true
+		}
+	}
+	var missing []string
+	if len(password) < policy.MinLength {
+		missing = append(missing, fmt.Sprintf("at least %d characters", policy.MinLength))
+	}
+	if policy.RequireUpper && !upper {
+		missing = append(missing, "an upper case letter")
+	}
+	if policy.RequireLower && !lower {
+		missing = append(missing, "a lower case letter")
+	}
+	if policy.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if policy.RequireSpecial && !special {
+		missing = append(missing, "a special character")
+	}
+	if len(missing) > 0 {
+		err = NewCLError(ErrPasswordTooWeak, "Password must contain "+strings.Join(missing, ", "), nil)
+		return
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		err = NewCLError(ErrPasswordTooWeak, "Password must not contain the username", nil)
+		return
+	}
+	return
+}
+
+// ChangePassword replaces the password of a local user after the complexity and history checks
+func (a *UserAdmin) ChangePassword(ctx context.Context, user *model.User, password string) (err error) {
+	err = a.CheckPassword(user.Username, password)
+	if err != nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	policy := getPasswordPolicy()
+	if policy.History > 0 {
+		hashes := []string{user.Password}
+		histories := []*model.PasswordHistory{}
+		err = db.Where("user_id = ?", user.ID).Order("created_at desc").Limit(policy.History).Find(&histories).Error
+		if err != nil {
+			logger.Error("DB failed to query password history", err)
+			err = NewCLError(ErrDatabaseError, "Failed to query password history", err)
+			return
+		}
+		for _, history := range histories {
+			hashes = append(hashes, history.Hash)
+		}
+		for _, hash := range hashes {
+			if hash != "" && a.CompareHashAndPassword(hash, password) == nil {
+				err = NewCLError(ErrPasswordReused, fmt.Sprintf("Password must differ from the last %d passwords", policy.History), nil)
+				return
+			}
+		}
+	}
+	hash, err := a.GenerateFromPassword(password)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	err = db.Model(user).Updates(map[string]interface{}{"password": hash, "password_changed_at": &now}).Error
+	if err != nil {
+		logger.Error("DB failed to update user password", err)
+		err = NewCLError(ErrUserUpdateFailed, "Failed to update user password", err)
+		return
+	}
+	err = a.recordPassword(db, user.ID, hash)
+	return
+}
+
+// recordPassword appends the hash to the history and drops the entries beyond password.history
+func (a *UserAdmin) recordPassword(db *gorm.DB, userID int64, hash string) (err error) {
+	policy := getPasswordPolicy()
+	if policy.History <= 0 {
+		return
+	}
+	err = db.Create(&model.PasswordHistory{UserID: userID, Hash: hash}).Error
+	if err != nil {
+		logger.Error("DB failed to create password history", err)
+		err = NewCLError(ErrUserUpdateFailed, "Failed to record password history", err)
+		return
+	}
+	stale := []*model.PasswordHistory{}
+	err = db.Where("user_id = ?", userID).Order("created_at desc").Offset(policy.History).Find(&stale).Error
+	if err != nil {
+		logger.Error("DB failed to query password history", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query password history", err)
+		return
+	}
+	for _, history := range stale {
+		err = db.Unscoped().Delete(history).Error
+		if err != nil {
+			logger.Error("DB failed to delete password history", err)
+			err = NewCLError(ErrUserUpdateFailed, "Failed to trim password history", err)
+			return
+		}
+	}
+	return
+}
+
+// PasswordExpired tells if a local user must change the password before login, password.max_age_days in the config
+func (a *UserAdmin) PasswordExpired(user *model.User) bool {
+	policy := getPasswordPolicy()
+	if policy.MaxAge <= 0 || (user.Source != "" && user.Source != identity.SourceLocal) {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > policy.MaxAge
+}
+
+// checkLocked refuses login to an account locked by failed attempts, admin is never locked so that it can unlock others
+func (a *UserAdmin) checkLocked(user *model.User) (err error) {
+	if user.Username == "admin" || user.LockedUntil == nil {
+		return
+	}
+	// a lock without expiry is stored as the zero time
+	if user.LockedUntil.IsZero() || time.Now().Before(*user.LockedUntil) {
+		err = NewCLError(ErrUserLocked, fmt.Sprintf("User %s is locked after too many failed logins", user.Username), nil)
+	}
+	return
+}
+
+// Locked tells if the user is currently locked out
+func (a *UserAdmin) Locked(user *model.User) bool {
+	return a.checkLocked(user) != nil
+}
+
+// loginFailed counts the failure and locks the account at password.lockout_threshold
+func (a *UserAdmin) loginFailed(ctx context.Context, user *model.User) {
+	policy := getPasswordPolicy()
+	if policy.LockoutThreshold < 0 || user.Username == "admin" {
+		return
+	}
+	var err error
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	// counted on the locked row, concurrent failures must not overwrite each other below the threshold
+	locked := &model.User{}
+	err = db.Set("gorm:query_option", "FOR UPDATE").Take(locked, user.ID).Error
+	if err != nil {
+		logger.Error("DB failed to query failed logins", err)
+		return
+	}
+	failures := locked.FailedLogins + 1
+	if locked.LockedUntil != nil && !locked.LockedUntil.IsZero() && time.Now().After(*locked.LockedUntil) {
+		// the previous lock expired, start counting again
+		failures = 1
+	}
+	updates := map[string]interface{}{"failed_logins": failures}
+	if failures >= policy.LockoutThreshold {
+		lockedUntil := time.Time{}
+		if policy.LockoutDuration > 0 {
+			lockedUntil = time.Now().Add(policy.LockoutDuration)
+		}
+		updates["locked_until"] = &lockedUntil
+		logger.Errorf("User %s is locked after %d failed logins", user.Username, failures)
+	}
+	err = db.Model(locked).UpdateColumns(updates).Error
+	if err != nil {
+		logger.Error("DB failed to update failed logins", err)
+	}
+}
+
+// loginSucceeded clears the failure counter
+func (a *UserAdmin) loginSucceeded(ctx context.Context, user *model.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	err := db.Model(user).UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": gorm.Expr("NULL")}).Error
+	if err != nil {
+		logger.Error("DB failed to reset failed logins", err)
+	}
+}
+
+// Unlock lets a locked user login again, admin only
+func (a *UserAdmin) Unlock(ctx context.Context, user *model.User) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Admin)
+	if !permit {
+		logger.Error("Not authorized to unlock the user")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to unlock the user", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(user).UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": gorm.Expr("NULL")}).Error
+	if err != nil {
+		logger.Error("DB failed to unlock user", err)
+		err = NewCLError(ErrUserUpdateFailed, "Failed to unlock user", err)
+		return
+	}
+	logger.Infof("User %s is unlocked by %s", user.Username, memberShip.UserName)
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	return
+}
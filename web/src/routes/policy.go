@@ -0,0 +1,333 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var (
+	policyAdmin = &PolicyAdmin{}
+)
+
+// PolicyAdmin manages the custom roles of an organization and binds them to its members,
+// the actions of the bound roles are checked by the *Admin methods through MemberShip.For
+type PolicyAdmin struct{}
+
+func (a *PolicyAdmin) normalizeActions(actions []string) (normalized string, err error) {
+	actionSet := make(map[string]struct{})
+	for _, action := range actions {
+		action = strings.ToLower(strings.TrimSpace(action))
+		if action == "" {
+			continue
+		}
+		if !ValidAction(action) {
+			err = NewCLError(ErrInvalidAction, fmt.Sprintf("Invalid action %s, resource:verb is expected", action), nil)
+			return
+		}
+		actionSet[action] = struct{}{}
+	}
+	if len(actionSet) == 0 {
+		err = NewCLError(ErrInvalidAction, "At least one action is required", nil)
+		return
+	}
+	sorted := []string{}
+	for action := range actionSet {
+		sorted = append(sorted, action)
+	}
+	sort.Strings(sorted)
+	normalized = strings.Join(sorted, ",")
+	return
+}
+
+func (a *PolicyAdmin) checkName(ctx context.Context, owner, id int64, name string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	count := 0
+	err = db.Model(&model.CustomRole{}).Where("owner = ? and name = ? and id != ?", owner, name, id).Count(&count).Error
+	if err != nil {
+		logger.Error("DB failed to count custom roles", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count custom roles", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrCustomRoleExists, fmt.Sprintf("Custom role %s already exists", name), nil)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) CreateRole(ctx context.Context, name, description string, actions []string) (role *model.CustomRole, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.CheckPermission(model.Owner)
+	if !permit {
+		logger.Error("Not authorized to create custom role")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create custom role", nil)
+		return
+	}
+	normalized, err := a.normalizeActions(actions)
+	if err != nil {
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.checkName(ctx, memberShip.OrgID, 0, name)
+	if err != nil {
+		return
+	}
+	role = &model.CustomRole{
+		Model:       model.Model{Creater: memberShip.UserID},
+		Owner:       memberShip.OrgID,
+		Name:        name,
+		Description: description,
+		Actions:     normalized,
+	}
+	err = db.Create(role).Error
+	if err != nil {
+		logger.Error("DB failed to create custom role", err)
+		err = NewCLError(ErrCustomRoleCreateFailed, "Failed to create custom role", err)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) GetRoleByUUID(ctx context.Context, uuID string) (role *model.CustomRole, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	role = &model.CustomRole{}
+	err = db.Where(where).Where("uuid = ?", uuID).Take(role).Error
+	if err != nil {
+		logger.Error("Failed to query custom role, %v", err)
+		err = NewCLError(ErrCustomRoleNotFound, "Failed to find custom role", err)
+		return
+	}
+	permit := memberShip.ValidateOwner(model.Reader, role.Owner)
+	if !permit {
+		logger.Error("Not authorized to read the custom role")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the custom role", nil)
+		return
+	}
+	return
+}
+
+// UpdateRole changes the name or the actions of a role, the members bound to it get the new actions with their next request
+func (a *PolicyAdmin) UpdateRole(ctx context.Context, role *model.CustomRole, name, description string, actions []string) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.ValidateOwner(model.Owner, role.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the custom role")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the custom role", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if name != "" && name != role.Name {
+		err = a.checkName(ctx, role.Owner, role.ID, name)
+		if err != nil {
+			return
+		}
+		role.Name = name
+	}
+	if description != "" {
+		role.Description = description
+	}
+	if len(actions) > 0 {
+		role.Actions, err = a.normalizeActions(actions)
+		if err != nil {
+			return
+		}
+	}
+	err = db.Model(role).Updates(map[string]interface{}{"name": role.Name, "description": role.Description, "actions": role.Actions}).Error
+	if err != nil {
+		logger.Error("DB failed to update custom role", err)
+		err = NewCLError(ErrCustomRoleUpdateFailed, "Failed to update custom role", err)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) DeleteRole(ctx context.Context, role *model.CustomRole) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.ValidateOwner(model.Owner, role.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the custom role")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the custom role", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = db.Where("role_id = ?", role.ID).Delete(&model.RoleBinding{}).Error
+	if err != nil {
+		logger.Error("DB failed to delete role bindings", err)
+		err = NewCLError(ErrRoleBindingDeleteFailed, "Failed to delete role bindings", err)
+		return
+	}
+	err = db.Delete(role).Error
+	if err != nil {
+		logger.Error("DB failed to delete custom role", err)
+		err = NewCLError(ErrCustomRoleDeleteFailed, "Failed to delete custom role", err)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) ListRoles(ctx context.Context, offset, limit int64, order string) (total int64, roles []*model.CustomRole, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := memberShip.GetWhere()
+	roles = []*model.CustomRole{}
+	if err = ListFilter(ctx, db.Model(&model.CustomRole{})).Where(where).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count custom roles, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count custom roles", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.CustomRole{}, offset, limit, order).Where(where).Find(&roles).Error; err != nil {
+		logger.Error("DB failed to query custom roles, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query custom roles", err)
+		return
+	}
+	return
+}
+
+// CreateBinding binds the role to a member of the organization of the role
+func (a *PolicyAdmin) CreateBinding(ctx context.Context, role *model.CustomRole, username string) (binding *model.RoleBinding, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.ValidateOwner(model.Owner, role.Owner)
+	if !permit {
+		logger.Error("Not authorized to bind the custom role")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to bind the custom role", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	member := &model.Member{}
+	err = db.Where("user_name = ? and org_id = ?", username, role.Owner).Take(member).Error
+	if err != nil {
+		logger.Error("Failed to query member", err)
+		err = NewCLError(ErrMemberNotFound, fmt.Sprintf("User %s is not a member of the organization", username), err)
+		return
+	}
+	binding = &model.RoleBinding{}
+	err = db.Where("role_id = ? and user_id = ?", role.ID, member.UserID).Take(binding).Error
+	if err == nil {
+		err = NewCLError(ErrRoleBindingCreateFailed, fmt.Sprintf("Role %s is already bound to user %s", role.Name, username), nil)
+		return
+	}
+	binding = &model.RoleBinding{
+		Model:  model.Model{Creater: memberShip.UserID},
+		Owner:  role.Owner,
+		RoleID: role.ID,
+		UserID: member.UserID,
+	}
+	err = db.Create(binding).Error
+	if err != nil {
+		logger.Error("DB failed to create role binding", err)
+		err = NewCLError(ErrRoleBindingCreateFailed, "Failed to create role binding", err)
+		return
+	}
+	binding.Role = role
+	binding.User = &model.User{Model: model.Model{ID: member.UserID}}
+	err = db.Take(binding.User).Error
+	if err != nil {
+		logger.Error("DB failed to query user", err)
+		err = NewCLError(ErrUserNotFound, "Failed to query user", err)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) GetBindingByUUID(ctx context.Context, role *model.CustomRole, uuID string) (binding *model.RoleBinding, err error) {
+	ctx, db := GetContextDB(ctx)
+	binding = &model.RoleBinding{}
+	err = db.Preload("User").Where("role_id = ? and uuid = ?", role.ID, uuID).Take(binding).Error
+	if err != nil {
+		logger.Error("Failed to query role binding, %v", err)
+		err = NewCLError(ErrRoleBindingNotFound, "Failed to find role binding", err)
+		return
+	}
+	binding.Role = role
+	return
+}
+
+func (a *PolicyAdmin) DeleteBinding(ctx context.Context, binding *model.RoleBinding) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.ValidateOwner(model.Owner, binding.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the role binding")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the role binding", nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	err = db.Delete(binding).Error
+	if err != nil {
+		logger.Error("DB failed to delete role binding", err)
+		err = NewCLError(ErrRoleBindingDeleteFailed, "Failed to delete role binding", err)
+		return
+	}
+	return
+}
+
+func (a *PolicyAdmin) ListBindings(ctx context.Context, role *model.CustomRole) (bindings []*model.RoleBinding, err error) {
+	ctx, db := GetContextDB(ctx)
+	bindings = []*model.RoleBinding{}
+	err = db.Preload("User").Where("role_id = ?", role.ID).Order("created_at").Find(&bindings).Error
+	if err != nil {
+		logger.Error("DB failed to query role bindings, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query role bindings", err)
+		return
+	}
+	for _, binding := range bindings {
+		binding.Role = role
+	}
+	return
+}
+
+// DeleteMemberBindings drops the bindings of a user removed from an organization, or from all organizations if orgID is 0
+func (a *PolicyAdmin) DeleteMemberBindings(ctx context.Context, userID, orgID int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	db = db.Where("user_id = ?", userID)
+	if orgID > 0 {
+		db = db.Where("owner = ?", orgID)
+	}
+	err = db.Delete(&model.RoleBinding{}).Error
+	if err != nil {
+		logger.Error("DB failed to delete role bindings of member", err)
+		err = NewCLError(ErrRoleBindingDeleteFailed, "Failed to delete role bindings of member", err)
+		return
+	}
+	return
+}
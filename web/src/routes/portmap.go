@@ -0,0 +1,333 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	portmapAdmin = &PortmapAdmin{}
+)
+
+const (
+	defaultPortmapMin = 18000
+	defaultPortmapMax = 20000
+)
+
+type PortmapAdmin struct{}
+
+func getPortmapRange() (min, max int32) {
+	min = int32(viper.GetInt("network.portmap_min"))
+	max = int32(viper.GetInt("network.portmap_max"))
+	if min <= 0 || max <= min || max > 65535 {
+		min = defaultPortmapMin
+		max = defaultPortmapMax
+	}
+	return
+}
+
+// checkPortmapQuota returns an error if the organization has used up its port forwarding quota,
+// a missing quota record or a zero limit means unlimited
+func (a *PortmapAdmin) checkPortmapQuota(ctx context.Context, owner int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	quota := &model.Quota{}
+	err = db.Where("owner = ?", owner).Take(quota).Error
+	if err != nil {
+		logger.Debugf("No quota defined for org %d, %v", owner, err)
+		err = nil
+		return
+	}
+	if quota.Portmap <= 0 {
+		return
+	}
+	count := 0
+	err = db.Model(&model.Portmap{}).Where("owner = ?", owner).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count port forwardings", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count port forwardings", err)
+		return
+	}
+	if int32(count) >= quota.Portmap {
+		logger.Errorf("Port forwarding quota %d exceeded for org %d", quota.Portmap, owner)
+		err = NewCLError(ErrQuotaExceeded, fmt.Sprintf("Port forwarding quota %d exceeded", quota.Portmap), nil)
+		return
+	}
+	return
+}
+
+func (a *PortmapAdmin) allocateRemotePort(ctx context.Context, remotePort int32) (port int32, err error) {
+	ctx, db := GetContextDB(ctx)
+	min, max := getPortmapRange()
+	count := 0
+	if remotePort > 0 {
+		if remotePort < min || remotePort > max {
+			logger.Errorf("Remote port %d is out of range %d-%d", remotePort, min, max)
+			err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Remote port must be between %d and %d", min, max), nil)
+			return
+		}
+		// all port forwardings listen on the same remote address, so the port is taken across vpcs
+		err = db.Model(&model.Portmap{}).Where("remote_port = ?", remotePort).Count(&count).Error
+		if err != nil {
+			logger.Error("Failed to query existing remote port", err)
+			err = NewCLError(ErrDatabaseError, "Failed to query existing remote port", err)
+			return
+		}
+		if count > 0 {
+			logger.Errorf("Remote port %d is already used", remotePort)
+			err = NewCLError(ErrPortmapPortConflict, fmt.Sprintf("Remote port %d is already used", remotePort), nil)
+			return
+		}
+		port = remotePort
+		return
+	}
+	for i := 0; i < int(max-min); i++ {
+		port = rand.Int31n(max-min) + min
+		err = db.Model(&model.Portmap{}).Where("remote_port = ?", port).Count(&count).Error
+		if err != nil {
+			logger.Error("Failed to query existing remote port", err)
+			err = NewCLError(ErrDatabaseError, "Failed to query existing remote port", err)
+			return
+		}
+		if count == 0 {
+			return
+		}
+	}
+	logger.Errorf("No free remote port in range %d-%d", min, max)
+	err = NewCLError(ErrInsufficientResource, "No free remote port available", nil)
+	return
+}
+
+func (a *PortmapAdmin) Create(ctx context.Context, name string, router *model.Router, instance *model.Instance, localPort, remotePort int32) (portmap *model.Portmap, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("portmap:create").ValidateOwner(model.Writer, router.Owner)
+	if !permit {
+		logger.Error("Not authorized to create port forwarding")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create port forwarding", nil)
+		return
+	}
+	if instance.RouterID != router.ID {
+		logger.Errorf("Instance %d is not in router %d", instance.ID, router.ID)
+		err = NewCLError(ErrInvalidParameter, "Instance is not in the vpc", nil)
+		return
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		logger.Errorf("Instance %d is not running", instance.ID)
+		err = NewCLError(ErrInstanceInvalidState, "Instance is not running", nil)
+		return
+	}
+	var primaryIface *model.Interface
+	for i, iface := range instance.Interfaces {
+		if iface.PrimaryIf {
+			primaryIface = instance.Interfaces[i]
+			break
+		}
+	}
+	if primaryIface == nil || primaryIface.Address == nil {
+		err = NewCLError(ErrInstanceNoPrimaryInterface, fmt.Sprintf("No primary interface for the instance, %d", instance.ID), nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.checkPortmapQuota(ctx, router.Owner)
+	if err != nil {
+		return
+	}
+	count := 0
+	err = db.Model(&model.Portmap{}).Where("instance_id = ? and local_port = ?", instance.ID, localPort).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to query existing local port", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query existing local port", err)
+		return
+	}
+	if count > 0 {
+		logger.Errorf("Local port %d of instance %d is already forwarded", localPort, instance.ID)
+		err = NewCLError(ErrPortmapPortConflict, fmt.Sprintf("Local port %d of the instance is already forwarded", localPort), nil)
+		return
+	}
+	remotePort, err = a.allocateRemotePort(ctx, remotePort)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%d-%d", instance.Hostname, localPort, remotePort)
+	}
+	portmap = &model.Portmap{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, Status: "pending", RouterID: router.ID, InstanceID: instance.ID, LocalAddress: primaryIface.Address.Address, LocalPort: localPort, RemotePort: remotePort}
+	err = db.Create(portmap).Error
+	if err != nil {
+		logger.Error("DB failed to create port forwarding", err)
+		err = NewCLError(ErrPortmapCreateFailed, "Failed to create port forwarding", err)
+		return
+	}
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_portmap.sh '%d' '%s' '%d' '%d'", router.ID, portmap.LocalAddress, portmap.LocalPort, portmap.RemotePort)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Create port forwarding failed", err)
+		return
+	}
+	portmap.Router = router
+	portmap.Instance = instance
+	return
+}
+
+func (a *PortmapAdmin) Get(ctx context.Context, id int64) (portmap *model.Portmap, err error) {
+	if id <= 0 {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid port forwarding ID: %d", id), nil)
+		logger.Error(err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	portmap = &model.Portmap{Model: model.Model{ID: id}}
+	err = db.Preload("Router").Preload("Instance").Where(where).Take(portmap).Error
+	if err != nil {
+		logger.Error("Failed to query port forwarding", err)
+		err = NewCLError(ErrPortmapNotFound, "Failed to find port forwarding", err)
+		return
+	}
+	return
+}
+
+func (a *PortmapAdmin) GetPortmapByUUID(ctx context.Context, uuID string) (portmap *model.Portmap, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	portmap = &model.Portmap{}
+	err = db.Preload("Router").Preload("Instance").Where(where).Where("uuid = ?", uuID).Take(portmap).Error
+	if err != nil {
+		logger.Error("Failed to query port forwarding, %v", err)
+		err = NewCLError(ErrPortmapNotFound, "Failed to find port forwarding", err)
+		return
+	}
+	permit := memberShip.For("portmap:get").ValidateOwner(model.Reader, portmap.Owner)
+	if !permit {
+		logger.Error("Not authorized to read the port forwarding")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the port forwarding", nil)
+		return
+	}
+	return
+}
+
+func (a *PortmapAdmin) Update(ctx context.Context, portmap *model.Portmap, name string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("portmap:update").ValidateOwner(model.Writer, portmap.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the port forwarding")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the port forwarding", nil)
+		return
+	}
+	if name != "" && portmap.Name != name {
+		portmap.Name = name
+		if err = db.Model(portmap).Update("name", portmap.Name).Error; err != nil {
+			logger.Error("Failed to save port forwarding", err)
+			err = NewCLError(ErrPortmapUpdateFailed, "Failed to update port forwarding", err)
+			return
+		}
+	}
+	return
+}
+
+func (a *PortmapAdmin) Delete(ctx context.Context, portmap *model.Portmap) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("portmap:delete").ValidateOwner(model.Writer, portmap.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the port forwarding")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the port forwarding", nil)
+		return
+	}
+	instance := portmap.Instance
+	if instance == nil && portmap.InstanceID > 0 {
+		instance = &model.Instance{Model: model.Model{ID: portmap.InstanceID}}
+		if err = db.Unscoped().Take(instance).Error; err != nil {
+			logger.Error("Failed to query instance of port forwarding", err)
+			instance = nil
+			err = nil
+		}
+	}
+	control := "toall="
+	if instance != nil && instance.Hyper >= 0 {
+		control = fmt.Sprintf("inter=%d", instance.Hyper)
+	}
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/clear_portmap.sh '%d' '%s' '%d' '%d'", portmap.RouterID, portmap.LocalAddress, portmap.LocalPort, portmap.RemotePort)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Clear port forwarding failed", err)
+		return
+	}
+	if err = db.Delete(portmap).Error; err != nil {
+		logger.Error("DB failed to delete port forwarding", err)
+		err = NewCLError(ErrPortmapDeleteFailed, "Failed to delete port forwarding", err)
+		return
+	}
+	return
+}
+
+// DeleteByInstance removes all port forwardings pointing to the instance
+func (a *PortmapAdmin) DeleteByInstance(ctx context.Context, instance *model.Instance) (err error) {
+	ctx, db := GetContextDB(ctx)
+	portmaps := []*model.Portmap{}
+	if err = db.Where("instance_id = ?", instance.ID).Find(&portmaps).Error; err != nil {
+		logger.Error("Failed to query port forwardings of instance", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query port forwardings of instance", err)
+		return
+	}
+	for _, portmap := range portmaps {
+		portmap.Instance = instance
+		err = a.Delete(ctx, portmap)
+		if err != nil {
+			logger.Error("Failed to delete port forwarding", err)
+			return
+		}
+	}
+	return
+}
+
+func (a *PortmapAdmin) List(ctx context.Context, offset, limit int64, order string, router *model.Router) (total int64, portmaps []*model.Portmap, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := memberShip.GetWhere()
+	portmaps = []*model.Portmap{}
+	if err = ListFilter(ctx, db.Model(&model.Portmap{})).Where(where).Where("router_id = ?", router.ID).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count port forwardings, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count port forwardings", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.Portmap{}, offset, limit, order).Preload("Instance").Where(where).Where("router_id = ?", router.ID).Find(&portmaps).Error; err != nil {
+		logger.Error("DB failed to query port forwardings, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query port forwardings", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,499 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var (
+	routeTableAdmin = &RouteTableAdmin{}
+)
+
+type RouteTableAdmin struct{}
+
+// RouteSpec is a route entry requested by the caller, NexthopID refers to an instance,
+// an interface or a router depending on NexthopType, Nexthop is only used for ip type
+type RouteSpec struct {
+	Destination string
+	NexthopType string
+	NexthopID   int64
+	Nexthop     string
+}
+
+type RouteTableRoute struct {
+	Destination string `json:"destination"`
+	Nexthop     string `json:"nexthop"`
+	VPC         int64  `json:"vpc"`
+}
+
+type RouteTableData struct {
+	Subnets []string           `json:"subnets"`
+	Routes  []*RouteTableRoute `json:"routes"`
+}
+
+func subnetCIDR(subnet *model.Subnet) (ipNet *net.IPNet) {
+	_, ipNet, err := net.ParseCIDR(subnet.Network)
+	if err == nil {
+		return
+	}
+	ip := net.ParseIP(subnet.Network)
+	mask := net.ParseIP(subnet.Netmask)
+	if ip == nil || mask == nil {
+		return nil
+	}
+	ipNet = &net.IPNet{IP: ip.Mask(net.IPMask(mask.To4())), Mask: net.IPMask(mask.To4())}
+	return
+}
+
+func routesOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func (a *RouteTableAdmin) getPrimaryAddress(ctx context.Context, instanceID int64) (address string, err error) {
+	ctx, db := GetContextDB(ctx)
+	iface := &model.Interface{}
+	err = db.Preload("Address").Where("instance = ? and primary_if = ?", instanceID, true).Take(iface).Error
+	if err != nil || iface.Address == nil {
+		logger.Error("Failed to query primary interface of instance", err)
+		err = NewCLError(ErrInstanceNoPrimaryInterface, "No primary interface for the instance", err)
+		return
+	}
+	address = iface.Address.Address
+	return
+}
+
+// buildEntries validates the requested routes and resolves their next hops,
+// the destinations of one route table must not overlap with each other
+func (a *RouteTableAdmin) buildEntries(ctx context.Context, router *model.Router, owner int64, specs []*RouteSpec) (entries []*model.RouteEntry, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	subnets := []*model.Subnet{}
+	err = db.Where("router_id = ? and type = ?", router.ID, "internal").Find(&subnets).Error
+	if err != nil {
+		logger.Error("Failed to query subnets of router", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query subnets of the vpc", err)
+		return
+	}
+	destNets := []*net.IPNet{}
+	entries = []*model.RouteEntry{}
+	for _, spec := range specs {
+		var destNet *net.IPNet
+		_, destNet, err = net.ParseCIDR(spec.Destination)
+		if err != nil || destNet.IP.To4() == nil {
+			logger.Errorf("Invalid route destination %s, %v", spec.Destination, err)
+			err = NewCLError(ErrInvalidCIDR, fmt.Sprintf("Invalid route destination %s", spec.Destination), err)
+			return
+		}
+		for _, dn := range destNets {
+			if routesOverlap(dn, destNet) {
+				logger.Errorf("Route destination %s overlaps with %s", destNet, dn)
+				err = NewCLError(ErrRouteOverlap, fmt.Sprintf("Route destination %s overlaps with %s", destNet, dn), nil)
+				return
+			}
+		}
+		destNets = append(destNets, destNet)
+		entry := &model.RouteEntry{Model: model.Model{Creater: memberShip.UserID}, Owner: owner, Destination: destNet.String(), NexthopType: spec.NexthopType, NexthopID: spec.NexthopID}
+		switch spec.NexthopType {
+		case model.RouteNexthopIP:
+			nexthop := net.ParseIP(spec.Nexthop)
+			inVPC := false
+			for _, subnet := range subnets {
+				ipNet := subnetCIDR(subnet)
+				if nexthop != nil && ipNet != nil && ipNet.Contains(nexthop) {
+					inVPC = true
+					break
+				}
+			}
+			if !inVPC {
+				logger.Errorf("Next hop %s is not in vpc %d", spec.Nexthop, router.ID)
+				err = NewCLError(ErrInvalidRouteNexthop, fmt.Sprintf("Next hop %s is not in any subnet of the vpc", spec.Nexthop), nil)
+				return
+			}
+			entry.Nexthop = nexthop.String()
+		case model.RouteNexthopInstance:
+			instance := &model.Instance{Model: model.Model{ID: spec.NexthopID}}
+			err = db.Take(instance).Error
+			if err != nil || instance.RouterID != router.ID {
+				logger.Errorf("Next hop instance %d is not in vpc %d, %v", spec.NexthopID, router.ID, err)
+				err = NewCLError(ErrInvalidRouteNexthop, "Next hop instance is not in the vpc", err)
+				return
+			}
+			entry.Nexthop, err = a.getPrimaryAddress(ctx, instance.ID)
+			if err != nil {
+				return
+			}
+		case model.RouteNexthopInterface:
+			iface := &model.Interface{Model: model.Model{ID: spec.NexthopID}}
+			err = db.Preload("Address").Take(iface).Error
+			if err != nil || iface.RouterID != router.ID || iface.Address == nil {
+				logger.Errorf("Next hop interface %d is not in vpc %d, %v", spec.NexthopID, router.ID, err)
+				err = NewCLError(ErrInvalidRouteNexthop, "Next hop interface is not in the vpc", err)
+				return
+			}
+			entry.Nexthop = iface.Address.Address
+		case model.RouteNexthopVPC:
+			if spec.NexthopID == router.ID {
+				err = NewCLError(ErrInvalidRouteNexthop, "Next hop vpc can not be the vpc itself", nil)
+				return
+			}
+			peer := &model.Router{Model: model.Model{ID: spec.NexthopID}}
+			err = db.Take(peer).Error
+			if err != nil {
+				logger.Errorf("Next hop vpc %d not found, %v", spec.NexthopID, err)
+				err = NewCLError(ErrInvalidRouteNexthop, "Next hop vpc not found", err)
+				return
+			}
+			if !memberShip.For("vpc:update").ValidateOwner(model.Writer, peer.Owner) {
+				logger.Errorf("Not authorized to route to vpc %d", peer.ID)
+				err = NewCLError(ErrPermissionDenied, "Not authorized to route to the next hop vpc", nil)
+				return
+			}
+		default:
+			err = NewCLError(ErrInvalidRouteNexthop, fmt.Sprintf("Invalid next hop type %s", spec.NexthopType), nil)
+			return
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+func (a *RouteTableAdmin) checkSubnets(ctx context.Context, routeTable *model.RouteTable, subnets []*model.Subnet) (err error) {
+	for _, subnet := range subnets {
+		if subnet.RouterID != routeTable.RouterID || subnet.Type != "internal" {
+			logger.Errorf("Subnet %d can not be associated with route table of router %d", subnet.ID, routeTable.RouterID)
+			err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Subnet %s is not an internal subnet of the vpc", subnet.Name), nil)
+			return
+		}
+		if subnet.RouteTableID > 0 && subnet.RouteTableID != routeTable.ID {
+			logger.Errorf("Subnet %d is already associated with route table %d", subnet.ID, subnet.RouteTableID)
+			err = NewCLError(ErrRouteTableInUse, fmt.Sprintf("Subnet %s is already associated with another route table", subnet.Name), nil)
+			return
+		}
+	}
+	return
+}
+
+func (a *RouteTableAdmin) setSubnets(ctx context.Context, routeTable *model.RouteTable, subnets []*model.Subnet) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = a.checkSubnets(ctx, routeTable, subnets)
+	if err != nil {
+		return
+	}
+	err = db.Model(&model.Subnet{}).Where("route_table_id = ?", routeTable.ID).Update("route_table_id", 0).Error
+	if err != nil {
+		logger.Error("Failed to clear subnet associations", err)
+		err = NewCLError(ErrRouteTableUpdateFailed, "Failed to clear subnet associations", err)
+		return
+	}
+	for _, subnet := range subnets {
+		subnet.RouteTableID = routeTable.ID
+		err = db.Model(subnet).Update("route_table_id", routeTable.ID).Error
+		if err != nil {
+			logger.Error("Failed to associate subnet with route table", err)
+			err = NewCLError(ErrRouteTableUpdateFailed, "Failed to associate subnet with route table", err)
+			return
+		}
+	}
+	routeTable.Subnets = subnets
+	return
+}
+
+func (a *RouteTableAdmin) setEntries(ctx context.Context, routeTable *model.RouteTable, entries []*model.RouteEntry) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Where("route_table_id = ?", routeTable.ID).Delete(&model.RouteEntry{}).Error
+	if err != nil {
+		logger.Error("Failed to delete route entries", err)
+		err = NewCLError(ErrRouteTableUpdateFailed, "Failed to delete route entries", err)
+		return
+	}
+	for _, entry := range entries {
+		entry.RouteTableID = routeTable.ID
+		err = db.Create(entry).Error
+		if err != nil {
+			logger.Error("Failed to create route entry", err)
+			err = NewCLError(ErrRouteTableUpdateFailed, "Failed to create route entry", err)
+			return
+		}
+	}
+	routeTable.Entries = entries
+	return
+}
+
+func (a *RouteTableAdmin) getRouteTableData(routeTable *model.RouteTable) (data []byte, err error) {
+	rtData := &RouteTableData{Subnets: []string{}, Routes: []*RouteTableRoute{}}
+	for _, subnet := range routeTable.Subnets {
+		ipNet := subnetCIDR(subnet)
+		if ipNet != nil {
+			rtData.Subnets = append(rtData.Subnets, ipNet.String())
+		}
+	}
+	for _, entry := range routeTable.Entries {
+		route := &RouteTableRoute{Destination: entry.Destination, Nexthop: entry.Nexthop}
+		if entry.NexthopType == model.RouteNexthopVPC {
+			route.VPC = entry.NexthopID
+		}
+		rtData.Routes = append(rtData.Routes, route)
+	}
+	data, err = json.Marshal(rtData)
+	if err != nil {
+		logger.Error("Failed to marshal route table data", err)
+		return
+	}
+	return
+}
+
+// Apply pushes the route table to the router namespaces, hyper < 0 means all hypervisors
+func (a *RouteTableAdmin) Apply(ctx context.Context, routeTable *model.RouteTable, hyper int32) (err error) {
+	data, err := a.getRouteTableData(routeTable)
+	if err != nil {
+		return
+	}
+	control := "toall="
+	if hyper >= 0 {
+		control = fmt.Sprintf("inter=%d", hyper)
+	}
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/set_route_table.sh '%d' '%d'<<EOF\n%s\nEOF", routeTable.RouterID, routeTable.ID, data)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Set route table failed", err)
+		return
+	}
+	return
+}
+
+// SyncRouter re-applies all route tables of a router to one hypervisor
+func (a *RouteTableAdmin) SyncRouter(ctx context.Context, routerID int64, hyper int32) (err error) {
+	ctx, db := GetContextDB(ctx)
+	routeTables := []*model.RouteTable{}
+	err = db.Preload("Entries").Preload("Subnets").Where("router_id = ?", routerID).Find(&routeTables).Error
+	if err != nil {
+		logger.Error("Failed to query route tables", err)
+		return
+	}
+	for _, routeTable := range routeTables {
+		err = a.Apply(ctx, routeTable, hyper)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (a *RouteTableAdmin) Create(ctx context.Context, name string, router *model.Router, specs []*RouteSpec, subnets []*model.Subnet) (routeTable *model.RouteTable, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("route_table:create").ValidateOwner(model.Writer, router.Owner)
+	if !permit {
+		logger.Error("Not authorized to create route table")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create route table", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	entries, err := a.buildEntries(ctx, router, router.Owner, specs)
+	if err != nil {
+		return
+	}
+	routeTable = &model.RouteTable{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, RouterID: router.ID}
+	err = a.checkSubnets(ctx, routeTable, subnets)
+	if err != nil {
+		return
+	}
+	err = db.Create(routeTable).Error
+	if err != nil {
+		logger.Error("DB failed to create route table", err)
+		err = NewCLError(ErrRouteTableCreateFailed, "Failed to create route table", err)
+		return
+	}
+	err = a.setEntries(ctx, routeTable, entries)
+	if err != nil {
+		return
+	}
+	err = a.setSubnets(ctx, routeTable, subnets)
+	if err != nil {
+		return
+	}
+	err = a.Apply(ctx, routeTable, -1)
+	if err != nil {
+		return
+	}
+	routeTable.Router = router
+	return
+}
+
+func (a *RouteTableAdmin) Get(ctx context.Context, id int64) (routeTable *model.RouteTable, err error) {
+	if id <= 0 {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid route table ID: %d", id), nil)
+		logger.Error(err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	routeTable = &model.RouteTable{Model: model.Model{ID: id}}
+	err = db.Preload("Router").Preload("Entries").Preload("Subnets").Where(where).Take(routeTable).Error
+	if err != nil {
+		logger.Error("Failed to query route table", err)
+		err = NewCLError(ErrRouteTableNotFound, "Failed to find route table", err)
+		return
+	}
+	return
+}
+
+func (a *RouteTableAdmin) GetRouteTableByUUID(ctx context.Context, uuID string) (routeTable *model.RouteTable, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := memberShip.GetWhere()
+	routeTable = &model.RouteTable{}
+	err = db.Preload("Router").Preload("Entries").Preload("Subnets").Where(where).Where("uuid = ?", uuID).Take(routeTable).Error
+	if err != nil {
+		logger.Error("Failed to query route table, %v", err)
+		err = NewCLError(ErrRouteTableNotFound, "Failed to find route table", err)
+		return
+	}
+	permit := memberShip.For("route_table:get").ValidateOwner(model.Reader, routeTable.Owner)
+	if !permit {
+		logger.Error("Not authorized to read the route table")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the route table", nil)
+		return
+	}
+	return
+}
+
+// Update changes the name, and replaces the routes or the associated subnets if they are not nil
+func (a *RouteTableAdmin) Update(ctx context.Context, routeTable *model.RouteTable, name string, specs []*RouteSpec, subnets []*model.Subnet) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("route_table:update").ValidateOwner(model.Writer, routeTable.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the route table")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the route table", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	if name != "" && routeTable.Name != name {
+		routeTable.Name = name
+		if err = db.Model(routeTable).Update("name", routeTable.Name).Error; err != nil {
+			logger.Error("Failed to save route table", err)
+			err = NewCLError(ErrRouteTableUpdateFailed, "Failed to update route table", err)
+			return
+		}
+	}
+	if specs == nil && subnets == nil {
+		return
+	}
+	router := routeTable.Router
+	if router == nil {
+		router = &model.Router{Model: model.Model{ID: routeTable.RouterID}}
+		if err = db.Take(router).Error; err != nil {
+			logger.Error("Failed to query router of route table", err)
+			err = NewCLError(ErrRouterNotFound, "Failed to find vpc of route table", err)
+			return
+		}
+	}
+	if specs != nil {
+		var entries []*model.RouteEntry
+		entries, err = a.buildEntries(ctx, router, routeTable.Owner, specs)
+		if err != nil {
+			return
+		}
+		err = a.setEntries(ctx, routeTable, entries)
+		if err != nil {
+			return
+		}
+	}
+	if subnets != nil {
+		err = a.setSubnets(ctx, routeTable, subnets)
+		if err != nil {
+			return
+		}
+	}
+	err = a.Apply(ctx, routeTable, -1)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (a *RouteTableAdmin) Delete(ctx context.Context, routeTable *model.RouteTable) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("route_table:delete").ValidateOwner(model.Writer, routeTable.Owner)
+	if !permit {
+		logger.Error("Not authorized to delete the route table")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the route table", nil)
+		return
+	}
+	routeTable.Subnets = nil
+	routeTable.Entries = nil
+	err = a.Apply(ctx, routeTable, -1)
+	if err != nil {
+		return
+	}
+	err = db.Model(&model.Subnet{}).Where("route_table_id = ?", routeTable.ID).Update("route_table_id", 0).Error
+	if err != nil {
+		logger.Error("Failed to clear subnet associations", err)
+		err = NewCLError(ErrRouteTableDeleteFailed, "Failed to clear subnet associations", err)
+		return
+	}
+	if err = db.Where("route_table_id = ?", routeTable.ID).Delete(&model.RouteEntry{}).Error; err != nil {
+		logger.Error("DB failed to delete route entries", err)
+		err = NewCLError(ErrRouteTableDeleteFailed, "Failed to delete route entries", err)
+		return
+	}
+	if err = db.Delete(routeTable).Error; err != nil {
+		logger.Error("DB failed to delete route table", err)
+		err = NewCLError(ErrRouteTableDeleteFailed, "Failed to delete route table", err)
+		return
+	}
+	return
+}
+
+func (a *RouteTableAdmin) List(ctx context.Context, offset, limit int64, order string, router *model.Router) (total int64, routeTables []*model.RouteTable, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := memberShip.GetWhere()
+	routeTables = []*model.RouteTable{}
+	if err = ListFilter(ctx, db.Model(&model.RouteTable{})).Where(where).Where("router_id = ?", router.ID).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count route tables, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count route tables", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.RouteTable{}, offset, limit, order).Preload("Entries").Preload("Subnets").Where(where).Where("router_id = ?", router.ID).Find(&routeTables).Error; err != nil {
+		logger.Error("DB failed to query route tables, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query route tables", err)
+		return
+	}
+	return
+}
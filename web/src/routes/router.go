@@ -14,7 +14,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -73,7 +72,7 @@ func createRouterIface(ctx context.Context, rtype string, router *model.Router,
 
 func (a *RouterAdmin) Create(ctx context.Context, name string) (router *model.Router, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("vpc:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create routers")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create routers", nil)
@@ -121,7 +120,7 @@ func (a *RouterAdmin) Get(ctx context.Context, id int64) (router *model.Router,
 		err = NewCLError(ErrRouterNotFound, "Failed to find router", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, router.Owner)
+	permit := memberShip.For("vpc:get").ValidateOwner(model.Reader, router.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the router")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the router", nil)
@@ -141,7 +140,7 @@ func (a *RouterAdmin) GetRouterByUUID(ctx context.Context, uuID string) (router
 		err = NewCLError(ErrRouterNotFound, "Failed to find router", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, router.Owner)
+	permit := memberShip.For("vpc:get").ValidateOwner(model.Reader, router.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the router")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the router", nil)
@@ -161,7 +160,7 @@ func (a *RouterAdmin) GetRouterByName(ctx context.Context, name string) (router
 		err = NewCLError(ErrRouterNotFound, "Failed to find router", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, router.Owner)
+	permit := memberShip.For("vpc:get").ValidateOwner(model.Reader, router.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the router")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the router", nil)
@@ -213,7 +212,7 @@ func (a *RouterAdmin) Delete(ctx context.Context, router *model.Router) (err err
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, router.Owner)
+	permit := memberShip.For("vpc:delete").ValidateOwner(model.Writer, router.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the router")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the router", nil)
@@ -265,6 +264,44 @@ func (a *RouterAdmin) Delete(ctx context.Context, router *model.Router) (err err
 		err = NewCLError(ErrRouterHasPortmaps, "There are associated load balancers", nil)
 		return
 	}
+	err = db.Model(&model.VpcPeering{}).Where("router_id = ? or peer_router_id = ?", router.ID, router.ID).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count vpc peering")
+		err = NewCLError(ErrDatabaseError, "Failed to count vpc peering of the router", err)
+		return
+	}
+	if count > 0 {
+		logger.Error("There are associated vpc peerings")
+		err = NewCLError(ErrRouterInUse, "There are associated vpc peerings", nil)
+		return
+	}
+	err = db.Model(&model.RouteTable{}).Where("router_id = ?", router.ID).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count route table")
+		err = NewCLError(ErrDatabaseError, "Failed to count route table in the router", err)
+		return
+	}
+	if count > 0 {
+		logger.Error("There are associated route tables")
+		err = NewCLError(ErrRouterInUse, "There are associated route tables", nil)
+		return
+	}
+	err = db.Model(&model.NatGateway{}).Where("router_id = ?", router.ID).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count nat gateway")
+		err = NewCLError(ErrDatabaseError, "Failed to count nat gateway in the router", err)
+		return
+	}
+	if count > 0 {
+		logger.Error("There are associated nat gateways")
+		err = NewCLError(ErrRouterHasNatGateways, "There are associated nat gateways", nil)
+		return
+	}
+	err = dnsAdmin.DeleteRouterZones(ctx, router.ID)
+	if err != nil {
+		logger.Error("Failed to delete dns zones")
+		return
+	}
 	control := "toall="
 	command := fmt.Sprintf("/opt/cloudland/scripts/backend/clear_local_router.sh '%d'", router.ID)
 	err = HyperExecute(ctx, control, command)
@@ -331,13 +368,12 @@ func (a *RouterAdmin) List(ctx context.Context, offset, limit int64, order, quer
 	}
 	where := memberShip.GetWhere()
 	routers = []*model.Router{}
-	if err = db.Model(&model.Router{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Router{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count router, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count router", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Subnets").Where(where).Where(query).Find(&routers).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Router{}, offset, limit, order).Preload("Subnets").Where(where).Where(query).Find(&routers).Error; err != nil {
 		logger.Error("DB failed to query routers, %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query routers", err)
 		return
@@ -429,7 +465,7 @@ func (v *RouterView) Delete(c *macaron.Context, store session.Store) (err error)
 
 func (v *RouterView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("vpc:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -450,7 +486,7 @@ func (v *RouterView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "routers", int64(routerID))
+	permit, err := memberShip.For("vpc:update").CheckOwner(model.Writer, "routers", int64(routerID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -477,7 +513,7 @@ func (v *RouterView) Patch(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "routers", int64(routerID))
+	permit, err := memberShip.For("vpc:update").CheckOwner(model.Writer, "routers", int64(routerID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -101,6 +101,10 @@ func New() (m *macaron.Macaron) {
 	m.Get("/dashboard/getdata", dashboard.GetData)
 	m.Get("/login", userView.LoginGet)
 	m.Post("/login", userView.LoginPost)
+	m.Post("/login/mfa", userView.LoginMfaPost)
+	m.Post("/login/password", userView.LoginPasswordPost)
+	m.Get("/login/oidc", userView.OidcLogin)
+	m.Get("/login/oidc/callback", userView.OidcCallback)
 	m.Get("/zones", zoneView.List)
 	m.Get("/zones/new", zoneView.New)
 	m.Post("/zones/new", zoneView.Create)
@@ -111,10 +115,17 @@ func New() (m *macaron.Macaron) {
 	m.Get("/hypers/edit", hyperView.Edit)
 	m.Post("/hypers/edit", hyperView.Patch)
 	m.Get("/hypers/set_status", hyperView.SetHyperStatus)
+	m.Get("/mfa", mfaView.Show)
+	m.Post("/mfa/enroll", mfaView.Enroll)
+	m.Post("/mfa/activate", mfaView.Activate)
+	m.Post("/mfa/disable", mfaView.Disable)
+	m.Post("/mfa/recovery_codes", mfaView.RecoveryCodes)
+	m.Post("/mfa/verify", mfaView.Verify)
 	m.Get("/users", userView.List)
 	m.Get("/users/:id", userView.Edit)
 	m.Post("/users/:id", userView.Patch)
 	m.Get("/users/:id/chorg", userView.Change)
+	m.Post("/users/:id/unlock", userView.Unlock)
 	m.Delete("/users/:id", userView.Delete)
 	m.Get("/users/new", userView.New)
 	m.Post("/users/new", userView.Create)
@@ -144,6 +155,7 @@ func New() (m *macaron.Macaron) {
 	m.Get("/instances/:id/end_rescue", instanceView.EndRescue)
 	m.Post("/instances/:id/end_rescue", instanceView.EndRescue)
 	m.Post("/instances/:id/console", consoleView.ConsoleURL)
+	m.Get("/serial_console", consoleView.SerialConsole)
 	m.Get("/instances/:id/interfaces/new", interfaceView.New)
 	m.Post("/instances/:id/interfaces/new", interfaceView.Create)
 	m.Get("/instances/:instid/interfaces", interfaceView.List)
@@ -300,6 +312,7 @@ func LinkHandler(c *macaron.Context, store session.Store) {
 		strings.TrimSuffix(c.Req.URL.Path, "/"))
 	logger.Debugf("LinkHandler: %s\n", link)
 	c.Data["Link"] = link
+	// pages without a session go to login, except the serial console which authenticates with its token
 	if login, ok := store.Get("login").(string); ok {
 		// logger.Debug("$$$$$$$$$$$$$$$$$$", c.Locale.Language())
 		memberShip := &MemberShip{
@@ -309,6 +322,12 @@ func LinkHandler(c *macaron.Context, store session.Store) {
 			OrgName:  store.Get("org").(string),
 			Role:     store.Get("role").(model.Role),
 		}
+		if err := memberShip.LoadActions(); err != nil {
+			logger.Error("Failed to load actions of custom roles", err)
+		}
+		if mfaAt, ok := store.Get("mfa_at").(int64); ok {
+			memberShip.MfaAt = mfaAt
+		}
 		c.Req.Request = c.Req.WithContext(memberShip.SetContext(c.Req.Context()))
 		c.Data["IsSignedIn"] = true
 		if memberShip.Role == model.Admin || login == "admin" {
@@ -317,7 +336,7 @@ func LinkHandler(c *macaron.Context, store session.Store) {
 		}
 		c.Data["Organization"] = store.Get("org").(string)
 		c.Data["Members"] = store.Get("members").([]*model.Member)
-	} else if link != "" && link != "/" && !strings.HasPrefix(link, "/login") {
+	} else if link != "" && link != "/" && !strings.HasPrefix(link, "/login") && link != "/serial_console" {
 		UrlBefore = link
 		c.Redirect("/login?redirect_to=")
 	}
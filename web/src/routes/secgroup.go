@@ -141,7 +141,7 @@ func (a *SecgroupAdmin) Get(ctx context.Context, id int64) (secgroup *model.Secu
 		}
 	}
 	if secgroup.Name != "system-default" {
-		permit := memberShip.ValidateOwner(model.Reader, secgroup.Owner)
+		permit := memberShip.For("security_group:get").ValidateOwner(model.Reader, secgroup.Owner)
 		if !permit {
 			logger.Error("Not authorized to get security group")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to get security group", nil)
@@ -172,7 +172,7 @@ func (a *SecgroupAdmin) GetSecgroupByUUID(ctx context.Context, uuID string) (sec
 		}
 	}
 	if secgroup.Name != "system-default" {
-		permit := memberShip.ValidateOwner(model.Reader, secgroup.Owner)
+		permit := memberShip.For("security_group:get").ValidateOwner(model.Reader, secgroup.Owner)
 		if !permit {
 			logger.Error("Not authorized to get security group")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to get security group", nil)
@@ -237,7 +237,7 @@ func (a *SecgroupAdmin) GetSecgroupByName(ctx context.Context, name string) (sec
 		}
 	}
 	if secgroup.Name != "system-default" {
-		permit := memberShip.ValidateOwner(model.Reader, secgroup.Owner)
+		permit := memberShip.For("security_group:get").ValidateOwner(model.Reader, secgroup.Owner)
 		if !permit {
 			logger.Error("Not authorized to get security group")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to get security group", nil)
@@ -340,7 +340,7 @@ func (a *SecgroupAdmin) Create(ctx context.Context, name string, isDefault bool,
 	owner := memberShip.OrgID
 	var routerID int64
 	if router != nil {
-		permit := memberShip.ValidateOwner(model.Writer, router.Owner)
+		permit := memberShip.For("security_group:create").ValidateOwner(model.Writer, router.Owner)
 		if !permit {
 			logger.Error("Not authorized for this operation")
 			err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -348,7 +348,7 @@ func (a *SecgroupAdmin) Create(ctx context.Context, name string, isDefault bool,
 		}
 		routerID = router.ID
 	} else {
-		permit := memberShip.CheckPermission(model.Owner)
+		permit := memberShip.For("security_group:create").CheckPermission(model.Owner)
 		if !permit {
 			logger.Error("Not authorized for this operation")
 			err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -442,7 +442,7 @@ func (a *SecgroupAdmin) Delete(ctx context.Context, secgroup *model.SecurityGrou
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, secgroup.Owner)
+	permit := memberShip.For("security_group:delete").ValidateOwner(model.Writer, secgroup.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the security group")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the security group", nil)
@@ -488,6 +488,9 @@ func (a *SecgroupAdmin) Delete(ctx context.Context, secgroup *model.SecurityGrou
 		err = NewCLError(ErrSecurityGroupDeleteFailed, "Failed to delete security group", err)
 		return
 	}
+	if err = tagAdmin.replace(db, "security_groups", secgroup.ID, secgroup.Owner, nil); err != nil {
+		return
+	}
 	secgroup.Name = fmt.Sprintf("%s-%d", secgroup.Name, secgroup.CreatedAt.Unix())
 	err = db.Model(&model.SecurityGroup{}).Unscoped().Where("id = ?", secgroup.ID).Update("name", secgroup.Name).Error
 	if err != nil {
@@ -500,7 +503,7 @@ func (a *SecgroupAdmin) Delete(ctx context.Context, secgroup *model.SecurityGrou
 
 func (a *SecgroupAdmin) List(ctx context.Context, offset, limit int64, order, query string) (total int64, secgroups []*model.SecurityGroup, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("security_group:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -518,13 +521,12 @@ func (a *SecgroupAdmin) List(ctx context.Context, offset, limit int64, order, qu
 
 	where := memberShip.GetWhere()
 	secgroups = []*model.SecurityGroup{}
-	if err = db.Model(&model.SecurityGroup{}).Where(where).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.SecurityGroup{})).Where(where).Where(query).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count security group(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count security group(s)", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(where).Where(query).Find(&secgroups).Error; err != nil {
+	if err = ListPage(ctx, db, &model.SecurityGroup{}, offset, limit, order).Where(where).Where(query).Find(&secgroups).Error; err != nil {
 		logger.Error("DB failed to query security group(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query security group(s)", err)
 		return
@@ -559,7 +561,7 @@ func (a *SecgroupAdmin) List(ctx context.Context, offset, limit int64, order, qu
 // List4View lists security groups with structured search params for Web Console.
 func (a *SecgroupAdmin) List4View(ctx context.Context, offset, limit int64, order string, params *SecgroupSearchParams) (total int64, secgroups []*model.SecurityGroup, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("security_group:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -667,7 +669,7 @@ func (v *SecgroupView) Delete(c *macaron.Context, store session.Store) (err erro
 }
 func (v *SecgroupView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("security_group:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -694,7 +696,7 @@ func (v *SecgroupView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "security_groups", int64(sgID))
+	permit, err := memberShip.For("security_group:update").CheckOwner(model.Writer, "security_groups", int64(sgID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -724,7 +726,7 @@ func (v *SecgroupView) Patch(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "security_groups", int64(sgID))
+	permit, err := memberShip.For("security_group:update").CheckOwner(model.Writer, "security_groups", int64(sgID))
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -14,7 +14,6 @@ import (
 	"strings"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -135,7 +134,7 @@ func (a *SecruleAdmin) Update(ctx context.Context, id int64, name, remoteIp, dir
 
 func (a *SecruleAdmin) Create(ctx context.Context, name, remoteIp, direction, protocol string, portMin, portMax int32, secgroup *model.SecurityGroup) (secrule *model.SecurityRule, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, secgroup.Owner)
+	permit := memberShip.For("security_rule:create").ValidateOwner(model.Writer, secgroup.Owner)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -184,7 +183,7 @@ func (a *SecruleAdmin) Create(ctx context.Context, name, remoteIp, direction, pr
 
 func (a *SecruleAdmin) GetRule(ctx context.Context, remoteIp, direction, protocol string, portMin, portMax int32, secgroup *model.SecurityGroup) (secrule *model.SecurityRule, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Reader, secgroup.Owner)
+	permit := memberShip.For("security_rule:get").ValidateOwner(model.Reader, secgroup.Owner)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -222,7 +221,7 @@ func (a *SecruleAdmin) Delete(ctx context.Context, secrule *model.SecurityRule,
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, secrule.Owner)
+	permit := memberShip.For("security_rule:delete").ValidateOwner(model.Writer, secrule.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the router")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -243,7 +242,7 @@ func (a *SecruleAdmin) Delete(ctx context.Context, secrule *model.SecurityRule,
 
 func (a *SecruleAdmin) List(ctx context.Context, offset, limit int64, order string, secgroup *model.SecurityGroup) (total int64, secrules []*model.SecurityRule, err error) {
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Reader, secgroup.Owner)
+	permit := memberShip.For("security_rule:list").ValidateOwner(model.Reader, secgroup.Owner)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -264,13 +263,12 @@ func (a *SecruleAdmin) List(ctx context.Context, offset, limit int64, order stri
 		where = fmt.Sprintf("%s and %s", where, wm)
 	}
 	secrules = []*model.SecurityRule{}
-	if err = db.Model(&model.SecurityRule{}).Where(where).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.SecurityRule{})).Where(where).Count(&total).Error; err != nil {
 		logger.Error("DB failed to count security rule(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count security rule(s)", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(where).Find(&secrules).Error; err != nil {
+	if err = ListPage(ctx, db, &model.SecurityRule{}, offset, limit, order).Where(where).Find(&secrules).Error; err != nil {
 		logger.Error("DB failed to query security rule(s), %v", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query security rule(s)", err)
 		return
@@ -384,7 +382,7 @@ func (v *SecruleView) Delete(c *macaron.Context, store session.Store) (err error
 
 func (v *SecruleView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("security_rule:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -451,7 +449,7 @@ func (a *SecruleAdmin) Get(ctx context.Context, id int64, secgroup *model.Securi
 		logger.Error("Failed to query secrule", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, secrule.Owner)
+	permit := memberShip.For("security_rule:get").ValidateOwner(model.Reader, secrule.Owner)
 	if !permit {
 		logger.Error("Not authorized to get security group")
 		err = fmt.Errorf("Not authorized")
@@ -470,7 +468,7 @@ func (a *SecruleAdmin) GetSecruleByUUID(ctx context.Context, uuID string, secgro
 		logger.Error("Failed to query secrule", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, secrule.Owner)
+	permit := memberShip.For("security_rule:get").ValidateOwner(model.Reader, secrule.Owner)
 	if !permit {
 		logger.Error("Not authorized to get security group")
 		err = fmt.Errorf("Not authorized")
@@ -0,0 +1,193 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/websocket"
+	"gopkg.in/macaron.v1"
+)
+
+const (
+	defaultSerialTokenExpire    = 300
+	defaultSerialSessionTimeout = 3600
+	serialConsoleWait           = 10
+)
+
+// serialTokenExpire bounds how long a serial console token waits for its connection
+func serialTokenExpire() time.Duration {
+	seconds := viper.GetInt("console.serial_token_expire")
+	if seconds <= 0 {
+		seconds = defaultSerialTokenExpire
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// serialSessionTimeout bounds how long a serial console session lasts
+func serialSessionTimeout() time.Duration {
+	seconds := viper.GetInt("console.serial_session_timeout")
+	if seconds <= 0 {
+		seconds = defaultSerialSessionTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SerialConsole upgrades to a WebSocket and proxies it to the serial console the hypervisor exposes
+// for the instance. The token is used up by the connection and every session is recorded with
+// the user, the remote address, its duration and the bytes passed each way
+func (a *ConsoleView) SerialConsole(c *macaron.Context) {
+	ctx := c.Req.Context()
+	console, memberShip, err := ResolveToken(ctx, c.Query("token"), model.ConsoleTypeSerial)
+	if err != nil {
+		logger.Error("Unable to resolve token", err)
+		code := http.StatusUnauthorized
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	db := DB()
+	instance := &model.Instance{Model: model.Model{ID: console.Instance}}
+	err = db.Take(instance).Error
+	if err != nil {
+		logger.Error("Failed to get instance", err)
+		code := http.StatusBadRequest
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	// the custom roles of the member grant the action as they did for the token
+	if err = memberShip.LoadActions(); err != nil {
+		logger.Error("Failed to load actions of custom roles", err)
+	}
+	if !memberShip.For("instance:serial_console").ValidateOwner(model.Writer, instance.Owner) {
+		logger.Error("Not authorized for the serial console")
+		code := http.StatusForbidden
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	// only one connection gets to delete the record of the token
+	result := db.Where("id = ? and hash_secret = ?", console.ID, console.HashSecret).Delete(&model.Console{})
+	if result.Error != nil {
+		logger.Error("Failed to use up the serial console token", result.Error)
+		code := http.StatusInternalServerError
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	if result.RowsAffected != 1 {
+		logger.Errorf("Serial console token of instance %d is already used", console.Instance)
+		code := http.StatusUnauthorized
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	session := &model.ConsoleSession{
+		Owner:      instance.Owner,
+		InstanceID: instance.ID,
+		UserID:     memberShip.UserID,
+		Type:       model.ConsoleTypeSerial,
+		RemoteAddr: c.RemoteAddr(),
+	}
+	err = db.Create(session).Error
+	if err != nil {
+		logger.Error("Failed to create console session", err)
+		code := http.StatusInternalServerError
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	// the hypervisor only attaches a connection that starts with the secret of the session
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		logger.Error("Failed to generate console session secret", err)
+		code := http.StatusInternalServerError
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	sessionSecret := hex.EncodeToString(secret)
+	timeout := serialSessionTimeout()
+	control := fmt.Sprintf("inter=%d", instance.Hyper)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/serial_console.sh '%d' '%d' '%d' <<EOF\n%s\nEOF", instance.ID, session.ID, int(timeout.Seconds()), sessionSecret)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Serial console execution failed", err)
+		code := http.StatusInternalServerError
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	for i := 0; i < serialConsoleWait && session.LocalPort == 0 && session.EndedAt == nil; i++ {
+		time.Sleep(time.Second)
+		err = db.Take(session).Error
+		if err != nil {
+			logger.Error("Failed to query console session", err)
+		}
+	}
+	if session.LocalPort == 0 {
+		logger.Errorf("Hypervisor %d did not expose the serial console of instance %d", instance.Hyper, instance.ID)
+		code := http.StatusServiceUnavailable
+		c.Error(code, http.StatusText(code))
+		return
+	}
+	logger.Infof("User %d opens serial console session %d of instance %d from %s", session.UserID, session.ID, instance.ID, session.RemoteAddr)
+	server := websocket.Server{
+		// the single use token authenticates the connection, so any origin and scripts without one are fine
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   func(ws *websocket.Conn) { proxySerialConsole(ws, session, sessionSecret, timeout) },
+	}
+	server.ServeHTTP(c.Resp, c.Req.Request)
+}
+
+func proxySerialConsole(ws *websocket.Conn, session *model.ConsoleSession, secret string, timeout time.Duration) {
+	defer ws.Close()
+	ws.PayloadType = websocket.BinaryFrame
+	address := net.JoinHostPort(session.LocalAddress, strconv.Itoa(int(session.LocalPort)))
+	conn, err := net.DialTimeout("tcp", address, serialConsoleWait*time.Second)
+	if err == nil {
+		if _, err = io.WriteString(conn, secret+"\n"); err != nil {
+			conn.Close()
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to connect serial console session %d to %s, %v", session.ID, address, err)
+	} else {
+		deadline := time.Now().Add(timeout)
+		conn.SetDeadline(deadline)
+		ws.SetDeadline(deadline)
+		var bytesIn, bytesOut int64
+		done := make(chan bool, 2)
+		go func() {
+			bytesIn, _ = io.Copy(conn, ws)
+			conn.Close()
+			done <- true
+		}()
+		go func() {
+			bytesOut, _ = io.Copy(ws, conn)
+			ws.Close()
+			done <- true
+		}()
+		<-done
+		<-done
+		session.BytesIn, session.BytesOut = bytesIn, bytesOut
+	}
+	now := time.Now()
+	err = DB().Model(session).Updates(map[string]interface{}{
+		"bytes_in":  session.BytesIn,
+		"bytes_out": session.BytesOut,
+		"ended_at":  &now,
+	}).Error
+	if err != nil {
+		logger.Error("Failed to update console session", err)
+	}
+	logger.Infof("Serial console session %d of instance %d ends, %d bytes in, %d bytes out", session.ID, session.InstanceID, session.BytesIn, session.BytesOut)
+}
@@ -20,7 +20,6 @@ import (
 	"time"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/apparentlymart/go-cidr/cidr"
@@ -142,7 +141,7 @@ func (a *SubnetAdmin) Get(ctx context.Context, id int64) (subnet *model.Subnet,
 		}
 	}
 	if subnet.Type == "internal" {
-		permit := memberShip.ValidateOwner(model.Reader, subnet.Owner)
+		permit := memberShip.For("subnet:get").ValidateOwner(model.Reader, subnet.Owner)
 		if !permit {
 			logger.Error("Not authorized to read the subnet")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to read the subnet", nil)
@@ -172,7 +171,7 @@ func (a *SubnetAdmin) GetSubnetByUUID(ctx context.Context, uuID string) (subnet
 		}
 	}
 	if subnet.Type == "internal" {
-		permit := memberShip.ValidateOwner(model.Reader, subnet.Owner)
+		permit := memberShip.For("subnet:get").ValidateOwner(model.Reader, subnet.Owner)
 		if !permit {
 			logger.Error("Not authorized to read the subnet")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to read the subnet", nil)
@@ -202,7 +201,7 @@ func (a *SubnetAdmin) GetSubnetByName(ctx context.Context, name string) (subnet
 		}
 	}
 	if subnet.Type == "internal" {
-		permit := memberShip.ValidateOwner(model.Reader, subnet.Owner)
+		permit := memberShip.For("subnet:get").ValidateOwner(model.Reader, subnet.Owner)
 		if !permit {
 			logger.Error("Not authorized to read the subnet")
 			err = NewCLError(ErrPermissionDenied, "Not authorized to read the subnet", nil)
@@ -329,7 +328,7 @@ func (a *SubnetAdmin) Create(ctx context.Context, vlan int, name, network, gatew
 			EndTransaction(ctx, err)
 		}
 	}()
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("subnet:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		err = NewCLError(ErrPermissionDenied, "Not authorized for this operation", nil)
@@ -377,6 +376,13 @@ func (a *SubnetAdmin) Create(ctx context.Context, vlan int, name, network, gatew
 		err = NewCLError(ErrInvalidCIDR, "Invalid CIDR", err)
 		return
 	}
+	if routerID > 0 {
+		err = vpcPeeringAdmin.CheckSubnet(ctx, routerID, network)
+		if err != nil {
+			logger.Error("Subnet overlaps with peer vpc", err)
+			return
+		}
+	}
 	addrCount := cidr.AddressCount(ipNet)
 	if addrCount < 5 || addrCount > 1000 {
 		err = NewCLError(ErrCIDRTooBig, "Network/mask must have more than 5 but less than 1000 addresses", nil)
@@ -471,6 +477,13 @@ func (a *SubnetAdmin) Create(ctx context.Context, vlan int, name, network, gatew
 			logger.Error("Failed to set routing for subnet")
 			return
 		}
+		if subnet.Type == string(Internal) {
+			err = natGatewayAdmin.AttachSubnet(ctx, subnet)
+			if err != nil {
+				logger.Error("Failed to attach subnet to nat gateway")
+				return
+			}
+		}
 	}
 	return
 }
@@ -483,7 +496,7 @@ func (a *SubnetAdmin) Delete(ctx context.Context, subnet *model.Subnet) (err err
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, subnet.Owner)
+	permit := memberShip.For("subnet:delete").ValidateOwner(model.Writer, subnet.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the subnet")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the subnet", nil)
@@ -504,6 +517,11 @@ func (a *SubnetAdmin) Delete(ctx context.Context, subnet *model.Subnet) (err err
 			return
 		}
 	}
+	if subnet.RouteTableID > 0 {
+		err = NewCLError(ErrRouteTableInUse, "The subnet is still associated with a route table", nil)
+		logger.Error("The subnet is still associated with a route table")
+		return
+	}
 	err = db.Model(&model.Subnet{}).Where("vlan = ?", subnet.Vlan).Count(&count).Error
 	if err != nil {
 		logger.Error("Database failed to count subnet", err)
@@ -516,6 +534,9 @@ func (a *SubnetAdmin) Delete(ctx context.Context, subnet *model.Subnet) (err err
 		err = NewCLError(ErrSubnetDeleteFailed, "Database delete subnet failed", err)
 		return
 	}
+	if err = tagAdmin.replace(db, "subnets", subnet.ID, subnet.Owner, nil); err != nil {
+		return
+	}
 	subnet.Name = fmt.Sprintf("%s-%d", subnet.Name, subnet.CreatedAt.Unix())
 	err = db.Model(&model.Subnet{}).Unscoped().Where("id = ?", subnet.ID).Update("name", subnet.Name).Error
 	if err != nil {
@@ -652,16 +673,15 @@ func (a *SubnetAdmin) List(ctx context.Context, offset, limit int64, order, quer
 		where = fmt.Sprintf("type = 'public' or type = 'site' or %s", where)
 	}
 	subnets = []*model.Subnet{}
-	if err = db.Model(&model.Subnet{}).Where(where).Where(query).Where(intQuery).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Subnet{})).Where(where).Where(query).Where(intQuery).Count(&total).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Database failed to count subnets", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Group").Preload("Router").Where(where).Where(query).Where(intQuery).Find(&subnets).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Subnet{}, offset, limit, order).Preload("Group").Preload("Router").Where(where).Where(query).Where(intQuery).Find(&subnets).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Database failed to query subnets", err)
 		return
 	}
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("subnet:list").CheckPermission(model.Writer)
 	if permit {
 		db = db.Offset(0).Limit(-1)
 		for _, subnet := range subnets {
@@ -679,7 +699,7 @@ func (a *SubnetAdmin) List(ctx context.Context, offset, limit int64, order, quer
 
 func (v *SubnetView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("subnet:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -761,7 +781,7 @@ func (v *SubnetView) Delete(c *macaron.Context, store session.Store) (err error)
 func (v *SubnetView) New(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("subnet:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -786,7 +806,7 @@ func (v *SubnetView) New(c *macaron.Context, store session.Store) {
 
 func (v *SubnetView) Edit(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("subnet:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -799,7 +819,7 @@ func (v *SubnetView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Reader, "subnets", id)
+	permit, err := memberShip.For("subnet:update").CheckOwner(model.Reader, "subnets", id)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -919,7 +939,7 @@ func (v *SubnetView) checkRoutes(network, netmask, gateway, start, end, dns, rou
 func (v *SubnetView) Patch(c *macaron.Context, store session.Store) {
 	ctx := c.Req.Context()
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("subnet:update").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -933,7 +953,7 @@ func (v *SubnetView) Patch(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "subnets", id)
+	permit, err := memberShip.For("subnet:update").CheckOwner(model.Writer, "subnets", id)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
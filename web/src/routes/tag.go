@@ -0,0 +1,227 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	maxTagsPerResource = 50
+	maxTagKeyLength    = 128
+	maxTagValueLength  = 256
+)
+
+var tagAdmin = &TagAdmin{}
+
+// taggableResources maps the table of a taggable resource to the resource of its actions,
+// the table is also the collection of the resource in the REST API
+var taggableResources = map[string]string{
+	"instances":       "instance",
+	"volumes":         "volume",
+	"floating_ips":    "floating_ip",
+	"subnets":         "subnet",
+	"security_groups": "security_group",
+	"load_balancers":  "load_balancer",
+	"images":          "image",
+}
+
+type TagAdmin struct{}
+
+type taggedResource struct {
+	ID    int64
+	Owner int64
+}
+
+// Taggable tells if resources of the table can be tagged
+func Taggable(resourceType string) bool {
+	_, ok := taggableResources[resourceType]
+	return ok
+}
+
+// getResource finds a taggable resource by UUID and checks the resource:verb action on it
+func (a *TagAdmin) getResource(ctx context.Context, resourceType, uuID, verb string, reqRole model.Role) (resource *taggedResource, err error) {
+	action, ok := taggableResources[resourceType]
+	if !ok {
+		err = NewCLError(ErrNotTaggable, fmt.Sprintf("Resource %s can not be tagged", resourceType), nil)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	resource = &taggedResource{}
+	err = db.Table(resourceType).Select("id, owner").Where("uuid = ? AND deleted_at IS NULL", uuID).Scan(resource).Error
+	if err != nil {
+		logger.Errorf("Failed to query %s %s, %v", resourceType, uuID, err)
+		err = NewCLError(ErrResourceNotFound, "Resource not found", err)
+		return
+	}
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For(action+":"+verb).ValidateOwner(reqRole, resource.Owner)
+	if !permit {
+		logger.Errorf("Not authorized to %s tags of %s %s", verb, resourceType, uuID)
+		err = NewCLError(ErrPermissionDenied, "Not authorized to access the tags", nil)
+		return
+	}
+	return
+}
+
+func (a *TagAdmin) checkTag(key, value string) (err error) {
+	if key == "" || len(key) > maxTagKeyLength || strings.TrimSpace(key) != key {
+		return NewCLError(ErrInvalidTag, fmt.Sprintf("Tag key must have 1 to %d characters without leading or trailing spaces", maxTagKeyLength), nil)
+	}
+	if len(value) > maxTagValueLength {
+		return NewCLError(ErrInvalidTag, fmt.Sprintf("Tag value of %s must not exceed %d characters", key, maxTagValueLength), nil)
+	}
+	return
+}
+
+// Tags returns the tags of a resource by its ID, used internally without permission checks
+func (a *TagAdmin) Tags(ctx context.Context, resourceType string, resourceID int64) (tags map[string]string, err error) {
+	ctx, db := GetContextDB(ctx)
+	records := []*model.Tag{}
+	err = db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Order("key").Find(&records).Error
+	if err != nil {
+		logger.Error("DB failed to query tags", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query tags", err)
+		return
+	}
+	tags = make(map[string]string, len(records))
+	for _, record := range records {
+		tags[record.Key] = record.Value
+	}
+	return
+}
+
+// List returns the tags of a resource by its UUID
+func (a *TagAdmin) List(ctx context.Context, resourceType, uuID string) (tags map[string]string, err error) {
+	resource, err := a.getResource(ctx, resourceType, uuID, "get", model.Reader)
+	if err != nil {
+		return
+	}
+	return a.Tags(ctx, resourceType, resource.ID)
+}
+
+// Replace replaces all tags of a resource by its UUID
+func (a *TagAdmin) Replace(ctx context.Context, resourceType, uuID string, tags map[string]string) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	resource, err := a.getResource(ctx, resourceType, uuID, "tag", model.Writer)
+	if err != nil {
+		return
+	}
+	return a.replace(db, resourceType, resource.ID, resource.Owner, tags)
+}
+
+// replace is also used to tag resources on creation, the caller checks the permission
+func (a *TagAdmin) replace(db *gorm.DB, resourceType string, resourceID, owner int64, tags map[string]string) (err error) {
+	if len(tags) > maxTagsPerResource {
+		return NewCLError(ErrTooManyTags, fmt.Sprintf("A resource can have at most %d tags", maxTagsPerResource), nil)
+	}
+	for key, value := range tags {
+		if err = a.checkTag(key, value); err != nil {
+			return
+		}
+	}
+	err = db.Unscoped().Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Delete(&model.Tag{}).Error
+	if err != nil {
+		logger.Error("DB failed to delete tags", err)
+		return NewCLError(ErrDatabaseError, "Failed to delete tags", err)
+	}
+	for key, value := range tags {
+		err = db.Create(&model.Tag{Owner: owner, ResourceType: resourceType, ResourceID: resourceID, Key: key, Value: value}).Error
+		if err != nil {
+			logger.Error("DB failed to create tag", err)
+			return NewCLError(ErrDatabaseError, "Failed to create tag", err)
+		}
+	}
+	return
+}
+
+// Clear removes the tags of a deleted resource, for deletions finished outside of this package
+func (a *TagAdmin) Clear(ctx context.Context, resourceType string, resourceID int64) (err error) {
+	_, db := GetContextDB(ctx)
+	return a.replace(db, resourceType, resourceID, 0, nil)
+}
+
+// Set adds or updates one tag of a resource by its UUID
+func (a *TagAdmin) Set(ctx context.Context, resourceType, uuID, key, value string) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	resource, err := a.getResource(ctx, resourceType, uuID, "tag", model.Writer)
+	if err != nil {
+		return
+	}
+	if err = a.checkTag(key, value); err != nil {
+		return
+	}
+	tag := &model.Tag{}
+	err = db.Where("resource_type = ? AND resource_id = ? AND key = ?", resourceType, resource.ID, key).Take(tag).Error
+	if err == nil {
+		err = db.Model(tag).Update("value", value).Error
+		if err != nil {
+			logger.Error("DB failed to update tag", err)
+			err = NewCLError(ErrDatabaseError, "Failed to update tag", err)
+		}
+		return
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		logger.Error("DB failed to query tag", err)
+		return NewCLError(ErrDatabaseError, "Failed to query tag", err)
+	}
+	count := 0
+	err = db.Model(&model.Tag{}).Where("resource_type = ? AND resource_id = ?", resourceType, resource.ID).Count(&count).Error
+	if err != nil {
+		logger.Error("DB failed to count tags", err)
+		return NewCLError(ErrDatabaseError, "Failed to count tags", err)
+	}
+	if count >= maxTagsPerResource {
+		return NewCLError(ErrTooManyTags, fmt.Sprintf("A resource can have at most %d tags", maxTagsPerResource), nil)
+	}
+	err = db.Create(&model.Tag{Owner: resource.Owner, ResourceType: resourceType, ResourceID: resource.ID, Key: key, Value: value}).Error
+	if err != nil {
+		logger.Error("DB failed to create tag", err)
+		err = NewCLError(ErrDatabaseError, "Failed to create tag", err)
+	}
+	return
+}
+
+// Delete removes one tag of a resource by its UUID
+func (a *TagAdmin) Delete(ctx context.Context, resourceType, uuID, key string) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	resource, err := a.getResource(ctx, resourceType, uuID, "tag", model.Writer)
+	if err != nil {
+		return
+	}
+	result := db.Unscoped().Where("resource_type = ? AND resource_id = ? AND key = ?", resourceType, resource.ID, key).Delete(&model.Tag{})
+	if result.Error != nil {
+		logger.Error("DB failed to delete tag", result.Error)
+		return NewCLError(ErrDatabaseError, "Failed to delete tag", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return NewCLError(ErrTagNotFound, fmt.Sprintf("Tag %s not found", key), nil)
+	}
+	return
+}
@@ -12,7 +12,6 @@ import (
 	"net/http"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -42,7 +41,7 @@ func (a *TaskAdmin) Get(ctx context.Context, taskID int64) (task *model.Task, er
 		err = NewCLError(ErrTaskNotFound, "Task not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, task.Owner)
+	permit := memberShip.For("task:get").ValidateOwner(model.Reader, task.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the task")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the task", nil)
@@ -62,7 +61,7 @@ func (a *TaskAdmin) GetTaskByUUID(ctx context.Context, uuid string) (task *model
 		err = NewCLError(ErrTaskNotFound, "Task not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, task.Owner)
+	permit := memberShip.For("task:get").ValidateOwner(model.Reader, task.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the task")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the task", nil)
@@ -103,13 +102,12 @@ func (a *TaskAdmin) List(ctx context.Context, offset, limit int64, order string,
 	}
 
 	tasks = []*model.Task{}
-	if err = db.Model(&model.Task{}).Where(where).Where(query).Where(source_where).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Task{})).Where(where).Where(query).Where(source_where).Count(&total).Error; err != nil {
 		logger.Error("DB: count tasks failed", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count tasks", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Where(source_where).Where(where).Where(query).Find(&tasks).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Task{}, offset, limit, order).Where(source_where).Where(where).Where(query).Find(&tasks).Error; err != nil {
 		logger.Error("DB: query tasks failed", err)
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query tasks", err)
 		return
@@ -145,7 +143,7 @@ func (v *TaskView) Get(c *macaron.Context, store session.Store) {
 
 func (v *TaskView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("task:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
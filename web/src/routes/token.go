@@ -0,0 +1,227 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	tokenAdmin = &TokenAdmin{}
+)
+
+type TokenAdmin struct{}
+
+func refreshTokenHash(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshExpiry is the lifetime of a refresh token, token.refresh_hours in the config, one week by default
+func refreshExpiry() time.Duration {
+	hours := viper.GetInt("token.refresh_hours")
+	if hours <= 0 {
+		hours = 168
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (a *TokenAdmin) record(ctx context.Context, token *model.Token) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Create(token).Error
+	if err != nil {
+		logger.Error("DB failed to record token", err)
+		err = NewCLError(ErrTokenCreateFailed, "Failed to record token", err)
+		return
+	}
+	return
+}
+
+// purge drops the records of the tokens which are expired for a day, they are rejected by the signature check anyway
+func (a *TokenAdmin) purge(ctx context.Context) {
+	ctx, db := GetContextDB(ctx)
+	err := db.Unscoped().Where("expires_at < ?", time.Now().Add(-24*time.Hour)).Delete(&model.Token{}).Error
+	if err != nil {
+		logger.Error("DB failed to purge expired tokens", err)
+	}
+}
+
+func (a *TokenAdmin) newRefreshToken(ctx context.Context, uid, oid int64, family string) (refreshToken string, err error) {
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		logger.Error("Failed to generate refresh token", err)
+		err = NewCLError(ErrTokenCreateFailed, "Failed to generate refresh token", err)
+		return
+	}
+	refreshToken = hex.EncodeToString(buf)
+	now := time.Now()
+	token := &model.Token{
+		Jti:       claimsID(now),
+		Kind:      model.TokenRefresh,
+		Family:    family,
+		UserID:    uid,
+		OrgID:     oid,
+		Hash:      refreshTokenHash(refreshToken),
+		ExpiresAt: now.Add(refreshExpiry()),
+	}
+	err = a.record(ctx, token)
+	return
+}
+
+// Issue starts a new token family with an access token and a refresh token
+func (a *TokenAdmin) Issue(ctx context.Context, uid int64, username, organization string) (role model.Role, token, refreshToken string, expiresAt int64, err error) {
+	ctx, _, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	a.purge(ctx)
+	oid, role, token, jti, _, expiresAt, err := userAdmin.accessToken(ctx, uid, username, organization, "")
+	if err != nil {
+		return
+	}
+	refreshToken, err = a.newRefreshToken(ctx, uid, oid, jti)
+	return
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens in the same family, the old refresh token can not be used again,
+// presenting a used refresh token revokes the whole family since it means the token was stolen
+func (a *TokenAdmin) Refresh(ctx context.Context, refreshToken string) (user *model.User, org *model.Organization, role model.Role, token, newRefreshToken string, expiresAt int64, err error) {
+	var record *model.Token
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+		// revoked after the rollback of the transaction on the error, which would undo it otherwise
+		if clErr, ok := err.(*CLError); ok && clErr.Code == ErrTokenRevoked && record != nil {
+			if revokeErr := a.RevokeFamily(SetContextDB(ctx, DB()), record.Family); revokeErr != nil {
+				logger.Errorf("Failed to revoke token family %s, %v", record.Family, revokeErr)
+			}
+		}
+	}()
+	record, err = a.rotate(ctx, refreshToken)
+	if err != nil {
+		return
+	}
+	user = &model.User{Model: model.Model{ID: record.UserID}}
+	err = db.Take(user).Error
+	if err != nil {
+		logger.Error("DB failed to query user", err)
+		err = NewCLError(ErrUserNotFound, "User not found", err)
+		return
+	}
+	org = &model.Organization{Model: model.Model{ID: record.OrgID}}
+	err = db.Take(org).Error
+	if err != nil {
+		logger.Error("DB failed to query organization", err)
+		err = NewCLError(ErrOrgNotFound, "Organization not found", err)
+		return
+	}
+	_, role, token, _, _, expiresAt, err = userAdmin.accessToken(ctx, user.ID, user.Username, org.Name, record.Family)
+	if err != nil {
+		return
+	}
+	newRefreshToken, err = a.newRefreshToken(ctx, user.ID, org.ID, record.Family)
+	return
+}
+
+// rotate marks a refresh token used, a token used already is a replay and the caller has to revoke its family
+func (a *TokenAdmin) rotate(ctx context.Context, refreshToken string) (record *model.Token, err error) {
+	ctx, db := GetContextDB(ctx)
+	record = &model.Token{}
+	err = db.Where("hash = ? and kind = ?", refreshTokenHash(refreshToken), model.TokenRefresh).Take(record).Error
+	if err != nil {
+		logger.Error("Failed to find refresh token", err)
+		err = NewCLError(ErrTokenInvalid, "Invalid refresh token", err)
+		return
+	}
+	if !record.Revoked && record.ExpiresAt.Before(time.Now()) {
+		err = NewCLError(ErrTokenExpired, "Refresh token expired", nil)
+		return
+	}
+	// a used token updates nothing, and only one of concurrent refreshes with the same token gets to revoke it
+	result := db.Model(record).Where("revoked = ?", false).Update("revoked", true)
+	if result.Error != nil {
+		logger.Error("DB failed to revoke refresh token", result.Error)
+		err = NewCLError(ErrTokenRevokeFailed, "Failed to revoke refresh token", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		logger.Errorf("Refresh token of family %s was reused", record.Family)
+		err = NewCLError(ErrTokenRevoked, "Refresh token was revoked", nil)
+		return
+	}
+	return
+}
+
+// IsRevoked tells if the token with the jti was revoked, tokens issued without a record are not revoked
+func (a *TokenAdmin) IsRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	ctx, db := GetContextDB(ctx)
+	count := 0
+	err = db.Model(&model.Token{}).Where("jti = ? and revoked = ?", jti, true).Count(&count).Error
+	if err != nil {
+		logger.Error("DB failed to query revoked tokens", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query revoked tokens", err)
+		return
+	}
+	revoked = count > 0
+	return
+}
+
+func (a *TokenAdmin) RevokeFamily(ctx context.Context, family string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = db.Model(&model.Token{}).Where("family = ? and revoked = ?", family, false).Update("revoked", true).Error
+	if err != nil {
+		logger.Error("DB failed to revoke tokens", err)
+		err = NewCLError(ErrTokenRevokeFailed, "Failed to revoke tokens", err)
+		return
+	}
+	return
+}
+
+// Logout revokes the access token with the jti together with all tokens refreshed from the same login
+func (a *TokenAdmin) Logout(ctx context.Context, jti string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	record := &model.Token{}
+	err = db.Where("jti = ?", jti).Take(record).Error
+	if err != nil {
+		logger.Error("Failed to find token", err)
+		err = NewCLError(ErrTokenInvalid, "Token was not issued with a record", err)
+		return
+	}
+	err = a.RevokeFamily(ctx, record.Family)
+	return
+}
+
+// RevokeMember revokes all tokens of a user in an organization, or in all organizations if orgID is 0
+func (a *TokenAdmin) RevokeMember(ctx context.Context, userID, orgID int64) (err error) {
+	ctx, db := GetContextDB(ctx)
+	db = db.Model(&model.Token{}).Where("user_id = ? and revoked = ?", userID, false)
+	if orgID > 0 {
+		db = db.Where("org_id = ?", orgID)
+	}
+	err = db.Update("revoked", true).Error
+	if err != nil {
+		logger.Error("DB failed to revoke tokens of member", err)
+		err = NewCLError(ErrTokenRevokeFailed, "Failed to revoke tokens of member", err)
+		return
+	}
+	return
+}
@@ -18,12 +18,14 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	. "web/src/common"
-	"web/src/dbs"
+	"web/src/identity"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 	macaron "gopkg.in/macaron.v1"
 )
@@ -54,7 +56,8 @@ func (a *UserAdmin) Create(ctx context.Context, username, password, uuid string)
 	if password, err = a.GenerateFromPassword(password); err != nil {
 		return
 	}
-	user = &model.User{Model: model.Model{Creater: memberShip.UserID}, Username: username, Password: password}
+	now := time.Now()
+	user = &model.User{Model: model.Model{Creater: memberShip.UserID}, Username: username, Password: password, PasswordChangedAt: &now}
 	if uuid != "" {
 		logger.Infof("Creating new user with uuid %s", uuid)
 		user.UUID = uuid
@@ -62,6 +65,11 @@ func (a *UserAdmin) Create(ctx context.Context, username, password, uuid string)
 	err = db.Create(user).Error
 	if err != nil {
 		logger.Error("DB failed to create user, %v", err)
+		err = NewCLError(ErrUserCreationFailed, "Failed to create user", err)
+		return
+	}
+	if err = a.recordPassword(db, user.ID, password); err != nil {
+		return
 	}
 	if memberShip.OrgName != "admin" {
 		member := &model.Member{UserID: user.ID, UserName: username, OrgID: memberShip.OrgID, OrgName: memberShip.OrgName, Role: model.Reader}
@@ -150,6 +158,20 @@ func (a *UserAdmin) Delete(ctx context.Context, user *model.User) (err error) {
 		err = NewCLError(ErrMemberDeleteFailed, "Failed to delete organization members", err)
 		return
 	}
+	if err = tokenAdmin.RevokeMember(ctx, user.ID, 0); err != nil {
+		return
+	}
+	if err = policyAdmin.DeleteMemberBindings(ctx, user.ID, 0); err != nil {
+		return
+	}
+	if err = mfaAdmin.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+		return
+	}
+	if err = db.Where("user_id = ?", user.ID).Delete(&model.AppCredential{}).Error; err != nil {
+		logger.Error("DB failed to delete application credentials", err)
+		err = NewCLError(ErrAppCredentialDeleteFailed, "Failed to delete application credentials", err)
+		return
+	}
 	if err = db.Delete(user).Error; err != nil {
 		logger.Error("DB failed to delete user", err)
 		err = NewCLError(ErrUserDeleteFailed, "Failed to delete user", err)
@@ -175,14 +197,12 @@ func (a *UserAdmin) Update(ctx context.Context, id int64, password string, membe
 		return
 	}
 	password = strings.TrimSpace(password)
+	if password != "" && user.Source != identity.SourceLocal && user.Source != "" {
+		err = NewCLError(ErrIdentitySourceMismatch, fmt.Sprintf("Password of user %s is managed by %s", user.Username, user.Source), nil)
+		return
+	}
 	if password != "" {
-		if password, err = a.GenerateFromPassword(password); err != nil {
-			return
-		}
-		err = db.Model(user).Update("password", password).Error
-		if err != nil {
-			logger.Error("DB failed to update user password", err)
-			err = NewCLError(ErrUserUpdateFailed, "Failed to update user password", err)
+		if err = a.ChangePassword(ctx, user, password); err != nil {
 			return
 		}
 	}
@@ -236,25 +256,23 @@ func (a *UserAdmin) List(ctx context.Context, offset, limit int64, order, query
 				userIDs = append(userIDs, member.UserID)
 			}
 		}
-		if err = db.Model(&model.User{}).Where(userIDs).Where(query).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.User{})).Where(userIDs).Where(query).Count(&total).Error; err != nil {
 			logger.Error("DB failed to count users", err)
 			err = NewCLError(ErrDatabaseError, "Failed to count users", err)
 			return
 		}
-		db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-		if err = db.Where(userIDs).Where(query).Find(&users).Error; err != nil {
+		if err = ListPage(ctx, db, &model.User{}, offset, limit, order).Where(userIDs).Where(query).Find(&users).Error; err != nil {
 			logger.Error("DB failed to get user list, %v", err)
 			err = NewCLError(ErrDatabaseError, "Failed to get user list", err)
 			return
 		}
 	} else {
-		if err = db.Model(&model.User{}).Where(query).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.User{})).Where(query).Count(&total).Error; err != nil {
 			logger.Error("DB failed to count users", err)
 			err = NewCLError(ErrDatabaseError, "Failed to count users", err)
 			return
 		}
-		db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-		if err = db.Where(query).Find(&users).Error; err != nil {
+		if err = ListPage(ctx, db, &model.User{}, offset, limit, order).Where(query).Find(&users).Error; err != nil {
 			logger.Error("DB failed to get user list, %v", err)
 			err = NewCLError(ErrDatabaseError, "Failed to get user list", err)
 			return
@@ -264,18 +282,63 @@ func (a *UserAdmin) List(ctx context.Context, offset, limit int64, order, query
 	return
 }
 
+// Validate checks the credential of a login, failures are counted against the account for the lockout
 func (a *UserAdmin) Validate(ctx context.Context, username, password string) (user *model.User, err error) {
+	ctx, db := GetContextDB(ctx)
+	known := &model.User{}
+	if db.Take(known, "username = ?", username).Error == nil {
+		err = a.checkLocked(known)
+		if err != nil {
+			logger.Errorf("Login of locked user %s refused", username)
+			return
+		}
+	}
+	user, err = a.validate(ctx, username, password)
+	if err != nil {
+		logger.Errorf("Failed login of user %s, %v", username, err)
+		if known.ID > 0 {
+			a.loginFailed(ctx, known)
+		}
+		return
+	}
+	a.loginSucceeded(ctx, known)
+	return
+}
+
+func (a *UserAdmin) validate(ctx context.Context, username, password string) (user *model.User, err error) {
 	ctx, db := GetContextDB(ctx)
 	user = &model.User{}
 	err = db.Take(user, "username = ?", username).Error
 	if err != nil {
 		logger.Error("DB failed to qeury user", err)
 	}
+	// admin always logs in locally so that a broken directory can not lock it out
+	if username != "admin" && identityAdmin.LdapEnabled() {
+		linkLocal := user.Source == identity.SourceLocal && viper.GetBool("identity.link_local_users")
+		if err != nil || user.Source == identity.SourceLdap || linkLocal {
+			var ldapUser *model.User
+			ldapUser, err = identityAdmin.LdapLogin(ctx, username, password)
+			if err == nil || !linkLocal {
+				user = ldapUser
+				return
+			}
+		}
+	}
+	if user.Source != identity.SourceLocal && user.Source != "" {
+		err = NewCLError(ErrIdentitySourceMismatch, fmt.Sprintf("User %s must login with %s", username, user.Source), nil)
+		return
+	}
 	err = a.CompareHashAndPassword(user.Password, password)
 	return
 }
 
 func (a *UserAdmin) AccessToken(ctx context.Context, uid int64, username, organization string) (oid int64, role model.Role, token string, issueAt, expiresAt int64, err error) {
+	oid, role, token, _, issueAt, expiresAt, err = a.accessToken(ctx, uid, username, organization, "")
+	return
+}
+
+// accessToken issues an access token and records its jti in the token family, a new family is started if family is empty
+func (a *UserAdmin) accessToken(ctx context.Context, uid int64, username, organization, family string) (oid int64, role model.Role, token, jti string, issueAt, expiresAt int64, err error) {
 	ctx, db := GetContextDB(ctx)
 	member := &model.Member{}
 	err = db.Take(member, "user_name = ? and org_name = ?", username, organization).Error
@@ -304,7 +367,15 @@ func (a *UserAdmin) AccessToken(ctx context.Context, uid int64, username, organi
 		err = NewCLError(ErrUserNotFound, "User not found", err)
 		return
 	}
-	token, issueAt, expiresAt, err = NewToken(username, organization, userInstance.UUID, orgInstance.UUID, role)
+	token, jti, issueAt, expiresAt, err = newToken(username, organization, userInstance.UUID, orgInstance.UUID, role)
+	if err != nil {
+		err = NewCLError(ErrTokenCreateFailed, "Failed to sign token", err)
+		return
+	}
+	if family == "" {
+		family = jti
+	}
+	err = tokenAdmin.record(ctx, &model.Token{Jti: jti, Kind: model.TokenAccess, Family: family, UserID: uid, OrgID: oid, ExpiresAt: time.Unix(expiresAt, 0)})
 	return
 }
 
@@ -332,6 +403,7 @@ func (v *UserView) LoginGet(c *macaron.Context, store session.Store) {
 	logout := c.QueryTrim("logout")
 	if logout == "" {
 		c.Data["PageIsSignIn"] = true
+		c.Data["OidcEnabled"] = identityAdmin.OidcEnabled()
 		c.HTML(200, "login")
 	}
 }
@@ -339,12 +411,179 @@ func (v *UserView) LoginGet(c *macaron.Context, store session.Store) {
 func (v *UserView) LoginPost(c *macaron.Context, store session.Store) {
 	username := c.QueryTrim("username")
 	password := c.QueryTrim("password")
-	user, err := userAdmin.Validate(c.Req.Context(), username, password)
+	ctx := c.Req.Context()
+	if !v.throttle(c) {
+		return
+	}
+	user, err := userAdmin.Validate(ctx, username, password)
+	if err != nil {
+		LoginThrottle.Fail(c.RemoteAddr())
+		logger.Errorf("Failed login of user %s from %s", username, c.RemoteAddr())
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(401, "401")
+		return
+	}
+	v.secondFactor(c, store, user)
+}
+
+// secondFactor asks the user authenticated by the first factor for the second one if it is enrolled,
+// every login path goes through it before the session is started
+func (v *UserView) secondFactor(c *macaron.Context, store session.Store, user *model.User) {
+	ctx := c.Req.Context()
+	if user.MfaEnabled {
+		// The first factor is good, the session is only started after the second factor
+		store.Set("mfa_uid", user.ID)
+		c.Data["PageIsSignIn"] = true
+		c.HTML(200, "login_mfa")
+		return
+	}
+	org, err := orgAdmin.GetOrgByName(ctx, user.Username)
+	if err == nil {
+		err = mfaAdmin.Check(ctx, user, org, "")
+	}
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(403, "403")
+		return
+	}
+	v.finishLogin(c, store, user)
+}
+
+// throttle refuses the login form of a client with too many recent failures
+func (v *UserView) throttle(c *macaron.Context) bool {
+	retryAfter, ok := LoginThrottle.Allow(c.RemoteAddr())
+	if !ok {
+		logger.Errorf("Login from %s is throttled", c.RemoteAddr())
+		c.Resp.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		c.Data["ErrorMsg"] = "Too many failed logins, please try again later"
+		c.HTML(http.StatusTooManyRequests, "error")
+		return false
+	}
+	return true
+}
+
+// LoginMfaPost verifies the second factor of the user whose password was checked by LoginPost
+func (v *UserView) LoginMfaPost(c *macaron.Context, store session.Store) {
+	ctx := c.Req.Context()
+	uid, ok := store.Get("mfa_uid").(int64)
+	if !ok || uid == 0 {
+		c.Redirect("/login")
+		return
+	}
+	if !v.throttle(c) {
+		return
+	}
+	user := &model.User{Model: model.Model{ID: uid}}
+	err := DB().Take(user).Error
+	if err == nil {
+		err = mfaAdmin.Verify(ctx, user, c.QueryTrim("passcode"))
+	}
+	if err != nil {
+		LoginThrottle.Fail(c.RemoteAddr())
+		logger.Error("Failed multi-factor authentication", err)
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(401, "401")
+		return
+	}
+	store.Delete("mfa_uid")
+	store.Set("mfa_at", time.Now().Unix())
+	v.finishLogin(c, store, user)
+}
+
+// finishLogin asks for a new password if the current one expired, otherwise starts the session
+func (v *UserView) finishLogin(c *macaron.Context, store session.Store, user *model.User) {
+	if userAdmin.PasswordExpired(user) {
+		store.Set("pwd_uid", user.ID)
+		c.Data["PageIsSignIn"] = true
+		c.HTML(200, "login_password")
+		return
+	}
+	v.startSession(c, store, user)
+}
+
+// LoginPasswordPost replaces the expired password of the user authenticated by LoginPost
+func (v *UserView) LoginPasswordPost(c *macaron.Context, store session.Store) {
+	ctx := c.Req.Context()
+	uid, ok := store.Get("pwd_uid").(int64)
+	if !ok || uid == 0 {
+		c.Redirect("/login")
+		return
+	}
+	password := c.QueryTrim("password")
+	confirm := c.QueryTrim("confirm")
+	user := &model.User{Model: model.Model{ID: uid}}
+	err := DB().Take(user).Error
+	if err == nil && password != confirm {
+		err = NewCLError(ErrPasswordMismatch, "Passwords do not match", nil)
+	}
+	if err == nil {
+		err = userAdmin.ChangePassword(ctx, user, password)
+	}
+	if err != nil {
+		logger.Error("Failed to change expired password", err)
+		c.Data["ErrorMsg"] = err.Error()
+		c.Data["PageIsSignIn"] = true
+		c.HTML(http.StatusBadRequest, "login_password")
+		return
+	}
+	store.Delete("pwd_uid")
+	v.startSession(c, store, user)
+}
+
+// OidcLogin redirects to the authorization endpoint of the issuer, state and nonce are kept in the session
+func (v *UserView) OidcLogin(c *macaron.Context, store session.Store) {
+	provider, err := identityAdmin.Oidc(c.Req.Context())
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	state, err := identity.NewState()
+	if err != nil {
+		logger.Error("Failed to generate oidc state", err)
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusInternalServerError, "error")
+		return
+	}
+	nonce, err := identity.NewState()
+	if err != nil {
+		logger.Error("Failed to generate oidc nonce", err)
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusInternalServerError, "error")
+		return
+	}
+	store.Set("oidc_state", state)
+	store.Set("oidc_nonce", nonce)
+	c.Redirect(provider.AuthCodeURL(state, nonce))
+}
+
+func (v *UserView) OidcCallback(c *macaron.Context, store session.Store) {
+	state, _ := store.Get("oidc_state").(string)
+	nonce, _ := store.Get("oidc_nonce").(string)
+	store.Delete("oidc_state")
+	store.Delete("oidc_nonce")
+	if errMsg := c.QueryTrim("error"); errMsg != "" {
+		c.Data["ErrorMsg"] = errMsg + ": " + c.QueryTrim("error_description")
+		c.HTML(401, "401")
+		return
+	}
+	if state == "" || c.QueryTrim("state") != state {
+		c.Data["ErrorMsg"] = "Invalid OIDC state"
+		c.HTML(401, "401")
+		return
+	}
+	user, err := identityAdmin.OidcLogin(c.Req.Context(), c.QueryTrim("code"), nonce)
 	if err != nil {
 		c.Data["ErrorMsg"] = err.Error()
 		c.HTML(401, "401")
 		return
 	}
+	v.secondFactor(c, store, user)
+}
+
+// startSession logs the authenticated user into its personal organization
+func (v *UserView) startSession(c *macaron.Context, store session.Store, user *model.User) {
+	username := user.Username
 	organization := username
 	uid := user.ID
 	oid, role, token, _, _, err := userAdmin.AccessToken(c.Req.Context(), uid, username, organization)
@@ -442,6 +681,7 @@ func (v *UserView) Edit(c *macaron.Context, store session.Store) {
 		return
 	}
 	c.Data["User"] = user
+	c.Data["Locked"] = userAdmin.Locked(user)
 	c.HTML(200, "users_patch")
 }
 
@@ -477,8 +717,13 @@ func (v *UserView) Change(c *macaron.Context, store session.Store) {
 			if em.OrgName == orgName {
 				org := &model.Organization{Model: model.Model{ID: em.OrgID}}
 				err = db.Take(org).Error
+				mfaAt, _ := store.Get("mfa_at").(int64)
 				if err != nil {
 					logger.Error("Failed to query organization")
+				} else if org.RequireMfa && mfaAt == 0 && user.Username != "admin" {
+					c.Data["ErrorMsg"] = fmt.Sprintf("Organization %s requires multi-factor authentication, enroll it and login again", org.Name)
+					c.HTML(http.StatusForbidden, "error")
+					return
 				} else {
 					store.Set("oid", org.ID)
 					store.Set("role", em.Role)
@@ -529,6 +774,28 @@ func (v *UserView) Patch(c *macaron.Context, store session.Store) {
 	c.HTML(200, "ok")
 }
 
+// Unlock clears the lockout of a user after too many failed logins
+func (v *UserView) Unlock(c *macaron.Context, store session.Store) {
+	ctx := c.Req.Context()
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	user, err := userAdmin.Get(ctx, int64(userID))
+	if err == nil {
+		err = userAdmin.Unlock(ctx, user)
+	}
+	if err != nil {
+		logger.Error("Failed to unlock user", err)
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	c.Redirect(fmt.Sprintf("/users/%d", userID))
+}
+
 func (v *UserView) Delete(c *macaron.Context, store session.Store) (err error) {
 	ctx := c.Req.Context()
 	id := c.Params("id")
@@ -597,7 +864,13 @@ func (v *UserView) Create(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	_, err := userAdmin.Create(c.Req.Context(), username, password, "")
+	err := userAdmin.CheckPassword(username, password)
+	if err != nil {
+		c.Data["ErrorMsg"] = err.Error()
+		c.HTML(http.StatusBadRequest, "error")
+		return
+	}
+	_, err = userAdmin.Create(c.Req.Context(), username, password, "")
 	if err != nil {
 		logger.Error("Failed to create user, %v", err)
 		c.HTML(500, "500")
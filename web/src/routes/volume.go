@@ -53,7 +53,7 @@ func (a *VolumeAdmin) Get(ctx context.Context, id int64) (volume *model.Volume,
 		err = NewCLError(ErrVolumeNotFound, "Failed to query volume", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, volume.Owner)
+	permit := memberShip.For("volume:get").ValidateOwner(model.Reader, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the volume", nil)
@@ -73,7 +73,7 @@ func (a *VolumeAdmin) GetVolumeByUUID(ctx context.Context, uuID string) (volume
 		err = NewCLError(ErrVolumeNotFound, "Volume not found", err)
 		return
 	}
-	permit := memberShip.ValidateOwner(model.Reader, volume.Owner)
+	permit := memberShip.For("volume:get").ValidateOwner(model.Reader, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to read the volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to read the volume", nil)
@@ -148,7 +148,7 @@ func (a *VolumeAdmin) Create(ctx context.Context, name string, size int32,
 	iopsLimit int32, iopsBurst int32, bpsLimit int32, bpsBurst int32, poolID string) (volume *model.Volume, err error) {
 	memberShip := GetMemberShip(ctx)
 	// check the permission
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("volume:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized to create volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to create volume", nil)
@@ -235,7 +235,7 @@ func (a *VolumeAdmin) UpdateQos(ctx context.Context, id int64, iopsLimit int32,
 	}
 	// check the permission
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("volume:update_qos").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to update the volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to update the volume", nil)
@@ -289,7 +289,7 @@ func (a *VolumeAdmin) Update(ctx context.Context, id int64, name string, instID
 	}
 	// check the permission
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("volume:update").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to update the volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to update the volume", nil)
@@ -396,7 +396,7 @@ func (a *VolumeAdmin) Delete(ctx context.Context, volume *model.Volume) (err err
 	}()
 	// check the permission
 	memberShip := GetMemberShip(ctx)
-	permit := memberShip.ValidateOwner(model.Writer, volume.Owner)
+	permit := memberShip.For("volume:delete").ValidateOwner(model.Writer, volume.Owner)
 	if !permit {
 		logger.Error("Not authorized to delete the volume")
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the volume", nil)
@@ -427,6 +427,9 @@ func (a *VolumeAdmin) Delete(ctx context.Context, volume *model.Volume) (err err
 		err = NewCLError(ErrVolumeDeleteFailed, "Failed to delete volume", err)
 		return
 	}
+	if err = tagAdmin.replace(db, "volumes", volume.ID, volume.Owner, nil); err != nil {
+		return
+	}
 	control := fmt.Sprintf("inter=")
 	vol_driver := GetVolumeDriver()
 	uuid := volume.UUID
@@ -463,14 +466,14 @@ func (a *VolumeAdmin) DeleteVolumeByUUID(ctx context.Context, uuID string) (err
 
 func (a *VolumeAdmin) Resize(ctx context.Context, volume *model.Volume, size int32) (err error) {
 	logger.Debugf("Resize volume %d with size %d", volume.ID, size)
-	ctx, db, newTransaction := StartTransaction(ctx)
+	ctx, _, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
 			EndTransaction(ctx, nil)
 		}
 	}()
 	memberShip := GetMemberShip(ctx)
-	permit, err := memberShip.CheckOwner(model.Writer, "volumes", volume.ID)
+	permit, err := memberShip.For("volume:resize").CheckOwner(model.Writer, "volumes", volume.ID)
 	if !permit {
 		logger.Error("Failed to check owner")
 		return
@@ -480,6 +483,12 @@ func (a *VolumeAdmin) Resize(ctx context.Context, volume *model.Volume, size int
 		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the instance", nil)
 		return
 	}
+	return a.resize(ctx, volume, size, false)
+}
+
+// resize grows the volume, online grows it under the running guest instead of stopping the guest
+func (a *VolumeAdmin) resize(ctx context.Context, volume *model.Volume, size int32, online bool) (err error) {
+	ctx, db := GetContextDB(ctx)
 	if volume.IsError() {
 		logger.Error("Volume is in error status")
 		err = NewCLError(ErrVolumeInvalidState, "Volume is in error status", nil)
@@ -541,7 +550,7 @@ func (a *VolumeAdmin) Resize(ctx context.Context, volume *model.Volume, size int
 	if volume.InstanceID != 0 {
 		control = fmt.Sprintf("inter=%d", volume.Instance.Hyper)
 	}
-	command := fmt.Sprintf("/opt/cloudland/scripts/backend/resize_volume_%s.sh '%d' '%s' '%d' '%t' '%d'", volDriver, volume.ID, uuid, size, volume.Booting, volume.InstanceID)
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/resize_volume_%s.sh '%d' '%s' '%d' '%t' '%d' '%t'", volDriver, volume.ID, uuid, size, volume.Booting, volume.InstanceID, online)
 	err = HyperExecute(ctx, control, command)
 	if err != nil {
 		logger.Error("Resize remote exec failed", err)
@@ -647,18 +656,17 @@ func (a *VolumeAdmin) ListVolume(ctx context.Context, offset, limit int64, order
 
 	volumes = []*model.Volume{}
 	if booting_where != "" {
-		if err = db.Model(&model.Volume{}).Where(where).Where(query).Where(booting_where).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.Volume{})).Where(where).Where(query).Where(booting_where).Count(&total).Error; err != nil {
 			err = NewCLError(ErrSQLSyntaxError, "Failed to count volumes", err)
 			return
 		}
 	} else {
-		if err = db.Model(&model.Volume{}).Where(where).Where(query).Count(&total).Error; err != nil {
+		if err = ListFilter(ctx, db.Model(&model.Volume{})).Where(where).Where(query).Count(&total).Error; err != nil {
 			err = NewCLError(ErrSQLSyntaxError, "Failed to count volumes", err)
 			return
 		}
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Preload("Instance").Where(where).Where(query).Find(&volumes).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Volume{}, offset, limit, order).Preload("Instance").Where(where).Where(query).Find(&volumes).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query volumes", err)
 		return
 	}
@@ -696,7 +704,7 @@ func (a *VolumeAdmin) UpdateDataVolumeStatus(ctx context.Context, instanceID int
 
 func (v *VolumeView) List(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Reader)
+	permit := memberShip.For("volume:list").CheckPermission(model.Reader)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -784,7 +792,7 @@ func (v *VolumeView) Delete(c *macaron.Context, store session.Store) (err error)
 
 func (v *VolumeView) New(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("volume:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
@@ -813,7 +821,7 @@ func (v *VolumeView) Edit(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "volumes", int64(volID))
+	permit, err := memberShip.For("volume:update").CheckOwner(model.Writer, "volumes", int64(volID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
@@ -849,7 +857,7 @@ func (v *VolumeView) Patch(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "volumes", int64(volID))
+	permit, err := memberShip.For("volume:update").CheckOwner(model.Writer, "volumes", int64(volID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
@@ -872,7 +880,7 @@ func (v *VolumeView) Patch(c *macaron.Context, store session.Store) {
 	}
 	if instID > 0 {
 		// have to check the instance permission
-		permit, err = memberShip.CheckOwner(model.Writer, "instances", int64(instID))
+		permit, err = memberShip.For("volume:update").CheckOwner(model.Writer, "instances", int64(instID))
 		if err != nil {
 			logger.Error("Failed to check permission", err)
 			c.Data["ErrorMsg"] = err.Error()
@@ -907,7 +915,7 @@ func (v *VolumeView) EditQos(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "volumes", int64(volID))
+	permit, err := memberShip.For("volume:update_qos").CheckOwner(model.Writer, "volumes", int64(volID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
@@ -944,7 +952,7 @@ func (v *VolumeView) UpdateQos(c *macaron.Context, store session.Store) {
 		c.HTML(http.StatusBadRequest, "error")
 		return
 	}
-	permit, err := memberShip.CheckOwner(model.Writer, "volumes", int64(volID))
+	permit, err := memberShip.For("volume:update_qos").CheckOwner(model.Writer, "volumes", int64(volID))
 	if err != nil {
 		logger.Error("Failed to check permission", err)
 		c.Data["ErrorMsg"] = err.Error()
@@ -982,7 +990,7 @@ func (v *VolumeView) UpdateQos(c *macaron.Context, store session.Store) {
 
 func (v *VolumeView) Create(c *macaron.Context, store session.Store) {
 	memberShip := GetMemberShip(c.Req.Context())
-	permit := memberShip.CheckPermission(model.Writer)
+	permit := memberShip.For("volume:create").CheckPermission(model.Writer)
 	if !permit {
 		logger.Error("Not authorized for this operation")
 		c.Data["ErrorMsg"] = "Not authorized for this operation"
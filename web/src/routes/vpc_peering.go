@@ -0,0 +1,530 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+
+*/
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+var (
+	vpcPeeringAdmin = &VpcPeeringAdmin{}
+)
+
+type VpcPeeringAdmin struct{}
+
+type FdbRule struct {
+	Instance string `json:"instance"`
+	Vni      int64  `json:"vni"`
+	InnerIP  string `json:"inner_ip"`
+	InnerMac string `json:"inner_mac"`
+	OuterIP  string `json:"outer_ip"`
+	Gateway  string `json:"gateway"`
+	Router   int64  `json:"router"`
+}
+
+type PeeringSubnet struct {
+	Vni     int64  `json:"vni"`
+	Gateway string `json:"gateway"`
+	Network string `json:"network"`
+}
+
+type PeeringRouter struct {
+	ID      int64            `json:"id"`
+	Subnets []*PeeringSubnet `json:"subnets"`
+}
+
+type VpcPeeringData struct {
+	Routers []*PeeringRouter `json:"routers"`
+}
+
+func (a *VpcPeeringAdmin) getWhere(memberShip *MemberShip) (where string) {
+	where = memberShip.GetWhere()
+	if where != "" {
+		where = fmt.Sprintf("owner = %d or peer_owner = %d", memberShip.OrgID, memberShip.OrgID)
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) getRouterSubnets(ctx context.Context, routerID int64) (subnets []*model.Subnet, err error) {
+	ctx, db := GetContextDB(ctx)
+	subnets = []*model.Subnet{}
+	err = db.Where("router_id = ? and type = ?", routerID, "internal").Find(&subnets).Error
+	if err != nil {
+		logger.Error("Failed to query subnets of router", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query subnets of the vpc", err)
+		return
+	}
+	return
+}
+
+// checkOverlap makes sure no subnet of the router overlaps with any subnet of the peer router
+func (a *VpcPeeringAdmin) checkOverlap(ctx context.Context, routerID, peerRouterID int64) (err error) {
+	subnets, err := a.getRouterSubnets(ctx, routerID)
+	if err != nil {
+		return
+	}
+	peerSubnets, err := a.getRouterSubnets(ctx, peerRouterID)
+	if err != nil {
+		return
+	}
+	for _, subnet := range subnets {
+		ipNet := subnetCIDR(subnet)
+		if ipNet == nil {
+			continue
+		}
+		for _, peerSubnet := range peerSubnets {
+			peerNet := subnetCIDR(peerSubnet)
+			if peerNet != nil && routesOverlap(ipNet, peerNet) {
+				logger.Errorf("Subnet %s overlaps with peer subnet %s", ipNet, peerNet)
+				err = NewCLError(ErrVpcPeeringCIDROverlap, fmt.Sprintf("Subnet %s overlaps with peer subnet %s", ipNet, peerNet), nil)
+				return
+			}
+		}
+	}
+	return
+}
+
+// CheckSubnet rejects a new subnet of the router overlapping with the subnets of its active peers
+func (a *VpcPeeringAdmin) CheckSubnet(ctx context.Context, routerID int64, network string) (err error) {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		err = NewCLError(ErrInvalidCIDR, "Invalid CIDR", err)
+		return
+	}
+	peerIDs, err := a.GetPeerRouterIDs(ctx, routerID)
+	if err != nil {
+		return
+	}
+	for _, peerID := range peerIDs[1:] {
+		var peerSubnets []*model.Subnet
+		peerSubnets, err = a.getRouterSubnets(ctx, peerID)
+		if err != nil {
+			return
+		}
+		for _, peerSubnet := range peerSubnets {
+			peerNet := subnetCIDR(peerSubnet)
+			if peerNet != nil && routesOverlap(ipNet, peerNet) {
+				logger.Errorf("Subnet %s overlaps with peer subnet %s", ipNet, peerNet)
+				err = NewCLError(ErrVpcPeeringCIDROverlap, fmt.Sprintf("Subnet %s overlaps with peer subnet %s", ipNet, peerNet), nil)
+				return
+			}
+		}
+	}
+	return
+}
+
+// GetPeerRouterIDs returns the router itself followed by all routers actively peered with it
+func (a *VpcPeeringAdmin) GetPeerRouterIDs(ctx context.Context, routerID int64) (routerIDs []int64, err error) {
+	ctx, db := GetContextDB(ctx)
+	routerIDs = []int64{routerID}
+	peerings := []*model.VpcPeering{}
+	err = db.Where("(router_id = ? or peer_router_id = ?) and status = ?", routerID, routerID, model.VpcPeeringStatusActive).Find(&peerings).Error
+	if err != nil {
+		logger.Error("Failed to query vpc peerings", err)
+		err = NewCLError(ErrDatabaseError, "Failed to query vpc peerings", err)
+		return
+	}
+	for _, peering := range peerings {
+		if peering.RouterID == routerID {
+			routerIDs = append(routerIDs, peering.PeerRouterID)
+		} else {
+			routerIDs = append(routerIDs, peering.RouterID)
+		}
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) getPeeringData(ctx context.Context, peering *model.VpcPeering) (data []byte, err error) {
+	peeringData := &VpcPeeringData{Routers: []*PeeringRouter{}}
+	for _, routerID := range []int64{peering.RouterID, peering.PeerRouterID} {
+		var subnets []*model.Subnet
+		subnets, err = a.getRouterSubnets(ctx, routerID)
+		if err != nil {
+			return
+		}
+		peeringRouter := &PeeringRouter{ID: routerID, Subnets: []*PeeringSubnet{}}
+		for _, subnet := range subnets {
+			ipNet := subnetCIDR(subnet)
+			if ipNet == nil {
+				continue
+			}
+			peeringRouter.Subnets = append(peeringRouter.Subnets, &PeeringSubnet{Vni: subnet.Vlan, Gateway: subnet.Gateway, Network: ipNet.String()})
+		}
+		peeringData.Routers = append(peeringData.Routers, peeringRouter)
+	}
+	data, err = json.Marshal(peeringData)
+	if err != nil {
+		logger.Error("Failed to marshal vpc peering data", err)
+		return
+	}
+	return
+}
+
+// syncFdb installs the fdb entries of the interfaces of one router on the hypervisors hosting the other router
+func (a *VpcPeeringAdmin) syncFdb(ctx context.Context, peering *model.VpcPeering) (err error) {
+	ctx, db := GetContextDB(ctx)
+	ifaces := []*model.Interface{}
+	err = db.Preload("Address").Preload("Address.Subnet").Where("router_id in (?) and type <> 'gateway' and hyper >= 0", []int64{peering.RouterID, peering.PeerRouterID}).Find(&ifaces).Error
+	if err != nil {
+		logger.Error("Failed to query interfaces of peered routers", err)
+		return
+	}
+	hyperRouters := make(map[int32]map[int64]struct{})
+	hyperIPs := make(map[int32]string)
+	for _, iface := range ifaces {
+		if iface.Address == nil || iface.Address.Subnet == nil || iface.Address.Subnet.Type == string(Public) {
+			continue
+		}
+		if _, ok := hyperIPs[iface.Hyper]; !ok {
+			hyper := &model.Hyper{}
+			hyperErr := db.Where("hostid = ?", iface.Hyper).Take(hyper).Error
+			if hyperErr != nil {
+				logger.Error("Failed to query hypervisor", hyperErr)
+				continue
+			}
+			hyperIPs[iface.Hyper] = hyper.HostIP
+			hyperRouters[iface.Hyper] = make(map[int64]struct{})
+		}
+		hyperRouters[iface.Hyper][iface.RouterID] = struct{}{}
+	}
+	for hyperID, routers := range hyperRouters {
+		rules := []*FdbRule{}
+		for _, iface := range ifaces {
+			if iface.Hyper == hyperID || iface.Address == nil || iface.Address.Subnet == nil || iface.Address.Subnet.Type == string(Public) {
+				continue
+			}
+			if _, ok := routers[iface.RouterID]; ok {
+				continue
+			}
+			outerIP, ok := hyperIPs[iface.Hyper]
+			if !ok {
+				continue
+			}
+			rules = append(rules, &FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: outerIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		fdbJson, _ := json.Marshal(rules)
+		control := fmt.Sprintf("inter=%d", hyperID)
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/add_fwrule.sh <<EOF\n%s\nEOF", fdbJson)
+		err = HyperExecute(ctx, control, command)
+		if err != nil {
+			logger.Error("Add_fwrule execution failed", err)
+			return
+		}
+	}
+	return
+}
+
+// Apply installs the cross vpc routes on the hypervisors hosting either router, hyper < 0 means all hypervisors
+func (a *VpcPeeringAdmin) Apply(ctx context.Context, peering *model.VpcPeering, hyper int32) (err error) {
+	data, err := a.getPeeringData(ctx, peering)
+	if err != nil {
+		return
+	}
+	control := "toall="
+	if hyper >= 0 {
+		control = fmt.Sprintf("inter=%d", hyper)
+	}
+	command := fmt.Sprintf("/opt/cloudland/scripts/backend/create_vpc_peering.sh '%d'<<EOF\n%s\nEOF", peering.ID, data)
+	err = HyperExecute(ctx, control, command)
+	if err != nil {
+		logger.Error("Create vpc peering failed", err)
+		return
+	}
+	return
+}
+
+// SyncRouter re-applies all active peerings of a router to one hypervisor
+func (a *VpcPeeringAdmin) SyncRouter(ctx context.Context, routerID int64, hyper int32) (err error) {
+	ctx, db := GetContextDB(ctx)
+	peerings := []*model.VpcPeering{}
+	err = db.Where("(router_id = ? or peer_router_id = ?) and status = ?", routerID, routerID, model.VpcPeeringStatusActive).Find(&peerings).Error
+	if err != nil {
+		logger.Error("Failed to query vpc peerings", err)
+		return
+	}
+	for _, peering := range peerings {
+		err = a.Apply(ctx, peering, hyper)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) activate(ctx context.Context, peering *model.VpcPeering) (err error) {
+	ctx, db := GetContextDB(ctx)
+	err = a.checkOverlap(ctx, peering.RouterID, peering.PeerRouterID)
+	if err != nil {
+		return
+	}
+	peering.Status = model.VpcPeeringStatusActive
+	err = db.Model(peering).Update("status", peering.Status).Error
+	if err != nil {
+		logger.Error("Failed to update vpc peering status", err)
+		err = NewCLError(ErrVpcPeeringUpdateFailed, "Failed to update vpc peering", err)
+		return
+	}
+	err = a.Apply(ctx, peering, -1)
+	if err != nil {
+		return
+	}
+	err = a.syncFdb(ctx, peering)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// Create requests a peering from a router to a peer router, the peering becomes active
+// immediately if the requester also owns the peer router, otherwise the peer has to accept it
+func (a *VpcPeeringAdmin) Create(ctx context.Context, name string, router, peerRouter *model.Router) (peering *model.VpcPeering, err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("vpc_peering:create").ValidateOwner(model.Writer, router.Owner)
+	if !permit {
+		logger.Error("Not authorized to create vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to create vpc peering", nil)
+		return
+	}
+	if router.ID == peerRouter.ID {
+		err = NewCLError(ErrInvalidParameter, "Can not peer a vpc with itself", nil)
+		return
+	}
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	count := 0
+	err = db.Model(&model.VpcPeering{}).Where("((router_id = ? and peer_router_id = ?) or (router_id = ? and peer_router_id = ?)) and status <> ?", router.ID, peerRouter.ID, peerRouter.ID, router.ID, model.VpcPeeringStatusRejected).Count(&count).Error
+	if err != nil {
+		logger.Error("Failed to count vpc peerings", err)
+		err = NewCLError(ErrDatabaseError, "Failed to count vpc peerings", err)
+		return
+	}
+	if count > 0 {
+		err = NewCLError(ErrVpcPeeringAlreadyExists, "The vpcs are already peered", nil)
+		return
+	}
+	err = a.checkOverlap(ctx, router.ID, peerRouter.ID)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", router.Name, peerRouter.Name)
+	}
+	peering = &model.VpcPeering{Model: model.Model{Creater: memberShip.UserID}, Owner: router.Owner, Name: name, Status: model.VpcPeeringStatusPending, RouterID: router.ID, PeerOwner: peerRouter.Owner, PeerRouterID: peerRouter.ID}
+	err = db.Create(peering).Error
+	if err != nil {
+		logger.Error("DB failed to create vpc peering", err)
+		err = NewCLError(ErrVpcPeeringCreateFailed, "Failed to create vpc peering", err)
+		return
+	}
+	if memberShip.For("vpc_peering:create").ValidateOwner(model.Writer, peerRouter.Owner) {
+		err = a.activate(ctx, peering)
+		if err != nil {
+			return
+		}
+	}
+	peering.Router = router
+	peering.PeerRouter = peerRouter
+	return
+}
+
+// GetPeerRouterByUUID looks up the router to peer with, which may belong to another organization
+func (a *VpcPeeringAdmin) GetPeerRouterByUUID(ctx context.Context, uuID string) (router *model.Router, err error) {
+	ctx, db := GetContextDB(ctx)
+	router = &model.Router{}
+	err = db.Where("uuid = ?", uuID).Take(router).Error
+	if err != nil {
+		logger.Error("Failed to query peer router, %v", err)
+		err = NewCLError(ErrRouterNotFound, "Failed to find peer vpc", err)
+		return
+	}
+	return
+}
+
+// Accept is called by the owner of the peer router to activate a pending peering
+func (a *VpcPeeringAdmin) Accept(ctx context.Context, peering *model.VpcPeering) (err error) {
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("vpc_peering:accept").ValidateOwner(model.Writer, peering.PeerOwner)
+	if !permit {
+		logger.Error("Not authorized to accept the vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to accept the vpc peering", nil)
+		return
+	}
+	if peering.Status != model.VpcPeeringStatusPending {
+		err = NewCLError(ErrVpcPeeringInvalidState, "Only pending vpc peering can be accepted", nil)
+		return
+	}
+	ctx, _, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	err = a.activate(ctx, peering)
+	return
+}
+
+// Reject is called by the owner of the peer router to decline a pending peering
+func (a *VpcPeeringAdmin) Reject(ctx context.Context, peering *model.VpcPeering) (err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("vpc_peering:reject").ValidateOwner(model.Writer, peering.PeerOwner)
+	if !permit {
+		logger.Error("Not authorized to reject the vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to reject the vpc peering", nil)
+		return
+	}
+	if peering.Status != model.VpcPeeringStatusPending {
+		err = NewCLError(ErrVpcPeeringInvalidState, "Only pending vpc peering can be rejected", nil)
+		return
+	}
+	peering.Status = model.VpcPeeringStatusRejected
+	err = db.Model(peering).Update("status", peering.Status).Error
+	if err != nil {
+		logger.Error("Failed to update vpc peering status", err)
+		err = NewCLError(ErrVpcPeeringUpdateFailed, "Failed to update vpc peering", err)
+		return
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) Get(ctx context.Context, id int64) (peering *model.VpcPeering, err error) {
+	if id <= 0 {
+		err = NewCLError(ErrInvalidParameter, fmt.Sprintf("Invalid vpc peering ID: %d", id), nil)
+		logger.Error(err)
+		return
+	}
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := a.getWhere(memberShip)
+	peering = &model.VpcPeering{Model: model.Model{ID: id}}
+	err = db.Preload("Router").Preload("PeerRouter").Where(where).Take(peering).Error
+	if err != nil {
+		logger.Error("Failed to query vpc peering", err)
+		err = NewCLError(ErrVpcPeeringNotFound, "Failed to find vpc peering", err)
+		return
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) GetVpcPeeringByUUID(ctx context.Context, uuID string) (peering *model.VpcPeering, err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	where := a.getWhere(memberShip)
+	peering = &model.VpcPeering{}
+	err = db.Preload("Router").Preload("PeerRouter").Where(where).Where("uuid = ?", uuID).Take(peering).Error
+	if err != nil {
+		logger.Error("Failed to query vpc peering, %v", err)
+		err = NewCLError(ErrVpcPeeringNotFound, "Failed to find vpc peering", err)
+		return
+	}
+	permit := memberShip.For("vpc_peering:get").ValidateOwner(model.Reader, peering.Owner) || memberShip.For("vpc_peering:get").ValidateOwner(model.Reader, peering.PeerOwner)
+	if !permit {
+		logger.Error("Not authorized to read the vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to read the vpc peering", nil)
+		return
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) Update(ctx context.Context, peering *model.VpcPeering, name string) (err error) {
+	ctx, db := GetContextDB(ctx)
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("vpc_peering:update").ValidateOwner(model.Writer, peering.Owner)
+	if !permit {
+		logger.Error("Not authorized to update the vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to update the vpc peering", nil)
+		return
+	}
+	if name != "" && peering.Name != name {
+		peering.Name = name
+		if err = db.Model(peering).Update("name", peering.Name).Error; err != nil {
+			logger.Error("Failed to save vpc peering", err)
+			err = NewCLError(ErrVpcPeeringUpdateFailed, "Failed to update vpc peering", err)
+			return
+		}
+	}
+	return
+}
+
+// Delete tears down a peering, either side of the peering is allowed to do it
+func (a *VpcPeeringAdmin) Delete(ctx context.Context, peering *model.VpcPeering) (err error) {
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	memberShip := GetMemberShip(ctx)
+	permit := memberShip.For("vpc_peering:delete").ValidateOwner(model.Writer, peering.Owner) || memberShip.For("vpc_peering:delete").ValidateOwner(model.Writer, peering.PeerOwner)
+	if !permit {
+		logger.Error("Not authorized to delete the vpc peering")
+		err = NewCLError(ErrPermissionDenied, "Not authorized to delete the vpc peering", nil)
+		return
+	}
+	if peering.Status == model.VpcPeeringStatusActive {
+		var data []byte
+		data, err = a.getPeeringData(ctx, peering)
+		if err != nil {
+			return
+		}
+		control := "toall="
+		command := fmt.Sprintf("/opt/cloudland/scripts/backend/clear_vpc_peering.sh '%d'<<EOF\n%s\nEOF", peering.ID, data)
+		err = HyperExecute(ctx, control, command)
+		if err != nil {
+			logger.Error("Clear vpc peering failed", err)
+			return
+		}
+	}
+	if err = db.Delete(peering).Error; err != nil {
+		logger.Error("DB failed to delete vpc peering", err)
+		err = NewCLError(ErrVpcPeeringDeleteFailed, "Failed to delete vpc peering", err)
+		return
+	}
+	return
+}
+
+func (a *VpcPeeringAdmin) List(ctx context.Context, offset, limit int64, order string, router *model.Router) (total int64, peerings []*model.VpcPeering, err error) {
+	memberShip := GetMemberShip(ctx)
+	ctx, db := GetContextDB(ctx)
+	if limit == 0 {
+		limit = 16
+	}
+
+	if order == "" {
+		order = "created_at"
+	}
+	where := a.getWhere(memberShip)
+	if router != nil {
+		db = db.Where("router_id = ? or peer_router_id = ?", router.ID, router.ID)
+	}
+	peerings = []*model.VpcPeering{}
+	if err = ListFilter(ctx, db.Model(&model.VpcPeering{})).Where(where).Count(&total).Error; err != nil {
+		logger.Error("DB failed to count vpc peerings, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to count vpc peerings", err)
+		return
+	}
+	if err = ListPage(ctx, db, &model.VpcPeering{}, offset, limit, order).Preload("Router").Preload("PeerRouter").Where(where).Find(&peerings).Error; err != nil {
+		logger.Error("DB failed to query vpc peerings, %v", err)
+		err = NewCLError(ErrSQLSyntaxError, "Failed to query vpc peerings", err)
+		return
+	}
+	return
+}
@@ -14,7 +14,6 @@ import (
 	"strconv"
 
 	. "web/src/common"
-	"web/src/dbs"
 	"web/src/model"
 
 	"github.com/go-macaron/session"
@@ -43,12 +42,11 @@ func (a *ZoneAdmin) List(ctx context.Context, offset, limit int64, order, query
 	}
 
 	zones = []*model.Zone{}
-	if err = db.Model(&model.Zone{}).Where(query).Count(&total).Error; err != nil {
+	if err = ListFilter(ctx, db.Model(&model.Zone{})).Where(query).Count(&total).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to count zones", err)
 		return
 	}
-	db = dbs.Sortby(db.Offset(offset).Limit(limit), order)
-	if err = db.Model(&model.Zone{}).Where(query).Find(&zones).Error; err != nil {
+	if err = ListPage(ctx, db, &model.Zone{}, offset, limit, order).Model(&model.Zone{}).Where(query).Find(&zones).Error; err != nil {
 		err = NewCLError(ErrSQLSyntaxError, "Failed to query zones", err)
 		return
 	}
@@ -18,6 +18,7 @@ import (
 )
 
 var volumeAdmin = &routes.VolumeAdmin{}
+var tagAdmin = &routes.TagAdmin{}
 
 func init() {
 	Add("clear_vm", ClearVM)
@@ -48,7 +49,13 @@ func deleteInterfaces(ctx context.Context, instance *model.Instance, vrrpInstanc
 		return
 	}
 	if routerID > 0 {
-		err = db.Where("router_id = ?", routerID).Find(&instances).Error
+		var routerIDs []int64
+		routerIDs, err = vpcPeeringAdmin.GetPeerRouterIDs(ctx, routerID)
+		if err != nil {
+			logger.Error("Failed to query peer routers", err)
+			return
+		}
+		err = db.Where("router_id in (?)", routerIDs).Find(&instances).Error
 		if err != nil {
 			logger.Error("Failed to query all instances", err)
 			return
@@ -59,7 +66,7 @@ func deleteInterfaces(ctx context.Context, instance *model.Instance, vrrpInstanc
 			}
 		}
 		vrrpIfaces := []*model.Interface{}
-		err = db.Where("router_id = ?", routerID).Find(&vrrpIfaces).Error
+		err = db.Where("router_id in (?)", routerIDs).Find(&vrrpIfaces).Error
 		if err != nil {
 			logger.Error("Failed to query all instances", err)
 			return
@@ -118,7 +125,7 @@ func deleteInterfaces(ctx context.Context, instance *model.Instance, vrrpInstanc
 			return
 		}
 		if routerID > 0 && hyperNode >= 0 {
-			spreadRules := []*FdbRule{{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID}}
+			spreadRules := []*routes.FdbRule{{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID}}
 			fdbJson, _ := json.Marshal(spreadRules)
 			control := "toall=" + hyperList
 			command := fmt.Sprintf("/opt/cloudland/scripts/backend/del_fwrule.sh <<EOF\n%s\nEOF", fdbJson)
@@ -165,6 +172,11 @@ func ClearVM(ctx context.Context, args []string) (status string, err error) {
 		reason = err.Error()
 		return
 	}
+	err = dnsAdmin.RemoveInstanceRecords(ctx, instance)
+	if err != nil {
+		logger.Error("Failed to remove dns records", err)
+		return
+	}
 	err = deleteInterfaces(ctx, instance, nil, nil)
 	if err != nil {
 		logger.Error("Failed to delete interfaces", err)
@@ -174,6 +186,10 @@ func ClearVM(ctx context.Context, args []string) (status string, err error) {
 		logger.Error("Failed to delete instance, %v", err)
 		return
 	}
+	if err = tagAdmin.Clear(ctx, "instances", instance.ID); err != nil {
+		logger.Error("Failed to delete instance tags", err)
+		return
+	}
 	if err = volumeAdmin.UpdateDataVolumeStatus(ctx, instance.ID, model.VolumeStatusAvailable); err != nil {
 		logger.Error("Failed to update attached volumes", err)
 		return
@@ -0,0 +1,64 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func init() {
+	Add("console_log", ConsoleLog)
+}
+
+func ConsoleLog(ctx context.Context, args []string) (status string, err error) {
+	//|:-COMMAND-:| console_log.sh '5' '100' 'base64 of the log lines'
+	ctx, db := GetContextDB(ctx)
+	argn := len(args)
+	if argn < 3 {
+		err = fmt.Errorf("Wrong params")
+		logger.Error("Invalid args", err)
+		return
+	}
+	instID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		logger.Error("Invalid instance ID", err)
+		return
+	}
+	lines, err := strconv.ParseInt(args[2], 10, 32)
+	if err != nil {
+		logger.Error("Invalid lines", err)
+		return
+	}
+	output := []byte{}
+	if argn > 3 && args[3] != "" {
+		output, err = base64.StdEncoding.DecodeString(args[3])
+		if err != nil {
+			logger.Error("Invalid console log", err)
+			return
+		}
+	}
+	// serial logs carry control bytes the database does not take in text
+	text := strings.ToValidUTF8(strings.ReplaceAll(string(output), "\x00", ""), "")
+	err = db.Where("instance_id = ?", instID).Assign(map[string]interface{}{
+		"lines":      int32(lines),
+		"output":     text,
+		"updated_at": time.Now(),
+	}).FirstOrCreate(&model.ConsoleLog{InstanceID: instID}).Error
+	if err != nil {
+		logger.Error("Failed to save console log", err)
+		return
+	}
+	return
+}
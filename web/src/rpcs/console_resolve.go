@@ -48,8 +48,11 @@ func ResolveToken(ctx context.Context, tokenString string) (int, *MemberShip, er
 	if !ok || !token.Valid {
 		return 0, nil, errors.New("invalid token")
 	}
+	if claims.Type != "" && claims.Type != model.ConsoleTypeVnc {
+		return 0, nil, errors.New("not a vnc console token")
+	}
 	instanceID := claims.InstanceID
-	console := &model.Console{Instance: int64(instanceID)}
+	console := &model.Console{Instance: int64(instanceID), Type: model.ConsoleTypeVnc}
 	ctx, db := GetContextDB(ctx)
 	err = db.Where(console).Take(console).Error
 	if err != nil {
@@ -9,6 +9,7 @@ package rpcs
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	. "web/src/common"
 	"web/src/model"
@@ -19,7 +20,7 @@ func init() {
 }
 
 func CreatePortmap(ctx context.Context, args []string) (status string, err error) {
-	//|:-COMMAND-:| create_portmap.sh 1.2.3.4 18010
+	//|:-COMMAND-:| create_portmap.sh 1.2.3.4 18010 5
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
 		if newTransaction {
@@ -32,9 +33,19 @@ func CreatePortmap(ctx context.Context, args []string) (status string, err error
 		logger.Error("Invalid args", err)
 		return
 	}
-	err = db.Model(&model.Portmap{}).Where("remote_port = ?", args[2]).Updates(map[string]interface{}{"remote_address": args[1], "status": "ready"}).Error
+	query := db.Model(&model.Portmap{}).Where("remote_port = ?", args[2])
+	if argn >= 4 {
+		routerID, er := strconv.Atoi(args[3])
+		if er != nil {
+			logger.Error("Invalid router ID", er)
+			err = er
+			return
+		}
+		query = query.Where("router_id = ?", routerID)
+	}
+	err = query.Updates(map[string]interface{}{"remote_address": args[1], "status": "ready"}).Error
 	if err != nil {
-		logger.Error("Update hyper/Peer ID failed", err)
+		logger.Error("Update portmap remote address failed", err)
 		return
 	}
 	return
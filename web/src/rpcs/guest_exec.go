@@ -0,0 +1,64 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func init() {
+	Add("guest_exec", GuestExec)
+	Add("guest_file", GuestExec)
+}
+
+func GuestExec(ctx context.Context, args []string) (status string, err error) {
+	//|:-COMMAND-:| guest_exec.sh '3' 'done' '0' 'base64 of the output'
+	//|:-COMMAND-:| guest_file.sh '4' 'error' '0' ''
+	ctx, db := GetContextDB(ctx)
+	argn := len(args)
+	if argn < 4 {
+		err = fmt.Errorf("Wrong params")
+		logger.Error("Invalid args", err)
+		return
+	}
+	cmdID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		logger.Error("Invalid guest command ID", err)
+		return
+	}
+	exitCode, err := strconv.ParseInt(args[3], 10, 32)
+	if err != nil {
+		logger.Error("Invalid exit code", err)
+		return
+	}
+	output := []byte{}
+	if argn > 4 && args[4] != "" {
+		output, err = base64.StdEncoding.DecodeString(args[4])
+		if err != nil {
+			logger.Error("Invalid guest command output", err)
+			return
+		}
+	}
+	text := strings.ToValidUTF8(strings.ReplaceAll(string(output), "\x00", ""), "")
+	err = db.Model(&model.GuestCommand{Model: model.Model{ID: cmdID}}).Updates(map[string]interface{}{
+		"status":    args[2],
+		"exit_code": int32(exitCode),
+		"output":    text,
+	}).Error
+	if err != nil {
+		logger.Error("Failed to update guest command", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,64 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func init() {
+	Add("guest_info", GuestInfo)
+}
+
+func GuestInfo(ctx context.Context, args []string) (status string, err error) {
+	//|:-COMMAND-:| guest_info.sh '5' 'base64 of the os name' '192.168.1.5,fd00::5'
+	ctx, db := GetContextDB(ctx)
+	argn := len(args)
+	if argn < 4 {
+		err = fmt.Errorf("Wrong params")
+		logger.Error("Invalid args", err)
+		return
+	}
+	instID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		logger.Error("Invalid instance ID", err)
+		return
+	}
+	guestOS, err := base64.StdEncoding.DecodeString(args[2])
+	if err != nil {
+		logger.Error("Invalid guest os", err)
+		return
+	}
+	// keep within the columns, a guest with too many addresses reports the first ones
+	osName, ips := strings.ToValidUTF8(string(guestOS), ""), args[3]
+	if len(osName) > 128 {
+		osName = strings.ToValidUTF8(osName[:128], "")
+	}
+	if len(ips) > 512 {
+		ips = ips[:strings.LastIndex(ips[:513], ",")+1]
+		ips = strings.TrimSuffix(ips, ",")
+	}
+	now := time.Now()
+	err = db.Model(&model.Instance{Model: model.Model{ID: instID}}).Updates(map[string]interface{}{
+		"guest_os":      osName,
+		"guest_ips":     ips,
+		"guest_info_at": &now,
+	}).Error
+	if err != nil {
+		logger.Error("Failed to update guest info", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,64 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func init() {
+	Add("hotplug_vm", HotplugVM)
+}
+
+func HotplugVM(ctx context.Context, args []string) (status string, err error) {
+	//|:-COMMAND-:| hotplug_vm.sh '5' '4' '8192' 'running'
+	ctx, db, newTransaction := StartTransaction(ctx)
+	defer func() {
+		if newTransaction {
+			EndTransaction(ctx, err)
+		}
+	}()
+	argn := len(args)
+	if argn < 5 {
+		err = fmt.Errorf("Wrong params")
+		logger.Error("Invalid args", err)
+		return
+	}
+	instID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		logger.Error("Invalid instance ID", err)
+		return
+	}
+	instance := &model.Instance{Model: model.Model{ID: instID}}
+	err = db.Take(instance).Error
+	if err != nil {
+		logger.Error("Invalid instance ID", err)
+		return
+	}
+	status = args[4]
+	values := map[string]interface{}{"status": status}
+	// the hypervisor reports the size the guest really has, a failed hot-add keeps the old one
+	cpu, err := strconv.ParseInt(args[2], 10, 32)
+	if err == nil && cpu > 0 {
+		values["cpu"] = cpu
+	}
+	memory, err := strconv.ParseInt(args[3], 10, 32)
+	if err == nil && memory > 0 {
+		values["memory"] = memory
+	}
+	err = db.Model(instance).Updates(values).Error
+	if err != nil {
+		logger.Error("Failed to update instance", err)
+		return
+	}
+	return
+}
@@ -20,7 +20,7 @@ func init() {
 }
 
 func HyperStatus(ctx context.Context, args []string) (status string, err error) {
-	//"|:-COMMAND-:| hyper_status.sh '$SCI_CLIENT_ID' '$HOSTNAME' '$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state' '$vtep_ip' '$ZONE_NAME' '$cpu_over_rate' '$mem_over_rate' '$disk_over_rate' '$cpu_model'"
+	//"|:-COMMAND-:| hyper_status.sh '$SCI_CLIENT_ID' '$HOSTNAME' '$cpu' '$total_cpu' '$memory' '$total_memory' '$disk' '$total_disk' '$state' '$vtep_ip' '$ZONE_NAME' '$cpu_over_rate' '$mem_over_rate' '$disk_over_rate' '$cpu_model' '$numa_nodes' '$dedicated_cpus' '$hp_2m_free' '$hp_1g_free' '$cpu_models' '$cpu_flags'"
 	logger.Debugf("HyperStatus updates %+v", args)
 	ctx, db, newTransaction := StartTransaction(ctx)
 	defer func() {
@@ -29,7 +29,7 @@ func HyperStatus(ctx context.Context, args []string) (status string, err error)
 		}
 	}()
 	argn := len(args)
-	if argn < 15 {
+	if argn < 16 {
 		err = fmt.Errorf("Wrong params")
 		logger.Error("Invalid args", err)
 		return
@@ -119,14 +119,31 @@ func HyperStatus(ctx context.Context, args []string) (status string, err error)
 	// end PET-769
 	// PET-1218 fix hyper status
 	logger.Debugf("Updating hypervisor %s status to %d", hyperName, hyperStatus)
-	err = db.Model(&model.Hyper{}).Where("hostid = ?", hyperID).Updates(map[string]interface{}{
+	values := map[string]interface{}{
 		"hostname":  hyperName,
 		"status":    hyperStatus,
 		"cpu_model": cpuModel,
 		"virt_type": "kvm-x86_64",
 		"zone":      zone,
 		"host_ip":   hostIP,
-	}).Error
+	}
+	// args 16 to 21 are the capabilities checked against flavor extra specs, older hypervisors do not report them
+	if argn >= 22 {
+		numaNodes, _ := strconv.Atoi(args[16])
+		freePinCpus, _ := strconv.Atoi(args[17])
+		hugepages2M, _ := strconv.ParseInt(args[18], 10, 64)
+		hugepages1G, _ := strconv.ParseInt(args[19], 10, 64)
+		if numaNodes < 1 {
+			numaNodes = 1
+		}
+		values["numa_nodes"] = numaNodes
+		values["free_pin_cpus"] = freePinCpus
+		values["hugepages_2m"] = hugepages2M
+		values["hugepages_1g"] = hugepages1G
+		values["cpu_models"] = args[20]
+		values["cpu_flags"] = args[21]
+	}
+	err = db.Model(&model.Hyper{}).Where("hostid = ?", hyperID).Updates(values).Error
 	if err != nil {
 		logger.Error("Failed to update hyper", err)
 		return
@@ -140,6 +157,9 @@ func HyperStatus(ctx context.Context, args []string) (status string, err error)
 		Disk:        int64(availDisk),
 		DiskTotal:   int64(totalDisk),
 	}
+	resource.CpuOverRate = hyper.CpuOverRate
+	resource.MemOverRate = hyper.MemOverRate
+	resource.DiskOverRate = hyper.DiskOverRate
 	err = db.Where("hostid = ?", hyperID).Assign(resource).FirstOrCreate(&model.Resource{}).Error
 	if err != nil {
 		logger.Error("Failed to create or update hyper resource", err)
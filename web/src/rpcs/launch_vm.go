@@ -20,29 +20,23 @@ import (
 )
 
 var floatingIpAdmin = &routes.FloatingIpAdmin{}
+var routeTableAdmin = &routes.RouteTableAdmin{}
+var vpcPeeringAdmin = &routes.VpcPeeringAdmin{}
+var natGatewayAdmin = &routes.NatGatewayAdmin{}
+var dnsAdmin = &routes.DnsAdmin{}
 
 func init() {
 	Add("launch_vm", LaunchVM)
 }
 
-type FdbRule struct {
-	Instance string `json:"instance"`
-	Vni      int64  `json:"vni"`
-	InnerIP  string `json:"inner_ip"`
-	InnerMac string `json:"inner_mac"`
-	OuterIP  string `json:"outer_ip"`
-	Gateway  string `json:"gateway"`
-	Router   int64  `json:"router"`
-}
-
 func sendFdbRules(ctx context.Context, instance *model.Instance, vrrpInstance *model.VrrpInstance, instIface *model.Interface) (err error) {
 	if instance != nil && instance.RouterID == 0 {
 		logger.Error("No need to send fdb for classic")
 		return
 	}
 	ctx, db := GetContextDB(ctx)
-	localRules := []*FdbRule{}
-	spreadRules := []*FdbRule{}
+	localRules := []*routes.FdbRule{}
+	spreadRules := []*routes.FdbRule{}
 	hyperNode := int32(-1)
 	var interfaces []*model.Interface
 	routerID := int64(0)
@@ -70,12 +64,17 @@ func sendFdbRules(ctx context.Context, instance *model.Instance, vrrpInstance *m
 	for _, iface := range interfaces {
 		subnetType := iface.Address.Subnet.Type
 		if subnetType != string(Public) {
-			spreadRules = append(spreadRules, &FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
+			spreadRules = append(spreadRules, &routes.FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
 		}
 	}
+	routerIDs, err := vpcPeeringAdmin.GetPeerRouterIDs(ctx, routerID)
+	if err != nil {
+		logger.Error("Failed to query peer routers", err)
+		return
+	}
 	allIfaces := []*model.Interface{}
 	hyperSet := make(map[int32]struct{})
-	err = db.Preload("Address").Preload("Address.Subnet").Preload("Address.Subnet.Router").Where("router_id = ? and type <> 'gateway' and hyper <> ?", routerID, hyperNode).Find(&allIfaces).Error
+	err = db.Preload("Address").Preload("Address.Subnet").Preload("Address.Subnet.Router").Where("router_id in (?) and type <> 'gateway' and hyper <> ?", routerIDs, hyperNode).Find(&allIfaces).Error
 	if err != nil {
 		logger.Error("Failed to query all interfaces", err)
 		return
@@ -97,7 +96,7 @@ func sendFdbRules(ctx context.Context, instance *model.Instance, vrrpInstance *m
 		if iface.Hyper >= 0 {
 			hyperSet[iface.Hyper] = struct{}{}
 		}
-		localRules = append(localRules, &FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
+		localRules = append(localRules, &routes.FdbRule{Instance: iface.Name, Vni: iface.Address.Subnet.Vlan, InnerIP: iface.Address.Address, InnerMac: iface.MacAddr, OuterIP: hyper.HostIP, Gateway: iface.Address.Subnet.Gateway, Router: iface.Address.Subnet.RouterID})
 	}
 	if len(hyperSet) > 0 && len(spreadRules) > 0 {
 		hyperList := fmt.Sprintf("group-fdb-%d", hyperNode)
@@ -234,6 +233,33 @@ func LaunchVM(ctx context.Context, args []string) (status string, err error) {
 			}
 		}
 	}
+	if serverStatus == string(model.InstanceStatusRunning) && instance.RouterID > 0 {
+		err = routeTableAdmin.SyncRouter(ctx, instance.RouterID, instance.Hyper)
+		if err != nil {
+			logger.Error("Failed to sync route tables", err)
+			err = nil
+		}
+		err = vpcPeeringAdmin.SyncRouter(ctx, instance.RouterID, instance.Hyper)
+		if err != nil {
+			logger.Error("Failed to sync vpc peerings", err)
+			err = nil
+		}
+		err = natGatewayAdmin.SyncRouter(ctx, instance.RouterID, instance.Hyper)
+		if err != nil {
+			logger.Error("Failed to sync nat gateways", err)
+			err = nil
+		}
+		err = dnsAdmin.AddInstanceRecords(ctx, instance)
+		if err != nil {
+			logger.Error("Failed to add dns records", err)
+			err = nil
+		}
+		err = dnsAdmin.SyncRouter(ctx, instance.RouterID, instance.Hyper)
+		if err != nil {
+			logger.Error("Failed to sync dns zones", err)
+			err = nil
+		}
+	}
 	return
 }
 
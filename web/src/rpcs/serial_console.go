@@ -0,0 +1,56 @@
+/*
+Copyright <holder> All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	. "web/src/common"
+	"web/src/model"
+)
+
+func init() {
+	Add("serial_console", SerialConsole)
+}
+
+func SerialConsole(ctx context.Context, args []string) (status string, err error) {
+	//|:-COMMAND-:| serial_console.sh '12' '192.168.10.100' '16000'
+	db := DB()
+	argn := len(args)
+	if argn < 4 {
+		err = fmt.Errorf("Wrong params")
+		logger.Error("Invalid args", err)
+		return
+	}
+	sessionID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		logger.Error("Invalid session ID", err)
+		return
+	}
+	port, err := strconv.Atoi(args[3])
+	if err != nil {
+		logger.Error("Invalid port number", err)
+		return
+	}
+	values := map[string]interface{}{
+		"local_address": args[2],
+		"local_port":    int32(port),
+	}
+	if port == 0 {
+		// the hypervisor could not expose the console, the session ends before it starts
+		values["ended_at"] = time.Now()
+	}
+	err = db.Model(&model.ConsoleSession{Model: model.Model{ID: sessionID}}).Updates(values).Error
+	if err != nil {
+		logger.Error("Failed to update console session", err)
+		return
+	}
+	return
+}